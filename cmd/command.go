@@ -17,13 +17,16 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
 	"github.com/chronicleprotocol/oracle-suite/pkg/supervisor"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/hcl"
 )
@@ -44,16 +47,24 @@ func NewRootCommand(name, version string, sets ...FlagSetter) *cobra.Command {
 }
 
 func NewRunCmd(cfg supervisor.Config, cf *ConfigFlags, lf *LoggerFlags) *cobra.Command {
+	var watchInterval time.Duration
 	cmd := &cobra.Command{
 		Use:     "run",
 		Args:    cobra.NoArgs,
 		Short:   "Run the main service",
 		Aliases: []string{"agent", "server"},
+		Long: `Run the main service.
+
+If the services support it, the configuration is reloaded every time the
+config files, or files included by them, change and when the SIGHUP signal
+is received. A configuration that fails to load is rejected and the running
+one is kept.`,
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			if err := cf.Load(cfg); err != nil {
 				return err
 			}
-			s, err := cfg.Services(lf.Logger(), cmd.Root().Use, cmd.Root().Version)
+			logger := lf.Logger()
+			s, err := cfg.Services(logger, cmd.Root().Use, cmd.Root().Version)
 			if err != nil {
 				return err
 			}
@@ -62,12 +73,21 @@ func NewRunCmd(cfg supervisor.Config, cf *ConfigFlags, lf *LoggerFlags) *cobra.C
 			if err = s.Start(ctx); err != nil {
 				return err
 			}
+			if r, ok := s.(supervisor.Reloadable); ok {
+				go newReloader(r, cfg, cf, watchInterval, logger).run(ctx)
+			}
 			return <-s.Wait()
 		},
 	}
 	flags := cmd.Flags()
 	flags.AddFlagSet(cf.FlagSet())
 	flags.AddFlagSet(lf.FlagSet())
+	flags.DurationVar(
+		&watchInterval,
+		"config.watch-interval",
+		5*time.Second,
+		"interval at which config files are checked for changes, 0 disables watching",
+	)
 	return cmd
 }
 
@@ -88,12 +108,49 @@ func NewRenderConfigCmd(cfg supervisor.Config, cf *ConfigFlags) *cobra.Command {
 			if diags.HasErrors() {
 				return diags
 			}
-			fmt.Println(string(content))
+			fmt.Println(string(config.RedactSecrets(content)))
 			return nil
 		},
 	}
 	flags := cmd.Flags()
 	flags.AddFlagSet(cf.FlagSet())
+	cmd.AddCommand(NewConfigSchemaCmd(cfg))
+	return cmd
+}
+
+func NewConfigSchemaCmd(cfg supervisor.Config) *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Args:  cobra.NoArgs,
+		Short: "Print the config schema",
+		Long: `Print the description of all blocks and attributes supported in the
+config file, either as a JSON Schema or as a Markdown reference.
+
+The JSON Schema describes the config in the HCL JSON syntax and can be used
+by editors to validate and autocomplete config files.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			schema, diags := hcl.GetSchema(cfg)
+			if diags.HasErrors() {
+				return diags
+			}
+			title := fmt.Sprintf("%s configuration", cmd.Root().Use)
+			switch format {
+			case "json":
+				b, err := json.MarshalIndent(schema.JSONSchema(title), "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(b))
+			case "markdown":
+				fmt.Print(schema.Markdown(title))
+			default:
+				return fmt.Errorf("unsupported format %q, must be one of: json, markdown", format)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "json", "output format: json or markdown")
 	return cmd
 }
 
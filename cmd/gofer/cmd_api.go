@@ -0,0 +1,102 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chronicleprotocol/oracle-suite/cmd"
+	gofer "github.com/chronicleprotocol/oracle-suite/pkg/config/gofernext"
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/httpapi"
+	"github.com/chronicleprotocol/oracle-suite/pkg/httpserver"
+	"github.com/chronicleprotocol/oracle-suite/pkg/httpserver/middleware"
+	"github.com/chronicleprotocol/oracle-suite/pkg/supervisor"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/treerender"
+)
+
+func NewAPICmd(cfg supervisor.Config, cf *cmd.ConfigFlags, lf *cmd.LoggerFlags) *cobra.Command {
+	var (
+		listen   string
+		interval time.Duration
+	)
+	cc := &cobra.Command{
+		Use:   "api [MODEL...]",
+		Args:  cobra.MinimumNArgs(0),
+		Short: "Serve data points for given models over HTTP",
+		RunE: func(cc *cobra.Command, args []string) (err error) {
+			if err := cf.Load(cfg); err != nil {
+				return err
+			}
+			// Terminal colors make no sense in HTTP responses.
+			treerender.NoColors = true
+			services, err := cfg.Services(lf.Logger(), cc.Root().Use, cc.Root().Version)
+			if err != nil {
+				return err
+			}
+			s, ok := services.(*gofer.Services)
+			if !ok {
+				return fmt.Errorf("services are not gofer.Services")
+			}
+			srv := httpserver.New(&http.Server{
+				Addr:              listen,
+				ReadHeaderTimeout: 10 * time.Second,
+			})
+			srv.Use(&middleware.Recover{}, &middleware.Logger{Log: s.Logger})
+			api, err := httpapi.New(httpapi.Config{
+				Provider: s.DataProvider,
+				Server:   srv,
+				Ticker:   timeutil.NewTicker(interval),
+				Models:   args,
+				Logger:   s.Logger,
+			})
+			if err != nil {
+				return err
+			}
+			ctx, ctxCancel := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer ctxCancel()
+			sup := supervisor.New(s.Logger)
+			sup.Watch(services, api)
+			if s.Metrics != nil {
+				sup.Watch(s.Metrics)
+			}
+			if err = sup.Start(ctx); err != nil {
+				return err
+			}
+			return <-sup.Wait()
+		},
+	}
+	cc.Flags().StringVar(
+		&listen,
+		"listen",
+		"127.0.0.1:8080",
+		"address to listen on",
+	)
+	cc.Flags().DurationVar(
+		&interval,
+		"interval",
+		time.Minute,
+		"interval between data point updates",
+	)
+	return cc
+}
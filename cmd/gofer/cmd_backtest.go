@@ -0,0 +1,357 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chronicleprotocol/oracle-suite/cmd"
+	dataproviderConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/dataprovider"
+	ethereumConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/ethereum"
+	gofer "github.com/chronicleprotocol/oracle-suite/pkg/config/gofernext"
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/backtest"
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/origin"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/httpfixture"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/maputil"
+)
+
+func NewBacktestCmd(cfg *gofer.Config, cf *cmd.ConfigFlags, lf *cmd.LoggerFlags) *cobra.Command {
+	var (
+		format    formatTypeValue
+		from      string
+		to        string
+		step      time.Duration
+		snapshots string
+	)
+	cc := &cobra.Command{
+		Use:   "backtest MODEL...",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "Evaluate data models over a historical period",
+		Long: `Evaluate data models over a historical period.
+
+EVM origins read the state at the last block before every step, which requires
+archive nodes. HTTP origins are served from fixture snapshots recorded using
+the --record flag, e.g. "gofer data --record DIR/$(date +%s)". Without the
+--snapshots flag, HTTP origins return errors.`,
+		RunE: func(cc *cobra.Command, args []string) (err error) {
+			if format.String() == formatTrace {
+				return fmt.Errorf("unsupported format: %s", formatTrace)
+			}
+			fromTime, err := parseBacktestTime(from)
+			if err != nil {
+				return fmt.Errorf("invalid --from flag: %w", err)
+			}
+			toTime, err := parseBacktestTime(to)
+			if err != nil {
+				return fmt.Errorf("invalid --to flag: %w", err)
+			}
+			var snaps backtest.Snapshots
+			if snapshots != "" {
+				if snaps, err = backtest.LoadSnapshots(snapshots); err != nil {
+					return err
+				}
+			}
+			if err := cf.Load(cfg); err != nil {
+				return err
+			}
+			services, err := cfg.Services(lf.Logger(), cc.Root().Use, cc.Root().Version)
+			if err != nil {
+				return err
+			}
+			ctx, ctxCancel := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer ctxCancel()
+			if err = services.Start(ctx); err != nil {
+				return err
+			}
+			s, ok := services.(*gofer.Services)
+			if !ok {
+				return fmt.Errorf("services are not gofer.Services")
+			}
+			finders := make(map[string]*backtest.BlockFinder, len(s.Clients))
+			for name, client := range s.Clients {
+				finders[name] = backtest.NewBlockFinder(client)
+			}
+			reports, err := backtest.Run(ctx, backtest.Config{
+				Provider: func(_ context.Context, t time.Time) (datapoint.Provider, error) {
+					clients := make(ethereumConfig.ClientRegistry, len(finders))
+					for name, finder := range finders {
+						clients[name] = backtest.NewClient(finder, t)
+					}
+					transport, err := snapshotTransport(snaps, t)
+					if err != nil {
+						return nil, err
+					}
+					return cfg.Gofer.ConfigureDataProvider(dataproviderConfig.Dependencies{
+						HTTPClient: &http.Client{Transport: transport},
+						Clients:    clients,
+						Logger:     s.Logger,
+						OriginWrapper: func(_ string, o origin.Origin) origin.Origin {
+							return backtest.NewOrigin(o, t)
+						},
+					})
+				},
+				Models: args,
+				From:   fromTime,
+				To:     toTime,
+				Step:   step,
+			})
+			if err != nil {
+				return err
+			}
+			marshaled, err := marshalBacktestReports(reports, format.String())
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(marshaled))
+			return nil
+		},
+	}
+	cc.Flags().VarP(
+		&format,
+		"format",
+		"o",
+		"output format (plain or json)",
+	)
+	cc.Flags().StringVar(
+		&from,
+		"from",
+		"",
+		"start time, as RFC3339 time or Unix timestamp",
+	)
+	cc.Flags().StringVar(
+		&to,
+		"to",
+		"",
+		"end time, as RFC3339 time or Unix timestamp",
+	)
+	cc.Flags().DurationVar(
+		&step,
+		"step",
+		time.Minute,
+		"interval between evaluations",
+	)
+	cc.Flags().StringVar(
+		&snapshots,
+		"snapshots",
+		"",
+		"directory with HTTP fixture snapshots, one subdirectory per snapshot named by its Unix timestamp",
+	)
+	_ = cc.MarkFlagRequired("from")
+	_ = cc.MarkFlagRequired("to")
+	return cc
+}
+
+// snapshotTransport returns an HTTP transport that serves responses from
+// the last snapshot recorded before the given time.
+func snapshotTransport(snaps backtest.Snapshots, t time.Time) (http.RoundTripper, error) {
+	if snaps == nil {
+		return errorTransport{err: fmt.Errorf("HTTP origins require the --snapshots flag")}, nil
+	}
+	snap, ok := snaps.At(t)
+	if !ok {
+		return errorTransport{err: fmt.Errorf("no snapshot recorded before %s", t.Format(time.RFC3339))}, nil
+	}
+	fixtures, err := httpfixture.New(httpfixture.Replay, snap.Dir)
+	if err != nil {
+		return nil, err
+	}
+	return fixtures.Transport("http", nil), nil
+}
+
+// errorTransport is an HTTP transport that always returns an error.
+type errorTransport struct {
+	err error
+}
+
+func (t errorTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, t.err
+}
+
+func parseBacktestTime(s string) (time.Time, error) {
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(ts, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func marshalBacktestReports(reports map[string]*backtest.Report, format string) ([]byte, error) {
+	switch format {
+	case formatPlain:
+		return marshalBacktestReportsPlain(reports)
+	case formatJSON:
+		return marshalBacktestReportsJSON(reports)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+func marshalBacktestReportsPlain(reports map[string]*backtest.Report) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, name := range maputil.SortKeys(reports, sort.Strings) {
+		r := reports[name]
+		if i > 0 {
+			buf.WriteString("\n\n")
+		}
+		buf.WriteString(name)
+		buf.WriteString("\n  samples:")
+		for _, s := range r.Samples {
+			buf.WriteString(fmt.Sprintf("\n    %s %s", s.Time.In(time.UTC).Format(time.RFC3339), samplePrint(s)))
+		}
+		if len(r.Medians) > 0 {
+			buf.WriteString("\n  medians:")
+			for _, path := range maputil.SortKeys(r.Medians, sort.Strings) {
+				m := r.Medians[path]
+				buf.WriteString(fmt.Sprintf(
+					"\n    %s: min values: %d, fired: %d/%d, min valid values: %d",
+					path,
+					m.MinValues,
+					m.Fired,
+					m.Evaluations,
+					m.MinValid,
+				))
+				if len(m.Sources) > 0 {
+					buf.WriteString("\n      sources:")
+				}
+				for _, name := range maputil.SortKeys(m.Sources, sort.Strings) {
+					src := m.Sources[name]
+					buf.WriteString(fmt.Sprintf(
+						"\n        %s: samples: %d, errors: %d, mean deviation: %s, max deviation: %s",
+						name,
+						src.Samples,
+						src.Errors,
+						percent(src.MeanDeviation),
+						percent(src.MaxDeviation),
+					))
+				}
+			}
+		}
+		if len(r.CircuitBreakers) > 0 {
+			buf.WriteString("\n  circuit breakers:")
+			for _, path := range maputil.SortKeys(r.CircuitBreakers, sort.Strings) {
+				cb := r.CircuitBreakers[path]
+				buf.WriteString(fmt.Sprintf(
+					"\n    %s: threshold: %s, fired: %d/%d, max deviation: %s",
+					path,
+					percent(cb.Threshold),
+					cb.Fired,
+					cb.Evaluations,
+					percent(cb.MaxDeviation),
+				))
+			}
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func marshalBacktestReportsJSON(reports map[string]*backtest.Report) ([]byte, error) {
+	type sample struct {
+		Time  string `json:"time"`
+		Value string `json:"value,omitempty"`
+		Error string `json:"error,omitempty"`
+	}
+	type source struct {
+		Samples       int     `json:"samples"`
+		Errors        int     `json:"errors"`
+		MeanDeviation float64 `json:"mean_deviation"`
+		MaxDeviation  float64 `json:"max_deviation"`
+	}
+	type median struct {
+		MinValues   int               `json:"min_values"`
+		Evaluations int               `json:"evaluations"`
+		Fired       int               `json:"fired"`
+		MinValid    int               `json:"min_valid_values"`
+		Sources     map[string]source `json:"sources"`
+	}
+	type circuitBreaker struct {
+		Threshold    float64 `json:"threshold"`
+		Evaluations  int     `json:"evaluations"`
+		Fired        int     `json:"fired"`
+		MaxDeviation float64 `json:"max_deviation"`
+	}
+	type report struct {
+		Samples         []sample                  `json:"samples"`
+		Medians         map[string]median         `json:"medians"`
+		CircuitBreakers map[string]circuitBreaker `json:"circuit_breakers"`
+	}
+	out := make(map[string]report, len(reports))
+	for name, r := range reports {
+		rep := report{
+			Samples:         make([]sample, 0, len(r.Samples)),
+			Medians:         make(map[string]median, len(r.Medians)),
+			CircuitBreakers: make(map[string]circuitBreaker, len(r.CircuitBreakers)),
+		}
+		for _, s := range r.Samples {
+			js := sample{Time: s.Time.In(time.UTC).Format(time.RFC3339)}
+			if err := s.Point.Validate(); err != nil {
+				js.Error = err.Error()
+			} else {
+				js.Value = s.Point.Value.Print()
+			}
+			rep.Samples = append(rep.Samples, js)
+		}
+		for p, m := range r.Medians {
+			med := median{
+				MinValues:   m.MinValues,
+				Evaluations: m.Evaluations,
+				Fired:       m.Fired,
+				MinValid:    m.MinValid,
+				Sources:     make(map[string]source, len(m.Sources)),
+			}
+			for n, s := range m.Sources {
+				med.Sources[n] = source{
+					Samples:       s.Samples,
+					Errors:        s.Errors,
+					MeanDeviation: s.MeanDeviation,
+					MaxDeviation:  s.MaxDeviation,
+				}
+			}
+			rep.Medians[p] = med
+		}
+		for p, cb := range r.CircuitBreakers {
+			rep.CircuitBreakers[p] = circuitBreaker{
+				Threshold:    cb.Threshold,
+				Evaluations:  cb.Evaluations,
+				Fired:        cb.Fired,
+				MaxDeviation: cb.MaxDeviation,
+			}
+		}
+		out[name] = rep
+	}
+	return json.Marshal(out)
+}
+
+func samplePrint(s backtest.Sample) string {
+	if err := s.Point.Validate(); err != nil {
+		return err.Error()
+	}
+	return s.Point.Value.Print()
+}
+
+func percent(f float64) string {
+	return strconv.FormatFloat(f*100, 'f', 4, 64) + "%"
+}
@@ -23,6 +23,7 @@ import (
 	"os"
 	"os/signal"
 	"sort"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -31,11 +32,16 @@ import (
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
 	"github.com/chronicleprotocol/oracle-suite/pkg/supervisor"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/maputil"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/treerender"
 )
 
 func NewDataCmd(cfg supervisor.Config, cf *cmd.ConfigFlags, lf *cmd.LoggerFlags) *cobra.Command {
-	var format formatTypeValue
+	var (
+		format   formatTypeValue
+		watch    bool
+		interval time.Duration
+	)
 	cmd := &cobra.Command{
 		Use:     "data [MODEL...]",
 		Aliases: []string{"price", "prices"},
@@ -58,6 +64,18 @@ func NewDataCmd(cfg supervisor.Config, cf *cmd.ConfigFlags, lf *cmd.LoggerFlags)
 			if !ok {
 				return fmt.Errorf("services are not gofer.Services")
 			}
+			if watch {
+				ticker := timeutil.NewTicker(interval)
+				ticker.Start(ctx)
+				return watchDataPoints(
+					ctx,
+					s.DataProvider,
+					getModelsNames(ctx, s.DataProvider, args),
+					ticker,
+					format.String(),
+					os.Stdout,
+				)
+			}
 			points, err := s.DataProvider.DataPoints(ctx, getModelsNames(ctx, s.DataProvider, args)...)
 			if err != nil {
 				return err
@@ -76,6 +94,19 @@ func NewDataCmd(cfg supervisor.Config, cf *cmd.ConfigFlags, lf *cmd.LoggerFlags)
 		"o",
 		"output format",
 	)
+	cmd.Flags().BoolVarP(
+		&watch,
+		"watch",
+		"w",
+		false,
+		"refresh data points periodically and show changes, in the json format every update is printed as a single line",
+	)
+	cmd.Flags().DurationVar(
+		&interval,
+		"interval",
+		10*time.Second,
+		"refresh interval in the watch mode",
+	)
 	cmd.Flags().BoolVar(
 		&treerender.NoColors,
 		"no-color",
@@ -0,0 +1,258 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/value"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/bn"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/maputil"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+// dataPointUpdate describes how a data point changed between two consecutive
+// updates in the watch mode.
+type dataPointUpdate struct {
+	Model string
+	Time  time.Time
+	Point datapoint.Point
+
+	// Delta is the difference between the current and the previous value.
+	// It is nil if there is no previous value, or values are not numeric.
+	Delta *bn.FloatNumber
+
+	// Changed is a list of origins whose values changed since the previous
+	// update.
+	Changed []string
+
+	// Errored is a list of origins that returned an invalid data point.
+	Errored map[string]error
+
+	// Stale is a list of origins whose data points are older than their
+	// freshness threshold, which means that the last update failed and
+	// the previous data point is still being used.
+	Stale []string
+}
+
+// watchDataPoints fetches data points for given models on every tick of the
+// ticker and writes the differences between updates to the writer, until
+// the context is canceled. The ticker must be already started.
+func watchDataPoints(
+	ctx context.Context,
+	provider datapoint.Provider,
+	models []string,
+	ticker *timeutil.Ticker,
+	format string,
+	w io.Writer,
+) error {
+
+	prev := make(map[string]datapoint.Point)
+	for {
+		points, err := provider.DataPoints(ctx, models...)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, name := range maputil.SortKeys(points, sort.Strings) {
+			var prevPoint *datapoint.Point
+			if p, ok := prev[name]; ok {
+				prevPoint = &p
+			}
+			u := diffDataPoint(name, prevPoint, points[name], now)
+			marshaled, err := marshalDataPointUpdate(u, format)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(w, string(marshaled)); err != nil {
+				return err
+			}
+			prev[name] = points[name]
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.TickCh():
+		}
+	}
+}
+
+// diffDataPoint compares the current data point with the previous one. The
+// prev argument may be nil for the first update.
+func diffDataPoint(model string, prev *datapoint.Point, curr datapoint.Point, now time.Time) dataPointUpdate {
+	u := dataPointUpdate{
+		Model:   model,
+		Time:    now,
+		Point:   curr,
+		Errored: make(map[string]error),
+	}
+	var prevOrigins map[string]datapoint.Point
+	if prev != nil {
+		u.Delta = valueDelta(*prev, curr)
+		prevOrigins = originDataPoints(*prev)
+	}
+	currOrigins := originDataPoints(curr)
+	for _, name := range maputil.SortKeys(currOrigins, sort.Strings) {
+		point := currOrigins[name]
+		if err := point.Validate(); err != nil {
+			u.Errored[name] = err
+			continue
+		}
+		if threshold, ok := point.Meta["freshness_threshold"].(time.Duration); ok && threshold > 0 {
+			if now.Sub(point.Time) > threshold {
+				u.Stale = append(u.Stale, name)
+				continue
+			}
+		}
+		if prevPoint, ok := prevOrigins[name]; ok {
+			if prevPoint.Validate() != nil || prevPoint.Value.Print() != point.Value.Print() {
+				u.Changed = append(u.Changed, name)
+			}
+		}
+	}
+	return u
+}
+
+// valueDelta returns the difference between numeric values of given data
+// points or nil if the difference cannot be calculated.
+func valueDelta(prev, curr datapoint.Point) *bn.FloatNumber {
+	if prev.Validate() != nil || curr.Validate() != nil {
+		return nil
+	}
+	pv, ok := prev.Value.(value.NumericValue)
+	if !ok || pv.Number() == nil {
+		return nil
+	}
+	cv, ok := curr.Value.(value.NumericValue)
+	if !ok || cv.Number() == nil {
+		return nil
+	}
+	return cv.Number().Sub(pv.Number())
+}
+
+// originDataPoints returns all origin data points used to calculate the given
+// data point, indexed by the origin name and the query.
+func originDataPoints(p datapoint.Point) map[string]datapoint.Point {
+	points := make(map[string]datapoint.Point)
+	var walk func(p datapoint.Point)
+	walk = func(p datapoint.Point) {
+		if typ, _ := p.Meta["type"].(string); typ == "origin" {
+			points[fmt.Sprintf("%v(%v)", p.Meta["origin"], p.Meta["query"])] = p
+			return
+		}
+		for _, sp := range p.SubPoints {
+			walk(sp)
+		}
+	}
+	walk(p)
+	return points
+}
+
+func marshalDataPointUpdate(u dataPointUpdate, format string) ([]byte, error) {
+	switch format {
+	case formatPlain:
+		return marshalDataPointUpdatePlain(u)
+	case formatTrace:
+		return marshalDataPointUpdateTrace(u)
+	case formatJSON:
+		return marshalDataPointUpdateJSON(u)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+func marshalDataPointUpdatePlain(u dataPointUpdate) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("[%s] %s: ", u.Time.In(time.UTC).Format(time.RFC3339), u.Model))
+	if err := u.Point.Validate(); err != nil {
+		buf.WriteString(err.Error())
+	} else {
+		buf.WriteString(u.Point.Value.Print())
+	}
+	if u.Delta != nil {
+		buf.WriteString(" (")
+		if u.Delta.Sign() >= 0 {
+			buf.WriteString("+")
+		}
+		buf.WriteString(u.Delta.String())
+		buf.WriteString(")")
+	}
+	if len(u.Changed) > 0 {
+		buf.WriteString("\n  changed: ")
+		buf.WriteString(strings.Join(u.Changed, ", "))
+	}
+	for _, name := range maputil.SortKeys(u.Errored, sort.Strings) {
+		buf.WriteString(fmt.Sprintf("\n  errored: %s: %s", name, u.Errored[name]))
+	}
+	if len(u.Stale) > 0 {
+		buf.WriteString("\n  stale: ")
+		buf.WriteString(strings.Join(u.Stale, ", "))
+	}
+	return buf.Bytes(), nil
+}
+
+func marshalDataPointUpdateTrace(u dataPointUpdate) ([]byte, error) {
+	summary, err := marshalDataPointUpdatePlain(u)
+	if err != nil {
+		return nil, err
+	}
+	trace, err := u.Point.MarshalTrace()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Write(summary)
+	buf.WriteString("\n")
+	buf.Write(bytes.TrimRight(trace, "\n"))
+	return buf.Bytes(), nil
+}
+
+// marshalDataPointUpdateJSON marshals the update as a single line, so
+// the output of the watch mode is a valid NDJSON stream.
+func marshalDataPointUpdateJSON(u dataPointUpdate) ([]byte, error) {
+	var delta any
+	if u.Delta != nil {
+		delta = u.Delta.String()
+	}
+	errored := make(map[string]string, len(u.Errored))
+	for name, err := range u.Errored {
+		errored[name] = err.Error()
+	}
+	return json.Marshal(map[string]any{
+		"model":   u.Model,
+		"time":    u.Time.In(time.UTC).Format(time.RFC3339Nano),
+		"point":   u.Point,
+		"delta":   delta,
+		"changed": nonNilSlice(u.Changed),
+		"errored": errored,
+		"stale":   nonNilSlice(u.Stale),
+	})
+}
+
+func nonNilSlice(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
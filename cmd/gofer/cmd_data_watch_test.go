@@ -0,0 +1,134 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/mocks"
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/value"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/bn"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+func watchTestPoint(now time.Time, price float64, a, b, c datapoint.Point) datapoint.Point {
+	return datapoint.Point{
+		Value:     value.StaticValue{Value: bn.DecFloatPoint(price)},
+		Time:      now,
+		SubPoints: []datapoint.Point{a, b, c},
+		Meta:      map[string]any{"type": "median"},
+	}
+}
+
+func watchTestOrigin(origin string, t time.Time, price float64, err error) datapoint.Point {
+	return datapoint.Point{
+		Value: value.StaticValue{Value: bn.DecFloatPoint(price)},
+		Time:  t,
+		Meta: map[string]any{
+			"type":                "origin",
+			"origin":              origin,
+			"query":               "AAA/BBB",
+			"freshness_threshold": time.Minute,
+		},
+		Error: err,
+	}
+}
+
+func Test_diffDataPoint(t *testing.T) {
+	now := time.Now()
+	prev := watchTestPoint(
+		now,
+		10,
+		watchTestOrigin("a", now, 10, nil),
+		watchTestOrigin("b", now, 10, nil),
+		watchTestOrigin("c", now, 10, nil),
+	)
+	curr := watchTestPoint(
+		now,
+		12,
+		watchTestOrigin("a", now, 12, nil),
+		watchTestOrigin("b", now.Add(-time.Hour), 10, nil),
+		watchTestOrigin("c", now, 0, errors.New("foo")),
+	)
+
+	// First update:
+	u := diffDataPoint("AAA/BBB", nil, prev, now)
+	assert.Nil(t, u.Delta)
+	assert.Empty(t, u.Changed)
+	assert.Empty(t, u.Errored)
+	assert.Empty(t, u.Stale)
+
+	// Next update:
+	u = diffDataPoint("AAA/BBB", &prev, curr, now)
+	require.NotNil(t, u.Delta)
+	assert.Equal(t, "2", u.Delta.String())
+	assert.Equal(t, []string{"a(AAA/BBB)"}, u.Changed)
+	assert.Equal(t, []string{"b(AAA/BBB)"}, u.Stale)
+	assert.Len(t, u.Errored, 1)
+	assert.EqualError(t, u.Errored["c(AAA/BBB)"], "foo")
+
+	plain, err := marshalDataPointUpdatePlain(u)
+	require.NoError(t, err)
+	assert.Contains(t, string(plain), "AAA/BBB: 12 (+2)")
+	assert.Contains(t, string(plain), "changed: a(AAA/BBB)")
+	assert.Contains(t, string(plain), "errored: c(AAA/BBB): foo")
+	assert.Contains(t, string(plain), "stale: b(AAA/BBB)")
+}
+
+func Test_watchDataPoints(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	now := time.Now()
+	point := func(price float64) map[string]datapoint.Point {
+		return map[string]datapoint.Point{
+			"AAA/BBB": {Value: value.StaticValue{Value: bn.DecFloatPoint(price)}, Time: now},
+		}
+	}
+	provider := &mocks.Provider{}
+	provider.On("DataPoints", mock.Anything, []string{"AAA/BBB"}).Return(point(1), nil).Once()
+	provider.On("DataPoints", mock.Anything, []string{"AAA/BBB"}).Return(point(3), nil).Run(func(mock.Arguments) {
+		ctxCancel()
+	}).Once()
+
+	var buf bytes.Buffer
+	ticker := timeutil.NewTicker(0)
+	ticker.Start(ctx)
+	done := make(chan error)
+	go func() { done <- watchDataPoints(ctx, provider, []string{"AAA/BBB"}, ticker, formatJSON, &buf) }()
+	ticker.Tick()
+	require.NoError(t, <-done)
+
+	// Every update must be printed as a single JSON line.
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var first, second map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Nil(t, first["delta"])
+	assert.Equal(t, "2", second["delta"])
+}
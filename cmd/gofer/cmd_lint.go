@@ -0,0 +1,170 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/chronicleprotocol/oracle-suite/cmd"
+	gofer "github.com/chronicleprotocol/oracle-suite/pkg/config/gofernext"
+)
+
+func NewLintCmd(cfg *gofer.Config, cf *cmd.ConfigFlags, _ *cmd.LoggerFlags) *cobra.Command {
+	var (
+		format formatTypeValue
+		strict bool
+	)
+	cc := &cobra.Command{
+		Use:   "lint",
+		Args:  cobra.NoArgs,
+		Short: "Check data models for common mistakes",
+		Long: `Check data models for common mistakes.
+
+Reports unknown and unused origins, indirect chains whose pairs do not connect,
+medians whose min_values exceeds the number of sources, aliases that do not
+match their child node, circuit breakers with missing nodes, reference cycles
+and duplicate origin queries.
+
+The command exits with a non-zero status code if any error is found, or, with
+the --strict flag, if any warning is found.`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if format.String() == formatTrace {
+				return fmt.Errorf("unsupported format: %s", formatTrace)
+			}
+			if err := cf.Load(cfg); err != nil {
+				return err
+			}
+			diags := cfg.Gofer.Lint()
+			marshaled, err := marshalLintDiagnostics(diags, format.String())
+			if err != nil {
+				return err
+			}
+			if len(marshaled) > 0 {
+				fmt.Println(string(marshaled))
+			}
+			errs, warns := countDiagnostics(diags)
+			if errs > 0 || (strict && warns > 0) {
+				return fmt.Errorf("lint failed: %d error(s), %d warning(s)", errs, warns)
+			}
+			return nil
+		},
+	}
+	cc.Flags().VarP(
+		&format,
+		"format",
+		"o",
+		"output format (plain or json)",
+	)
+	cc.Flags().BoolVar(
+		&strict,
+		"strict",
+		false,
+		"treat warnings as errors",
+	)
+	return cc
+}
+
+func countDiagnostics(diags hcl.Diagnostics) (errs, warns int) {
+	for _, d := range diags {
+		switch d.Severity {
+		case hcl.DiagError:
+			errs++
+		case hcl.DiagWarning:
+			warns++
+		}
+	}
+	return errs, warns
+}
+
+func marshalLintDiagnostics(diags hcl.Diagnostics, format string) ([]byte, error) {
+	switch format {
+	case formatPlain:
+		return marshalLintDiagnosticsPlain(diags)
+	case formatJSON:
+		return marshalLintDiagnosticsJSON(diags)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+func marshalLintDiagnosticsPlain(diags hcl.Diagnostics) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, d := range diags {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		if d.Subject != nil {
+			buf.WriteString(d.Subject.String())
+			buf.WriteString(": ")
+		}
+		buf.WriteString(diagnosticSeverity(d.Severity))
+		buf.WriteString(": ")
+		buf.WriteString(d.Detail)
+	}
+	return buf.Bytes(), nil
+}
+
+func marshalLintDiagnosticsJSON(diags hcl.Diagnostics) ([]byte, error) {
+	type pos struct {
+		Line   int `json:"line"`
+		Column int `json:"column"`
+		Byte   int `json:"byte"`
+	}
+	type rng struct {
+		Filename string `json:"filename"`
+		Start    pos    `json:"start"`
+		End      pos    `json:"end"`
+	}
+	type diagnostic struct {
+		Severity string `json:"severity"`
+		Summary  string `json:"summary"`
+		Detail   string `json:"detail"`
+		Range    *rng   `json:"range,omitempty"`
+	}
+	out := make([]diagnostic, 0, len(diags))
+	for _, d := range diags {
+		jd := diagnostic{
+			Severity: diagnosticSeverity(d.Severity),
+			Summary:  d.Summary,
+			Detail:   d.Detail,
+		}
+		if d.Subject != nil {
+			jd.Range = &rng{
+				Filename: d.Subject.Filename,
+				Start:    pos{Line: d.Subject.Start.Line, Column: d.Subject.Start.Column, Byte: d.Subject.Start.Byte},
+				End:      pos{Line: d.Subject.End.Line, Column: d.Subject.End.Column, Byte: d.Subject.End.Byte},
+			}
+		}
+		out = append(out, jd)
+	}
+	return json.Marshal(out)
+}
+
+func diagnosticSeverity(s hcl.DiagnosticSeverity) string {
+	switch s {
+	case hcl.DiagError:
+		return "error"
+	case hcl.DiagWarning:
+		return "warning"
+	default:
+		return "invalid"
+	}
+}
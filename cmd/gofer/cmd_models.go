@@ -35,13 +35,36 @@ import (
 )
 
 func NewModelsCmd(cfg supervisor.Config, cf *cmd.ConfigFlags, lf *cmd.LoggerFlags) *cobra.Command {
-	var format formatTypeValue
+	var (
+		format formatTypeValue
+		diff   bool
+	)
 	cc := &cobra.Command{
 		Use:     "models [MODEL...]",
 		Aliases: []string{"model"},
 		Args:    cobra.MinimumNArgs(0),
 		Short:   "List all supported models",
 		RunE: func(cc *cobra.Command, args []string) (err error) {
+			if diff {
+				if len(args) != 2 {
+					return fmt.Errorf("the --diff flag requires exactly two arguments: OLD_CONFIG NEW_CONFIG")
+				}
+				ctx := context.Background()
+				oldModels, err := loadModels(ctx, args[0], lf.Logger())
+				if err != nil {
+					return err
+				}
+				newModels, err := loadModels(ctx, args[1], lf.Logger())
+				if err != nil {
+					return err
+				}
+				marshaled, err := marshalModelsDiff(diffModels(oldModels, newModels), format.String())
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(marshaled))
+				return nil
+			}
 			if err := cf.Load(cfg); err != nil {
 				return err
 			}
@@ -76,6 +99,12 @@ func NewModelsCmd(cfg supervisor.Config, cf *cmd.ConfigFlags, lf *cmd.LoggerFlag
 		"o",
 		"output format",
 	)
+	cc.Flags().BoolVar(
+		&diff,
+		"diff",
+		false,
+		"compare data models of two config files given as arguments: OLD_CONFIG NEW_CONFIG",
+	)
 	cc.Flags().BoolVar(
 		&treerender.NoColors,
 		"no-color",
@@ -0,0 +1,326 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+	gofer "github.com/chronicleprotocol/oracle-suite/pkg/config/gofernext"
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/maputil"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/treerender"
+)
+
+type diffStatus string
+
+const (
+	diffAdded     diffStatus = "added"
+	diffRemoved   diffStatus = "removed"
+	diffChanged   diffStatus = "changed"
+	diffUnchanged diffStatus = "unchanged"
+)
+
+// modelDiff describes differences between two nodes of data models.
+type modelDiff struct {
+	Type   string
+	Status diffStatus
+
+	// Meta contains the node meta, without the type. For removed nodes it
+	// contains the old meta, for other nodes the new one.
+	Meta map[string]any
+
+	// Changes contains meta values that differ between the old and the new
+	// node, indexed by the meta key.
+	Changes map[string]metaChange
+
+	Nodes []*modelDiff
+}
+
+type metaChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// loadModels loads data models from the given config file.
+func loadModels(ctx context.Context, path string, logger log.Logger) (map[string]datapoint.Model, error) {
+	var cfg gofer.Config
+	if err := config.LoadFiles(&cfg, []string{path}); err != nil {
+		return nil, fmt.Errorf("unable to load %s: %w", path, err)
+	}
+	services, err := cfg.Services(logger, "gofer", "")
+	if err != nil {
+		return nil, fmt.Errorf("unable to load %s: %w", path, err)
+	}
+	s, ok := services.(*gofer.Services)
+	if !ok {
+		return nil, fmt.Errorf("services are not gofer.Services")
+	}
+	return s.DataProvider.Models(ctx, s.DataProvider.ModelNames(ctx)...)
+}
+
+// diffModels compares data models and returns differences for models that
+// were added, removed or changed, indexed by the model name.
+func diffModels(old, new map[string]datapoint.Model) map[string]*modelDiff {
+	diffs := make(map[string]*modelDiff)
+	for name, o := range old {
+		o := o
+		var n *datapoint.Model
+		if m, ok := new[name]; ok {
+			n = &m
+		}
+		if d := diffModel(&o, n); d.Status != diffUnchanged {
+			diffs[name] = d
+		}
+	}
+	for name, n := range new {
+		n := n
+		if _, ok := old[name]; !ok {
+			diffs[name] = diffModel(nil, &n)
+		}
+	}
+	return diffs
+}
+
+// diffModel compares two model nodes. One of the arguments may be nil, in
+// which case the node is reported as added or removed.
+func diffModel(old, new *datapoint.Model) *modelDiff {
+	switch {
+	case old == nil:
+		d := &modelDiff{Type: modelType(*new), Status: diffAdded, Meta: modelMeta(*new)}
+		for i := range new.Models {
+			d.Nodes = append(d.Nodes, diffModel(nil, &new.Models[i]))
+		}
+		return d
+	case new == nil:
+		d := &modelDiff{Type: modelType(*old), Status: diffRemoved, Meta: modelMeta(*old)}
+		for i := range old.Models {
+			d.Nodes = append(d.Nodes, diffModel(&old.Models[i], nil))
+		}
+		return d
+	}
+	d := &modelDiff{
+		Type:    modelType(*new),
+		Status:  diffUnchanged,
+		Meta:    modelMeta(*new),
+		Changes: make(map[string]metaChange),
+	}
+	oldMeta := modelMeta(*old)
+	for k, v := range d.Meta {
+		if ov, ok := oldMeta[k]; !ok || fmt.Sprint(ov) != fmt.Sprint(v) {
+			d.Changes[k] = metaChange{Old: oldMeta[k], New: v}
+		}
+	}
+	for k, v := range oldMeta {
+		if _, ok := d.Meta[k]; !ok {
+			d.Changes[k] = metaChange{Old: v}
+		}
+	}
+	if len(d.Changes) > 0 {
+		d.Status = diffChanged
+	}
+	for _, p := range matchModels(old.Models, new.Models) {
+		nd := diffModel(p.old, p.new)
+		if nd.Status != diffUnchanged {
+			d.Status = diffChanged
+		}
+		d.Nodes = append(d.Nodes, nd)
+	}
+	return d
+}
+
+type modelPair struct {
+	old, new *datapoint.Model
+}
+
+// matchModels matches sub models of the old and the new node. Origins are
+// matched by the origin name and query first, and then by the origin name
+// only, so a changed query is reported as a change rather than a removed
+// and an added origin. Other nodes are matched by the type in order.
+//
+// Pairs are returned in the order of the new sub models, followed by
+// removed ones.
+func matchModels(old, new []datapoint.Model) []modelPair {
+	pairs := make([]modelPair, len(new))
+	oldUsed := make([]bool, len(old))
+	newUsed := make([]bool, len(new))
+	for i := range new {
+		pairs[i].new = &new[i]
+	}
+	for _, key := range []func(datapoint.Model) string{strictModelKey, looseModelKey} {
+		for i := range new {
+			if newUsed[i] {
+				continue
+			}
+			for j := range old {
+				if oldUsed[j] || key(old[j]) != key(new[i]) {
+					continue
+				}
+				pairs[i].old = &old[j]
+				oldUsed[j], newUsed[i] = true, true
+				break
+			}
+		}
+	}
+	for j := range old {
+		if !oldUsed[j] {
+			pairs = append(pairs, modelPair{old: &old[j]})
+		}
+	}
+	return pairs
+}
+
+func strictModelKey(m datapoint.Model) string {
+	if modelType(m) == "origin" {
+		return fmt.Sprintf("origin:%v:%v", m.Meta["origin"], m.Meta["query"])
+	}
+	return looseModelKey(m)
+}
+
+func looseModelKey(m datapoint.Model) string {
+	if modelType(m) == "origin" {
+		return fmt.Sprintf("origin:%v", m.Meta["origin"])
+	}
+	return modelType(m)
+}
+
+func modelType(m datapoint.Model) string {
+	typ, _ := m.Meta["type"].(string)
+	if typ == "" {
+		return "node"
+	}
+	return typ
+}
+
+func modelMeta(m datapoint.Model) map[string]any {
+	meta := make(map[string]any, len(m.Meta))
+	for k, v := range m.Meta {
+		if k == "type" || k == "models" {
+			continue
+		}
+		meta[k] = v
+	}
+	return meta
+}
+
+func marshalModelsDiff(diffs map[string]*modelDiff, format string) ([]byte, error) {
+	switch format {
+	case formatPlain, formatTrace:
+		return marshalModelsDiffTrace(diffs)
+	case formatJSON:
+		return marshalModelsDiffJSON(diffs)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// marshalModelsDiffTrace renders differences as trees. Nodes are prefixed
+// with "+" if added, "-" if removed and "~" if changed. Unchanged subtrees
+// are collapsed.
+func marshalModelsDiffTrace(diffs map[string]*modelDiff) ([]byte, error) {
+	if len(diffs) == 0 {
+		return []byte("No changes"), nil
+	}
+	var buf bytes.Buffer
+	for _, name := range maputil.SortKeys(diffs, sort.Strings) {
+		d := diffs[name]
+		buf.WriteString(fmt.Sprintf("%s model %s:\n", diffPrefix(d.Status), name))
+		buf.Write(treerender.RenderTree(func(node any) treerender.NodeData {
+			d := node.(*modelDiff)
+			params := make(map[string]any, len(d.Meta)+len(d.Changes))
+			for k, v := range d.Meta {
+				params[k] = v
+			}
+			for k, c := range d.Changes {
+				params[k] = fmt.Sprintf("%v → %v", printMetaValue(c.Old), printMetaValue(c.New))
+			}
+			var nodes []any
+			if d.Status != diffUnchanged {
+				for _, n := range d.Nodes {
+					nodes = append(nodes, n)
+				}
+			}
+			return treerender.NodeData{
+				Name:      diffPrefix(d.Status) + " " + d.Type,
+				Params:    params,
+				Ancestors: nodes,
+			}
+		}, []any{d}, 0))
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func marshalModelsDiffJSON(diffs map[string]*modelDiff) ([]byte, error) {
+	type jsonNode struct {
+		Type    string                `json:"type"`
+		Status  diffStatus            `json:"status"`
+		Meta    map[string]any        `json:"meta"`
+		Changes map[string]metaChange `json:"changes,omitempty"`
+		Nodes   []any                 `json:"nodes"`
+	}
+	var toJSON func(d *modelDiff) jsonNode
+	toJSON = func(d *modelDiff) jsonNode {
+		n := jsonNode{
+			Type:    d.Type,
+			Status:  d.Status,
+			Meta:    d.Meta,
+			Changes: d.Changes,
+			Nodes:   []any{},
+		}
+		for _, sd := range d.Nodes {
+			n.Nodes = append(n.Nodes, toJSON(sd))
+		}
+		return n
+	}
+	out := map[string]any{
+		"added":   []string{},
+		"removed": []string{},
+		"changed": []string{},
+	}
+	models := make(map[string]jsonNode, len(diffs))
+	for _, name := range maputil.SortKeys(diffs, sort.Strings) {
+		d := diffs[name]
+		out[string(d.Status)] = append(out[string(d.Status)].([]string), name)
+		models[name] = toJSON(d)
+	}
+	out["models"] = models
+	return json.Marshal(out)
+}
+
+func diffPrefix(s diffStatus) string {
+	switch s {
+	case diffAdded:
+		return "+"
+	case diffRemoved:
+		return "-"
+	case diffChanged:
+		return "~"
+	default:
+		return "="
+	}
+}
+
+func printMetaValue(v any) string {
+	if v == nil {
+		return "<none>"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,109 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
+)
+
+func testOriginModel(origin, query string) datapoint.Model {
+	return datapoint.Model{Meta: map[string]any{"type": "origin", "origin": origin, "query": query}}
+}
+
+func testMedianModel(min int, models ...datapoint.Model) datapoint.Model {
+	return datapoint.Model{Meta: map[string]any{"type": "median", "min_values": min}, Models: models}
+}
+
+func TestDiffModels(t *testing.T) {
+	old := map[string]datapoint.Model{
+		"AAA/BBB": testMedianModel(2,
+			testOriginModel("a", "AAA/BBB"),
+			testOriginModel("b", "AAA/BBB"),
+			testOriginModel("c", "AAA/BBB"),
+		),
+		"CCC/DDD": testOriginModel("a", "CCC/DDD"),
+		"EEE/FFF": testOriginModel("a", "EEE/FFF"),
+	}
+	new := map[string]datapoint.Model{
+		"AAA/BBB": testMedianModel(3,
+			testOriginModel("c", "AAA/BBB"),
+			testOriginModel("a", "AAA/BBBB"),
+			testOriginModel("d", "AAA/BBB"),
+		),
+		"CCC/DDD": testOriginModel("a", "CCC/DDD"),
+		"GGG/HHH": testOriginModel("a", "GGG/HHH"),
+	}
+
+	diffs := diffModels(old, new)
+	require.Len(t, diffs, 3)
+	assert.NotContains(t, diffs, "CCC/DDD")
+	assert.Equal(t, diffRemoved, diffs["EEE/FFF"].Status)
+	assert.Equal(t, diffAdded, diffs["GGG/HHH"].Status)
+
+	median := diffs["AAA/BBB"]
+	assert.Equal(t, diffChanged, median.Status)
+	assert.Equal(t, metaChange{Old: 2, New: 3}, median.Changes["min_values"])
+	require.Len(t, median.Nodes, 4)
+
+	// Nodes are in the order of the new model, followed by removed nodes.
+	assert.Equal(t, diffUnchanged, median.Nodes[0].Status)
+	assert.Equal(t, "c", median.Nodes[0].Meta["origin"])
+	assert.Equal(t, diffChanged, median.Nodes[1].Status)
+	assert.Equal(t, metaChange{Old: "AAA/BBB", New: "AAA/BBBB"}, median.Nodes[1].Changes["query"])
+	assert.Equal(t, diffAdded, median.Nodes[2].Status)
+	assert.Equal(t, "d", median.Nodes[2].Meta["origin"])
+	assert.Equal(t, diffRemoved, median.Nodes[3].Status)
+	assert.Equal(t, "b", median.Nodes[3].Meta["origin"])
+}
+
+func TestMarshalModelsDiffJSON(t *testing.T) {
+	diffs := diffModels(
+		map[string]datapoint.Model{"AAA/BBB": testOriginModel("a", "AAA/BBB")},
+		map[string]datapoint.Model{"AAA/BBB": testOriginModel("a", "AAA/CCC")},
+	)
+	b, err := marshalModelsDiffJSON(diffs)
+	require.NoError(t, err)
+
+	var out struct {
+		Added   []string                   `json:"added"`
+		Removed []string                   `json:"removed"`
+		Changed []string                   `json:"changed"`
+		Models  map[string]json.RawMessage `json:"models"`
+	}
+	require.NoError(t, json.Unmarshal(b, &out))
+	assert.Empty(t, out.Added)
+	assert.Empty(t, out.Removed)
+	assert.Equal(t, []string{"AAA/BBB"}, out.Changed)
+	assert.JSONEq(t, `{
+		"type": "origin",
+		"status": "changed",
+		"meta": {"origin": "a", "query": "AAA/CCC"},
+		"changes": {"query": {"old": "AAA/BBB", "new": "AAA/CCC"}},
+		"nodes": []
+	}`, string(out.Models["AAA/BBB"]))
+}
+
+func TestMarshalModelsDiffTrace_NoChanges(t *testing.T) {
+	b, err := marshalModelsDiffTrace(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "No changes", string(b))
+}
@@ -0,0 +1,64 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/httpfixture"
+)
+
+// fixtureFlags is a set of flags for recording and replaying HTTP responses
+// of origins and Ethereum clients.
+type fixtureFlags struct {
+	record string
+	replay string
+}
+
+// FlagSet binds CLI args [--record] and [--replay] as a pflag.FlagSet.
+func (ff *fixtureFlags) FlagSet() *pflag.FlagSet {
+	fs := pflag.NewFlagSet("fixture", pflag.PanicOnError)
+	fs.StringVar(
+		&ff.record,
+		"record",
+		"",
+		"record HTTP and RPC responses to the given fixture directory",
+	)
+	fs.StringVar(
+		&ff.replay,
+		"replay",
+		"",
+		"serve HTTP and RPC responses from the given fixture directory instead of the network",
+	)
+	return fs
+}
+
+// Fixtures returns the fixtures configured by flags or nil if neither
+// the record nor the replay mode is enabled.
+func (ff *fixtureFlags) Fixtures() (*httpfixture.Fixtures, error) {
+	switch {
+	case ff.record != "" && ff.replay != "":
+		return nil, fmt.Errorf("--record and --replay flags cannot be used together")
+	case ff.record != "":
+		return httpfixture.New(httpfixture.Record, ff.record)
+	case ff.replay != "":
+		return httpfixture.New(httpfixture.Replay, ff.replay)
+	default:
+		return nil, nil
+	}
+}
@@ -18,6 +18,8 @@ package main
 import (
 	"os"
 
+	"github.com/spf13/cobra"
+
 	suite "github.com/chronicleprotocol/oracle-suite"
 	"github.com/chronicleprotocol/oracle-suite/cmd"
 	gofer "github.com/chronicleprotocol/oracle-suite/pkg/config/gofernext"
@@ -28,13 +30,21 @@ func main() {
 	cf := cmd.ConfigFlagsForConfig(config)
 
 	var lf cmd.LoggerFlags
-	c := cmd.NewRootCommand("gofer", suite.Version, &cf, &lf)
+	var ff fixtureFlags
+	c := cmd.NewRootCommand("gofer", suite.Version, &cf, &lf, &ff)
+	c.PersistentPreRunE = func(_ *cobra.Command, _ []string) (err error) {
+		config.HTTPFixtures, err = ff.Fixtures()
+		return err
+	}
 
 	c.AddCommand(
 		cmd.NewRunCmd(&config, &cf, &lf),
 		cmd.NewRenderConfigCmd(&config, &cf),
 		NewModelsCmd(&config, &cf, &lf),
 		NewDataCmd(&config, &cf, &lf),
+		NewAPICmd(&config, &cf, &lf),
+		NewBacktestCmd(&config, &cf, &lf),
+		NewLintCmd(&config, &cf, &lf),
 	)
 
 	if err := c.Execute(); err != nil {
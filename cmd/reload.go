@@ -0,0 +1,132 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/supervisor"
+)
+
+const reloadLoggerTag = "CONFIG_RELOAD"
+
+// reloader reloads the running services every time the config files
+// change or the SIGHUP signal is received.
+type reloader struct {
+	services supervisor.Reloadable
+	config   supervisor.Config
+	flags    *ConfigFlags
+	interval time.Duration
+	log      log.Logger
+
+	fingerprint string
+}
+
+// newReloader returns a new reloader. The config argument must be a pointer
+// to the config type used to create the services.
+func newReloader(
+	services supervisor.Reloadable,
+	config supervisor.Config,
+	flags *ConfigFlags,
+	interval time.Duration,
+	logger log.Logger,
+) *reloader {
+	return &reloader{
+		services:    services,
+		config:      config,
+		flags:       flags,
+		interval:    interval,
+		log:         logger.WithField("tag", reloadLoggerTag),
+		fingerprint: flags.fingerprint(),
+	}
+}
+
+// run blocks until the context is canceled.
+func (r *reloader) run(ctx context.Context) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+
+	// Config files are watched only if they were provided, the embedded
+	// config cannot change.
+	var tickCh <-chan time.Time
+	if r.interval > 0 && len(r.flags.paths) > 0 {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		tickCh = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigCh:
+			r.fingerprint = r.flags.fingerprint()
+			r.log.Info("SIGHUP received, reloading configuration")
+			r.reload()
+		case <-tickCh:
+			fingerprint := r.flags.fingerprint()
+			if fingerprint == r.fingerprint {
+				continue
+			}
+			r.fingerprint = fingerprint
+			r.log.Info("Config files changed, reloading configuration")
+			r.reload()
+		}
+	}
+}
+
+func (r *reloader) reload() {
+	// A new config instance is used, so a config that fails to load does
+	// not affect the running one.
+	cfg := reflect.New(reflect.TypeOf(r.config).Elem()).Interface().(supervisor.Config)
+	if err := r.flags.Load(cfg); err != nil {
+		r.log.
+			WithError(err).
+			WithAdvice("Fix the configuration, the running configuration is kept until then").
+			Error("Unable to load configuration")
+		return
+	}
+	if err := r.services.Reload(cfg); err != nil {
+		advice := "Fix the configuration, the running configuration is kept until then"
+		if errors.Is(err, supervisor.ErrRestartRequired) {
+			advice = "Restart the application to apply the configuration, the running configuration is kept until then"
+		}
+		r.log.
+			WithError(err).
+			WithAdvice(advice).
+			Error("Unable to apply configuration")
+		return
+	}
+	r.config = cfg
+	r.log.Info("Configuration reloaded")
+}
+
+// fingerprint returns the fingerprint of the config files, see
+// config.Fingerprint.
+func (cf *ConfigFlags) fingerprint() string {
+	if len(cf.paths) == 0 {
+		return ""
+	}
+	return config.Fingerprint(cf.paths)
+}
@@ -6,6 +6,7 @@ variables {
   )))
 
   webapi_enable              = tobool(env("CFG_WEBAPI_ENABLE", "0"))
+  webapi_tor_address_book    = tobool(env("CFG_WEBAPI_TOR_ADDR_BOOK", "0"))
   webapi_eth_address_book    = env("CFG_WEBAPI_ETH_ADDR_BOOK", try(var.contract_map["${var.environment}-${var.chain_name}-TorAddressRegister"], ""))
   webapi_static_address_book = explode(var.item_separator, env("CFG_WEBAPI_STATIC_ADDR_BOOK", join(
     var.item_separator,
@@ -28,6 +29,8 @@ transport {
       disable_discovery    = tobool(env("CFG_LIBP2P_DISABLE_DISCOVERY", "0"))
       ethereum_key         = "default"
       external_addr        = env("CFG_LIBP2P_EXTERNAL_ADDR", env("CFG_LIBP2P_EXTERNAL_IP", ""))
+      batch_topics         = explode(var.item_separator, env("CFG_LIBP2P_BATCH_TOPICS", ""))
+      batch_interval       = tonumber(env("CFG_LIBP2P_BATCH_INTERVAL", "1"))
     }
   }
 
@@ -39,13 +42,33 @@ transport {
       listen_addr       = env("CFG_WEBAPI_LISTEN_ADDR", "")
       socks5_proxy_addr = env("CFG_WEBAPI_SOCKS5_PROXY_ADDR", "")
       ethereum_key      = "default"
+      pull_allowlist    = explode(var.item_separator, env("CFG_WEBAPI_PULL_ALLOWLIST", ""))
+      pull_producers    = explode(var.item_separator, env("CFG_WEBAPI_PULL_PRODUCERS", ""))
+      pull_interval     = tonumber(env("CFG_WEBAPI_PULL_INTERVAL", "60"))
+      queue_max_age     = tonumber(env("CFG_WEBAPI_QUEUE_MAX_AGE", "900"))
+      queue_max_size    = tonumber(env("CFG_WEBAPI_QUEUE_MAX_SIZE", "1000"))
+
+      dead_consumer_threshold = tonumber(env("CFG_WEBAPI_DEAD_CONSUMER_THRESHOLD", "5"))
+      dead_consumer_backoff   = tonumber(env("CFG_WEBAPI_DEAD_CONSUMER_BACKOFF", "300"))
 
       # Ethereum based address book. Enabled if CFG_WEBAPI_ETH_ADDR_BOOK is set to a contract address.
       dynamic "ethereum_address_book" {
-        for_each = var.webapi_eth_address_book == "" ? [] : [1]
+        for_each = var.webapi_eth_address_book == "" || var.webapi_tor_address_book ? [] : [1]
+        content {
+          contract_addr   = var.webapi_eth_address_book
+          ethereum_client = "default"
+        }
+      }
+
+      # Tor address book. Used instead of the Ethereum based address book if CFG_WEBAPI_TOR_ADDR_BOOK is set to true.
+      # Only valid onion addresses are used and consumers are health-checked through the SOCKS5 proxy.
+      dynamic "tor_address_book" {
+        for_each = var.webapi_eth_address_book != "" && var.webapi_tor_address_book ? [1] : []
         content {
           contract_addr   = var.webapi_eth_address_book
           ethereum_client = "default"
+          check_interval  = tonumber(env("CFG_WEBAPI_TOR_CHECK_INTERVAL", "300"))
+          cooldown        = tonumber(env("CFG_WEBAPI_TOR_COOLDOWN", "600"))
         }
       }
 
@@ -16,13 +16,18 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/ext/tryfunc"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/zclconf/go-cty/cty"
 	"github.com/zclconf/go-cty/cty/function"
 	"github.com/zclconf/go-cty/cty/function/stdlib"
@@ -33,6 +38,7 @@ import (
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/hcl/ext/include"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/hcl/ext/variables"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/hcl/funcs"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/secret"
 )
 
 var hclContext = &hcl.EvalContext{
@@ -51,13 +57,24 @@ var hclContext = &hcl.EvalContext{
 		"length":   stdlib.LengthFunc,
 		"merge":    stdlib.MergeFunc,
 		"concat":   stdlib.ConcatFunc,
+		"flatten":  stdlib.FlattenFunc,
+		"lookup":   stdlib.LookupFunc,
 		// sequence
 		"range": stdlib.RangeFunc,
 		// string
-		"join":  stdlib.JoinFunc,
-		"split": stdlib.SplitFunc,
+		"join":       stdlib.JoinFunc,
+		"split":      stdlib.SplitFunc,
+		"lower":      stdlib.LowerFunc,
+		"upper":      stdlib.UpperFunc,
+		"format":     stdlib.FormatFunc,
+		"formatlist": stdlib.FormatListFunc,
+		"regex":      stdlib.RegexFunc,
 		// string replace
 		"replace": stdlib.ReplaceFunc,
+		// encoding
+		"csvdecode":  stdlib.CSVDecodeFunc,
+		"jsondecode": stdlib.JSONDecodeFunc,
+		"jsonencode": stdlib.JSONEncodeFunc,
 
 		// Custom functions:
 		// convert
@@ -74,6 +91,113 @@ var hclContext = &hcl.EvalContext{
 	},
 }
 
+// newSecretResolver returns a resolver for references used in the secret()
+// function. The "vault" provider is configured using the VAULT_ADDR,
+// VAULT_TOKEN and VAULT_NAMESPACE environment variables.
+//
+// Every load uses a new resolver, so secrets are resolved again on reload
+// and values used for redaction are not collected across loads.
+func newSecretResolver() *secret.Resolver {
+	return secret.NewResolver(map[string]secret.Provider{
+		"file":  secret.FileProvider{},
+		"env":   secret.EnvProvider{},
+		"vault": &secret.VaultProvider{},
+	})
+}
+
+// lastSecrets is the secret resolver used by the last successful load.
+// It is used by RedactSecrets.
+var (
+	lastSecretsMu sync.Mutex
+	lastSecrets   = newSecretResolver()
+)
+
+// setLastSecrets sets the resolver used by the last successful load.
+func setLastSecrets(r *secret.Resolver) {
+	lastSecretsMu.Lock()
+	defer lastSecretsMu.Unlock()
+	lastSecrets = r
+}
+
+// RedactSecrets replaces values resolved by the secret() function in the
+// given rendered config with the secret() function call that resolves them,
+// so that the rendered config does not reveal secrets. Only secrets resolved
+// by the last successful load are redacted.
+//
+// Only string literals whose whole value was derived from secrets are
+// redacted, so other values that happen to contain a secret value, e.g.
+// a short one, are left unchanged.
+func RedactSecrets(src []byte) []byte {
+	lastSecretsMu.Lock()
+	secrets := lastSecrets
+	lastSecretsMu.Unlock()
+	sensitive := make(map[string]bool)
+	for _, val := range secrets.Sensitive() {
+		if lit := quotedLit(val); len(lit) > 0 {
+			sensitive[string(lit)] = true
+		}
+	}
+	resolved := secrets.Resolved()
+	refs := make([]string, 0, len(resolved))
+	for ref, val := range resolved {
+		if val != "" {
+			refs = append(refs, ref)
+		}
+	}
+	// Longer values are replaced first, so that a secret that contains
+	// another secret is redacted as a whole.
+	sort.Slice(refs, func(i, j int) bool {
+		if len(resolved[refs[i]]) != len(resolved[refs[j]]) {
+			return len(resolved[refs[i]]) > len(resolved[refs[j]])
+		}
+		return refs[i] < refs[j]
+	})
+	tokens, _ := hclsyntax.LexConfig(src, "", hcl.InitialPos)
+	var (
+		dst  []byte
+		last int
+	)
+	for _, t := range tokens {
+		if t.Type != hclsyntax.TokenQuotedLit || !sensitive[string(t.Bytes)] {
+			continue
+		}
+		lit := t.Bytes
+		for _, ref := range refs {
+			lit = bytes.ReplaceAll(lit, quotedLit(resolved[ref]), []byte(fmt.Sprintf("${secret(%q)}", ref)))
+		}
+		dst = append(dst, src[last:t.Range.Start.Byte]...)
+		dst = append(dst, lit...)
+		last = t.Range.End.Byte
+	}
+	return append(dst, src[last:]...)
+}
+
+// quotedLit returns the given string escaped as in a quoted HCL string,
+// without the quotes.
+func quotedLit(s string) []byte {
+	var lit []byte
+	for _, t := range hclwrite.TokensForValue(cty.StringVal(s)) {
+		if t.Type == hclsyntax.TokenQuotedLit {
+			lit = append(lit, t.Bytes...)
+		}
+	}
+	return lit
+}
+
+// evalContext returns an evaluation context used to load config files. Paths
+// passed to the file() function in files parsed by parseFiles or included by
+// them are resolved against the directory of these files, other relative
+// paths are resolved against the wd directory. The secret() function resolves
+// references using the given resolver.
+func evalContext(wd string, secrets *secret.Resolver) *hcl.EvalContext {
+	ctx := hclContext.NewChild()
+	ctx.Functions = map[string]function.Function{
+		"file":   funcs.MakeFileFunc(wd),
+		"secret": funcs.MakeSecretFunc(secrets),
+	}
+	return ctx
+}
+
 // getEnvVars retrieves environment variables from the system and returns
 // them as a cty object type, where keys are variable names and values are
 // their corresponding values.
@@ -149,24 +273,79 @@ func LoadFiles(config any, paths []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to get working directory: %w", err)
 	}
-	if body, diags = utilHCL.ParseFiles(paths, nil); diags.HasErrors() {
+	if body, diags = parseFiles(paths); diags.HasErrors() {
 		return diags
 	}
 	if len(paths) > 0 {
 		wd = filepath.Dir(paths[0])
 	}
-	if body, diags = include.Include(hclContext, body, wd, 10); diags.HasErrors() {
+	secrets := newSecretResolver()
+	ctx := evalContext(wd, secrets)
+	if body, diags = include.Visit(ctx, body, wd, 10, resolveFilePaths); diags.HasErrors() {
 		return diags
 	}
-	if body, diags = variables.Variables(hclContext, body); diags.HasErrors() {
+	if body, diags = variables.Variables(ctx, body); diags.HasErrors() {
 		return diags
 	}
-	if diags = utilHCL.Decode(hclContext, dynblock.Expand(body, hclContext), config); diags.HasErrors() {
+	if diags = utilHCL.Decode(ctx, dynblock.Expand(body, ctx), config); diags.HasErrors() {
 		return diags
 	}
+	setLastSecrets(secrets)
 	return nil
 }
 
+// Files returns the given paths together with the paths of all files
+// included by them using the "include" attribute and files read by them
+// using the file() function. Files read using paths that depend on
+// variables are not returned.
+func Files(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	files := append([]string{}, paths...)
+	var read []string
+	bodies := make([]hcl.Body, len(paths))
+	for n, path := range paths {
+		body, diags := utilHCL.ParseFile(path, nil)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		read = append(read, filePaths(hclContext, path, body)...)
+		bodies[n] = body
+	}
+	_, diags := include.Visit(hclContext, hcl.MergeBodies(bodies), filepath.Dir(paths[0]), 10, func(path string, body hcl.Body) {
+		files = append(files, path)
+		read = append(read, filePaths(hclContext, path, body)...)
+	})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return append(files, read...), nil
+}
+
+// Fingerprint returns a string that changes every time any of the given
+// config files, or files returned for them by Files, is modified, created
+// or removed.
+//
+// If the files cannot be read or parsed, an error message is returned as
+// the fingerprint, so that fixing the files changes the fingerprint.
+func Fingerprint(paths []string) string {
+	files, err := Files(paths)
+	if err != nil {
+		return err.Error()
+	}
+	var b strings.Builder
+	for _, path := range files {
+		fi, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(&b, "%s:%s\n", path, err)
+			continue
+		}
+		fmt.Fprintf(&b, "%s:%d:%d\n", path, fi.ModTime().UnixNano(), fi.Size())
+	}
+	return b.String()
+}
+
 // LoadEmbeds populates config with data from []utilHCL.NamedBytes into the given config,
 // and expanding dynamic blocks before decoding the HCL content.
 func LoadEmbeds(config any, embeds [][]byte) (err error) {
@@ -178,12 +357,15 @@ func LoadEmbeds(config any, embeds [][]byte) (err error) {
 	if body, diags = utilHCL.ParseSources(embeds); diags.HasErrors() {
 		return diags
 	}
-	if body, diags = variables.Variables(hclContext, body); diags.HasErrors() {
+	secrets := newSecretResolver()
+	ctx := evalContext("", secrets)
+	if body, diags = variables.Variables(ctx, body); diags.HasErrors() {
 		return diags
 	}
-	if diags = utilHCL.Decode(hclContext, dynblock.Expand(body, hclContext), config); diags.HasErrors() {
+	if diags = utilHCL.Decode(ctx, dynblock.Expand(body, ctx), config); diags.HasErrors() {
 		return diags
 	}
+	setLastSecrets(secrets)
 	return nil
 }
 
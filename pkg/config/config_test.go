@@ -0,0 +1,177 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	utilHCL "github.com/chronicleprotocol/oracle-suite/pkg/util/hcl"
+)
+
+func TestSecretFunc(t *testing.T) {
+	t.Setenv("CONFIG_TEST_SECRET", `pass"word`)
+
+	var cfg struct {
+		Password string `hcl:"password"`
+		Header   string `hcl:"header"`
+	}
+	require.NoError(t, LoadEmbeds(&cfg, [][]byte{[]byte(`
+		password = secret("env://CONFIG_TEST_SECRET")
+		header   = "Bearer ${secret("env://CONFIG_TEST_SECRET")}"
+	`)}))
+	assert.Equal(t, `pass"word`, cfg.Password)
+	assert.Equal(t, `Bearer pass"word`, cfg.Header)
+
+	body := &utilHCL.Block{}
+	require.False(t, utilHCL.Encode(cfg, body).HasErrors())
+	content, diags := body.Bytes()
+	require.False(t, diags.HasErrors())
+
+	redacted := string(RedactSecrets(content))
+	assert.NotContains(t, redacted, `pass\"word`)
+	assert.Contains(t, redacted, `password = "${secret("env://CONFIG_TEST_SECRET")}"`)
+	assert.Contains(t, redacted, `header   = "Bearer ${secret("env://CONFIG_TEST_SECRET")}"`)
+}
+
+func TestSecretFuncShortValue(t *testing.T) {
+	t.Setenv("CONFIG_TEST_SHORT_SECRET", "1")
+
+	var cfg struct {
+		Token  string `hcl:"token"`
+		Header string `hcl:"header"`
+		URL    string `hcl:"url"`
+	}
+	require.NoError(t, LoadEmbeds(&cfg, [][]byte{[]byte(`
+		token  = secret("env://CONFIG_TEST_SHORT_SECRET")
+		header = "Bearer ${secret("env://CONFIG_TEST_SHORT_SECRET")}"
+		url    = "http://127.0.0.1:8081"
+	`)}))
+	assert.Equal(t, "1", cfg.Token)
+	assert.Equal(t, "Bearer 1", cfg.Header)
+
+	body := &utilHCL.Block{}
+	require.False(t, utilHCL.Encode(cfg, body).HasErrors())
+	content, diags := body.Bytes()
+	require.False(t, diags.HasErrors())
+
+	// Only values derived from the secret are redacted.
+	redacted := string(RedactSecrets(content))
+	assert.Contains(t, redacted, `token  = "${secret("env://CONFIG_TEST_SHORT_SECRET")}"`)
+	assert.Contains(t, redacted, `header = "Bearer ${secret("env://CONFIG_TEST_SHORT_SECRET")}"`)
+	assert.Contains(t, redacted, `url    = "http://127.0.0.1:8081"`)
+}
+
+func TestSecretFuncScopedToLoad(t *testing.T) {
+	t.Setenv("CONFIG_TEST_SCOPED_SECRET", "scoped")
+
+	var cfg struct {
+		Value string `hcl:"value"`
+	}
+	require.NoError(t, LoadEmbeds(&cfg, [][]byte{[]byte(`value = secret("env://CONFIG_TEST_SCOPED_SECRET")`)}))
+	assert.Equal(t, `value = "${secret("env://CONFIG_TEST_SCOPED_SECRET")}"`, string(RedactSecrets([]byte(`value = "scoped"`))))
+
+	// Secrets resolved by a previous load are not redacted anymore.
+	require.NoError(t, LoadEmbeds(&cfg, [][]byte{[]byte(`value = "scoped"`)}))
+	assert.Equal(t, `value = "scoped"`, string(RedactSecrets([]byte(`value = "scoped"`))))
+}
+
+func TestTemplateFunctions(t *testing.T) {
+	var cfg struct {
+		Models []struct {
+			Name    string   `hcl:"name"`
+			Symbol  string   `hcl:"symbol"`
+			Origins []string `hcl:"origins"`
+			URLs    []string `hcl:"urls"`
+			JSON    string   `hcl:"json"`
+		} `hcl:"model,block"`
+	}
+	require.NoError(t, LoadFiles(&cfg, []string{"./testdata/templates/config.hcl"}))
+	require.Len(t, cfg.Models, 2)
+	assert.Equal(t, "btc/usd", cfg.Models[0].Name)
+	assert.Equal(t, "BTC", cfg.Models[0].Symbol)
+	assert.Equal(t, []string{"binance", "coinbase"}, cfg.Models[0].Origins)
+	assert.Equal(t, []string{"https://api.binance.com/ticker", "https://api.coinbase.com/ticker"}, cfg.Models[0].URLs)
+	assert.Equal(t, `{"base":"BTC"}`, cfg.Models[0].JSON)
+	assert.Equal(t, "eth/usd", cfg.Models[1].Name)
+	assert.Equal(t, []string{"https://api.kraken.com/ticker"}, cfg.Models[1].URLs)
+}
+
+func TestFileFuncIncludedFile(t *testing.T) {
+	var cfg struct {
+		Assets []struct {
+			Symbol string `hcl:"symbol"`
+		} `hcl:"asset,block"`
+	}
+	paths := []string{"./testdata/templates/include.hcl"}
+
+	// Paths passed to file() are relative to the file that calls it.
+	require.NoError(t, LoadFiles(&cfg, paths))
+	require.Len(t, cfg.Assets, 2)
+	assert.Equal(t, "BTC", cfg.Assets[0].Symbol)
+	assert.Equal(t, "ETH", cfg.Assets[1].Symbol)
+
+	csv, err := filepath.Abs("./testdata/templates/assets/assets.csv")
+	require.NoError(t, err)
+	files, err := Files(paths)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"./testdata/templates/include.hcl",
+		filepath.Join("testdata", "templates", "assets", "assets.hcl"),
+		csv,
+	}, files)
+	assert.Contains(t, Fingerprint(paths), csv)
+}
+
+func TestEqual(t *testing.T) {
+	type block struct {
+		Name   string   `hcl:"name,label"`
+		Values []string `hcl:"values,optional"`
+
+		Range   hcl.Range       `hcl:",range"`
+		Content hcl.BodyContent `hcl:",content"`
+
+		cached any
+	}
+	type config struct {
+		Blocks []block `hcl:"block,block"`
+
+		Content hcl.BodyContent `hcl:",content"`
+	}
+	load := func(src string) *config {
+		var cfg config
+		require.NoError(t, LoadEmbeds(&cfg, [][]byte{[]byte(src)}))
+		return &cfg
+	}
+
+	a := load(`block "a" { values = ["x", "y"] }`)
+	a.Blocks[0].cached = struct{}{}
+
+	// Different formatting and unexported fields are ignored.
+	assert.True(t, Equal(a, load("\n\nblock \"a\" {\n  values = [\"x\", \"y\"]\n}\n")))
+	assert.True(t, Equal(a.Blocks, load(`block "a" { values = ["x", "y"] }`).Blocks))
+
+	assert.False(t, Equal(a, load(`block "a" { values = ["x"] }`)))
+	assert.False(t, Equal(a, load(`block "b" { values = ["x", "y"] }`)))
+	assert.False(t, Equal(a, load(`
+		block "a" { values = ["x", "y"] }
+		block "b" {}
+	`)))
+}
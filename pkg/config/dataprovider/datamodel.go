@@ -29,6 +29,7 @@ type configDataModel struct {
 // can be used in a price model.
 type configDynamicNode interface {
 	buildGraph(origins map[string]origin.Origin, roots map[string]graph.Node) ([]graph.Node, error)
+	childNodes() []configDynamicNode
 	hclRange() hcl.Range
 }
 
@@ -191,6 +192,10 @@ func (c configNode) OnEncodeBlock(block *utilHCL.Block) hcl.Diagnostics {
 	return nil
 }
 
+func (c *configNode) childNodes() []configDynamicNode {
+	return c.Nodes
+}
+
 func (c *configNode) hclRange() hcl.Range {
 	return c.Range
 }
@@ -0,0 +1,406 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dataprovider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/value"
+)
+
+// Lint statically checks data models for problems that do not prevent the
+// configuration from being loaded but are likely to be mistakes, such as
+// unused origins, disconnected indirect chains or medians that can never
+// collect enough values.
+//
+// Lint does not configure origins, so it can be used without access to
+// Ethereum clients or HTTP APIs.
+func (c *Config) Lint() hcl.Diagnostics {
+	l := &linter{
+		config:    c,
+		origins:   make(map[string]*configOrigin),
+		models:    make(map[string]*configDataModel),
+		used:      make(map[string]bool),
+		pairs:     make(map[string]value.Pair),
+		resolving: make(map[string]bool),
+	}
+	for i := range c.Origins {
+		l.origins[c.Origins[i].Name] = &c.Origins[i]
+	}
+	for i := range c.DataModels {
+		l.models[c.DataModels[i].Name] = &c.DataModels[i]
+	}
+	for i := range c.DataModels {
+		l.lintDataModel(&c.DataModels[i])
+	}
+	l.lintCycles()
+	for i := range c.Origins {
+		o := &c.Origins[i]
+		if !l.used[o.Name] {
+			l.diags = append(l.diags, &hcl.Diagnostic{
+				Severity: hcl.DiagWarning,
+				Summary:  "Unused origin",
+				Detail:   fmt.Sprintf("Origin %s is not used by any data model", o.Name),
+				Subject:  o.Range.Ptr(),
+			})
+		}
+	}
+	return l.diags
+}
+
+type linter struct {
+	config    *Config
+	origins   map[string]*configOrigin
+	models    map[string]*configDataModel
+	used      map[string]bool       // Origins used by data models.
+	pairs     map[string]value.Pair // Resolved pairs of data models.
+	resolving map[string]bool       // Data models whose pairs are being resolved.
+	diags     hcl.Diagnostics
+}
+
+func (l *linter) lintDataModel(m *configDataModel) {
+	if len(m.Nodes) != 1 {
+		l.error(m.Range, "Data model must have exactly one root node")
+	}
+	l.lintNodes(m.Nodes)
+}
+
+// lintCycles reports data models that reference themselves, directly or
+// through other data models.
+func (l *linter) lintCycles() {
+	state := make(map[string]int) // 1 - visiting, 2 - visited.
+	var visit func(name string, path []string) bool
+	visit = func(name string, path []string) bool {
+		m, ok := l.models[name]
+		if !ok {
+			return false
+		}
+		path = append(path, name)
+		switch state[name] {
+		case 1:
+			for len(path) > 0 && path[0] != name {
+				path = path[1:]
+			}
+			l.error(m.Range, fmt.Sprintf(
+				"Cycle detected in the data model %s: %s",
+				name,
+				strings.Join(path, " -> "),
+			))
+			return true
+		case 2:
+			return false
+		}
+		state[name] = 1
+		defer func() { state[name] = 2 }()
+		for _, ref := range references(m.Nodes) {
+			if visit(ref, path) {
+				return true
+			}
+		}
+		return false
+	}
+	for i := range l.config.DataModels {
+		visit(l.config.DataModels[i].Name, nil)
+	}
+}
+
+// lintNodes checks the given sibling nodes and all their descendants.
+func (l *linter) lintNodes(nodes []configDynamicNode) {
+	queries := make(map[string]bool)
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *configNodeOrigin:
+			l.lintOrigin(n)
+			key := n.Origin + "\x00" + queryString(n.Query)
+			if queries[key] {
+				l.warning(attrRange(n.configNode, "query"), fmt.Sprintf(
+					"Duplicate query %s for origin %s, the same value is used more than once",
+					queryString(n.Query),
+					n.Origin,
+				))
+			}
+			queries[key] = true
+		case *configNodeReference:
+			if _, ok := l.models[n.DataModel]; !ok {
+				l.error(attrRange(n.configNode, "data_model"), fmt.Sprintf("Unknown data model: %s", n.DataModel))
+			}
+		case *configNodeAlias:
+			l.lintAlias(n)
+		case *configNodeIndirect:
+			l.lintIndirect(n)
+		case *configNodeMedian:
+			l.lintMedian(n)
+		case *DeviationCircuitBreaker:
+			l.lintCircuitBreaker(n)
+		}
+		l.lintNodes(node.childNodes())
+	}
+}
+
+func (l *linter) lintOrigin(n *configNodeOrigin) {
+	l.used[n.Origin] = true
+	if _, ok := l.origins[n.Origin]; !ok {
+		l.error(n.Range, fmt.Sprintf("Unknown origin: %s", n.Origin))
+	}
+	if len(n.Nodes) > 0 {
+		l.error(n.Range, "Origin node cannot have child nodes")
+	}
+}
+
+func (l *linter) lintAlias(n *configNodeAlias) {
+	if len(n.Nodes) != 1 {
+		l.error(n.Range, "Alias node must have exactly one child node")
+		return
+	}
+	child, ok := l.nodePair(n.Nodes[0])
+	if !ok {
+		return
+	}
+	// Alias only renames assets, it does not invert the price, so an asset
+	// that appears on the other side of the pair is most likely a mistake.
+	if child.Base == n.Pair.Quote || child.Quote == n.Pair.Base {
+		l.error(n.Range, fmt.Sprintf(
+			"Alias %s does not match the pair of its child node %s, use the invert node to invert the pair",
+			n.Pair,
+			child,
+		))
+	}
+}
+
+func (l *linter) lintIndirect(n *configNodeIndirect) {
+	if len(n.Nodes) == 0 {
+		l.error(n.Range, "Indirect node must have at least one child node")
+		return
+	}
+	l.indirectPair(n, true)
+}
+
+func (l *linter) lintMedian(n *configNodeMedian) {
+	minValuesRange := attrRange(n.configNode, "min_values")
+	if n.MinValues <= 0 {
+		l.error(minValuesRange, "Minimum number of values must be greater than zero")
+	}
+	if n.MinValues > len(n.Nodes) {
+		l.error(minValuesRange, fmt.Sprintf(
+			"Minimum number of values is %d, but the median has only %d sources",
+			n.MinValues,
+			len(n.Nodes),
+		))
+	}
+	var first value.Pair
+	for _, node := range n.Nodes {
+		pair, ok := l.nodePair(node)
+		if !ok {
+			continue
+		}
+		if first.Empty() {
+			first = pair
+			continue
+		}
+		if !pair.Equal(first) {
+			l.error(node.hclRange(), fmt.Sprintf(
+				"Median sources must have the same pair, expected %s, got %s",
+				first,
+				pair,
+			))
+		}
+	}
+}
+
+func (l *linter) lintCircuitBreaker(n *DeviationCircuitBreaker) {
+	switch len(n.Nodes) {
+	case 0, 1:
+		l.error(n.Range, "Circuit breaker is missing a reference value node, it must have three child nodes: value, reference value and threshold")
+	case 2:
+		l.error(n.Range, "Circuit breaker is missing a threshold node, it must have three child nodes: value, reference value and threshold")
+	case 3:
+	default:
+		l.error(n.Range, "Circuit breaker must have exactly three child nodes: value, reference value and threshold")
+	}
+}
+
+// nodePair returns the pair of values returned by the given node. If the
+// pair cannot be determined statically, false is returned.
+func (l *linter) nodePair(node configDynamicNode) (value.Pair, bool) {
+	switch n := node.(type) {
+	case *configNodeOrigin:
+		if !n.Query.IsKnown() || n.Query.IsNull() || n.Query.Type() != cty.String {
+			return value.Pair{}, false
+		}
+		pair, err := value.PairFromString(n.Query.AsString())
+		return pair, err == nil
+	case *configNodeReference:
+		return l.modelPair(n.DataModel)
+	case *configNodeAlias:
+		return n.Pair, true
+	case *configNodeInvert:
+		if len(n.Nodes) != 1 {
+			return value.Pair{}, false
+		}
+		pair, ok := l.nodePair(n.Nodes[0])
+		return pair.Invert(), ok
+	case *configNodeIndirect:
+		return l.indirectPair(n, false)
+	case *configNodeMedian:
+		for _, child := range n.Nodes {
+			if pair, ok := l.nodePair(child); ok {
+				return pair, true
+			}
+		}
+	case *DeviationCircuitBreaker:
+		if len(n.Nodes) > 0 {
+			return l.nodePair(n.Nodes[0])
+		}
+	}
+	return value.Pair{}, false
+}
+
+// modelPair returns the pair of values returned by the data model. The pair
+// is resolved from the model nodes, and if that is not possible, from the
+// model name.
+//
+// Cycles are reported by lintCycles, here they are only skipped.
+func (l *linter) modelPair(name string) (value.Pair, bool) {
+	m, ok := l.models[name]
+	if !ok {
+		return value.Pair{}, false
+	}
+	if pair, ok := l.pairs[name]; ok {
+		return pair, true
+	}
+	if l.resolving[name] {
+		return modelNamePair(name)
+	}
+	l.resolving[name] = true
+	defer delete(l.resolving, name)
+	var pair value.Pair
+	ok = false
+	if len(m.Nodes) == 1 {
+		pair, ok = l.nodePair(m.Nodes[0])
+	}
+	if !ok {
+		pair, ok = modelNamePair(name)
+	}
+	if ok {
+		l.pairs[name] = pair
+	}
+	return pair, ok
+}
+
+// indirectPair calculates the pair of the indirect node in the same way as
+// the graph.TickIndirectNode does. If report is true, nodes with no common
+// asset are reported.
+func (l *linter) indirectPair(n *configNodeIndirect, report bool) (value.Pair, bool) {
+	var (
+		pair  value.Pair
+		known bool
+	)
+	for i, node := range n.Nodes {
+		next, ok := l.nodePair(node)
+		if !ok {
+			return value.Pair{}, false
+		}
+		if i == 0 {
+			pair, known = next, true
+			continue
+		}
+		switch {
+		case pair.Quote == next.Quote:
+			pair = value.Pair{Base: pair.Base, Quote: next.Base}
+		case pair.Base == next.Base:
+			pair = value.Pair{Base: pair.Quote, Quote: next.Quote}
+		case pair.Quote == next.Base:
+			pair = value.Pair{Base: pair.Base, Quote: next.Quote}
+		case pair.Base == next.Quote:
+			pair = value.Pair{Base: pair.Quote, Quote: next.Base}
+		default:
+			if report {
+				l.error(node.hclRange(), fmt.Sprintf(
+					"Indirect chain is broken, %s and %s do not have a common asset",
+					pair,
+					next,
+				))
+			}
+			return value.Pair{}, false
+		}
+	}
+	return pair, known
+}
+
+func (l *linter) error(rng hcl.Range, detail string) {
+	l.diags = append(l.diags, &hcl.Diagnostic{
+		Severity: hcl.DiagError,
+		Summary:  "Lint error",
+		Detail:   detail,
+		Subject:  rng.Ptr(),
+	})
+}
+
+func (l *linter) warning(rng hcl.Range, detail string) {
+	l.diags = append(l.diags, &hcl.Diagnostic{
+		Severity: hcl.DiagWarning,
+		Summary:  "Lint warning",
+		Detail:   detail,
+		Subject:  rng.Ptr(),
+	})
+}
+
+// references returns names of data models referenced by the given nodes
+// and their descendants.
+func references(nodes []configDynamicNode) []string {
+	var refs []string
+	for _, node := range nodes {
+		if n, ok := node.(*configNodeReference); ok {
+			refs = append(refs, n.DataModel)
+		}
+		refs = append(refs, references(node.childNodes())...)
+	}
+	return refs
+}
+
+// attrRange returns the range of the attribute with the given name, or the
+// range of the node if the attribute is not defined.
+func attrRange(n configNode, name string) hcl.Range {
+	if attr, ok := n.Content.Attributes[name]; ok {
+		return attr.Range
+	}
+	return n.Range
+}
+
+func queryString(q cty.Value) string {
+	if !q.IsKnown() || q.IsNull() {
+		return ""
+	}
+	switch q.Type() {
+	case cty.String:
+		return q.AsString()
+	case cty.Number:
+		return q.AsBigFloat().String()
+	}
+	return q.GoString()
+}
+
+func modelNamePair(name string) (value.Pair, bool) {
+	if !strings.Contains(name, "/") {
+		return value.Pair{}, false
+	}
+	pair, err := value.PairFromString(name)
+	return pair, err == nil
+}
@@ -0,0 +1,194 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dataprovider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	utilHCL "github.com/chronicleprotocol/oracle-suite/pkg/util/hcl"
+)
+
+const lintOrigins = `
+origin "a" { type = "static" }
+origin "b" { type = "static" }
+`
+
+func TestConfig_Lint(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		severity hcl.DiagnosticSeverity
+		detail   string
+		line     int
+	}{
+		{
+			name: "valid",
+			src: `
+data_model "AAA/USD" {
+  median {
+    min_values = 2
+    origin "a" { query = "AAA/USD" }
+    indirect {
+      origin "b" { query = "AAA/BBB" }
+      alias "BBB/USD" {
+        origin "a" { query = "WBBB/USD" }
+      }
+    }
+  }
+}`,
+		},
+		{
+			name: "unused origin",
+			src: `
+data_model "AAA/USD" {
+  origin "a" { query = "AAA/USD" }
+}`,
+			severity: hcl.DiagWarning,
+			detail:   "Origin b is not used by any data model",
+			line:     3,
+		},
+		{
+			name: "unknown origin",
+			src: `
+data_model "AAA/USD" {
+  median {
+    min_values = 1
+    origin "a" { query = "AAA/USD" }
+    origin "b" { query = "AAA/USD" }
+    origin "c" { query = "AAA/USD" }
+  }
+}`,
+			severity: hcl.DiagError,
+			detail:   "Unknown origin: c",
+			line:     10,
+		},
+		{
+			name: "broken indirect",
+			src: `
+data_model "AAA/USD" {
+  indirect {
+    origin "a" { query = "AAA/BBB" }
+    origin "b" { query = "CCC/USD" }
+  }
+}`,
+			severity: hcl.DiagError,
+			detail:   "Indirect chain is broken, AAA/BBB and CCC/USD do not have a common asset",
+			line:     8,
+		},
+		{
+			name: "min values",
+			src: `
+data_model "AAA/USD" {
+  median {
+    min_values = 3
+    origin "a" { query = "AAA/USD" }
+    origin "b" { query = "AAA/USD" }
+  }
+}`,
+			severity: hcl.DiagError,
+			detail:   "Minimum number of values is 3, but the median has only 2 sources",
+			line:     7,
+		},
+		{
+			name: "alias",
+			src: `
+data_model "AAA/USD" {
+  median {
+    min_values = 2
+    origin "a" { query = "AAA/USD" }
+    alias "AAA/USD" {
+      origin "b" { query = "USD/AAA" }
+    }
+  }
+}`,
+			severity: hcl.DiagError,
+			detail:   "Alias AAA/USD does not match the pair of its child node USD/AAA, use the invert node to invert the pair",
+			line:     9,
+		},
+		{
+			name: "circuit breaker",
+			src: `
+data_model "AAA/USD" {
+  deviation_circuit_breaker {
+    threshold = 0.1
+    origin "a" { query = "AAA/USD" }
+    origin "b" { query = "AAA/USD" }
+  }
+}`,
+			severity: hcl.DiagError,
+			detail:   "Circuit breaker is missing a threshold node, it must have three child nodes: value, reference value and threshold",
+			line:     6,
+		},
+		{
+			name: "duplicate query",
+			src: `
+data_model "AAA/USD" {
+  median {
+    min_values = 2
+    origin "a" { query = "AAA/USD" }
+    origin "b" { query = "AAA/USD" }
+    origin "a" { query = "AAA/USD" }
+  }
+}`,
+			severity: hcl.DiagWarning,
+			detail:   "Duplicate query AAA/USD for origin a, the same value is used more than once",
+			line:     10,
+		},
+		{
+			name: "cycle",
+			src: `
+data_model "AAA/USD" {
+  median {
+    min_values = 2
+    origin "a" { query = "AAA/USD" }
+    origin "b" { query = "AAA/USD" }
+  }
+}
+data_model "BBB/USD" {
+  reference { data_model = "CCC/USD" }
+}
+data_model "CCC/USD" {
+  reference { data_model = "BBB/USD" }
+}`,
+			severity: hcl.DiagError,
+			detail:   "Cycle detected in the data model BBB/USD: BBB/USD -> CCC/USD -> BBB/USD",
+			line:     12,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, diags := utilHCL.ParseSource("test.hcl", []byte(lintOrigins+tt.src))
+			require.False(t, diags.HasErrors(), diags.Error())
+			var cfg Config
+			diags = utilHCL.Decode(&hcl.EvalContext{}, body, &cfg)
+			require.False(t, diags.HasErrors(), diags.Error())
+
+			diags = cfg.Lint()
+			if tt.detail == "" {
+				assert.Empty(t, diags)
+				return
+			}
+			require.Len(t, diags, 1, diags.Error())
+			assert.Equal(t, tt.severity, diags[0].Severity)
+			assert.Equal(t, tt.detail, diags[0].Detail)
+			assert.Equal(t, tt.line, diags[0].Subject.Start.Line)
+		})
+	}
+}
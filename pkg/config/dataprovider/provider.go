@@ -35,6 +35,12 @@ type Dependencies struct {
 	HTTPClient *http.Client
 	Clients    ethereum.ClientRegistry
 	Logger     log.Logger
+
+	// OriginWrapper is an optional function that wraps every configured
+	// origin, e.g. to modify data points returned by origins. Wrapped origins
+	// are used only to fetch data points, data models are configured using
+	// the original ones.
+	OriginWrapper func(name string, o origin.Origin) origin.Origin
 }
 
 type Config struct {
@@ -61,6 +67,14 @@ func (c *Config) ConfigureDataProvider(d Dependencies) (datapoint.Provider, erro
 		return nil, err
 	}
 
+	// Wrap origins, after data models are configured, because queries are
+	// parsed depending on the origin type:
+	if d.OriginWrapper != nil {
+		for name, o := range origins {
+			origins[name] = d.OriginWrapper(name, o)
+		}
+	}
+
 	// Configure data provider:
 	return graph.NewProvider(models, graph.NewUpdater(origins, d.Logger)), nil
 }
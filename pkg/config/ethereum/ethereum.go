@@ -17,6 +17,7 @@ package ethereum
 
 import (
 	"fmt"
+	"io"
 	"math/big"
 	"net/http"
 	"os"
@@ -32,14 +33,17 @@ import (
 	"github.com/hashicorp/hcl/v2"
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/config"
+	"github.com/chronicleprotocol/oracle-suite/pkg/ethereum"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
 	"github.com/chronicleprotocol/oracle-suite/pkg/rpcsplitter"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/errutil"
 )
 
 const LoggerTag = "CONFIG_ETHEREUM"
 
 const (
 	splitterVirtualHost       = "rpc-splitter"
+	splitterCacheSize         = 1024
 	defaultTotalTimeout       = 10
 	defaultGracefulTimeout    = 1
 	defaultGasLimitMultiplier = 1.25
@@ -53,6 +57,11 @@ type (
 type Dependencies struct {
 	// Logger is the logger that is used by RPC-Splitter.
 	Logger log.Logger
+
+	// HTTPTransport is an optional function that wraps the HTTP transport
+	// of every Ethereum client, e.g. to record or replay RPC responses.
+	// The name argument is the name of the client.
+	HTTPTransport func(name string, next http.RoundTripper) http.RoundTripper
 }
 
 // Config contains the configuration for Ethereum clients and keys.
@@ -84,20 +93,43 @@ type ConfigKey struct {
 	// Address is the address of the key in hex format.
 	Address types.Address `hcl:"address"`
 
-	// KeystorePath is the path to the keystore directory.
-	KeystorePath string `hcl:"keystore_path"`
+	// KeystorePath is the path to the keystore directory. Either KeystorePath
+	// or RemoteSigner must be set.
+	KeystorePath string `hcl:"keystore_path,optional"`
 
 	// PassphraseFile is the path to the file containing the passphrase for the
 	// key. If empty, then the passphrase is not provided.
 	PassphraseFile string `hcl:"passphrase_file,optional"`
 
+	// Passphrase is the passphrase for the key, usually provided using the
+	// secret() function. Cannot be used together with PassphraseFile.
+	Passphrase string `hcl:"passphrase,optional"`
+
+	// RemoteSigner is the configuration of an external signing service that
+	// manages the key. Either KeystorePath or RemoteSigner must be set.
+	RemoteSigner *ConfigRemoteSigner `hcl:"remote_signer,block,optional"`
+
 	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
 	Content hcl.BodyContent `hcl:",content"`
 
 	// Configured key:
 	key wallet.Key
 }
 
+// ConfigRemoteSigner contains the configuration for an external signing
+// service, like Clef or Web3Signer.
+type ConfigRemoteSigner struct {
+	// URL is the JSON-RPC endpoint of the signing service.
+	URL config.URL `hcl:"url"`
+
+	// Timeout is the timeout for a single signing request, in seconds.
+	Timeout uint32 `hcl:"timeout,optional"`
+
+	// HCL fields:
+	Content hcl.BodyContent `hcl:",content"`
+}
+
 // ConfigClient contains the configuration for an Ethereum client.
 type ConfigClient struct {
 	// Name is the unique name of the client that can be referenced by other
@@ -124,6 +156,33 @@ type ConfigClient struct {
 	// block number.
 	MaxBlocksBehind uint64 `hcl:"max_blocks_behind,optional"`
 
+	// CacheTTL is the time, in seconds, for which responses to calls that
+	// refer to a specific block are cached. If zero, the cache is disabled.
+	CacheTTL uint32 `hcl:"cache_ttl,optional"`
+
+	// Endpoints contains weights and tags for the RPC URLs. URLs without an
+	// endpoint block have a weight of 1 and no tags.
+	Endpoints []ConfigEndpoint `hcl:"endpoint,block"`
+
+	// Routes defines to which endpoints calls to specific methods are sent.
+	// Calls to methods without a route are sent to all endpoints.
+	Routes []ConfigRoute `hcl:"route,block"`
+
+	// HealthCheck enables temporary ejection of unhealthy endpoints.
+	HealthCheck *ConfigHealthCheck `hcl:"health_check,block,optional"`
+
+	// Passthrough specifies how calls to methods that are not supported by
+	// the RPC-Splitter are handled. Possible values are "disabled", "first"
+	// and "majority". If empty, such calls are rejected.
+	Passthrough string `hcl:"passthrough,optional"`
+
+	// PassthroughMethods is a list of unsupported methods that can be
+	// forwarded to the endpoints if the passthrough is enabled. A method
+	// ending with "*" matches all methods with the same prefix, e.g. "eth_*".
+	// If empty, all methods except "admin_*", "personal_*" and "debug_*"
+	// are forwarded.
+	PassthroughMethods []string `hcl:"passthrough_methods,optional"`
+
 	// Key configuration:
 
 	// EthereumKey is the name of the Ethereum key to use for signing
@@ -141,12 +200,134 @@ type ConfigClient struct {
 	MaxGasPriorityFee        *big.Int `hcl:"max_gas_priority_fee,optional"`
 	MaxGasLimit              *big.Int `hcl:"max_gas_limit,optional"`
 
+	// FeeEstimator selects the strategy used to estimate transaction fees.
+	// If not set, fees are estimated using eth_gasPrice and, for EIP-1559
+	// transactions, eth_maxPriorityFeePerGas, multiplied by the gas fee
+	// multipliers.
+	FeeEstimator *ConfigFeeEstimator `hcl:"fee_estimator,block,optional"`
+
 	// HCL fields:
 	Range   hcl.Range       `hcl:",range"`
 	Content hcl.BodyContent `hcl:",content"`
 
 	// Configured services:
 	client rpc.RPC
+	closer io.Closer
+}
+
+// ConfigEndpoint contains the weight and tags for one of the RPC URLs of
+// an Ethereum client.
+type ConfigEndpoint struct {
+	// URL is the RPC URL, it must be one of the client's RPC URLs.
+	URL string `hcl:"url,label"`
+
+	// Weight is the relative weight of the endpoint used to select endpoints
+	// for routed methods. If zero, the weight is 1.
+	Weight uint32 `hcl:"weight,optional"`
+
+	// Tags are labels, like "archive", "fast" or "cheap", used by routes to
+	// select endpoints.
+	Tags []string `hcl:"tags,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+// ConfigFeeEstimator contains the configuration of the fee estimation
+// strategy of an Ethereum client.
+type ConfigFeeEstimator struct {
+	// Type is the type of the estimator, one of: multiplier, fee_history,
+	// fixed.
+	Type string `hcl:"type,label"`
+
+	// Blocks is the number of recent blocks used by the fee_history
+	// estimator. If zero, 10 blocks are used.
+	Blocks uint64 `hcl:"blocks,optional"`
+
+	// RewardPercentile is the percentile of priority fees paid in a block
+	// used by the fee_history estimator. If zero, the 50th percentile is
+	// used.
+	RewardPercentile float64 `hcl:"reward_percentile,optional"`
+
+	// BaseFeeMultiplier is applied to the base fee by the fee_history
+	// estimator. If zero, the multiplier is 2.
+	BaseFeeMultiplier float64 `hcl:"base_fee_multiplier,optional"`
+
+	// GasPrice is the gas price used by the fixed estimator for legacy
+	// transactions.
+	GasPrice *big.Int `hcl:"gas_price,optional"`
+
+	// MaxFeePerGas is the max fee per gas used by the fixed estimator for
+	// EIP-1559 transactions.
+	MaxFeePerGas *big.Int `hcl:"max_fee_per_gas,optional"`
+
+	// PriorityFeePerGas is the priority fee per gas used by the fixed
+	// estimator for EIP-1559 transactions.
+	PriorityFeePerGas *big.Int `hcl:"priority_fee_per_gas,optional"`
+
+	// L2Chain enables estimation of the L1 data fee for L2 chains, one of:
+	// op_stack, arbitrum.
+	L2Chain string `hcl:"l2_chain,optional"`
+
+	// MaxTotalFee is the maximum total fee of a transaction, in wei,
+	// including the L1 data fee. Transactions that exceed it are not sent.
+	// It can be used only together with L2Chain.
+	MaxTotalFee *big.Int `hcl:"max_total_fee,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+// ConfigHealthCheck contains the configuration for ejecting unhealthy
+// endpoints of an Ethereum client.
+type ConfigHealthCheck struct {
+	// Window is the number of recent calls used to calculate the health of
+	// an endpoint. If zero, the last 100 calls are used.
+	Window uint32 `hcl:"window,optional"`
+
+	// MinSamples is the minimum number of calls before an endpoint can be
+	// ejected. If zero, 10 calls are required.
+	MinSamples uint32 `hcl:"min_samples,optional"`
+
+	// MaxErrorRate is the maximum percentage of failed calls. If zero, the
+	// error rate is not checked.
+	MaxErrorRate float64 `hcl:"max_error_rate,optional"`
+
+	// MaxDisagreementRate is the maximum percentage of responses that are
+	// different from the majority. If zero, the disagreement rate is not
+	// checked.
+	MaxDisagreementRate float64 `hcl:"max_disagreement_rate,optional"`
+
+	// EjectionTime is the time, in seconds, for which an unhealthy endpoint
+	// is ejected. If zero, endpoints are ejected for 60 seconds.
+	EjectionTime uint32 `hcl:"ejection_time,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+// ConfigRoute contains the routing rule for an RPC method.
+type ConfigRoute struct {
+	// Method is the name of the RPC method, e.g. "eth_call".
+	Method string `hcl:"method,label"`
+
+	// Endpoints is the number of endpoints to which the call is sent. If
+	// zero, the call is sent to all matching endpoints.
+	Endpoints uint32 `hcl:"endpoints,optional"`
+
+	// Tags limits the route to endpoints that have all the given tags.
+	Tags []string `hcl:"tags,optional"`
+
+	// MinResponses is the minimum number of valid responses. If zero, the
+	// client's default is used, but no more than the number of endpoints.
+	MinResponses uint32 `hcl:"min_responses,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
 }
 
 // KeyRegistry returns the list of configured Ethereum keys.
@@ -171,6 +352,67 @@ func (c *Config) ClientRegistry(d Dependencies) (ClientRegistry, error) {
 	return c.clients, nil
 }
 
+// ClientsEqual reports whether configurations of the Ethereum clients with
+// the given names are equal in both configs.
+func ClientsEqual(a, b *Config, names ...string) bool {
+	for _, name := range names {
+		if !config.Equal(a.client(name), b.client(name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReuseClients moves Ethereum clients already created by the prev config
+// to this config, for clients whose configuration did not change. It must
+// be called before the ClientRegistry method.
+//
+// Moved clients are no longer closed by the CloseClients method of the prev
+// config.
+func (c *Config) ReuseClients(prev *Config) {
+	if c == nil || prev == nil {
+		return
+	}
+	for i := range c.Clients {
+		for j := range prev.Clients {
+			p := &prev.Clients[j]
+			if p.client == nil || p.Name != c.Clients[i].Name || !config.Equal(c.Clients[i], *p) {
+				continue
+			}
+			c.Clients[i].client, c.Clients[i].closer = p.client, p.closer
+			p.client, p.closer = nil, nil
+		}
+	}
+}
+
+func (c *Config) client(name string) *ConfigClient {
+	if c == nil {
+		return nil
+	}
+	for i := range c.Clients {
+		if c.Clients[i].Name == name {
+			return &c.Clients[i]
+		}
+	}
+	return nil
+}
+
+// CloseClients closes connections used by Ethereum clients created by the
+// config, except for clients moved to another config by ReuseClients.
+func (c *Config) CloseClients() error {
+	if c == nil {
+		return nil
+	}
+	var err error
+	for i := range c.Clients {
+		if c.Clients[i].closer != nil {
+			err = errutil.Append(err, c.Clients[i].closer.Close())
+		}
+		c.Clients[i].client, c.Clients[i].closer = nil, nil
+	}
+	return err
+}
+
 func (c *Config) prepare(d Dependencies) error {
 	if c.prepared {
 		return nil
@@ -179,7 +421,7 @@ func (c *Config) prepare(d Dependencies) error {
 	if err := c.prepareKeys(logger); err != nil {
 		return err
 	}
-	if err := c.prepareClients(logger); err != nil {
+	if err := c.prepareClients(logger, d.HTTPTransport); err != nil {
 		return err
 	}
 	c.prepared = true
@@ -228,9 +470,10 @@ func (c *Config) prepareKeys(logger log.Logger) error {
 	return nil
 }
 
-func (c *Config) prepareClients(logger log.Logger) error {
+func (c *Config) prepareClients(logger log.Logger, httpTransport func(string, http.RoundTripper) http.RoundTripper) error {
 	c.clients = make(map[string]rpc.RPC)
-	for _, clientCfg := range c.Clients {
+	for i := range c.Clients {
+		clientCfg := &c.Clients[i]
 		if _, ok := c.clients[clientCfg.Name]; ok {
 			return &hcl.Diagnostic{
 				Severity: hcl.DiagError,
@@ -239,7 +482,7 @@ func (c *Config) prepareClients(logger log.Logger) error {
 				Subject:  clientCfg.Range.Ptr(),
 			}
 		}
-		client, err := clientCfg.Client(logger, c.keys)
+		client, err := clientCfg.Client(logger, c.keys, httpTransport)
 		if err != nil {
 			return err
 		}
@@ -275,14 +518,66 @@ func (c *ConfigKey) Key(logger log.Logger) (wallet.Key, error) {
 		}
 	}
 
+	// Create remote key.
+	if c.RemoteSigner != nil {
+		if c.KeystorePath != "" {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   "Ethereum key cannot use both keystore_path and remote_signer",
+				Subject:  c.Content.Attributes["keystore_path"].Range.Ptr(),
+			}
+		}
+		key, err := ethereum.NewRemoteKey(ethereum.RemoteKeyOptions{
+			URL:     c.RemoteSigner.URL.String(),
+			Address: c.Address,
+			Timeout: time.Second * time.Duration(c.RemoteSigner.Timeout),
+		})
+		if err != nil {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Failed to create remote Ethereum key: %v", err),
+				Subject:  c.Content.Attributes["address"].Range.Ptr(),
+			}
+		}
+		logger.
+			WithField("name", c.Name).
+			WithField("address", key.Address().String()).
+			WithField("url", c.RemoteSigner.URL.String()).
+			Info("Ethereum Key")
+		c.key = key
+		return key, nil
+	}
+	if c.KeystorePath == "" {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Ethereum key requires either keystore_path or remote_signer",
+			Subject:  c.Range.Ptr(),
+		}
+	}
+
 	// Get passphrase.
-	passphrase, err := readAccountPassphrase(c.PassphraseFile)
-	if err != nil {
+	if c.Passphrase != "" && c.PassphraseFile != "" {
 		return nil, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
 			Summary:  "Validation error",
-			Detail:   fmt.Sprintf("Failed to read Ethereum key passphrase: %v", err),
-			Subject:  c.Content.Attributes["passphrase_file"].Range.Ptr(),
+			Detail:   "Ethereum key cannot use both passphrase and passphrase_file",
+			Subject:  c.Content.Attributes["passphrase"].Range.Ptr(),
+		}
+	}
+	passphrase := c.Passphrase
+	if c.PassphraseFile != "" {
+		var err error
+		passphrase, err = readAccountPassphrase(c.PassphraseFile)
+		if err != nil {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Failed to read Ethereum key passphrase: %v", err),
+				Subject:  c.Content.Attributes["passphrase_file"].Range.Ptr(),
+			}
 		}
 	}
 
@@ -306,18 +601,40 @@ func (c *ConfigKey) Key(logger log.Logger) (wallet.Key, error) {
 	return key, nil
 }
 
-// Client returns the configured RPC client.
-func (c *ConfigClient) Client(logger log.Logger, keys KeyRegistry) (rpc.RPC, error) {
+// Client returns the configured RPC client. The httpTransport argument
+// is optional and may be used to wrap the HTTP transport of the client.
+func (c *ConfigClient) Client(
+	logger log.Logger,
+	keys KeyRegistry,
+	httpTransport func(string, http.RoundTripper) http.RoundTripper,
+) (rpc.RPC, error) {
 	if c == nil {
 		return nil, fmt.Errorf("ethereum config: client is not configured")
 	}
 	if c.client != nil {
 		return c.client, nil
 	}
+	client, closer, err := c.newClient(logger, keys, httpTransport)
+	if err != nil {
+		return nil, err
+	}
+	c.client = client
+	c.closer = closer
+	return client, nil
+}
 
+// newClient creates the RPC client. It uses a value receiver, so default
+// values are applied to a copy and the config keeps the decoded values.
+//
+// The returned closer closes the connections used by the client.
+func (c ConfigClient) newClient(
+	logger log.Logger,
+	keys KeyRegistry,
+	httpTransport func(string, http.RoundTripper) http.RoundTripper,
+) (_ rpc.RPC, _ io.Closer, err error) {
 	// Validate the client configuration.
 	if len(c.Name) == 0 {
-		return nil, &hcl.Diagnostic{
+		return nil, nil, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
 			Summary:  "Validation error",
 			Detail:   "Ethereum client name is required",
@@ -325,7 +642,7 @@ func (c *ConfigClient) Client(logger log.Logger, keys KeyRegistry) (rpc.RPC, err
 		}
 	}
 	if !nameRegexp.MatchString(c.Name) {
-		return nil, &hcl.Diagnostic{
+		return nil, nil, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
 			Summary:  "Validation error",
 			Detail:   "Ethereum client name must contain only alphanumeric characters and underscores",
@@ -333,7 +650,7 @@ func (c *ConfigClient) Client(logger log.Logger, keys KeyRegistry) (rpc.RPC, err
 		}
 	}
 	if len(c.RPCURLs) == 0 {
-		return nil, &hcl.Diagnostic{
+		return nil, nil, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
 			Summary:  "Validation error",
 			Detail:   "At least one RPC URL is required",
@@ -347,7 +664,7 @@ func (c *ConfigClient) Client(logger log.Logger, keys KeyRegistry) (rpc.RPC, err
 		c.GracefulTimeout = defaultGracefulTimeout
 	}
 	if c.Timeout < 1 {
-		return nil, &hcl.Diagnostic{
+		return nil, nil, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
 			Summary:  "Validation error",
 			Detail:   "Timeout cannot be less than one second",
@@ -355,7 +672,7 @@ func (c *ConfigClient) Client(logger log.Logger, keys KeyRegistry) (rpc.RPC, err
 		}
 	}
 	if c.GracefulTimeout < 1 {
-		return nil, &hcl.Diagnostic{
+		return nil, nil, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
 			Summary:  "Validation error",
 			Detail:   "Graceful timeout cannot be less than one second",
@@ -364,10 +681,15 @@ func (c *ConfigClient) Client(logger log.Logger, keys KeyRegistry) (rpc.RPC, err
 	}
 
 	// Create the RPC client.
-	rpcTransport, err := c.transport(logger)
+	rpcTransport, splitter, err := c.transport(logger, httpTransport)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	defer func() {
+		if err != nil {
+			_ = splitter.Close()
+		}
+	}()
 	opts := []rpc.ClientOptions{
 		rpc.WithTransport(rpcTransport),
 		rpc.WithTXModifiers(
@@ -383,7 +705,7 @@ func (c *ConfigClient) Client(logger log.Logger, keys KeyRegistry) (rpc.RPC, err
 	if c.EthereumKey != "" {
 		key, ok := keys[c.EthereumKey]
 		if !ok {
-			return nil, &hcl.Diagnostic{
+			return nil, nil, &hcl.Diagnostic{
 				Severity: hcl.DiagError,
 				Summary:  "Validation error",
 				Detail:   fmt.Sprintf("Ethereum key %q is not configured", c.EthereumKey),
@@ -417,43 +739,12 @@ func (c *ConfigClient) Client(logger log.Logger, keys KeyRegistry) (rpc.RPC, err
 		c.GasPriorityFeeMultiplier = 1
 	}
 
-	switch c.TransactionType {
-	case "legacy":
-		opts = append(
-			opts,
-			rpc.WithTXModifiers(
-				txmodifier.NewLegacyGasFeeEstimator(txmodifier.LegacyGasFeeEstimatorOptions{
-					Multiplier:  c.GasFeeMultiplier,
-					MinGasPrice: nil,
-					MaxGasPrice: c.MaxGasFee,
-					Replace:     false,
-				}),
-			),
-		)
-	case "eip1559":
-		opts = append(
-			opts,
-			rpc.WithTXModifiers(
-				txmodifier.NewEIP1559GasFeeEstimator(txmodifier.EIP1559GasFeeEstimatorOptions{
-					GasPriceMultiplier:          c.GasFeeMultiplier,
-					PriorityFeePerGasMultiplier: c.GasPriorityFeeMultiplier,
-					MinGasPrice:                 nil,
-					MaxGasPrice:                 c.MaxGasFee,
-					MinPriorityFeePerGas:        nil,
-					MaxPriorityFeePerGas:        c.MaxGasPriorityFee,
-					Replace:                     false,
-				}),
-			),
-		)
-	case "":
-	// No-op.
-	default:
-		return nil, &hcl.Diagnostic{
-			Severity: hcl.DiagError,
-			Summary:  "Validation error",
-			Detail:   fmt.Sprintf("Invalid transaction type %q, must be one of: legacy, eip1559", c.TransactionType),
-			Subject:  c.Content.Attributes["tx_type"].Range.Ptr(),
-		}
+	feeEstimator, err := c.feeEstimator(rpcTransport)
+	if err != nil {
+		return nil, nil, err
+	}
+	if feeEstimator != nil {
+		opts = append(opts, rpc.WithTXModifiers(feeEstimator))
 	}
 
 	for _, u := range c.RPCURLs {
@@ -465,50 +756,92 @@ func (c *ConfigClient) Client(logger log.Logger, keys KeyRegistry) (rpc.RPC, err
 
 	client, err := rpc.NewClient(opts...)
 	if err != nil {
-		return nil, &hcl.Diagnostic{
+		return nil, nil, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
 			Summary:  "Runtime error",
 			Detail:   fmt.Sprintf("Failed to create the Ethereum client: %v", err),
 			Subject:  c.Range.Ptr(),
 		}
 	}
-
-	c.client = client
-	return client, nil
+	return client, splitter, nil
 }
 
-func (c *ConfigClient) transport(logger log.Logger) (transport.Transport, error) {
-	var err error
-	rpcURLs := make([]string, len(c.RPCURLs))
-	for i, u := range c.RPCURLs {
-		rpcURLs[i] = u.String()
+// transport creates the RPC transport together with the RPC-Splitter
+// used by it.
+func (c *ConfigClient) transport(
+	logger log.Logger,
+	httpTransport func(string, http.RoundTripper) http.RoundTripper,
+) (_ transport.Transport, _ *rpcsplitter.Transport, err error) {
+	endpoints, err := c.endpoints()
+	if err != nil {
+		return nil, nil, err
 	}
-	// In theory, we don't need to use RPC-Splitter for a single endpoint, but
-	// to make the application behavior consistent we use it.
-	splitter, err := rpcsplitter.NewTransport(
-		splitterVirtualHost,
-		nil,
-		rpcsplitter.WithEndpoints(rpcURLs),
-		rpcsplitter.WithTotalTimeout(time.Second*time.Duration(c.Timeout)),
-		rpcsplitter.WithGracefulTimeout(time.Second*time.Duration(c.GracefulTimeout)),
+	routes, err := c.routes()
+	if err != nil {
+		return nil, nil, err
+	}
+	passthrough, err := c.passthrough()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, m := range c.PassthroughMethods {
+		if m == "" || strings.Contains(strings.TrimSuffix(m, "*"), "*") {
+			return nil, nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Invalid passthrough method %q, only a single \"*\" at the end is allowed", m),
+				Subject:  c.Content.Attributes["passthrough_methods"].Range.Ptr(),
+			}
+		}
+	}
+	opts := []rpcsplitter.Option{
+		rpcsplitter.WithWeightedEndpoints(endpoints),
+		rpcsplitter.WithRoutes(routes),
+		rpcsplitter.WithPassthrough(passthrough),
+		rpcsplitter.WithPassthroughMethods(c.PassthroughMethods),
+		rpcsplitter.WithTotalTimeout(time.Second * time.Duration(c.Timeout)),
+		rpcsplitter.WithGracefulTimeout(time.Second * time.Duration(c.GracefulTimeout)),
 		rpcsplitter.WithRequirements(minimumRequiredResponses(len(c.RPCURLs)), int(c.MaxBlocksBehind)),
 		rpcsplitter.WithLogger(logger),
-	)
+		rpcsplitter.WithName(c.Name),
+	}
+	if c.CacheTTL > 0 {
+		opts = append(opts, rpcsplitter.WithCache(time.Second*time.Duration(c.CacheTTL), splitterCacheSize))
+	}
+	if c.HealthCheck != nil {
+		healthCheck, err := c.HealthCheck.options()
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, rpcsplitter.WithHealthCheck(healthCheck))
+	}
+	// In theory, we don't need to use RPC-Splitter for a single endpoint, but
+	// to make the application behavior consistent we use it.
+	splitter, err := rpcsplitter.NewTransport(splitterVirtualHost, nil, opts...)
 	if err != nil {
-		return nil, &hcl.Diagnostic{
+		return nil, nil, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
 			Summary:  "Runtime error",
 			Detail:   fmt.Sprintf("Failed to create RPC-Splitter: %v", err),
 			Subject:  c.Range.Ptr(),
 		}
 	}
+	defer func() {
+		if err != nil {
+			_ = splitter.Close()
+		}
+	}()
+	var httpClientTransport http.RoundTripper = splitter
+	if httpTransport != nil {
+		httpClientTransport = httpTransport(c.Name, splitter)
+	}
 	var rpcTransport transport.Transport
 	rpcTransport, err = transport.NewHTTP(transport.HTTPOptions{
 		URL:        fmt.Sprintf("http://%s", splitterVirtualHost),
-		HTTPClient: &http.Client{Transport: splitter},
+		HTTPClient: &http.Client{Transport: httpClientTransport},
 	})
 	if err != nil {
-		return nil, &hcl.Diagnostic{
+		return nil, nil, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
 			Summary:  "Runtime error",
 			Detail:   fmt.Sprintf("Failed to create the Ethereum RPC transport: %v", err),
@@ -522,14 +855,16 @@ func (c *ConfigClient) transport(logger log.Logger) (transport.Transport, error)
 		MaxRetries:  3,
 	})
 	if err != nil {
-		return nil, &hcl.Diagnostic{
+		return nil, nil, &hcl.Diagnostic{
 			Severity: hcl.DiagError,
 			Summary:  "Runtime error",
 			Detail:   fmt.Sprintf("Failed to create the Ethereum RPC transport: %v", err),
 			Subject:  c.Range.Ptr(),
 		}
 	}
-	return rpcTransport, nil
+	// Subscriptions cannot be used over HTTP, so they are handled by
+	// the in-process connection to RPC-Splitter.
+	return transport.NewCombined(rpcTransport, splitter), splitter, nil
 }
 
 func readAccountPassphrase(path string) (string, error) {
@@ -562,3 +897,259 @@ func minimumRequiredResponses(endpoints int) int {
 }
 
 var nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+// endpoints returns the list of RPC-Splitter endpoints with weights and tags
+// from the endpoint blocks.
+func (c *ConfigClient) endpoints() ([]rpcsplitter.Endpoint, error) {
+	endpoints := make([]rpcsplitter.Endpoint, len(c.RPCURLs))
+	index := make(map[string]int, len(c.RPCURLs))
+	for i, u := range c.RPCURLs {
+		endpoints[i] = rpcsplitter.Endpoint{URL: u.String()}
+		index[u.String()] = i
+	}
+	configured := make(map[string]bool, len(c.Endpoints))
+	for _, e := range c.Endpoints {
+		i, ok := index[e.URL]
+		if !ok {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Endpoint %q is not one of the RPC URLs", e.URL),
+				Subject:  e.Range.Ptr(),
+			}
+		}
+		if configured[e.URL] {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Endpoint %q is already configured", e.URL),
+				Subject:  e.Range.Ptr(),
+			}
+		}
+		configured[e.URL] = true
+		endpoints[i].Weight = int(e.Weight)
+		endpoints[i].Tags = e.Tags
+	}
+	return endpoints, nil
+}
+
+// routes returns the list of RPC-Splitter routes from the route blocks.
+func (c *ConfigClient) routes() ([]rpcsplitter.Route, error) {
+	routes := make([]rpcsplitter.Route, len(c.Routes))
+	configured := make(map[string]bool, len(c.Routes))
+	for i, r := range c.Routes {
+		if configured[r.Method] {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Route for the %q method is already defined", r.Method),
+				Subject:  r.Range.Ptr(),
+			}
+		}
+		if r.Endpoints > 0 && r.MinResponses > r.Endpoints {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   "Minimum number of responses cannot be greater than number of endpoints",
+				Subject:  r.Content.Attributes["min_responses"].Range.Ptr(),
+			}
+		}
+		configured[r.Method] = true
+		routes[i] = rpcsplitter.Route{
+			Method:       r.Method,
+			Endpoints:    int(r.Endpoints),
+			Tags:         r.Tags,
+			MinResponses: int(r.MinResponses),
+		}
+	}
+	return routes, nil
+}
+
+// feeEstimator returns the transaction modifier that estimates transaction
+// fees. If fees should be estimated by the Ethereum node, nil is returned.
+func (c *ConfigClient) feeEstimator(t transport.Transport) (rpc.TXModifier, error) {
+	switch c.TransactionType {
+	case "", "legacy", "eip1559":
+	default:
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   fmt.Sprintf("Invalid transaction type %q, must be one of: legacy, eip1559", c.TransactionType),
+			Subject:  c.Content.Attributes["tx_type"].Range.Ptr(),
+		}
+	}
+	fe := c.FeeEstimator
+	if fe == nil {
+		fe = &ConfigFeeEstimator{Type: "multiplier"}
+	}
+	var (
+		estimator rpc.TXModifier
+		err       error
+	)
+	switch fe.Type {
+	case "multiplier":
+		estimator = c.multiplierFeeEstimator()
+	case "fee_history":
+		if c.TransactionType == "legacy" {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   "The fee_history fee estimator cannot be used with legacy transactions",
+				Subject:  fe.Range.Ptr(),
+			}
+		}
+		estimator, err = ethereum.NewFeeHistoryEstimator(ethereum.FeeHistoryEstimatorOptions{
+			Transport:            t,
+			Blocks:               fe.Blocks,
+			Percentile:           fe.RewardPercentile,
+			BaseFeeMultiplier:    fe.BaseFeeMultiplier,
+			MaxFeePerGas:         c.MaxGasFee,
+			MaxPriorityFeePerGas: c.MaxGasPriorityFee,
+		})
+	case "fixed":
+		var opts ethereum.FixedFeeEstimatorOptions
+		switch c.TransactionType {
+		case "legacy":
+			opts.GasPrice = fe.GasPrice
+		case "eip1559":
+			opts.MaxFeePerGas = fe.MaxFeePerGas
+			opts.MaxPriorityFeePerGas = fe.PriorityFeePerGas
+		default:
+			opts.GasPrice = fe.GasPrice
+			opts.MaxFeePerGas = fe.MaxFeePerGas
+			opts.MaxPriorityFeePerGas = fe.PriorityFeePerGas
+		}
+		estimator, err = ethereum.NewFixedFeeEstimator(opts)
+	default:
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   fmt.Sprintf("Invalid fee estimator %q, must be one of: multiplier, fee_history, fixed", fe.Type),
+			Subject:  fe.Range.Ptr(),
+		}
+	}
+	if err != nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   fmt.Sprintf("Invalid fee estimator configuration: %v", err),
+			Subject:  fe.Range.Ptr(),
+		}
+	}
+	if fe.L2Chain == "" {
+		if fe.MaxTotalFee != nil {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   "Maximum total fee can be used only together with the l2_chain attribute",
+				Subject:  fe.Content.Attributes["max_total_fee"].Range.Ptr(),
+			}
+		}
+		return estimator, nil
+	}
+	if estimator == nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "The L1 data fee can be estimated only if the transaction type is set",
+			Subject:  fe.Content.Attributes["l2_chain"].Range.Ptr(),
+		}
+	}
+	estimator, err = ethereum.NewL2FeeEstimator(ethereum.L2FeeEstimatorOptions{
+		Chain:       fe.L2Chain,
+		Estimator:   estimator,
+		MaxTotalFee: fe.MaxTotalFee,
+	})
+	if err != nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   fmt.Sprintf("Invalid L2 chain %q, must be one of: op_stack, arbitrum", fe.L2Chain),
+			Subject:  fe.Content.Attributes["l2_chain"].Range.Ptr(),
+		}
+	}
+	return estimator, nil
+}
+
+// multiplierFeeEstimator returns the fee estimator that multiplies fees
+// returned by the Ethereum node. If the transaction type is not set, nil is
+// returned.
+func (c *ConfigClient) multiplierFeeEstimator() rpc.TXModifier {
+	switch c.TransactionType {
+	case "legacy":
+		return ethereum.NewNamedFeeEstimator(
+			"multiplier",
+			fmt.Sprintf("eth_gasPrice multiplied by %g", c.GasFeeMultiplier),
+			txmodifier.NewLegacyGasFeeEstimator(txmodifier.LegacyGasFeeEstimatorOptions{
+				Multiplier:  c.GasFeeMultiplier,
+				MinGasPrice: nil,
+				MaxGasPrice: c.MaxGasFee,
+				Replace:     false,
+			}),
+		)
+	case "eip1559":
+		return ethereum.NewNamedFeeEstimator(
+			"multiplier",
+			fmt.Sprintf(
+				"eth_gasPrice multiplied by %g, eth_maxPriorityFeePerGas multiplied by %g",
+				c.GasFeeMultiplier, c.GasPriorityFeeMultiplier,
+			),
+			txmodifier.NewEIP1559GasFeeEstimator(txmodifier.EIP1559GasFeeEstimatorOptions{
+				GasPriceMultiplier:          c.GasFeeMultiplier,
+				PriorityFeePerGasMultiplier: c.GasPriorityFeeMultiplier,
+				MinGasPrice:                 nil,
+				MaxGasPrice:                 c.MaxGasFee,
+				MinPriorityFeePerGas:        nil,
+				MaxPriorityFeePerGas:        c.MaxGasPriorityFee,
+				Replace:                     false,
+			}),
+		)
+	}
+	return nil
+}
+
+// passthrough returns the RPC-Splitter passthrough policy.
+func (c *ConfigClient) passthrough() (rpcsplitter.PassthroughPolicy, error) {
+	switch c.Passthrough {
+	case "", "disabled":
+		return rpcsplitter.PassthroughDisabled, nil
+	case "first":
+		return rpcsplitter.PassthroughFirst, nil
+	case "majority":
+		return rpcsplitter.PassthroughMajority, nil
+	default:
+		return rpcsplitter.PassthroughDisabled, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   fmt.Sprintf("Invalid passthrough policy %q, must be one of: disabled, first, majority", c.Passthrough),
+			Subject:  c.Content.Attributes["passthrough"].Range.Ptr(),
+		}
+	}
+}
+
+// options returns the RPC-Splitter health check options.
+func (c *ConfigHealthCheck) options() (rpcsplitter.HealthCheckOptions, error) {
+	if c.MaxErrorRate < 0 || c.MaxErrorRate > 100 {
+		return rpcsplitter.HealthCheckOptions{}, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Maximum error rate must be between 0 and 100",
+			Subject:  c.Content.Attributes["max_error_rate"].Range.Ptr(),
+		}
+	}
+	if c.MaxDisagreementRate < 0 || c.MaxDisagreementRate > 100 {
+		return rpcsplitter.HealthCheckOptions{}, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Maximum disagreement rate must be between 0 and 100",
+			Subject:  c.Content.Attributes["max_disagreement_rate"].Range.Ptr(),
+		}
+	}
+	return rpcsplitter.HealthCheckOptions{
+		Window:              int(c.Window),
+		MinSamples:          int(c.MinSamples),
+		MaxErrorRate:        c.MaxErrorRate / 100,
+		MaxDisagreementRate: c.MaxDisagreementRate / 100,
+		EjectionTime:        time.Second * time.Duration(c.EjectionTime),
+	}, nil
+}
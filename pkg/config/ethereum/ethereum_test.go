@@ -1,6 +1,7 @@
 package ethereum
 
 import (
+	"fmt"
 	"math/big"
 	"testing"
 
@@ -9,9 +10,12 @@ import (
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/config"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	utilHCL "github.com/chronicleprotocol/oracle-suite/pkg/util/hcl"
 )
 
 func TestConfig(t *testing.T) {
+	t.Setenv("ETHEREUM_TEST_PASSPHRASE", "test123")
+
 	tests := []struct {
 		name string
 		path string
@@ -31,6 +35,14 @@ func TestConfig(t *testing.T) {
 				assert.Equal(t, "./testdata/keystore", cfg.Keys[1].KeystorePath)
 				assert.Equal(t, "./testdata/keystore/passphrase", cfg.Keys[1].PassphraseFile)
 
+				assert.Equal(t, "key3", cfg.Keys[2].Name)
+				assert.Equal(t, "0x1f8fbe73820765677e68eb6e933dcb3c94c9b708", cfg.Keys[2].Address.String())
+				assert.Equal(t, "http://localhost:8550", cfg.Keys[2].RemoteSigner.URL.String())
+				assert.Equal(t, uint32(5), cfg.Keys[2].RemoteSigner.Timeout)
+
+				assert.Equal(t, "key4", cfg.Keys[3].Name)
+				assert.Equal(t, "test123", cfg.Keys[3].Passphrase)
+
 				assert.Equal(t, "client1", cfg.Clients[0].Name)
 				assert.Equal(t, "https://rpc1.example", cfg.Clients[0].RPCURLs[0].String())
 				assert.Equal(t, uint64(1), cfg.Clients[0].ChainID)
@@ -49,6 +61,33 @@ func TestConfig(t *testing.T) {
 				assert.Equal(t, big.NewInt(1000000000000), cfg.Clients[1].MaxGasFee)
 				assert.Equal(t, big.NewInt(1000000000000), cfg.Clients[1].MaxGasPriorityFee)
 				assert.Equal(t, big.NewInt(10000000), cfg.Clients[1].MaxGasLimit)
+
+				assert.Equal(t, "client3", cfg.Clients[2].Name)
+				assert.Len(t, cfg.Clients[2].RPCURLs, 3)
+				assert.Equal(t, uint32(6), cfg.Clients[2].CacheTTL)
+				assert.Equal(t, "first", cfg.Clients[2].Passthrough)
+				assert.Equal(t, []string{"eth_*", "net_peerCount"}, cfg.Clients[2].PassthroughMethods)
+				assert.Equal(t, "https://archive.example", cfg.Clients[2].Endpoints[0].URL)
+				assert.Equal(t, uint32(2), cfg.Clients[2].Endpoints[0].Weight)
+				assert.Equal(t, []string{"archive"}, cfg.Clients[2].Endpoints[0].Tags)
+				assert.Equal(t, "eth_call", cfg.Clients[2].Routes[0].Method)
+				assert.Equal(t, uint32(2), cfg.Clients[2].Routes[0].Endpoints)
+				assert.Equal(t, "eth_getLogs", cfg.Clients[2].Routes[1].Method)
+				assert.Equal(t, []string{"archive"}, cfg.Clients[2].Routes[1].Tags)
+				assert.Equal(t, uint32(1), cfg.Clients[2].Routes[1].MinResponses)
+				assert.Equal(t, uint32(50), cfg.Clients[2].HealthCheck.Window)
+				assert.Equal(t, uint32(5), cfg.Clients[2].HealthCheck.MinSamples)
+				assert.Equal(t, float64(50), cfg.Clients[2].HealthCheck.MaxErrorRate)
+				assert.Equal(t, float64(25), cfg.Clients[2].HealthCheck.MaxDisagreementRate)
+				assert.Equal(t, uint32(30), cfg.Clients[2].HealthCheck.EjectionTime)
+
+				assert.Equal(t, "client4", cfg.Clients[3].Name)
+				assert.Equal(t, "fee_history", cfg.Clients[3].FeeEstimator.Type)
+				assert.Equal(t, uint64(20), cfg.Clients[3].FeeEstimator.Blocks)
+				assert.Equal(t, float64(60), cfg.Clients[3].FeeEstimator.RewardPercentile)
+				assert.Equal(t, float64(1.5), cfg.Clients[3].FeeEstimator.BaseFeeMultiplier)
+				assert.Equal(t, "op_stack", cfg.Clients[3].FeeEstimator.L2Chain)
+				assert.Equal(t, big.NewInt(1000000000000000), cfg.Clients[3].FeeEstimator.MaxTotalFee)
 			},
 		},
 		{
@@ -58,10 +97,12 @@ func TestConfig(t *testing.T) {
 				keys, diags := cfg.KeyRegistry(Dependencies{Logger: null.New()})
 				require.NoError(t, diags)
 
-				require.Len(t, keys, 3)
+				require.Len(t, keys, 5)
 				assert.NotNil(t, keys["rand_key"])
 				assert.Equal(t, "0xd18d7f6d9e349d1d6bf33702192019f166a7201e", keys["key1"].Address().String())
 				assert.Equal(t, "0x2d800d93b065ce011af83f316cef9f0d005b0aa4", keys["key2"].Address().String())
+				assert.Equal(t, "0x1f8fbe73820765677e68eb6e933dcb3c94c9b708", keys["key3"].Address().String())
+				assert.Equal(t, "0x2d800d93b065ce011af83f316cef9f0d005b0aa4", keys["key4"].Address().String())
 			},
 		},
 		{
@@ -71,9 +112,25 @@ func TestConfig(t *testing.T) {
 				clients, diags := cfg.ClientRegistry(Dependencies{Logger: null.New()})
 				require.NoError(t, diags)
 
-				require.Len(t, clients, 2)
+				require.Len(t, clients, 4)
 				assert.NotNil(t, clients["client1"])
 				assert.NotNil(t, clients["client2"])
+				assert.NotNil(t, clients["client3"])
+				assert.NotNil(t, clients["client4"])
+			},
+		},
+		{
+			name: "redacted passphrase",
+			path: "config.hcl",
+			test: func(t *testing.T, cfg *Config) {
+				body := &utilHCL.Block{}
+				require.False(t, utilHCL.Encode(cfg, body).HasErrors())
+				content, diags := body.Bytes()
+				require.False(t, diags.HasErrors())
+
+				redacted := string(config.RedactSecrets(content))
+				assert.NotContains(t, redacted, `"test123"`)
+				assert.Contains(t, redacted, `passphrase    = "${secret("env://ETHEREUM_TEST_PASSPHRASE")}"`)
 			},
 		},
 	}
@@ -86,3 +143,65 @@ func TestConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigKey_PassphraseConflict(t *testing.T) {
+	var cfg Config
+	require.NoError(t, config.LoadEmbeds(&cfg, [][]byte{[]byte(`
+		key "key" {
+		  address         = "0x2d800d93b065ce011af83f316cef9f0d005b0aa4"
+		  keystore_path   = "./testdata/keystore"
+		  passphrase      = "test123"
+		  passphrase_file = "./testdata/keystore/passphrase"
+		}
+	`)}))
+	_, err := cfg.KeyRegistry(Dependencies{Logger: null.New()})
+	assert.ErrorContains(t, err, "cannot use both passphrase and passphrase_file")
+}
+
+func TestConfig_ReuseClients(t *testing.T) {
+	load := func(chainID int) *Config {
+		var cfg Config
+		require.NoError(t, config.LoadEmbeds(&cfg, [][]byte{[]byte(fmt.Sprintf(`
+			client "client1" {
+			  rpc_urls = ["https://rpc1.example"]
+			}
+			client "client2" {
+			  rpc_urls = ["https://rpc2.example"]
+			  chain_id = %d
+			}
+		`, chainID))}))
+		return &cfg
+	}
+	prev := load(1)
+	prevClients, err := prev.ClientRegistry(Dependencies{Logger: null.New()})
+	require.NoError(t, err)
+
+	next := load(2)
+	next.ReuseClients(prev)
+	nextClients, err := next.ClientRegistry(Dependencies{Logger: null.New()})
+	require.NoError(t, err)
+
+	// Unchanged client is reused, changed client is created again.
+	assert.Same(t, prevClients["client1"], nextClients["client1"])
+	assert.NotSame(t, prevClients["client2"], nextClients["client2"])
+
+	// Only the client that was not reused is closed.
+	require.NoError(t, prev.CloseClients())
+	assert.Nil(t, prev.Clients[0].closer)
+	assert.Nil(t, prev.Clients[1].closer)
+	assert.NotNil(t, next.Clients[0].closer)
+	assert.NotNil(t, next.Clients[1].closer)
+}
+
+func TestConfigClient_InvalidPassthroughMethod(t *testing.T) {
+	var cfg Config
+	require.NoError(t, config.LoadEmbeds(&cfg, [][]byte{[]byte(`
+		client "client1" {
+		  rpc_urls            = ["https://rpc1.example"]
+		  passthrough         = "first"
+		  passthrough_methods = ["eth_*_call"]
+		}
+	`)}))
+	_, err := cfg.ClientRegistry(Dependencies{Logger: null.New()})
+	assert.ErrorContains(t, err, "Invalid passthrough method")
+}
@@ -13,6 +13,22 @@ key "key2" {
   passphrase_file = "./testdata/keystore/passphrase"
 }
 
+# Remote signer
+key "key3" {
+  address = "0x1f8fbe73820765677e68eb6e933dcb3c94c9b708"
+  remote_signer {
+    url     = "http://localhost:8550"
+    timeout = 5
+  }
+}
+
+# Passphrase from a secret
+key "key4" {
+  address       = "0x2d800d93b065ce011af83f316cef9f0d005b0aa4"
+  keystore_path = "./testdata/keystore"
+  passphrase    = secret("env://ETHEREUM_TEST_PASSPHRASE")
+}
+
 # Without optionals
 client "client1" {
   rpc_urls     = ["https://rpc1.example"]
@@ -35,3 +51,47 @@ client "client2" {
   max_gas_priority_fee        = 1000000000000
   max_gas_limit               = 10000000
 }
+
+# With routing
+client "client3" {
+  rpc_urls            = ["https://rpc1.example", "https://rpc2.example", "https://archive.example"]
+  cache_ttl           = 6
+  passthrough         = "first"
+  passthrough_methods = ["eth_*", "net_peerCount"]
+
+  endpoint "https://archive.example" {
+    weight = 2
+    tags   = ["archive"]
+  }
+
+  route "eth_call" {
+    endpoints = 2
+  }
+
+  route "eth_getLogs" {
+    tags          = ["archive"]
+    min_responses = 1
+  }
+
+  health_check {
+    window                = 50
+    min_samples           = 5
+    max_error_rate        = 50
+    max_disagreement_rate = 25
+    ejection_time         = 30
+  }
+}
+
+# With fee estimator
+client "client4" {
+  rpc_urls = ["https://rpc4.example"]
+  tx_type  = "eip1559"
+
+  fee_estimator "fee_history" {
+    blocks              = 20
+    reward_percentile   = 60
+    base_fee_multiplier = 1.5
+    l2_chain            = "op_stack"
+    max_total_fee       = 1000000000000000
+  }
+}
@@ -19,16 +19,20 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/defiweb/go-eth/types"
 	"github.com/hashicorp/hcl/v2"
 
+	"github.com/chronicleprotocol/oracle-suite/pkg/contract/chronicle"
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/signer"
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/value"
 
 	ethereumConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/ethereum"
 	"github.com/chronicleprotocol/oracle-suite/pkg/feed"
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
 	"github.com/chronicleprotocol/oracle-suite/pkg/transport"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/sliceutil"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
 )
 
@@ -44,7 +48,27 @@ type Config struct {
 	// Interval is the interval at which to publish prices in seconds.
 	Interval uint32 `hcl:"interval"`
 
-	DataModels []string `hcl:"data_models"`
+	// Jitter is the maximum random delay in seconds added to every interval.
+	Jitter uint32 `hcl:"jitter,optional"`
+
+	DataModels []string `hcl:"data_models,optional"`
+
+	// Groups is a list of groups of data models, each published at its own
+	// interval.
+	Groups []configGroup `hcl:"group,block"`
+
+	// TriggerInterval is the interval in seconds at which data models with
+	// triggers are evaluated. Required if any trigger is defined.
+	TriggerInterval uint32 `hcl:"trigger_interval,optional"`
+
+	// Triggers enables event-driven publishing for the given data models.
+	// These data models are broadcast only when their value moves past the
+	// deviation or the heartbeat elapses, instead of on every interval.
+	Triggers []configTrigger `hcl:"trigger,block"`
+
+	// SanityGuard configures checks that reject obviously invalid data points
+	// before they are signed.
+	SanityGuard *configSanityGuard `hcl:"sanity_guard,block,optional"`
 
 	// HCL fields:
 	Range   hcl.Range       `hcl:",range"`
@@ -54,8 +78,92 @@ type Config struct {
 	feed *feed.Feed
 }
 
+type configGroup struct {
+	// Name is the name of the group.
+	Name string `hcl:"name,label"`
+
+	// Interval is the interval at which to publish prices in seconds.
+	Interval uint32 `hcl:"interval"`
+
+	// Jitter is the maximum random delay in seconds added to every interval.
+	Jitter uint32 `hcl:"jitter,optional"`
+
+	// DataModels is a list of data models in the group.
+	DataModels []string `hcl:"data_models"`
+
+	// SanityGuard configures the sanity guard for the group. If not set,
+	// the sanity guard from the main block is used.
+	SanityGuard *configSanityGuard `hcl:"sanity_guard,block,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+type configTrigger struct {
+	// DataModel is the name of the data model.
+	DataModel string `hcl:"data_model,label"`
+
+	// Deviation is a minimum deviation from the last broadcast value to
+	// trigger a broadcast. A deviation is represented as a percentage point,
+	// e.g. 1 means 1%. Zero disables the deviation trigger.
+	Deviation float64 `hcl:"deviation,optional"`
+
+	// Heartbeat is a maximum time in seconds between broadcasts. Zero
+	// disables the heartbeat trigger.
+	Heartbeat uint32 `hcl:"heartbeat,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+type configSanityGuard struct {
+	// MaxDeviation is the maximum allowed deviation from the last broadcast
+	// value. A deviation is represented as a percentage point, e.g. 1 means
+	// 1%. Zero disables the check.
+	MaxDeviation float64 `hcl:"max_deviation,optional"`
+
+	// Window is a time in seconds after the last broadcast during which the
+	// max_deviation check is applied.
+	Window uint32 `hcl:"window,optional"`
+
+	// MaxAge is the maximum age of a data point in seconds. Zero disables
+	// the check.
+	MaxAge uint32 `hcl:"max_age,optional"`
+
+	// MaxContractDeviation is the maximum allowed deviation from the current
+	// value of the contract. A deviation is represented as a percentage
+	// point. Zero disables the check.
+	MaxContractDeviation float64 `hcl:"max_contract_deviation,optional"`
+
+	// Contracts is a list of contracts whose values are compared with data
+	// points.
+	Contracts []configSanityGuardContract `hcl:"contract,block"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+type configSanityGuardContract struct {
+	// EthereumClient is a name of an Ethereum client to use.
+	EthereumClient string `hcl:"ethereum_client"`
+
+	// ContractAddr is an address of a Scribe contract.
+	ContractAddr types.Address `hcl:"contract_addr"`
+
+	// DataModel is a data model whose values are stored in the contract.
+	DataModel string `hcl:"data_model"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
 type Dependencies struct {
 	KeysRegistry ethereumConfig.KeyRegistry
+	Clients      ethereumConfig.ClientRegistry
 	DataProvider datapoint.Provider
 	Transport    transport.Service
 	Logger       log.Logger
@@ -82,19 +190,39 @@ func (c *Config) ConfigureFeed(d Dependencies) (*feed.Feed, error) {
 			Subject:  c.Content.Attributes["ethereum_key"].Range.Ptr(),
 		}
 	}
-	hooks := []feed.Hook{
-		feed.NewTickPrecisionHook(tickPriceBroadcastMaxPrecision, tickVolumeBroadcastMaxPrecision),
-		feed.NewTickTraceHook(),
+	triggers, err := c.configureTriggers()
+	if err != nil {
+		return nil, err
+	}
+	var sanityGuard *feed.SanityGuardHook
+	if c.SanityGuard != nil {
+		if sanityGuard, err = c.SanityGuard.configureHook(d); err != nil {
+			return nil, err
+		}
+	}
+	groups := make([]feed.Group, 0, len(c.Groups))
+	for _, g := range c.Groups {
+		group, err := g.configureGroup(d, sanityGuard)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
 	}
 	cfg := feed.Config{
 		DataModels:   c.DataModels,
 		DataProvider: d.DataProvider,
 		Signers:      []datapoint.Signer{signer.NewTickSigner(ethereumKey)},
-		Hooks:        hooks,
+		Hooks:        hooks(sanityGuard),
 		Transport:    d.Transport,
 		Interval:     timeutil.NewTicker(time.Second * time.Duration(c.Interval)),
+		Jitter:       time.Second * time.Duration(c.Jitter),
+		Groups:       groups,
+		Triggers:     triggers,
 		Logger:       d.Logger,
 	}
+	if c.TriggerInterval > 0 {
+		cfg.TriggerInterval = timeutil.NewTicker(time.Second * time.Duration(c.TriggerInterval))
+	}
 	feedService, err := feed.New(cfg)
 	if err != nil {
 		return nil, &hcl.Diagnostic{
@@ -107,3 +235,160 @@ func (c *Config) ConfigureFeed(d Dependencies) (*feed.Feed, error) {
 	c.feed = feedService
 	return feedService, nil
 }
+
+func (c *Config) configureTriggers() (map[string]feed.Trigger, error) {
+	if len(c.Triggers) == 0 {
+		return nil, nil
+	}
+	if c.TriggerInterval == 0 {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Trigger interval must be set if triggers are defined",
+			Subject:  c.Range.Ptr(),
+		}
+	}
+	triggers := make(map[string]feed.Trigger, len(c.Triggers))
+	for _, t := range c.Triggers {
+		if !sliceutil.Contains(c.allDataModels(), t.DataModel) {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Data model %q is not listed in data_models of the feed or any group", t.DataModel),
+				Subject:  t.Range.Ptr(),
+			}
+		}
+		if _, ok := triggers[t.DataModel]; ok {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Duplicate trigger for data model %q", t.DataModel),
+				Subject:  t.Range.Ptr(),
+			}
+		}
+		if t.Deviation < 0 {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   "Deviation cannot be negative",
+				Subject:  t.Content.Attributes["deviation"].Range.Ptr(),
+			}
+		}
+		if t.Deviation == 0 && t.Heartbeat == 0 {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   "At least one of deviation or heartbeat must be set",
+				Subject:  t.Range.Ptr(),
+			}
+		}
+		triggers[t.DataModel] = feed.Trigger{
+			Deviation: t.Deviation / 100,
+			Heartbeat: time.Second * time.Duration(t.Heartbeat),
+		}
+	}
+	return triggers, nil
+}
+
+// allDataModels returns data models listed in the main block and in
+// all groups.
+func (c *Config) allDataModels() []string {
+	models := append([]string{}, c.DataModels...)
+	for _, g := range c.Groups {
+		models = append(models, g.DataModels...)
+	}
+	return models
+}
+
+func (c *configGroup) configureGroup(d Dependencies, sanityGuard *feed.SanityGuardHook) (feed.Group, error) {
+	if c.Interval == 0 {
+		return feed.Group{}, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Interval cannot be zero",
+			Subject:  c.Content.Attributes["interval"].Range.Ptr(),
+		}
+	}
+	if c.SanityGuard != nil {
+		var err error
+		if sanityGuard, err = c.SanityGuard.configureHook(d); err != nil {
+			return feed.Group{}, err
+		}
+	}
+	return feed.Group{
+		Name:       c.Name,
+		DataModels: c.DataModels,
+		Interval:   timeutil.NewTicker(time.Second * time.Duration(c.Interval)),
+		Jitter:     time.Second * time.Duration(c.Jitter),
+		Hooks:      hooks(sanityGuard),
+	}, nil
+}
+
+// hooks returns the hooks used to broadcast data points. The sanity guard
+// is optional.
+func hooks(sanityGuard *feed.SanityGuardHook) []feed.Hook {
+	hooks := []feed.Hook{
+		feed.NewTickPrecisionHook(tickPriceBroadcastMaxPrecision, tickVolumeBroadcastMaxPrecision),
+	}
+	if sanityGuard != nil {
+		hooks = append(hooks, sanityGuard)
+	}
+	return append(hooks, feed.NewTickTraceHook())
+}
+
+func (c *configSanityGuard) configureHook(d Dependencies) (*feed.SanityGuardHook, error) {
+	if c.MaxDeviation < 0 {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Max deviation cannot be negative",
+			Subject:  c.Content.Attributes["max_deviation"].Range.Ptr(),
+		}
+	}
+	if c.MaxContractDeviation < 0 {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Max contract deviation cannot be negative",
+			Subject:  c.Content.Attributes["max_contract_deviation"].Range.Ptr(),
+		}
+	}
+	if c.MaxDeviation > 0 && c.Window == 0 {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Window must be set if max deviation is set",
+			Subject:  c.Range.Ptr(),
+		}
+	}
+	contracts := make(map[value.Pair]feed.ContractReader, len(c.Contracts))
+	for _, contract := range c.Contracts {
+		client, ok := d.Clients[contract.EthereumClient]
+		if !ok {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Ethereum client %q is not configured", contract.EthereumClient),
+				Subject:  contract.Content.Attributes["ethereum_client"].Range.Ptr(),
+			}
+		}
+		pair, err := value.PairFromString(contract.DataModel)
+		if err != nil {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Invalid data model: %v", err),
+				Subject:  contract.Content.Attributes["data_model"].Range.Ptr(),
+			}
+		}
+		contracts[pair] = chronicle.NewScribe(client, contract.ContractAddr)
+	}
+	return feed.NewSanityGuardHook(feed.SanityGuardConfig{
+		MaxDeviation:         c.MaxDeviation / 100,
+		Window:               time.Second * time.Duration(c.Window),
+		MaxAge:               time.Second * time.Duration(c.MaxAge),
+		Contracts:            contracts,
+		MaxContractDeviation: c.MaxContractDeviation / 100,
+		Logger:               d.Logger,
+	}), nil
+}
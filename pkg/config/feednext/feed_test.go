@@ -2,6 +2,7 @@ package feed
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -10,6 +11,7 @@ import (
 	"github.com/chronicleprotocol/oracle-suite/pkg/config/ethereum"
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/graph"
 	ethereumMocks "github.com/chronicleprotocol/oracle-suite/pkg/ethereum/mocks"
+	"github.com/chronicleprotocol/oracle-suite/pkg/feed"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
 	"github.com/chronicleprotocol/oracle-suite/pkg/transport/local"
 )
@@ -49,6 +51,63 @@ func TestConfig(t *testing.T) {
 				assert.NotNil(t, feed)
 			},
 		},
+		{
+			name: "triggers",
+			path: "triggers.hcl",
+			test: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, uint32(5), cfg.TriggerInterval)
+				require.Len(t, cfg.Triggers, 1)
+				assert.Equal(t, "BTC/USD", cfg.Triggers[0].DataModel)
+				assert.Equal(t, 0.5, cfg.Triggers[0].Deviation)
+				assert.Equal(t, uint32(3600), cfg.Triggers[0].Heartbeat)
+
+				triggers, err := cfg.configureTriggers()
+				require.NoError(t, err)
+				assert.Equal(t, map[string]feed.Trigger{
+					"BTC/USD": {Deviation: 0.005, Heartbeat: time.Hour},
+				}, triggers)
+			},
+		},
+		{
+			name: "groups",
+			path: "groups.hcl",
+			test: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, uint32(5), cfg.Jitter)
+				assert.Equal(t, []string{"ETH/USD"}, cfg.DataModels)
+				require.Len(t, cfg.Groups, 1)
+				assert.Equal(t, "slow", cfg.Groups[0].Name)
+				assert.Equal(t, uint32(600), cfg.Groups[0].Interval)
+				assert.Equal(t, uint32(30), cfg.Groups[0].Jitter)
+				assert.Equal(t, []string{"BTC/USD"}, cfg.Groups[0].DataModels)
+				assert.Nil(t, cfg.Groups[0].SanityGuard)
+				assert.Equal(t, []string{"ETH/USD", "BTC/USD"}, cfg.allDataModels())
+			},
+		},
+		{
+			name: "sanity guard",
+			path: "sanity-guard.hcl",
+			test: func(t *testing.T, cfg *Config) {
+				require.NotNil(t, cfg.SanityGuard)
+				assert.Equal(t, 10.0, cfg.SanityGuard.MaxDeviation)
+				assert.Equal(t, uint32(300), cfg.SanityGuard.Window)
+				assert.Equal(t, uint32(120), cfg.SanityGuard.MaxAge)
+				assert.Equal(t, 20.0, cfg.SanityGuard.MaxContractDeviation)
+				require.Len(t, cfg.SanityGuard.Contracts, 1)
+				assert.Equal(t, "client", cfg.SanityGuard.Contracts[0].EthereumClient)
+				assert.Equal(t, "0x1234567890123456789012345678901234567890", cfg.SanityGuard.Contracts[0].ContractAddr.String())
+				assert.Equal(t, "BTC/USD", cfg.SanityGuard.Contracts[0].DataModel)
+
+				feed, err := cfg.ConfigureFeed(Dependencies{
+					KeysRegistry: ethereum.KeyRegistry{"key": &ethereumMocks.Key{}},
+					Clients:      ethereum.ClientRegistry{"client": &ethereumMocks.RPC{}},
+					DataProvider: graph.NewProvider(nil, nil),
+					Transport:    local.New([]byte("test"), 1, nil),
+					Logger:       null.New(),
+				})
+				require.NoError(t, err)
+				assert.NotNil(t, feed)
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -0,0 +1,16 @@
+ethereum_key = "key"
+interval     = 60
+jitter       = 5
+
+data_models = [
+  "ETH/USD",
+]
+
+group "slow" {
+  interval = 600
+  jitter   = 30
+
+  data_models = [
+    "BTC/USD",
+  ]
+}
@@ -0,0 +1,20 @@
+ethereum_key = "key"
+interval     = 60
+
+data_models = [
+  "ETH/USD",
+  "BTC/USD",
+]
+
+sanity_guard {
+  max_deviation          = 10
+  window                 = 300
+  max_age                = 120
+  max_contract_deviation = 20
+
+  contract {
+    ethereum_client = "client"
+    contract_addr   = "0x1234567890123456789012345678901234567890"
+    data_model      = "BTC/USD"
+  }
+}
@@ -0,0 +1,13 @@
+ethereum_key     = "key"
+interval         = 60
+trigger_interval = 5
+
+data_models = [
+  "ETH/USD",
+  "BTC/USD",
+]
+
+trigger "BTC/USD" {
+  deviation = 0.5
+  heartbeat = 3600
+}
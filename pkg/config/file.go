@@ -0,0 +1,137 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	utilHCL "github.com/chronicleprotocol/oracle-suite/pkg/util/hcl"
+)
+
+// parseFiles works like utilHCL.ParseFiles, but relative paths passed to
+// the file() function are resolved against the directory of the file that
+// calls the function.
+func parseFiles(paths []string) (hcl.Body, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	bodies := make([]hcl.Body, len(paths))
+	for n, path := range paths {
+		body, fileDiags := utilHCL.ParseFile(path, nil)
+		diags = diags.Extend(fileDiags)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		resolveFilePaths(path, body)
+		bodies[n] = body
+	}
+	return hcl.MergeBodies(bodies), diags
+}
+
+// resolveFilePaths makes relative paths passed to the file() function in
+// the body parsed from the given path absolute, resolving them against
+// the directory of that path. Paths are resolved when the expressions are
+// evaluated, so they may use variables.
+func resolveFilePaths(path string, body hcl.Body) {
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return
+	}
+	dir := filepath.Dir(path)
+	_ = hclsyntax.VisitAll(syntaxBody, func(node hclsyntax.Node) hcl.Diagnostics {
+		if call, ok := fileCall(node); ok {
+			call.Args[0] = &relPathExpr{Expression: call.Args[0], dir: dir}
+		}
+		return nil
+	})
+}
+
+// filePaths returns the paths of the files read by the file() function in
+// the body parsed from the given path. Only paths that can be evaluated
+// using the given context are returned.
+func filePaths(ctx *hcl.EvalContext, path string, body hcl.Body) []string {
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return nil
+	}
+	dir := filepath.Dir(path)
+	var paths []string
+	_ = hclsyntax.VisitAll(syntaxBody, func(node hclsyntax.Node) hcl.Diagnostics {
+		call, ok := fileCall(node)
+		if !ok {
+			return nil
+		}
+		val, diags := (&relPathExpr{Expression: call.Args[0], dir: dir}).Value(ctx)
+		if diags.HasErrors() {
+			return nil
+		}
+		val, _ = val.Unmark()
+		if !val.IsKnown() || val.IsNull() || !val.Type().Equals(cty.String) {
+			return nil
+		}
+		paths = append(paths, val.AsString())
+		return nil
+	})
+	return paths
+}
+
+// fileCall returns the given node as a call to the file() function, if it
+// is one.
+func fileCall(node hclsyntax.Node) (*hclsyntax.FunctionCallExpr, bool) {
+	call, ok := node.(*hclsyntax.FunctionCallExpr)
+	if !ok || call.Name != "file" || len(call.Args) != 1 {
+		return nil, false
+	}
+	if _, ok := call.Args[0].(*relPathExpr); ok {
+		return nil, false
+	}
+	return call, true
+}
+
+// relPathExpr is an expression that makes a relative path returned by the
+// wrapped expression absolute, resolving it against the dir directory.
+type relPathExpr struct {
+	hclsyntax.Expression
+	dir string
+}
+
+func (e *relPathExpr) Value(ctx *hcl.EvalContext) (cty.Value, hcl.Diagnostics) {
+	val, diags := e.Expression.Value(ctx)
+	if diags.HasErrors() {
+		return val, diags
+	}
+	val, marks := val.Unmark()
+	if !val.IsKnown() || val.IsNull() || !val.Type().Equals(cty.String) {
+		return val.WithMarks(marks), diags
+	}
+	path := val.AsString()
+	if !filepath.IsAbs(path) {
+		abs, err := filepath.Abs(filepath.Join(e.dir, path))
+		if err != nil {
+			return cty.DynamicVal, diags.Append(&hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Invalid path",
+				Detail:   fmt.Sprintf("Cannot resolve path %s: %s.", path, err),
+				Subject:  e.Range().Ptr(),
+			})
+		}
+		path = abs
+	}
+	return cty.StringVal(path).WithMarks(marks), diags
+}
@@ -22,12 +22,15 @@ import (
 	"github.com/hashicorp/hcl/v2"
 
 	"github.com/chronicleprotocol/oracle-suite/config"
+	pkgConfig "github.com/chronicleprotocol/oracle-suite/pkg/config"
 	configGoferNext "github.com/chronicleprotocol/oracle-suite/pkg/config/dataprovider"
 	ethereumConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/ethereum"
 	feedConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/feednext"
 	loggerConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/logger"
+	metricsConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/metrics"
 	transportConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/transport"
 	"github.com/chronicleprotocol/oracle-suite/pkg/feed"
+	"github.com/chronicleprotocol/oracle-suite/pkg/httpserver"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
 	pkgSupervisor "github.com/chronicleprotocol/oracle-suite/pkg/supervisor"
 	pkgTransport "github.com/chronicleprotocol/oracle-suite/pkg/transport"
@@ -41,9 +44,10 @@ type Config struct {
 	Ethereum  ethereumConfig.Config  `hcl:"ethereum,block"`
 	Transport transportConfig.Config `hcl:"transport,block"`
 	Logger    *loggerConfig.Config   `hcl:"logger,block,optional"`
+	Metrics   *metricsConfig.Config  `hcl:"metrics,block,optional"`
 
 	// HCL fields:
-	Remain  hcl.Body        `hcl:",remain"` // To ignore unknown blocks.
+	Remain  hcl.Body        `hcl:",remain,strict"` // To ignore unknown blocks.
 	Content hcl.BodyContent `hcl:",content"`
 }
 
@@ -93,6 +97,59 @@ func (c *Config) Services(baseLogger log.Logger, appName string, appVersion stri
 	if err != nil {
 		return nil, err
 	}
+	feedService, err := c.feed(keys, clients, transport, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	var metricsServer httpserver.Service
+	if c.Metrics != nil {
+		metricsServer, err = c.Metrics.HTTPServer()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Services{
+		Feed:      feedService,
+		Transport: transport,
+		Metrics:   metricsServer,
+		Logger:    logger,
+		config:    c,
+	}, nil
+}
+
+// checkReload compares the config with the one used to create the running
+// services. It returns an error wrapping pkgSupervisor.ErrRestartRequired
+// if options of services that keep running during a reload were changed,
+// otherwise it returns true if the feed has to be rebuilt.
+func (c *Config) checkReload(prev *Config) (bool, error) {
+	switch {
+	case !pkgConfig.Equal(c.Ethereum.Keys, prev.Ethereum.Keys) ||
+		!pkgConfig.Equal(c.Ethereum.RandKeys, prev.Ethereum.RandKeys):
+		return false, fmt.Errorf("ethereum keys changed: %w", pkgSupervisor.ErrRestartRequired)
+	case !pkgConfig.Equal(c.Transport, prev.Transport):
+		return false, fmt.Errorf("transport config changed: %w", pkgSupervisor.ErrRestartRequired)
+	case !ethereumConfig.ClientsEqual(&c.Ethereum, &prev.Ethereum, c.Transport.EthereumClients()...):
+		return false, fmt.Errorf("ethereum clients used by transport changed: %w", pkgSupervisor.ErrRestartRequired)
+	case !pkgConfig.Equal(c.Logger, prev.Logger):
+		return false, fmt.Errorf("logger config changed: %w", pkgSupervisor.ErrRestartRequired)
+	case !pkgConfig.Equal(c.Metrics, prev.Metrics):
+		return false, fmt.Errorf("metrics config changed: %w", pkgSupervisor.ErrRestartRequired)
+	}
+	return !pkgConfig.Equal(c.Ghost, prev.Ghost) ||
+		!pkgConfig.Equal(c.Gofer, prev.Gofer) ||
+		!pkgConfig.Equal(c.Ethereum.Clients, prev.Ethereum.Clients), nil
+}
+
+// feed configures the feed service together with the data provider it
+// uses.
+func (c *Config) feed(
+	keys ethereumConfig.KeyRegistry,
+	clients ethereumConfig.ClientRegistry,
+	transport pkgTransport.Service,
+	logger log.Logger,
+) (*feed.Feed, error) {
 	dataProvider, err := c.Gofer.ConfigureDataProvider(configGoferNext.Dependencies{
 		Clients: clients,
 		Logger:  logger,
@@ -100,30 +157,26 @@ func (c *Config) Services(baseLogger log.Logger, appName string, appVersion stri
 	if err != nil {
 		return nil, err
 	}
-	feedService, err := c.Ghost.ConfigureFeed(feedConfig.Dependencies{
+	return c.Ghost.ConfigureFeed(feedConfig.Dependencies{
 		KeysRegistry: keys,
+		Clients:      clients,
 		DataProvider: dataProvider,
 		Transport:    transport,
 		Logger:       logger,
 	})
-	if err != nil {
-		return nil, err
-	}
-
-	return &Services{
-		Feed:      feedService,
-		Transport: transport,
-		Logger:    logger,
-	}, nil
 }
 
 // Services returns the services that are configured from the Config struct.
 type Services struct {
 	Feed      *feed.Feed
 	Transport pkgTransport.Service
+	Metrics   httpserver.Service
 	Logger    log.Logger
 
+	ctx        context.Context
+	config     *Config
 	supervisor *pkgSupervisor.Supervisor
+	feedCh     chan pkgSupervisor.Service
 }
 
 // Start implements the supervisor.Service interface.
@@ -131,8 +184,16 @@ func (s *Services) Start(ctx context.Context) error {
 	if s.supervisor != nil {
 		return fmt.Errorf("services already started")
 	}
+	s.ctx = ctx
+	s.feedCh = make(chan pkgSupervisor.Service)
 	s.supervisor = pkgSupervisor.New(s.Logger)
-	s.supervisor.Watch(s.Transport, s.Feed)
+	s.supervisor.Watch(s.Transport, pkgSupervisor.NewReloader(pkgSupervisor.ReloaderConfig{
+		Factory: pkgSupervisor.ChannelFactory(s.Feed, s.feedCh),
+		Logger:  s.Logger,
+	}))
+	if s.Metrics != nil {
+		s.supervisor.Watch(s.Metrics)
+	}
 	if l, ok := s.Logger.(pkgSupervisor.Service); ok {
 		s.supervisor.Watch(l)
 	}
@@ -143,3 +204,66 @@ func (s *Services) Start(ctx context.Context) error {
 func (s *Services) Wait() <-chan error {
 	return s.supervisor.Wait()
 }
+
+// Reload implements the supervisor.Reloadable interface.
+//
+// It rebuilds the feed, together with the data provider and Ethereum
+// clients used by it. The feed is kept, together with its state, if its
+// configuration did not change. The transport, logger and metrics server
+// keep running, so changes to their configuration, or to Ethereum keys,
+// are rejected because they require a restart.
+//
+// Ethereum clients whose configuration did not change are reused, the
+// remaining clients are closed once the new feed replaces the old one.
+func (s *Services) Reload(cfg pkgSupervisor.Config) (err error) {
+	if s.supervisor == nil {
+		return fmt.Errorf("services not started")
+	}
+	c, ok := cfg.(*Config)
+	if !ok {
+		return fmt.Errorf("unexpected config type: %T", cfg)
+	}
+	rebuild, err := c.checkReload(s.config)
+	if err != nil {
+		return err
+	}
+	c.Ethereum.ReuseClients(&s.config.Ethereum)
+	defer func() {
+		if err != nil {
+			// Give the clients back to the running config and close
+			// the ones created for the rejected config.
+			s.config.Ethereum.ReuseClients(&c.Ethereum)
+			if err := c.Ethereum.CloseClients(); err != nil {
+				s.Logger.WithError(err).Warn("Unable to close Ethereum clients")
+			}
+		}
+	}()
+	if !rebuild {
+		s.config = c
+		s.Logger.Info("Feed configuration unchanged, feed is not reloaded")
+		return nil
+	}
+	keys, err := c.Ethereum.KeyRegistry(ethereumConfig.Dependencies{Logger: s.Logger})
+	if err != nil {
+		return err
+	}
+	clients, err := c.Ethereum.ClientRegistry(ethereumConfig.Dependencies{Logger: s.Logger})
+	if err != nil {
+		return err
+	}
+	feedService, err := c.feed(keys, clients, s.Transport, s.Logger)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	case s.feedCh <- feedService:
+		if err := s.config.Ethereum.CloseClients(); err != nil {
+			s.Logger.WithError(err).Warn("Unable to close Ethereum clients")
+		}
+		s.Feed = feedService
+		s.config = c
+		return nil
+	}
+}
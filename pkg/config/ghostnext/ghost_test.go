@@ -20,10 +20,12 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/config"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/supervisor"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/hcl"
 )
 
@@ -51,6 +53,67 @@ func TestConfig(t *testing.T) {
 	}
 }
 
+func TestCheckReload(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(*Config)
+		wantRebuild bool
+		wantErr     bool
+	}{
+		{
+			name:        "unchanged",
+			modify:      func(*Config) {},
+			wantRebuild: false,
+		},
+		{
+			name:        "feed changed",
+			modify:      func(c *Config) { c.Ghost.Interval = 120 },
+			wantRebuild: true,
+		},
+		{
+			name:        "data models changed",
+			modify:      func(c *Config) { c.Ghost.DataModels = append(c.Ghost.DataModels, "ETH/USD") },
+			wantRebuild: true,
+		},
+		{
+			name:        "clients changed",
+			modify:      func(c *Config) { c.Ethereum.Clients[0].ChainID = 2 },
+			wantRebuild: true,
+		},
+		{
+			name:    "transport changed",
+			modify:  func(c *Config) { c.Transport.LibP2P.ListenAddrs = []string{"/ip4/0.0.0.0/tcp/7000"} },
+			wantErr: true,
+		},
+		{
+			name:    "keys changed",
+			modify:  func(c *Config) { c.Ethereum.RandKeys = []string{"key1", "key2"} },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prev, next Config
+			require.NoError(t, config.LoadFiles(&prev, []string{"./testdata/config.hcl"}))
+			require.NoError(t, config.LoadFiles(&next, []string{"./testdata/config.hcl"}))
+
+			// Services are created only for the previous config, the same
+			// as during a reload.
+			_, err := prev.Services(null.New(), "", "")
+			require.NoError(t, err)
+
+			tt.modify(&next)
+			rebuild, err := next.checkReload(&prev)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, supervisor.ErrRestartRequired)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRebuild, rebuild)
+		})
+	}
+}
+
 func TestDefaults(t *testing.T) {
 	cfg := &Config{}
 	require.NoError(t, config.LoadEmbeds(cfg, cfg.DefaultEmbeds()))
@@ -1062,5 +1062,7 @@ transport {
     direct_peers_addrs = []
     blocked_addrs      = []
     ethereum_key       = "default"
+    batch_topics       = []
+    batch_interval     = 1
   }
 }
@@ -26,9 +26,12 @@ import (
 	dataproviderConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/dataprovider"
 	ethereumConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/ethereum"
 	loggerConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/logger"
+	metricsConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/metrics"
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
+	"github.com/chronicleprotocol/oracle-suite/pkg/httpserver"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
 	pkgSupervisor "github.com/chronicleprotocol/oracle-suite/pkg/supervisor"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/httpfixture"
 )
 
 // Config is the configuration for Gofer.
@@ -36,9 +39,15 @@ type Config struct {
 	Gofer    dataproviderConfig.Config `hcl:"gofer,block"`
 	Ethereum *ethereumConfig.Config    `hcl:"ethereum,block,optional"`
 	Logger   *loggerConfig.Config      `hcl:"logger,block,optional"`
+	Metrics  *metricsConfig.Config     `hcl:"metrics,block,optional"`
+
+	// HTTPFixtures is an optional fixture directory used to record or replay
+	// HTTP responses of origins and Ethereum clients. It is not part of the
+	// configuration file and is set using command line flags.
+	HTTPFixtures *httpfixture.Fixtures
 
 	// HCL fields:
-	Remain  hcl.Body        `hcl:",remain"` // To ignore unknown blocks.
+	Remain  hcl.Body        `hcl:",remain,strict"` // To ignore unknown blocks.
 	Content hcl.BodyContent `hcl:",content"`
 }
 
@@ -54,8 +63,14 @@ func (Config) DefaultEmbeds() [][]byte {
 // Services returns the services that are configured from the Config struct.
 type Services struct {
 	DataProvider datapoint.Provider
+	Clients      ethereumConfig.ClientRegistry
 	Logger       log.Logger
 
+	// Metrics is an optional HTTP server that exposes metrics. It is not
+	// started by the Start method because most of the Gofer commands are
+	// short-lived, and only long-running commands should start it.
+	Metrics httpserver.Service
+
 	supervisor *pkgSupervisor.Supervisor
 }
 
@@ -89,20 +104,37 @@ func (c *Config) Services(baseLogger log.Logger, appName, appVersion string) (pk
 	if err != nil {
 		return nil, err
 	}
-	clients, err := c.Ethereum.ClientRegistry(ethereumConfig.Dependencies{Logger: logger})
+	ethereumDeps := ethereumConfig.Dependencies{Logger: logger}
+	httpClient := &http.Client{}
+	if c.HTTPFixtures != nil {
+		ethereumDeps.HTTPTransport = func(name string, next http.RoundTripper) http.RoundTripper {
+			return c.HTTPFixtures.Transport("ethereum_"+name, next)
+		}
+		httpClient.Transport = c.HTTPFixtures.Transport("http", nil)
+	}
+	clients, err := c.Ethereum.ClientRegistry(ethereumDeps)
 	if err != nil {
 		return nil, err
 	}
 	priceProvider, err := c.Gofer.ConfigureDataProvider(dataproviderConfig.Dependencies{
-		HTTPClient: &http.Client{},
+		HTTPClient: httpClient,
 		Clients:    clients,
 		Logger:     logger,
 	})
 	if err != nil {
 		return nil, err
 	}
+	var metricsServer httpserver.Service
+	if c.Metrics != nil {
+		metricsServer, err = c.Metrics.HTTPServer()
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &Services{
 		DataProvider: priceProvider,
+		Clients:      clients,
 		Logger:       logger,
+		Metrics:      metricsServer,
 	}, nil
 }
@@ -0,0 +1,78 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/httpserver"
+	"github.com/chronicleprotocol/oracle-suite/pkg/metrics"
+)
+
+const (
+	defaultPath    = "/metrics"
+	defaultTimeout = 10 * time.Second
+)
+
+// Config is the configuration for the Prometheus metrics endpoint.
+type Config struct {
+	// ListenAddr is the address on which the metrics endpoint is served.
+	ListenAddr string `hcl:"listen_addr"`
+
+	// Path is the HTTP path of the metrics endpoint. Default is "/metrics".
+	Path string `hcl:"path,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+
+	// Configured service:
+	server httpserver.Service
+}
+
+// HTTPServer returns an HTTP server that exposes metrics.
+func (c *Config) HTTPServer() (httpserver.Service, error) {
+	if c.server != nil {
+		return c.server, nil
+	}
+	if c.ListenAddr == "" {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Listen address must not be empty",
+			Subject:  c.Content.Attributes["listen_addr"].Range.Ptr(),
+		}
+	}
+	path := c.Path
+	if path == "" {
+		path = defaultPath
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, metrics.Handler())
+	srv := httpserver.New(&http.Server{
+		Addr:              c.ListenAddr,
+		ReadTimeout:       defaultTimeout,
+		ReadHeaderTimeout: defaultTimeout,
+		WriteTimeout:      defaultTimeout,
+		IdleTimeout:       defaultTimeout,
+	})
+	srv.SetHandler(mux)
+	c.server = srv
+	return srv, nil
+}
@@ -44,6 +44,13 @@ type Dependencies struct {
 	Clients   ethereumConfig.ClientRegistry
 	Transport transport.Service
 	Logger    log.Logger
+
+	// PriceStore and MuSigStore are optional. If provided, they are used
+	// instead of creating new stores, and their data models are replaced
+	// with the ones required by the configured contracts. This allows
+	// rebuilding the relay without losing collected data.
+	PriceStore *datapointStore.Store
+	MuSigStore *musigStore.Store
 }
 
 type Config struct {
@@ -158,28 +165,35 @@ func (c *Config) Relay(d Dependencies) (*Services, error) {
 		Debug("Data models")
 
 	// Create a data point store service for all median contracts.
-	priceStoreSrv, err := datapointStore.New(datapointStore.Config{
-		Storage:    datapointStore.NewMemoryStorage(),
-		Transport:  d.Transport,
-		Models:     dataModels,
-		Recoverers: []datapoint.Recoverer{signer.NewTickRecoverer(crypto.ECRecoverer)},
-		Logger:     d.Logger,
-	})
-	if err != nil {
-		return nil, &hcl.Diagnostic{
-			Severity: hcl.DiagError,
-			Summary:  "Store error",
-			Detail:   fmt.Sprintf("Failed to create the data point store service: %v", err),
-			Subject:  &c.Range,
+	priceStoreSrv := d.PriceStore
+	if priceStoreSrv == nil {
+		var err error
+		priceStoreSrv, err = datapointStore.New(datapointStore.Config{
+			Storage:    datapointStore.NewMemoryStorage(),
+			Transport:  d.Transport,
+			Models:     dataModels,
+			Recoverers: []datapoint.Recoverer{signer.NewTickRecoverer(crypto.ECRecoverer)},
+			Logger:     d.Logger,
+		})
+		if err != nil {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Store error",
+				Detail:   fmt.Sprintf("Failed to create the data point store service: %v", err),
+				Subject:  &c.Range,
+			}
 		}
 	}
 
 	// Create Store service.
-	musigStoreSrv := musigStore.New(musigStore.Config{
-		Transport:  d.Transport,
-		DataModels: scribeDataModels,
-		Logger:     d.Logger,
-	})
+	musigStoreSrv := d.MuSigStore
+	if musigStoreSrv == nil {
+		musigStoreSrv = musigStore.New(musigStore.Config{
+			Transport:  d.Transport,
+			DataModels: scribeDataModels,
+			Logger:     d.Logger,
+		})
+	}
 
 	var (
 		medianCfgs   []relay.ConfigMedian
@@ -282,6 +296,15 @@ func (c *Config) Relay(d Dependencies) (*Services, error) {
 		}
 	}
 
+	// Update data models of the reused stores only after the relay is
+	// successfully created, so a failed configuration does not affect them.
+	if d.PriceStore != nil {
+		d.PriceStore.SetModels(dataModels)
+	}
+	if d.MuSigStore != nil {
+		d.MuSigStore.SetDataModels(scribeDataModels)
+	}
+
 	c.services = &Services{
 		Relay:      relaySrv,
 		PriceStore: priceStoreSrv,
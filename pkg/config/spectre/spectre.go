@@ -22,11 +22,14 @@ import (
 	"github.com/hashicorp/hcl/v2"
 
 	"github.com/chronicleprotocol/oracle-suite/config"
+	pkgConfig "github.com/chronicleprotocol/oracle-suite/pkg/config"
 	ethereumConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/ethereum"
 	loggerConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/logger"
+	metricsConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/metrics"
 	relayConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/relay"
 	transportConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/transport"
 	datapointStore "github.com/chronicleprotocol/oracle-suite/pkg/datapoint/store"
+	"github.com/chronicleprotocol/oracle-suite/pkg/httpserver"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
 	musigStore "github.com/chronicleprotocol/oracle-suite/pkg/musig/store"
 	"github.com/chronicleprotocol/oracle-suite/pkg/relay"
@@ -42,9 +45,10 @@ type Config struct {
 	Transport transportConfig.Config `hcl:"transport,block"`
 	Ethereum  ethereumConfig.Config  `hcl:"ethereum,block"`
 	Logger    *loggerConfig.Config   `hcl:"logger,block,optional"`
+	Metrics   *metricsConfig.Config  `hcl:"metrics,block,optional"`
 
 	// HCL fields:
-	Remain  hcl.Body        `hcl:",remain"` // To ignore unknown blocks.
+	Remain  hcl.Body        `hcl:",remain,strict"` // To ignore unknown blocks.
 	Content hcl.BodyContent `hcl:",content"`
 }
 
@@ -64,9 +68,13 @@ type Services struct {
 	PriceStore *datapointStore.Store
 	MuSigStore *musigStore.Store
 	Transport  transport.Service
+	Metrics    httpserver.Service
 	Logger     log.Logger
 
+	ctx        context.Context
+	config     *Config
 	supervisor *supervisor.Supervisor
+	relayCh    chan supervisor.Service
 }
 
 // Start implements the supervisor.Service interface.
@@ -74,13 +82,21 @@ func (s *Services) Start(ctx context.Context) error {
 	if s.supervisor != nil {
 		return fmt.Errorf("services already started")
 	}
+	s.ctx = ctx
+	s.relayCh = make(chan supervisor.Service)
 	s.supervisor = supervisor.New(s.Logger)
 	s.supervisor.Watch(
 		s.Transport,
 		s.PriceStore,
 		s.MuSigStore,
-		s.Relay,
+		supervisor.NewReloader(supervisor.ReloaderConfig{
+			Factory: supervisor.ChannelFactory(s.Relay, s.relayCh),
+			Logger:  s.Logger,
+		}),
 	)
+	if s.Metrics != nil {
+		s.supervisor.Watch(s.Metrics)
+	}
 	if l, ok := s.Logger.(supervisor.Service); ok {
 		s.supervisor.Watch(l)
 	}
@@ -92,6 +108,85 @@ func (s *Services) Wait() <-chan error {
 	return s.supervisor.Wait()
 }
 
+// Reload implements the supervisor.Reloadable interface.
+//
+// It rebuilds the relay, together with the Ethereum clients used by it.
+// The price and MuSig stores keep running, with their data models updated
+// to match the new configuration. The transport, logger and metrics server
+// keep running, so changes to their configuration, or to Ethereum keys, are
+// rejected because they require a restart.
+//
+// Ethereum clients whose configuration did not change are reused, the
+// remaining clients are closed once the new relay replaces the old one.
+func (s *Services) Reload(cfg supervisor.Config) (err error) {
+	if s.supervisor == nil {
+		return fmt.Errorf("services not started")
+	}
+	c, ok := cfg.(*Config)
+	if !ok {
+		return fmt.Errorf("unexpected config type: %T", cfg)
+	}
+	if err := c.checkReload(s.config); err != nil {
+		return err
+	}
+	c.Ethereum.ReuseClients(&s.config.Ethereum)
+	defer func() {
+		if err != nil {
+			// Give the clients back to the running config and close
+			// the ones created for the rejected config.
+			s.config.Ethereum.ReuseClients(&c.Ethereum)
+			if err := c.Ethereum.CloseClients(); err != nil {
+				s.Logger.WithError(err).Warn("Unable to close Ethereum clients")
+			}
+		}
+	}()
+	clients, err := c.Ethereum.ClientRegistry(ethereumConfig.Dependencies{Logger: s.Logger})
+	if err != nil {
+		return err
+	}
+	srvs, err := c.Spectre.Relay(relayConfig.Dependencies{
+		Clients:    clients,
+		Transport:  s.Transport,
+		Logger:     s.Logger,
+		PriceStore: s.PriceStore,
+		MuSigStore: s.MuSigStore,
+	})
+	if err != nil {
+		return err
+	}
+	select {
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	case s.relayCh <- srvs.Relay:
+		if err := s.config.Ethereum.CloseClients(); err != nil {
+			s.Logger.WithError(err).Warn("Unable to close Ethereum clients")
+		}
+		s.Relay = srvs.Relay
+		s.config = c
+		return nil
+	}
+}
+
+// checkReload compares the config with the one used to create the running
+// services. It returns an error wrapping supervisor.ErrRestartRequired if
+// options of services that keep running during a reload were changed.
+func (c *Config) checkReload(prev *Config) error {
+	switch {
+	case !pkgConfig.Equal(c.Ethereum.Keys, prev.Ethereum.Keys) ||
+		!pkgConfig.Equal(c.Ethereum.RandKeys, prev.Ethereum.RandKeys):
+		return fmt.Errorf("ethereum keys changed: %w", supervisor.ErrRestartRequired)
+	case !pkgConfig.Equal(c.Transport, prev.Transport):
+		return fmt.Errorf("transport config changed: %w", supervisor.ErrRestartRequired)
+	case !ethereumConfig.ClientsEqual(&c.Ethereum, &prev.Ethereum, c.Transport.EthereumClients()...):
+		return fmt.Errorf("ethereum clients used by transport changed: %w", supervisor.ErrRestartRequired)
+	case !pkgConfig.Equal(c.Logger, prev.Logger):
+		return fmt.Errorf("logger config changed: %w", supervisor.ErrRestartRequired)
+	case !pkgConfig.Equal(c.Metrics, prev.Metrics):
+		return fmt.Errorf("metrics config changed: %w", supervisor.ErrRestartRequired)
+	}
+	return nil
+}
+
 // Services returns the services configured for Spectre.
 func (c *Config) Services(baseLogger log.Logger, appName string, appVersion string) (supervisor.Service, error) {
 	logger, err := c.Logger.Logger(loggerConfig.Dependencies{
@@ -141,11 +236,20 @@ func (c *Config) Services(baseLogger log.Logger, appName string, appVersion stri
 	if err != nil {
 		return nil, err
 	}
+	var metricsServer httpserver.Service
+	if c.Metrics != nil {
+		metricsServer, err = c.Metrics.HTTPServer()
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &Services{
 		Relay:      srvs.Relay,
 		PriceStore: srvs.PriceStore,
 		MuSigStore: srvs.MuSigStore,
 		Transport:  transportSrv,
+		Metrics:    metricsServer,
 		Logger:     logger,
+		config:     c,
 	}, nil
 }
@@ -24,6 +24,7 @@ import (
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/config"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/supervisor"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/hcl"
 )
 
@@ -53,6 +54,43 @@ func TestConfig(t *testing.T) {
 	}
 }
 
+func TestCheckReload(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr bool
+	}{
+		{
+			name:   "unchanged",
+			modify: func(*Config) {},
+		},
+		{
+			name:    "transport changed",
+			modify:  func(c *Config) { c.Transport.LibP2P.ListenAddrs = []string{"/ip4/0.0.0.0/tcp/7000"} },
+			wantErr: true,
+		},
+		{
+			name:    "address book client changed",
+			modify:  func(c *Config) { c.Ethereum.Clients[0].ChainID = 2 },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prev, next Config
+			require.NoError(t, config.LoadFiles(&prev, []string{"./testdata/config.hcl"}))
+			require.NoError(t, config.LoadFiles(&next, []string{"./testdata/config.hcl"}))
+			tt.modify(&next)
+			err := next.checkReload(&prev)
+			if tt.wantErr {
+				require.ErrorIs(t, err, supervisor.ErrRestartRequired)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
 func TestDefaults(t *testing.T) {
 	cfg := &Config{}
 	require.NoError(t, config.LoadEmbeds(cfg, cfg.DefaultEmbeds()))
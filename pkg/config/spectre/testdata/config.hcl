@@ -55,4 +55,15 @@ transport {
     disable_discovery = false
     ethereum_key      = "key1"
   }
+
+  webapi {
+    feeds        = ["0x1234567890123456789012345678901234567890"]
+    listen_addr  = "localhost:8080"
+    ethereum_key = "key1"
+
+    ethereum_address_book {
+      contract_addr   = "0x2345678901234567890123456789012345678901"
+      ethereum_client = "client1"
+    }
+  }
 }
@@ -278,6 +278,8 @@ transport {
     direct_peers_addrs = []
     blocked_addrs      = []
     ethereum_key       = "default"
+    batch_topics       = []
+    batch_interval     = 1
   }
 }
 ethereum {
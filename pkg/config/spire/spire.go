@@ -27,10 +27,13 @@ import (
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/signer"
 
+	pkgConfig "github.com/chronicleprotocol/oracle-suite/pkg/config"
 	ethereumConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/ethereum"
 	loggerConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/logger"
+	metricsConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/metrics"
 	transportConfig "github.com/chronicleprotocol/oracle-suite/pkg/config/transport"
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/store"
+	"github.com/chronicleprotocol/oracle-suite/pkg/httpserver"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
 	"github.com/chronicleprotocol/oracle-suite/pkg/spire"
 	pkgSupervisor "github.com/chronicleprotocol/oracle-suite/pkg/supervisor"
@@ -44,9 +47,10 @@ type Config struct {
 	Transport transportConfig.Config `hcl:"transport,block"`
 	Ethereum  ethereumConfig.Config  `hcl:"ethereum,block"`
 	Logger    *loggerConfig.Config   `hcl:"logger,block,optional"`
+	Metrics   *metricsConfig.Config  `hcl:"metrics,block,optional"`
 
 	// HCL fields:
-	Remain  hcl.Body        `hcl:",remain"` // To ignore unknown blocks.
+	Remain  hcl.Body        `hcl:",remain,strict"` // To ignore unknown blocks.
 	Content hcl.BodyContent `hcl:",content"`
 }
 
@@ -100,8 +104,10 @@ type AgentServices struct {
 	SpireAgent *spire.Agent
 	Transport  pkgTransport.Service
 	PriceStore *store.Store
+	Metrics    httpserver.Service
 	Logger     log.Logger
 
+	config     *Config
 	supervisor *pkgSupervisor.Supervisor
 }
 
@@ -138,6 +144,9 @@ func (s *AgentServices) Start(ctx context.Context) error {
 	}
 	s.supervisor = pkgSupervisor.New(s.Logger)
 	s.supervisor.Watch(s.Transport, s.PriceStore, s.SpireAgent)
+	if s.Metrics != nil {
+		s.supervisor.Watch(s.Metrics)
+	}
 	if l, ok := s.Logger.(pkgSupervisor.Service); ok {
 		s.supervisor.Watch(l)
 	}
@@ -149,6 +158,29 @@ func (s *AgentServices) Wait() <-chan error {
 	return s.supervisor.Wait()
 }
 
+// Reload implements the supervisor.Reloadable interface.
+//
+// The agent and the price store keep running, only the list of pairs
+// collected by the price store is updated. Changes to other options are
+// rejected because they require a restart.
+func (s *AgentServices) Reload(cfg pkgSupervisor.Config) error {
+	c, ok := cfg.(*Config)
+	if !ok {
+		return fmt.Errorf("unexpected config type: %T", cfg)
+	}
+	next := *c
+	next.Spire.Pairs = s.config.Spire.Pairs
+	if !pkgConfig.Equal(&next, s.config) {
+		return fmt.Errorf("only spire pairs can be changed without a restart: %w", pkgSupervisor.ErrRestartRequired)
+	}
+	s.PriceStore.SetModels(c.Spire.Pairs)
+	s.config = c
+	s.Logger.
+		WithField("pairs", c.Spire.Pairs).
+		Info("Price store pairs updated")
+	return nil
+}
+
 // Start implements the supervisor.Service interface.
 func (s *StreamServices) Start(ctx context.Context) error {
 	if s.supervisor != nil {
@@ -234,11 +266,20 @@ func (c *Config) Services(baseLogger log.Logger, appName string, appVersion stri
 	if err != nil {
 		return nil, err
 	}
+	var metricsServer httpserver.Service
+	if c.Metrics != nil {
+		metricsServer, err = c.Metrics.HTTPServer()
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &AgentServices{
 		SpireAgent: spireAgent,
 		Transport:  transport,
 		PriceStore: priceStore,
+		Metrics:    metricsServer,
 		Logger:     logger,
+		config:     c,
 	}, nil
 }
 
@@ -0,0 +1,6 @@
+dynamic "asset" {
+  for_each = csvdecode(file("assets.csv"))
+  content {
+    symbol = asset.value.symbol
+  }
+}
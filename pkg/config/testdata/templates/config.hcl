@@ -0,0 +1,16 @@
+variables {
+  models  = csvdecode(file("models.csv"))
+  origins = jsondecode(file("origins.json"))
+}
+
+dynamic "model" {
+  for_each = var.models
+  iterator = m
+  content {
+    name    = format("%s/%s", lower(m.value.base), lower(m.value.quote))
+    symbol  = upper(regex("^[a-z]+", lower(m.value.base)))
+    origins = flatten([split(" ", m.value.origins)])
+    urls    = formatlist("%s/ticker", [for o in split(" ", m.value.origins) : lookup(var.origins, o, {}).url])
+    json    = jsonencode({ base = m.value.base })
+  }
+}
@@ -0,0 +1 @@
+include = ["assets/assets.hcl"]
@@ -8,6 +8,8 @@ libp2p {
   disable_discovery  = true
   ethereum_key       = "key"
   external_addr      = "/dns/eee.example.com"
+  batch_topics       = ["data_point/v1"]
+  batch_interval     = 5
 }
 
 webapi {
@@ -15,6 +17,14 @@ webapi {
   listen_addr       = "localhost:8080"
   socks5_proxy_addr = "localhost:9050"
   ethereum_key      = "key"
+  pull_allowlist    = ["0x6789012345678901234567890123456789012345"]
+  pull_producers    = ["http://producer.example.com"]
+  pull_interval     = 30
+  queue_max_age     = 600
+  queue_max_size    = 500
+
+  dead_consumer_threshold = 3
+  dead_consumer_backoff   = 120
 
   ethereum_address_book {
     contract_addr   = "0x5678901234567890123456789012345678901234"
@@ -24,4 +34,11 @@ webapi {
   static_address_book {
     addresses = ["https://example.com/api/v1/endpoint"]
   }
+
+  tor_address_book {
+    contract_addr   = "0x7890123456789012345678901234567890123456"
+    ethereum_client = "client"
+    check_interval  = 120
+    cooldown        = 900
+  }
 }
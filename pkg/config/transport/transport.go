@@ -18,6 +18,7 @@ package transport
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/rand"
 	"encoding/hex"
@@ -122,6 +123,21 @@ type libP2PConfig struct {
 	// Required if the transport is used for sending messages.
 	EthereumKey string `hcl:"ethereum_key,optional"`
 
+	// DisableDirectMessages disables sending and receiving direct messages.
+	// Required if the Ethereum key does not expose its private key, e.g.
+	// when a remote signer is used, because direct messages are encrypted
+	// with the recipient's key.
+	DisableDirectMessages bool `hcl:"disable_direct_messages,optional"`
+
+	// BatchTopics is the list of topics for which messages will be published
+	// as a single compressed batch instead of individually. Requires
+	// `ethereum_key` to be set.
+	BatchTopics []string `hcl:"batch_topics,optional"`
+
+	// BatchInterval is the interval in seconds at which batches are published.
+	// If zero, default value of 1 second is used.
+	BatchInterval uint32 `hcl:"batch_interval,optional"`
+
 	// HCL fields:
 	Range   hcl.Range       `hcl:",range"`
 	Content hcl.BodyContent `hcl:",content"`
@@ -155,6 +171,43 @@ type webAPIConfig struct {
 	// StaticAddressBook is the configuration for the static address book.
 	StaticAddressBook *webAPIStaticAddressBook `hcl:"static_address_book,block,optional"`
 
+	// TorAddressBook is the configuration for the Tor address book.
+	TorAddressBook *webAPITorAddressBook `hcl:"tor_address_book,block,optional"`
+
+	// PullAllowlist is a list of Ethereum addresses of consumers that are
+	// allowed to pull messages from this node. If empty, pulling messages
+	// from this node is disabled.
+	PullAllowlist []types.Address `hcl:"pull_allowlist,optional"`
+
+	// PullProducers is a list of addresses of producers from which messages
+	// will be pulled periodically. Useful if the node cannot receive messages
+	// directly, e.g. when it is behind a firewall or NAT.
+	PullProducers []string `hcl:"pull_producers,optional"`
+
+	// PullInterval is the interval in seconds at which messages are pulled
+	// from producers. If zero, default value of 60 seconds is used.
+	PullInterval uint32 `hcl:"pull_interval,optional"`
+
+	// QueueMaxAge is the time in seconds for which undelivered messages are
+	// kept in the outbound queue of each consumer. If zero, default value of
+	// 900 seconds is used.
+	QueueMaxAge uint32 `hcl:"queue_max_age,optional"`
+
+	// QueueMaxSize is the maximum number of undelivered messages per topic
+	// kept in the outbound queue of each consumer. If zero, default value of
+	// 1000 messages is used.
+	QueueMaxSize uint32 `hcl:"queue_max_size,optional"`
+
+	// DeadConsumerThreshold is the number of consecutive failed deliveries
+	// after which a consumer is considered dead. If zero, default value of
+	// 5 deliveries is used.
+	DeadConsumerThreshold uint32 `hcl:"dead_consumer_threshold,optional"`
+
+	// DeadConsumerBackoff is the time in seconds after which the delivery
+	// to a dead consumer is retried. It is also the maximum delay between
+	// retries. If zero, default value of 300 seconds is used.
+	DeadConsumerBackoff uint32 `hcl:"dead_consumer_backoff,optional"`
+
 	// HCL fields:
 	Range   hcl.Range       `hcl:",range"`
 	Content hcl.BodyContent `hcl:",content"`
@@ -183,6 +236,28 @@ type webAPIStaticAddressBook struct {
 	Content hcl.BodyContent `hcl:",content"`
 }
 
+type webAPITorAddressBook struct {
+	// ContractAddr is the Ethereum address of the TorAddressRegister
+	// contract.
+	ContractAddr types.Address `hcl:"contract_addr"`
+
+	// EthereumClient is the name of the Ethereum client to use for reading
+	// the address register.
+	EthereumClient string `hcl:"ethereum_client"`
+
+	// CheckInterval is the interval in seconds at which reachable consumers
+	// are health-checked. If zero, default value of 300 seconds is used.
+	CheckInterval uint32 `hcl:"check_interval,optional"`
+
+	// Cooldown is the time in seconds for which unreachable consumers are
+	// skipped. If zero, default value of 600 seconds is used.
+	Cooldown uint32 `hcl:"cooldown,optional"`
+
+	// HCL fields:
+	Range   hcl.Range       `hcl:",range"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
 func (c *Config) Transport(d Dependencies) (transport.Service, error) {
 	if c.transport != nil {
 		return c.transport, nil
@@ -218,6 +293,22 @@ func (c *Config) Transport(d Dependencies) (transport.Service, error) {
 	return logger.New(c.transport, d.Logger), nil
 }
 
+// EthereumClients returns names of the Ethereum clients used by the
+// transport.
+func (c *Config) EthereumClients() []string {
+	if c == nil || c.WebAPI == nil {
+		return nil
+	}
+	var names []string
+	if c.WebAPI.EthereumAddressBook != nil {
+		names = append(names, c.WebAPI.EthereumAddressBook.EthereumClient)
+	}
+	if c.WebAPI.TorAddressBook != nil {
+		names = append(names, c.WebAPI.TorAddressBook.EthereumClient)
+	}
+	return names
+}
+
 func (c *Config) LibP2PBootstrap(d BootstrapDependencies) (transport.Service, error) {
 	if c.LibP2P == nil {
 		return nil, &hcl.Diagnostic{
@@ -272,6 +363,7 @@ func (c *Config) configureWebAPI(d Dependencies) (transport.Service, error) {
 
 	// Configure HTTP client:
 	httpClient := &http.Client{}
+	var dialContext webapi.DialContextFunc
 	if len(c.WebAPI.Socks5ProxyAddr) != 0 {
 		dialer, err := proxy.SOCKS5("tcp", c.WebAPI.Socks5ProxyAddr, nil, proxy.Direct)
 		if err != nil {
@@ -282,6 +374,12 @@ func (c *Config) configureWebAPI(d Dependencies) (transport.Service, error) {
 				Subject:  &c.WebAPI.Content.Attributes["socks5_proxy_addr"].Range,
 			}
 		}
+		dialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			if d, ok := dialer.(proxy.ContextDialer); ok {
+				return d.DialContext(ctx, network, address)
+			}
+			return dialer.Dial(network, address)
+		}
 		httpClient.Transport = &http.Transport{
 			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
 				return dialer.Dial(network, address)
@@ -312,10 +410,60 @@ func (c *Config) configureWebAPI(d Dependencies) (transport.Service, error) {
 			time.Hour,
 		))
 	}
+	var staticAddresses []string
 	if c.WebAPI.StaticAddressBook != nil {
+		staticAddresses = c.WebAPI.StaticAddressBook.Addresses
+	}
+	if c.WebAPI.TorAddressBook != nil {
+		l.WithField("address", c.WebAPI.TorAddressBook.ContractAddr).
+			Info("Tor address book")
+
+		if dialContext == nil {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   "SOCKS5 proxy must be configured to use the Tor address book",
+				Subject:  c.WebAPI.TorAddressBook.Range.Ptr(),
+			}
+		}
+		rpcClient := d.Clients[c.WebAPI.TorAddressBook.EthereumClient]
+		if rpcClient == nil {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   fmt.Sprintf("Ethereum client %q is not configured", c.WebAPI.TorAddressBook.EthereumClient),
+				Subject:  c.WebAPI.TorAddressBook.Content.Attributes["ethereum_client"].Range.Ptr(),
+			}
+		}
+		torAddressBook, err := webapi.NewTorAddressBook(webapi.TorAddressBookConfig{
+			Register: webapi.NewEthereumAddressBook(
+				rpcClient,
+				c.WebAPI.TorAddressBook.ContractAddr,
+				time.Hour,
+			),
+			Static:        staticAddresses,
+			Dial:          dialContext,
+			CheckInterval: time.Duration(c.WebAPI.TorAddressBook.CheckInterval) * time.Second,
+			Cooldown:      time.Duration(c.WebAPI.TorAddressBook.Cooldown) * time.Second,
+			Logger:        d.Logger,
+		})
+		if err != nil {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Runtime error",
+				Detail:   fmt.Sprintf("Failed to create the Tor address book: %v", err),
+				Subject:  c.WebAPI.TorAddressBook.Range.Ptr(),
+			}
+		}
+		addressBooks = append(addressBooks, torAddressBook)
+
+		// Static addresses are already included in the Tor address book.
+		staticAddresses = nil
+	}
+	if len(staticAddresses) > 0 {
 		addressBooks = append(
 			addressBooks,
-			webapi.NewStaticAddressBook(c.WebAPI.StaticAddressBook.Addresses),
+			webapi.NewStaticAddressBook(staticAddresses),
 		)
 	}
 
@@ -350,18 +498,55 @@ func (c *Config) configureWebAPI(d Dependencies) (transport.Service, error) {
 		}
 	}
 
+	// Configure pull mode:
+	if len(c.WebAPI.PullAllowlist) > 0 && key == nil {
+		return nil, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Validation error",
+			Detail:   "Ethereum key must be configured to serve messages to pulling consumers",
+			Subject:  c.WebAPI.Content.Attributes["pull_allowlist"].Range.Ptr(),
+		}
+	}
+	var pullTicker *timeutil.Ticker
+	if len(c.WebAPI.PullProducers) > 0 {
+		if key == nil {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   "Ethereum key must be configured to pull messages",
+				Subject:  c.WebAPI.Content.Attributes["pull_producers"].Range.Ptr(),
+			}
+		}
+		pullInterval := time.Minute
+		if c.WebAPI.PullInterval > 0 {
+			pullInterval = time.Duration(c.WebAPI.PullInterval) * time.Second
+		}
+		pullTicker = timeutil.NewTicker(pullInterval)
+		for _, p := range c.WebAPI.PullProducers {
+			l.WithField("address", p).
+				Info("Pull producer")
+		}
+	}
+
 	// Configure transport:
 	webapiTransport, err := webapi.New(webapi.Config{
-		ListenAddr:      c.WebAPI.ListenAddr,
-		AddressBook:     addressBook,
-		Topics:          d.Messages,
-		AuthorAllowlist: c.WebAPI.Feeds,
-		FlushTicker:     timeutil.NewTicker(time.Minute),
-		Signer:          key,
-		Client:          httpClient,
-		Logger:          d.Logger,
-		AppName:         d.AppName,
-		AppVersion:      d.AppVersion,
+		ListenAddr:            c.WebAPI.ListenAddr,
+		AddressBook:           addressBook,
+		Topics:                d.Messages,
+		AuthorAllowlist:       c.WebAPI.Feeds,
+		FlushTicker:           timeutil.NewTicker(time.Minute),
+		Signer:                key,
+		PullAllowlist:         c.WebAPI.PullAllowlist,
+		PullProducers:         c.WebAPI.PullProducers,
+		PullTicker:            pullTicker,
+		QueueMaxAge:           time.Duration(c.WebAPI.QueueMaxAge) * time.Second,
+		QueueMaxSize:          int(c.WebAPI.QueueMaxSize),
+		DeadConsumerThreshold: int(c.WebAPI.DeadConsumerThreshold),
+		DeadConsumerBackoff:   time.Duration(c.WebAPI.DeadConsumerBackoff) * time.Second,
+		Client:                httpClient,
+		Logger:                d.Logger,
+		AppName:               d.AppName,
+		AppVersion:            d.AppVersion,
 	})
 	if err != nil {
 		return nil, &hcl.Diagnostic{
@@ -392,15 +577,16 @@ func (c *Config) configureLibP2P(d Dependencies) (transport.Service, error) {
 		return nil, err
 	}
 	var messagePrivKey crypto.PrivKey
+	feeds := c.LibP2P.Feeds
 	if key != nil {
 		messagePrivKey = ethkey.NewPrivKey(key)
-		if !sliceutil.Contains(c.LibP2P.Feeds, key.Address()) {
-			c.LibP2P.Feeds = append(c.LibP2P.Feeds, key.Address())
+		if !sliceutil.Contains(feeds, key.Address()) {
+			feeds = append(feeds[:len(feeds):len(feeds)], key.Address())
 		}
 	}
 
 	if !c.LibP2P.DisableFeedFilter {
-		if len(c.LibP2P.Feeds) == 0 {
+		if len(feeds) == 0 {
 			return nil, &hcl.Diagnostic{
 				Severity: hcl.DiagError,
 				Summary:  "Validation error",
@@ -408,15 +594,15 @@ func (c *Config) configureLibP2P(d Dependencies) (transport.Service, error) {
 				Subject:  c.LibP2P.Content.Attributes["feeds"].Range.Ptr(),
 			}
 		}
-	} else if len(c.LibP2P.Feeds) != 0 {
+	} else if len(feeds) != 0 {
 		d.Logger.
-			WithField("feeds", c.LibP2P.Feeds).
+			WithField("feeds", feeds).
 			Warn("Feeds filter is disabled, the list of feeds will be ignored")
-		c.LibP2P.Feeds = nil
+		feeds = nil
 	}
 
 	logger := d.Logger.WithField("tag", LoggerTag)
-	for _, addr := range c.LibP2P.Feeds {
+	for _, addr := range feeds {
 		logger.
 			WithField("address", addr.String()).
 			Info("Feed")
@@ -443,23 +629,63 @@ func (c *Config) configureLibP2P(d Dependencies) (transport.Service, error) {
 			Info("External Ingress")
 	}
 
+	if key != nil && !c.LibP2P.DisableDirectMessages {
+		if _, ok := key.(interface{ PrivateKey() *ecdsa.PrivateKey }); !ok {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail: fmt.Sprintf(
+					"Ethereum key %q cannot decrypt direct messages because its private key is not available, "+
+						"set disable_direct_messages = true to use it",
+					c.LibP2P.EthereumKey,
+				),
+				Subject: c.LibP2P.Content.Attributes["ethereum_key"].Range.Ptr(),
+			}
+		}
+	}
+
+	var batchTicker *timeutil.Ticker
+	if len(c.LibP2P.BatchTopics) > 0 {
+		if key == nil {
+			return nil, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  "Validation error",
+				Detail:   "Ethereum key must be configured to publish batches",
+				Subject:  c.LibP2P.Content.Attributes["batch_topics"].Range.Ptr(),
+			}
+		}
+		batchInterval := time.Second
+		if c.LibP2P.BatchInterval > 0 {
+			batchInterval = time.Duration(c.LibP2P.BatchInterval) * time.Second
+		}
+		batchTicker = timeutil.NewTicker(batchInterval)
+		for _, topic := range c.LibP2P.BatchTopics {
+			logger.
+				WithField("topic", topic).
+				Info("Batch topic")
+		}
+	}
+
 	// Configure LibP2P transport:
 	cfg := libp2p.Config{
-		Mode:             libp2p.ClientMode,
-		Topics:           d.Messages,
-		PeerPrivKey:      peerPrivKey,
-		MessagePrivKey:   messagePrivKey,
-		ListenAddrs:      c.LibP2P.ListenAddrs,
-		ExternalAddr:     extAddr,
-		BootstrapAddrs:   c.LibP2P.BootstrapAddrs,
-		DirectPeersAddrs: c.LibP2P.DirectPeersAddrs,
-		BlockedAddrs:     c.LibP2P.BlockedAddrs,
-		AuthorAllowlist:  c.LibP2P.Feeds,
-		Discovery:        !c.LibP2P.DisableDiscovery,
-		Signer:           key,
-		Logger:           d.Logger,
-		AppName:          d.AppName,
-		AppVersion:       d.AppVersion,
+		Mode:                  libp2p.ClientMode,
+		Topics:                d.Messages,
+		PeerPrivKey:           peerPrivKey,
+		MessagePrivKey:        messagePrivKey,
+		ListenAddrs:           c.LibP2P.ListenAddrs,
+		ExternalAddr:          extAddr,
+		BootstrapAddrs:        c.LibP2P.BootstrapAddrs,
+		DirectPeersAddrs:      c.LibP2P.DirectPeersAddrs,
+		BlockedAddrs:          c.LibP2P.BlockedAddrs,
+		AuthorAllowlist:       feeds,
+		Discovery:             !c.LibP2P.DisableDiscovery,
+		Signer:                key,
+		BatchTopics:           c.LibP2P.BatchTopics,
+		DisableDirectMessages: c.LibP2P.DisableDirectMessages,
+		BatchTicker:           batchTicker,
+		Logger:                d.Logger,
+		AppName:               d.AppName,
+		AppVersion:            d.AppVersion,
 	}
 	libP2PTransport, err := libp2p.New(cfg)
 	if err != nil {
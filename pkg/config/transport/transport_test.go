@@ -53,6 +53,8 @@ func TestConfig(t *testing.T) {
 				assert.Equal(t, []string{"/ip4/0.0.0.0/tcp/9000"}, cfg.LibP2P.BlockedAddrs)
 				assert.Equal(t, true, cfg.LibP2P.DisableDiscovery)
 				assert.Equal(t, "key", cfg.LibP2P.EthereumKey)
+				assert.Equal(t, []string{"data_point/v1"}, cfg.LibP2P.BatchTopics)
+				assert.Equal(t, uint32(5), cfg.LibP2P.BatchInterval)
 
 				// WebAPI
 				assert.Equal(t, "0x3456789012345678901234567890123456789012", cfg.WebAPI.Feeds[0].String())
@@ -60,8 +62,16 @@ func TestConfig(t *testing.T) {
 				assert.Equal(t, "localhost:8080", cfg.WebAPI.ListenAddr)
 				assert.Equal(t, "localhost:9050", cfg.WebAPI.Socks5ProxyAddr)
 				assert.Equal(t, "key", cfg.WebAPI.EthereumKey)
+				assert.Equal(t, "0x6789012345678901234567890123456789012345", cfg.WebAPI.PullAllowlist[0].String())
+				assert.Equal(t, []string{"http://producer.example.com"}, cfg.WebAPI.PullProducers)
+				assert.Equal(t, uint32(30), cfg.WebAPI.PullInterval)
+				assert.Equal(t, uint32(600), cfg.WebAPI.QueueMaxAge)
+				assert.Equal(t, uint32(500), cfg.WebAPI.QueueMaxSize)
+				assert.Equal(t, uint32(3), cfg.WebAPI.DeadConsumerThreshold)
+				assert.Equal(t, uint32(120), cfg.WebAPI.DeadConsumerBackoff)
 				assert.NotNil(t, cfg.WebAPI.EthereumAddressBook)
 				assert.NotNil(t, cfg.WebAPI.StaticAddressBook)
+				assert.NotNil(t, cfg.WebAPI.TorAddressBook)
 
 				// EthereumAddressBook
 				assert.Equal(t, "0x5678901234567890123456789012345678901234", cfg.WebAPI.EthereumAddressBook.ContractAddr.String())
@@ -69,6 +79,12 @@ func TestConfig(t *testing.T) {
 
 				// StaticAddressBook
 				assert.Equal(t, []string{"https://example.com/api/v1/endpoint"}, cfg.WebAPI.StaticAddressBook.Addresses)
+
+				// TorAddressBook
+				assert.Equal(t, "0x7890123456789012345678901234567890123456", cfg.WebAPI.TorAddressBook.ContractAddr.String())
+				assert.Equal(t, "client", cfg.WebAPI.TorAddressBook.EthereumClient)
+				assert.Equal(t, uint32(120), cfg.WebAPI.TorAddressBook.CheckInterval)
+				assert.Equal(t, uint32(900), cfg.WebAPI.TorAddressBook.Cooldown)
 			},
 		},
 		{
@@ -81,23 +97,44 @@ func TestConfig(t *testing.T) {
 					"key": key,
 				}
 				rpc := &mocks.RPC{}
-				rpc.On("Call", context.Background(), types.Call{
-					To:    types.AddressFromHexPtr("0x5678901234567890123456789012345678901234"),
-					Input: hexutil.MustDecode("0x0f560cd7"),
-				}, types.LatestBlockNumber).Return(
-					hexutil.MustDecode("0x00000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000000"),
-					&types.Call{},
-					nil,
-				)
+				for _, addr := range []string{
+					"0x5678901234567890123456789012345678901234",
+					"0x7890123456789012345678901234567890123456",
+				} {
+					rpc.On("Call", context.Background(), types.Call{
+						To:    types.AddressFromHexPtr(addr),
+						Input: hexutil.MustDecode("0x0f560cd7"),
+					}, types.LatestBlockNumber).Return(
+						hexutil.MustDecode("0x00000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000000"),
+						&types.Call{},
+						nil,
+					)
+				}
 				clientRegistry := ethereum.ClientRegistry{
 					"client": rpc,
 				}
-				transport, err := cfg.Transport(Dependencies{
+				deps := Dependencies{
 					Keys:     keyRegistry,
 					Clients:  clientRegistry,
 					Messages: nil,
 					Logger:   null.New(),
-				})
+				}
+
+				// The mocked key does not expose a private key, so it cannot
+				// decrypt direct messages unless they are disabled.
+				_, err := cfg.Transport(deps)
+				require.ErrorContains(t, err, "disable_direct_messages")
+
+				cfg.LibP2P.DisableDirectMessages = true
+
+				// Messages cannot be served to pulling consumers without
+				// a key to sign them.
+				cfg.WebAPI.EthereumKey = ""
+				_, err = cfg.Transport(deps)
+				require.ErrorContains(t, err, "pulling consumers")
+
+				cfg.WebAPI.EthereumKey = "key"
+				transport, err := cfg.Transport(deps)
 				require.NoError(t, err)
 				assert.NotNil(t, transport)
 			},
@@ -0,0 +1,310 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package backtest evaluates data models over a historical period.
+//
+// For every step of the backtest, a data provider is created that fetches
+// data as it was at the step time, e.g. using recorded HTTP fixtures or an
+// archive node. Data points returned by the provider are then analyzed to
+// determine how sources deviated from the aggregated value and how often
+// the min_values requirements of medians and deviation circuit breakers
+// would have fired.
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/value"
+)
+
+// ProviderFunc returns a data provider that provides data points as they
+// were at the given time.
+type ProviderFunc func(ctx context.Context, t time.Time) (datapoint.Provider, error)
+
+// Config is the configuration for the Run function.
+type Config struct {
+	// Provider returns a data provider for every step of the backtest.
+	Provider ProviderFunc
+
+	// Models is a list of data models to evaluate.
+	Models []string
+
+	// From and To define the backtest period. Both times are inclusive.
+	From time.Time
+	To   time.Time
+
+	// Step is the interval between evaluations.
+	Step time.Duration
+}
+
+// Report contains the results of a backtest of a single data model.
+type Report struct {
+	Model string
+
+	// Samples is the series of data points, one for every step.
+	Samples []Sample
+
+	// Medians contains statistics of median nodes, indexed by the node path
+	// from the data model root. Elements of the path are node types followed
+	// by the index of the node among its siblings, e.g.
+	// "reference/deviation_circuit_breaker[0]/value[0]/median[1]".
+	Medians map[string]*MedianStats
+
+	// CircuitBreakers contains statistics of deviation circuit breakers,
+	// indexed by the node path, in the same format as Medians.
+	CircuitBreakers map[string]*CircuitBreakerStats
+}
+
+// nodePath returns the path of a node with the given type that is the child
+// with the given index of the node with the parent path. The root node has
+// an empty parent path and no index.
+func nodePath(parent, typ string, index int) string {
+	if parent == "" {
+		return typ
+	}
+	return fmt.Sprintf("%s/%s[%d]", parent, typ, index)
+}
+
+// Sample is a data point evaluated at the given time.
+type Sample struct {
+	Time  time.Time
+	Point datapoint.Point
+}
+
+// SourceStats contains deviations of a median source.
+type SourceStats struct {
+	// Samples is the number of valid values returned by the source.
+	Samples int
+
+	// Errors is the number of invalid data points returned by the source.
+	Errors int
+
+	// MeanDeviation and MaxDeviation are the mean and maximum relative
+	// deviations from the median value, e.g. 0.01 means 1%.
+	MeanDeviation float64
+	MaxDeviation  float64
+
+	sum float64
+}
+
+// MedianStats contains statistics of a median node.
+type MedianStats struct {
+	// MinValues is the configured minimum number of values.
+	MinValues int
+
+	// Evaluations is the number of times the node was evaluated.
+	Evaluations int
+
+	// Fired is the number of times there were not enough valid values to
+	// calculate the median.
+	Fired int
+
+	// MinValid is the smallest number of valid values observed.
+	MinValid int
+
+	// Sources contains deviations of the median sources from the median
+	// value, indexed by the source name.
+	Sources map[string]*SourceStats
+}
+
+// CircuitBreakerStats contains statistics of a deviation circuit breaker.
+type CircuitBreakerStats struct {
+	// Threshold is the configured deviation threshold.
+	Threshold float64
+
+	// Evaluations is the number of times the deviation was calculated.
+	Evaluations int
+
+	// Fired is the number of times the deviation exceeded the threshold.
+	Fired int
+
+	// MaxDeviation is the largest deviation observed.
+	MaxDeviation float64
+}
+
+// Run runs the backtest and returns reports indexed by the model name.
+func Run(ctx context.Context, cfg Config) (map[string]*Report, error) {
+	if cfg.Provider == nil {
+		return nil, fmt.Errorf("backtest: provider must not be nil")
+	}
+	if cfg.Step <= 0 {
+		return nil, fmt.Errorf("backtest: step must be greater than zero")
+	}
+	if cfg.To.Before(cfg.From) {
+		return nil, fmt.Errorf("backtest: end time must not be before start time")
+	}
+	reports := make(map[string]*Report, len(cfg.Models))
+	for _, model := range cfg.Models {
+		reports[model] = newReport(model)
+	}
+	for t := cfg.From; !t.After(cfg.To); t = t.Add(cfg.Step) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		provider, err := cfg.Provider(ctx, t)
+		if err != nil {
+			return nil, fmt.Errorf("backtest: unable to create provider for %s: %w", t.Format(time.RFC3339), err)
+		}
+		points, err := provider.DataPoints(ctx, cfg.Models...)
+		if err != nil {
+			return nil, fmt.Errorf("backtest: unable to fetch data points for %s: %w", t.Format(time.RFC3339), err)
+		}
+		for model, point := range points {
+			reports[model].add(Sample{Time: t, Point: point})
+		}
+	}
+	for _, r := range reports {
+		for _, m := range r.Medians {
+			for _, s := range m.Sources {
+				if s.Samples > 0 {
+					s.MeanDeviation = s.sum / float64(s.Samples)
+				}
+			}
+		}
+	}
+	return reports, nil
+}
+
+func newReport(model string) *Report {
+	return &Report{
+		Model:           model,
+		Medians:         make(map[string]*MedianStats),
+		CircuitBreakers: make(map[string]*CircuitBreakerStats),
+	}
+}
+
+func (r *Report) add(s Sample) {
+	r.Samples = append(r.Samples, s)
+	r.analyze(s.Point, "", 0)
+}
+
+// analyze walks the data point tree and updates the statistics. The index
+// is the index of the data point among the sub points of its parent.
+func (r *Report) analyze(p datapoint.Point, parent string, index int) {
+	typ, _ := p.Meta["type"].(string)
+	path := nodePath(parent, typ, index)
+	switch typ {
+	case "median":
+		r.analyzeMedian(p, path)
+	case "deviation_circuit_breaker":
+		r.analyzeCircuitBreaker(p, path)
+	}
+	for i, sp := range p.SubPoints {
+		r.analyze(sp, path, i)
+	}
+}
+
+func (r *Report) analyzeMedian(p datapoint.Point, path string) {
+	minValues, _ := p.Meta["min_values"].(int)
+	valid := 0
+	for _, sp := range p.SubPoints {
+		if sp.Validate() == nil {
+			valid++
+		}
+	}
+	stats, ok := r.Medians[path]
+	if !ok {
+		stats = &MedianStats{MinValues: minValues, MinValid: valid, Sources: make(map[string]*SourceStats)}
+		r.Medians[path] = stats
+	}
+	stats.Evaluations++
+	if valid < stats.MinValid {
+		stats.MinValid = valid
+	}
+	if p.Validate() != nil && valid < minValues {
+		stats.Fired++
+	}
+	median := numericValue(p)
+	for _, sp := range p.SubPoints {
+		name := sourceName(sp)
+		source, ok := stats.Sources[name]
+		if !ok {
+			source = &SourceStats{}
+			stats.Sources[name] = source
+		}
+		v := numericValue(sp)
+		if v == nil {
+			source.Errors++
+			continue
+		}
+		if median == nil || median.Sign() == 0 {
+			continue
+		}
+		deviation, _ := new(big.Float).Quo(new(big.Float).Abs(new(big.Float).Sub(v, median)), median).Float64()
+		source.Samples++
+		source.sum += deviation
+		if deviation > source.MaxDeviation {
+			source.MaxDeviation = deviation
+		}
+	}
+}
+
+func (r *Report) analyzeCircuitBreaker(p datapoint.Point, path string) {
+	deviation, ok := p.Meta["deviation"].(*big.Float)
+	if !ok {
+		// Deviation was not calculated because of invalid inputs.
+		return
+	}
+	stats, ok := r.CircuitBreakers[path]
+	if !ok {
+		stats = &CircuitBreakerStats{}
+		if threshold, ok := p.Meta["threshold"].(*big.Float); ok {
+			stats.Threshold, _ = threshold.Float64()
+		}
+		r.CircuitBreakers[path] = stats
+	}
+	stats.Evaluations++
+	d, _ := deviation.Float64()
+	if d > stats.MaxDeviation {
+		stats.MaxDeviation = d
+	}
+	if p.Error != nil {
+		stats.Fired++
+	}
+}
+
+// sourceName returns a human-readable name of a median source, e.g.
+// "binance(BTC/USDT)" or "indirect[binance(BTC/USDT),reference]".
+func sourceName(p datapoint.Point) string {
+	typ, _ := p.Meta["type"].(string)
+	switch typ {
+	case "origin":
+		return fmt.Sprintf("%v(%v)", p.Meta["origin"], p.Meta["query"])
+	case "reference", "":
+		return "reference"
+	}
+	names := make([]string, len(p.SubPoints))
+	for i, sp := range p.SubPoints {
+		names[i] = sourceName(sp)
+	}
+	return typ + "[" + strings.Join(names, ",") + "]"
+}
+
+// numericValue returns the numeric value of a valid data point or nil.
+func numericValue(p datapoint.Point) *big.Float {
+	if p.Validate() != nil {
+		return nil
+	}
+	v, ok := p.Value.(value.NumericValue)
+	if !ok || v.Number() == nil {
+		return nil
+	}
+	return v.Number().BigFloat()
+}
@@ -0,0 +1,195 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package backtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/graph"
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/value"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/bn"
+)
+
+func TestRun(t *testing.T) {
+	pair := value.Pair{Base: "BTC", Quote: "USD"}
+	from := time.Unix(1700000000, 0)
+
+	// Prices returned by origins a, b and c at every step. Zero means that
+	// the origin returned an error.
+	prices := [][3]float64{
+		{100, 100, 100},
+		{100, 110, 0},
+		{100, 0, 0},
+	}
+	reference := []float64{100, 100, 100}
+
+	step := 0
+	provider := func(_ context.Context, _ time.Time) (datapoint.Provider, error) {
+		median := graph.NewTickMedianNode(2)
+		for i, name := range []string{"a", "b", "c"} {
+			node := graph.NewOriginNode(name, pair, time.Minute, time.Minute*5)
+			point := datapoint.Point{Time: time.Now(), Error: errors.New("failed")}
+			if p := prices[step][i]; p != 0 {
+				point = datapoint.Point{Time: time.Now(), Value: value.NewTick(pair, p, 0)}
+			}
+			require.NoError(t, node.SetDataPoint(point))
+			require.NoError(t, median.AddNodes(node))
+		}
+		refNode := graph.NewOriginNode("ref", pair, time.Minute, time.Minute*5)
+		require.NoError(t, refNode.SetDataPoint(datapoint.Point{
+			Time:  time.Now(),
+			Value: value.NewTick(pair, reference[step], 0),
+		}))
+		thresholdNode := graph.NewOriginNode("static", 0.02, time.Minute, time.Minute*5)
+		require.NoError(t, thresholdNode.SetDataPoint(datapoint.Point{
+			Time:  time.Now(),
+			Value: value.StaticValue{Value: bn.DecFloatPoint(0.02)},
+		}))
+		cb := graph.NewDevCircuitBreakerNode()
+		require.NoError(t, cb.AddNodes(median, refNode, thresholdNode))
+		root := graph.NewReferenceNode()
+		require.NoError(t, root.AddNodes(cb))
+		step++
+		return graph.NewProvider(map[string]graph.Node{"BTC/USD": root}, nil), nil
+	}
+
+	reports, err := Run(context.Background(), Config{
+		Provider: provider,
+		Models:   []string{"BTC/USD"},
+		From:     from,
+		To:       from.Add(2 * time.Minute),
+		Step:     time.Minute,
+	})
+	require.NoError(t, err)
+	require.Contains(t, reports, "BTC/USD")
+	r := reports["BTC/USD"]
+
+	require.Len(t, r.Samples, 3)
+	assert.Equal(t, from, r.Samples[0].Time)
+	assert.Equal(t, from.Add(2*time.Minute), r.Samples[2].Time)
+	assert.NoError(t, r.Samples[0].Point.Validate())
+	assert.Error(t, r.Samples[1].Point.Validate()) // Circuit breaker.
+	assert.Error(t, r.Samples[2].Point.Validate()) // Not enough values.
+
+	median := r.Medians["reference/deviation_circuit_breaker[0]/value[0]/median[0]"]
+	require.NotNil(t, median)
+	assert.Equal(t, 2, median.MinValues)
+	assert.Equal(t, 3, median.Evaluations)
+	assert.Equal(t, 1, median.Fired)
+	assert.Equal(t, 1, median.MinValid)
+
+	cb := r.CircuitBreakers["reference/deviation_circuit_breaker[0]"]
+	require.NotNil(t, cb)
+	assert.InDelta(t, 0.02, cb.Threshold, 1e-9)
+	assert.Equal(t, 2, cb.Evaluations)
+	assert.Equal(t, 1, cb.Fired)
+	assert.InDelta(t, 1-100.0/105.0, cb.MaxDeviation, 1e-9)
+
+	a := median.Sources["a(BTC/USD)"]
+	require.NotNil(t, a)
+	assert.Equal(t, 2, a.Samples) // The last median is invalid.
+	assert.Equal(t, 0, a.Errors)
+	assert.InDelta(t, 5.0/105.0/2, a.MeanDeviation, 1e-9)
+	assert.InDelta(t, 5.0/105.0, a.MaxDeviation, 1e-9)
+
+	c := median.Sources["c(BTC/USD)"]
+	require.NotNil(t, c)
+	assert.Equal(t, 1, c.Samples)
+	assert.Equal(t, 2, c.Errors)
+}
+
+func TestRun_SiblingMedians(t *testing.T) {
+	pair := value.Pair{Base: "BTC", Quote: "USD"}
+	now := time.Now()
+
+	// Two sibling medians share the origin a, but deviations of the origin
+	// must be calculated separately for each of them.
+	provider := func(_ context.Context, _ time.Time) (datapoint.Provider, error) {
+		origin := func(name string, price float64) graph.Node {
+			node := graph.NewOriginNode(name, pair, time.Minute, time.Minute*5)
+			require.NoError(t, node.SetDataPoint(datapoint.Point{Time: time.Now(), Value: value.NewTick(pair, price, 0)}))
+			return node
+		}
+		median1 := graph.NewTickMedianNode(2)
+		require.NoError(t, median1.AddNodes(origin("a", 100), origin("b", 110)))
+		median2 := graph.NewTickMedianNode(1)
+		require.NoError(t, median2.AddNodes(origin("a", 100), origin("c", 120)))
+		root := graph.NewTickMedianNode(2)
+		require.NoError(t, root.AddNodes(median1, median2))
+		return graph.NewProvider(map[string]graph.Node{"BTC/USD": root}, nil), nil
+	}
+
+	reports, err := Run(context.Background(), Config{
+		Provider: provider,
+		Models:   []string{"BTC/USD"},
+		From:     now,
+		To:       now,
+		Step:     time.Minute,
+	})
+	require.NoError(t, err)
+	r := reports["BTC/USD"]
+	require.Len(t, r.Medians, 3)
+
+	median1 := r.Medians["median/median[0]"]
+	require.NotNil(t, median1)
+	assert.Equal(t, 2, median1.MinValues)
+	require.Contains(t, median1.Sources, "a(BTC/USD)")
+	assert.InDelta(t, 5.0/105.0, median1.Sources["a(BTC/USD)"].MaxDeviation, 1e-9)
+	assert.NotContains(t, median1.Sources, "c(BTC/USD)")
+
+	median2 := r.Medians["median/median[1]"]
+	require.NotNil(t, median2)
+	assert.Equal(t, 1, median2.MinValues)
+	require.Contains(t, median2.Sources, "a(BTC/USD)")
+	assert.InDelta(t, 10.0/110.0, median2.Sources["a(BTC/USD)"].MaxDeviation, 1e-9)
+	assert.NotContains(t, median2.Sources, "b(BTC/USD)")
+}
+
+func TestRun_InvalidConfig(t *testing.T) {
+	now := time.Now()
+	provider := func(context.Context, time.Time) (datapoint.Provider, error) {
+		return nil, errors.New("unexpected call")
+	}
+	_, err := Run(context.Background(), Config{Provider: provider, From: now, To: now})
+	assert.Error(t, err)
+	_, err = Run(context.Background(), Config{Provider: provider, From: now, To: now.Add(-time.Second), Step: time.Second})
+	assert.Error(t, err)
+}
+
+func TestSnapshots(t *testing.T) {
+	s := Snapshots{
+		{Time: time.Unix(100, 0), Dir: "100"},
+		{Time: time.Unix(200, 0), Dir: "200"},
+	}
+	_, ok := s.At(time.Unix(99, 0))
+	assert.False(t, ok)
+	snap, ok := s.At(time.Unix(100, 0))
+	assert.True(t, ok)
+	assert.Equal(t, "100", snap.Dir)
+	snap, ok = s.At(time.Unix(199, 0))
+	assert.True(t, ok)
+	assert.Equal(t, "100", snap.Dir)
+	snap, ok = s.At(time.Unix(300, 0))
+	assert.True(t, ok)
+	assert.Equal(t, "200", snap.Dir)
+}
@@ -0,0 +1,245 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+)
+
+// Client is an Ethereum RPC client that reads the state at the last block
+// before the given time.
+//
+// The BlockNumber method returns the number of that block and all reads at
+// the latest or pending block are performed at that block instead. Because
+// origins read the state at the block number returned by the BlockNumber
+// method, it allows to fetch data points from an archive node as they were
+// at the given time.
+//
+// The block number is found on the first use of the client, so clients that
+// are not used by any origin do not send any requests.
+type Client struct {
+	rpc.RPC
+	finder *BlockFinder
+	time   time.Time
+
+	mu    sync.Mutex
+	block *big.Int
+}
+
+// NewClient returns a new Client that reads the state at the last block
+// before the given time. The finder must use the same client.
+func NewClient(finder *BlockFinder, t time.Time) *Client {
+	return &Client{RPC: finder.client, finder: finder, time: t}
+}
+
+// BlockNumber implements the rpc.RPC interface.
+func (c *Client) BlockNumber(ctx context.Context) (*big.Int, error) {
+	return c.blockNumber(ctx)
+}
+
+// GetBalance implements the rpc.RPC interface.
+func (c *Client) GetBalance(ctx context.Context, address types.Address, block types.BlockNumber) (*big.Int, error) {
+	block, err := c.pin(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	return c.RPC.GetBalance(ctx, address, block)
+}
+
+// GetStorageAt implements the rpc.RPC interface.
+func (c *Client) GetStorageAt(
+	ctx context.Context,
+	account types.Address,
+	key types.Hash,
+	block types.BlockNumber,
+) (*types.Hash, error) {
+
+	block, err := c.pin(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	return c.RPC.GetStorageAt(ctx, account, key, block)
+}
+
+// GetTransactionCount implements the rpc.RPC interface.
+func (c *Client) GetTransactionCount(ctx context.Context, account types.Address, block types.BlockNumber) (uint64, error) {
+	block, err := c.pin(ctx, block)
+	if err != nil {
+		return 0, err
+	}
+	return c.RPC.GetTransactionCount(ctx, account, block)
+}
+
+// GetCode implements the rpc.RPC interface.
+func (c *Client) GetCode(ctx context.Context, account types.Address, block types.BlockNumber) ([]byte, error) {
+	block, err := c.pin(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	return c.RPC.GetCode(ctx, account, block)
+}
+
+// Call implements the rpc.RPC interface.
+func (c *Client) Call(ctx context.Context, call types.Call, block types.BlockNumber) ([]byte, *types.Call, error) {
+	block, err := c.pin(ctx, block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.RPC.Call(ctx, call, block)
+}
+
+// EstimateGas implements the rpc.RPC interface.
+func (c *Client) EstimateGas(ctx context.Context, call types.Call, block types.BlockNumber) (uint64, error) {
+	block, err := c.pin(ctx, block)
+	if err != nil {
+		return 0, err
+	}
+	return c.RPC.EstimateGas(ctx, call, block)
+}
+
+// BlockByNumber implements the rpc.RPC interface.
+func (c *Client) BlockByNumber(ctx context.Context, number types.BlockNumber, full bool) (*types.Block, error) {
+	number, err := c.pin(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	return c.RPC.BlockByNumber(ctx, number, full)
+}
+
+// GetLogs implements the rpc.RPC interface.
+func (c *Client) GetLogs(ctx context.Context, query types.FilterLogsQuery) ([]types.Log, error) {
+	if query.FromBlock != nil {
+		from, err := c.pin(ctx, *query.FromBlock)
+		if err != nil {
+			return nil, err
+		}
+		query.FromBlock = &from
+	}
+	to := types.LatestBlockNumber
+	if query.ToBlock != nil {
+		to = *query.ToBlock
+	}
+	to, err := c.pin(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	query.ToBlock = &to
+	return c.RPC.GetLogs(ctx, query)
+}
+
+func (c *Client) blockNumber(ctx context.Context) (*big.Int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.block == nil {
+		block, err := c.finder.BlockAt(ctx, c.time)
+		if err != nil {
+			return nil, err
+		}
+		c.block = block
+	}
+	return new(big.Int).Set(c.block), nil
+}
+
+func (c *Client) pin(ctx context.Context, block types.BlockNumber) (types.BlockNumber, error) {
+	if !block.IsLatest() && !block.IsPending() {
+		return block, nil
+	}
+	number, err := c.blockNumber(ctx)
+	if err != nil {
+		return block, err
+	}
+	return types.BlockNumberFromBigInt(number), nil
+}
+
+// BlockFinder finds block numbers for given times.
+//
+// Times passed to the BlockAt method are expected to be increasing, because
+// the previously found block is used as the lower bound for the next search.
+type BlockFinder struct {
+	mu     sync.Mutex
+	client rpc.RPC
+	latest *big.Int
+	lower  *big.Int
+	times  map[string]time.Time
+}
+
+// NewBlockFinder returns a new BlockFinder for the given client.
+func NewBlockFinder(client rpc.RPC) *BlockFinder {
+	return &BlockFinder{
+		client: client,
+		lower:  big.NewInt(0),
+		times:  make(map[string]time.Time),
+	}
+}
+
+// BlockAt returns the number of the last block with a timestamp that is not
+// after the given time. If the time is after the latest block, the latest
+// block number is returned.
+func (f *BlockFinder) BlockAt(ctx context.Context, t time.Time) (*big.Int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.latest == nil {
+		latest, err := f.client.BlockNumber(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch the latest block number: %w", err)
+		}
+		f.latest = latest
+	}
+	lowerTime, err := f.blockTime(ctx, f.lower)
+	if err != nil {
+		return nil, err
+	}
+	if lowerTime.After(t) {
+		return nil, fmt.Errorf("no block found before %s", t.Format(time.RFC3339))
+	}
+	lo, hi := new(big.Int).Set(f.lower), new(big.Int).Set(f.latest)
+	one := big.NewInt(1)
+	for lo.Cmp(hi) < 0 {
+		// mid = (lo + hi + 1) / 2
+		mid := new(big.Int).Add(lo, hi)
+		mid.Add(mid, one).Rsh(mid, 1)
+		midTime, err := f.blockTime(ctx, mid)
+		if err != nil {
+			return nil, err
+		}
+		if midTime.After(t) {
+			hi = mid.Sub(mid, one)
+		} else {
+			lo = mid
+		}
+	}
+	f.lower = lo
+	return new(big.Int).Set(lo), nil
+}
+
+func (f *BlockFinder) blockTime(ctx context.Context, number *big.Int) (time.Time, error) {
+	if t, ok := f.times[number.String()]; ok {
+		return t, nil
+	}
+	block, err := f.client.BlockByNumber(ctx, types.BlockNumberFromBigInt(number), false)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to fetch block %s: %w", number, err)
+	}
+	f.times[number.String()] = block.Timestamp
+	return block.Timestamp, nil
+}
@@ -0,0 +1,97 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package backtest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var genesisTime = time.Unix(1700000000, 0)
+
+// chainRPC simulates a chain with 1000 blocks produced every 12 seconds.
+type chainRPC struct {
+	rpc.RPC
+	calls []types.BlockNumber
+}
+
+func (c *chainRPC) BlockNumber(_ context.Context) (*big.Int, error) {
+	return big.NewInt(999), nil
+}
+
+func (c *chainRPC) BlockByNumber(_ context.Context, number types.BlockNumber, _ bool) (*types.Block, error) {
+	return &types.Block{
+		Number:    number.Big(),
+		Timestamp: genesisTime.Add(time.Duration(number.Big().Int64()) * 12 * time.Second),
+	}, nil
+}
+
+func (c *chainRPC) Call(_ context.Context, _ types.Call, block types.BlockNumber) ([]byte, *types.Call, error) {
+	c.calls = append(c.calls, block)
+	return nil, nil, nil
+}
+
+func TestBlockFinder(t *testing.T) {
+	ctx := context.Background()
+	f := NewBlockFinder(&chainRPC{})
+
+	tests := []struct {
+		time  time.Time
+		block int64
+	}{
+		{time: genesisTime, block: 0},
+		{time: genesisTime.Add(11 * time.Second), block: 0},
+		{time: genesisTime.Add(12 * time.Second), block: 1},
+		{time: genesisTime.Add(100 * 12 * time.Second), block: 100},
+		{time: genesisTime.Add(500*12*time.Second + 5*time.Second), block: 500},
+		{time: genesisTime.Add(24 * time.Hour), block: 999},
+	}
+	for _, tt := range tests {
+		block, err := f.BlockAt(ctx, tt.time)
+		require.NoError(t, err)
+		assert.Equal(t, tt.block, block.Int64())
+	}
+
+	// Times before the previously found block are not supported.
+	_, err := f.BlockAt(ctx, genesisTime)
+	assert.Error(t, err)
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	chain := &chainRPC{}
+	c := NewClient(NewBlockFinder(chain), genesisTime.Add(42*12*time.Second))
+
+	block, err := c.BlockNumber(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), block.Int64())
+
+	_, _, err = c.Call(ctx, types.Call{}, types.LatestBlockNumber)
+	require.NoError(t, err)
+	_, _, err = c.Call(ctx, types.Call{}, types.BlockNumberFromUint64(40))
+	require.NoError(t, err)
+
+	require.Len(t, chain.calls, 2)
+	assert.Equal(t, int64(42), chain.calls[0].Big().Int64())
+	assert.Equal(t, int64(40), chain.calls[1].Big().Int64())
+}
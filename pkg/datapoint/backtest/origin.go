@@ -0,0 +1,59 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package backtest
+
+import (
+	"context"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/origin"
+)
+
+// Origin wraps an origin that returns historical data points, so they are
+// treated by data models as if they were fetched at the backtest time.
+//
+// Data point times are shifted so that the age of a data point relative to
+// the current time is the same as its age relative to the backtest time.
+// Data points with times after the backtest time, like those fetched from
+// an archive node, are treated as if they were fetched just now.
+type Origin struct {
+	origin origin.Origin
+	time   time.Time
+}
+
+// NewOrigin returns a new Origin for the given backtest time.
+func NewOrigin(o origin.Origin, t time.Time) *Origin {
+	return &Origin{origin: o, time: t}
+}
+
+// FetchDataPoints implements the origin.Origin interface.
+func (o *Origin) FetchDataPoints(ctx context.Context, query []any) (map[any]datapoint.Point, error) {
+	points, err := o.origin.FetchDataPoints(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for q, p := range points {
+		age := o.time.Sub(p.Time)
+		if age < 0 {
+			age = 0
+		}
+		p.Time = now.Add(-age)
+		points[q] = p
+	}
+	return points, nil
+}
@@ -0,0 +1,84 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package backtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Snapshot is a fixture directory recorded at the given time.
+type Snapshot struct {
+	Time time.Time
+	Dir  string
+}
+
+// Snapshots is a list of snapshots sorted by time.
+type Snapshots []Snapshot
+
+// LoadSnapshots returns snapshots from subdirectories of the given directory.
+// Subdirectory names must be either Unix timestamps or RFC3339 times, e.g.
+// directories created by "gofer data --record DIR/$(date +%s)". Other
+// entries are ignored.
+func LoadSnapshots(dir string) (Snapshots, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read snapshot directory: %w", err)
+	}
+	var s Snapshots
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		t, ok := parseSnapshotTime(e.Name())
+		if !ok {
+			continue
+		}
+		s = append(s, Snapshot{Time: t, Dir: filepath.Join(dir, e.Name())})
+	}
+	if len(s) == 0 {
+		return nil, fmt.Errorf("no snapshots found in %s", dir)
+	}
+	sort.Slice(s, func(i, j int) bool {
+		return s[i].Time.Before(s[j].Time)
+	})
+	return s, nil
+}
+
+// At returns the last snapshot recorded not after the given time.
+func (s Snapshots) At(t time.Time) (Snapshot, bool) {
+	i := sort.Search(len(s), func(i int) bool {
+		return s[i].Time.After(t)
+	})
+	if i == 0 {
+		return Snapshot{}, false
+	}
+	return s[i-1], true
+}
+
+func parseSnapshotTime(name string) (time.Time, bool) {
+	if ts, err := strconv.ParseInt(name, 10, 64); err == nil {
+		return time.Unix(ts, 0), true
+	}
+	if t, err := time.Parse(time.RFC3339, name); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
@@ -75,18 +75,23 @@ func (n *DevCircuitBreakerNode) DataPoint() datapoint.Point {
 	valuePoint := n.valueNode.DataPoint()
 	refPoint := n.referenceNode.DataPoint()
 	thresholdPoint := n.thresholdNode.DataPoint()
+
+	// Invalid inputs are included as sub points, like in the median node,
+	// so traces show which of them caused the error.
 	if err := valuePoint.Validate(); err != nil {
 		return datapoint.Point{
-			Time:  time.Now(),
-			Meta:  n.Meta(),
-			Error: fmt.Errorf("invalid value data point: %w", err),
+			Time:      time.Now(),
+			SubPoints: []datapoint.Point{valuePoint, refPoint},
+			Meta:      n.Meta(),
+			Error:     fmt.Errorf("invalid value data point: %w", err),
 		}
 	}
 	if err := refPoint.Validate(); err != nil {
 		return datapoint.Point{
-			Time:  time.Now(),
-			Meta:  n.Meta(),
-			Error: fmt.Errorf("invalid reference data point: %w", err),
+			Time:      time.Now(),
+			SubPoints: []datapoint.Point{valuePoint, refPoint},
+			Meta:      n.Meta(),
+			Error:     fmt.Errorf("invalid reference data point: %w", err),
 		}
 	}
 	if err := thresholdPoint.Validate(); err != nil {
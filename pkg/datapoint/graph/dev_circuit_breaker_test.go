@@ -111,6 +111,41 @@ func TestDevCircuitBreakerNode(t *testing.T) {
 	}
 }
 
+func TestDevCircuitBreakerNode_InvalidInputSubPoints(t *testing.T) {
+	pricePoint := datapoint.Point{
+		Value: numericValue{bn.Float(14)},
+		Time:  time.Now(),
+	}
+	referencePoint := datapoint.Point{
+		Value: numericValue{bn.Float(12)},
+		Time:  time.Now(),
+		Error: errors.New("invalid reference price"),
+	}
+
+	// Mock nodes.
+	priceNode := new(mockNode)
+	referenceNode := new(mockNode)
+	thresholdNode := new(mockNode)
+	priceNode.On("DataPoint").Return(pricePoint)
+	referenceNode.On("DataPoint").Return(referencePoint)
+	thresholdNode.On("DataPoint").Return(datapoint.Point{
+		Value: numericValue{bn.Float(0.1)},
+		Time:  time.Now(),
+	})
+
+	// Create dev circuit breaker node.
+	node := NewDevCircuitBreakerNode()
+	require.NoError(t, node.AddNodes(priceNode, referenceNode, thresholdNode))
+
+	// Test.
+	point := node.DataPoint()
+	require.Error(t, point.Validate())
+	require.Len(t, point.SubPoints, 2)
+	assert.Equal(t, pricePoint.Value, point.SubPoints[0].Value)
+	assert.Equal(t, referencePoint.Error, point.SubPoints[1].Error)
+	assert.NotContains(t, point.Meta, "deviation")
+}
+
 func TestDevCircuitBreakerNode_AddNode(t *testing.T) {
 	node := new(mockNode)
 	tests := []struct {
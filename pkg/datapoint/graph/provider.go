@@ -6,6 +6,8 @@ import (
 	"sort"
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/value"
+	"github.com/chronicleprotocol/oracle-suite/pkg/metrics"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/maputil"
 )
 
@@ -51,7 +53,9 @@ func (p Provider) DataPoint(ctx context.Context, model string) (datapoint.Point,
 	if p.updater != nil {
 		p.updater.Update(ctx, []Node{node})
 	}
-	return node.DataPoint(), nil
+	point := node.DataPoint()
+	observeDataPoint(model, point)
+	return point, nil
 }
 
 // DataPoints implements the data.Provider interface.
@@ -70,6 +74,7 @@ func (p Provider) DataPoints(ctx context.Context, models ...string) (map[string]
 	points := make(map[string]datapoint.Point, len(models))
 	for i, model := range models {
 		points[model] = nodes[i].DataPoint()
+		observeDataPoint(model, points[model])
 	}
 	return points, nil
 }
@@ -111,3 +116,17 @@ func nodeToModel(n Node) datapoint.Model {
 	}
 	return m
 }
+
+// observeDataPoint updates data point metrics.
+func observeDataPoint(model string, point datapoint.Point) {
+	if point.Validate() != nil {
+		metrics.DataPointValid.WithLabelValues(model).Set(0)
+		return
+	}
+	metrics.DataPointValid.WithLabelValues(model).Set(1)
+	metrics.DataPointAge.Set(point.Time, model)
+	if v, ok := point.Value.(value.NumericValue); ok && v.Number() != nil {
+		f, _ := v.Number().BigFloat().Float64()
+		metrics.DataPointValue.WithLabelValues(model).Set(f)
+	}
+}
@@ -2,6 +2,7 @@ package graph
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/origin"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/metrics"
 )
 
 const UpdaterLoggerTag = "GRAPH_UPDATER"
@@ -98,7 +100,9 @@ func (u *Updater) fetchDataPoints(ctx context.Context, queries queryMap) dataPoi
 			defer func() { <-u.limiter }()
 
 			// Fetch data points from the origin and store them in the map.
+			fetchStart := time.Now()
 			points, err := origin.FetchDataPoints(ctx, queries)
+			metrics.OriginFetchDuration.WithLabelValues(originName).Observe(time.Since(fetchStart).Seconds())
 			mu.Lock()
 			if err != nil {
 				for _, query := range queries {
@@ -125,6 +129,9 @@ func (u *Updater) fetchDataPoints(ctx context.Context, queries queryMap) dataPoi
 func (u *Updater) updateNodesWithDataPoints(nodes nodesMap, points dataPointsMap) {
 	for k, nodes := range nodes {
 		point, ok := points[k]
+		if ok && point.Validate() != nil {
+			metrics.OriginFetchErrors.WithLabelValues(k.origin).Inc()
+		}
 		for _, node := range nodes {
 			if !ok {
 				u.logger.
@@ -146,6 +153,9 @@ func (u *Updater) updateNodesWithDataPoints(nodes nodesMap, points dataPointsMap
 					WithAdvice("Ignore if occurs occasionally").
 					Warn("Failed to set data point on the origin node")
 			}
+			if p := node.DataPoint(); p.Validate() == nil {
+				metrics.OriginDataPointAge.Set(p.Time, k.origin, fmt.Sprint(k.query))
+			}
 		}
 	}
 }
@@ -0,0 +1,342 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
+	"github.com/chronicleprotocol/oracle-suite/pkg/httpserver"
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/maputil"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+const LoggerTag = "DATA_POINT_API"
+
+const (
+	formatJSON  = "json"
+	formatTrace = "trace"
+)
+
+// API serves data points from a data provider over HTTP.
+//
+// Data points are updated periodically, on every tick of the ticker, so
+// requests do not trigger fetching data from origins. Every response contains
+// the ETag header, which changes only if the response content changes. If the
+// request contains the If-None-Match header with the current ETag, the
+// 304 Not Modified response is returned.
+//
+// Endpoints:
+//
+//	GET /models             - list of all models
+//	GET /data?models=a,b    - data points for given models, or all models
+//	GET /data/{model}       - data point for a single model
+//
+// All endpoints return JSON by default. The format=trace query parameter
+// may be used to return a human-readable trace of data points or models.
+type API struct {
+	mu     sync.RWMutex
+	ctx    context.Context
+	waitCh chan error
+
+	provider datapoint.Provider
+	server   httpserver.Service
+	ticker   *timeutil.Ticker
+	models   []string
+	log      log.Logger
+
+	// State updated on every tick:
+	points    map[string]datapoint.Point
+	modelInfo map[string]datapoint.Model
+	updated   time.Time
+}
+
+// Config is the configuration for the API.
+type Config struct {
+	// Provider is the data provider used to obtain data points.
+	Provider datapoint.Provider
+
+	// Server is the HTTP server used to serve the API. The server is
+	// started by the API.
+	Server httpserver.Service
+
+	// Ticker specifies how often data points are updated.
+	Ticker *timeutil.Ticker
+
+	// Models is an optional list of models to serve. If empty, all models
+	// supported by the provider are served.
+	Models []string
+
+	// Logger is a current logger interface used by the API.
+	Logger log.Logger
+}
+
+// New creates a new API.
+func New(cfg Config) (*API, error) {
+	if cfg.Provider == nil {
+		return nil, errors.New("provider must not be nil")
+	}
+	if cfg.Server == nil {
+		return nil, errors.New("server must not be nil")
+	}
+	if cfg.Ticker == nil {
+		return nil, errors.New("ticker must not be nil")
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	a := &API{
+		waitCh:   make(chan error),
+		provider: cfg.Provider,
+		server:   cfg.Server,
+		ticker:   cfg.Ticker,
+		models:   cfg.Models,
+		log:      cfg.Logger.WithField("tag", LoggerTag),
+	}
+	a.server.SetHandler(http.HandlerFunc(a.handler))
+	return a, nil
+}
+
+// Start implements the supervisor.Service interface.
+func (a *API) Start(ctx context.Context) error {
+	if a.ctx != nil {
+		return errors.New("service can be started only once")
+	}
+	if ctx == nil {
+		return errors.New("context must not be nil")
+	}
+	a.log.
+		WithField("listenAddr", a.server.Addr()).
+		Info("Starting")
+	a.ctx = ctx
+	if len(a.models) == 0 {
+		a.models = a.provider.ModelNames(ctx)
+	}
+	if err := a.server.Start(ctx); err != nil {
+		return fmt.Errorf("unable to start the HTTP server: %w", err)
+	}
+	a.ticker.Start(ctx)
+	go a.updateRoutine()
+	go a.contextCancelHandler()
+	return nil
+}
+
+// Wait implements the supervisor.Service interface.
+func (a *API) Wait() <-chan error {
+	return a.waitCh
+}
+
+// Updated returns the time of the last update. It returns zero time if
+// data points have not been updated yet.
+func (a *API) Updated() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.updated
+}
+
+func (a *API) update() {
+	points, err := a.provider.DataPoints(a.ctx, a.models...)
+	if err != nil {
+		a.log.
+			WithError(err).
+			Error("Unable to update data points")
+		return
+	}
+	models, err := a.provider.Models(a.ctx, a.models...)
+	if err != nil {
+		a.log.
+			WithError(err).
+			Error("Unable to update models")
+		return
+	}
+	a.mu.Lock()
+	a.points = points
+	a.modelInfo = models
+	a.updated = time.Now()
+	a.mu.Unlock()
+	a.log.
+		WithField("models", len(points)).
+		Debug("Data points updated")
+}
+
+func (a *API) handler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		http.Error(res, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = formatJSON
+	}
+	if format != formatJSON && format != formatTrace {
+		http.Error(res, fmt.Sprintf("unsupported format: %s", format), http.StatusBadRequest)
+		return
+	}
+
+	a.mu.RLock()
+	points, models, updated := a.points, a.modelInfo, a.updated
+	a.mu.RUnlock()
+	if updated.IsZero() {
+		http.Error(res, "data points are not available yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	var (
+		body []byte
+		err  error
+	)
+	switch path := strings.TrimRight(req.URL.Path, "/"); {
+	case path == "/models":
+		body, err = marshalModels(models, format)
+	case path == "/data":
+		var names []string
+		if q := req.URL.Query().Get("models"); q != "" {
+			names = strings.Split(q, ",")
+		}
+		selected := make(map[string]datapoint.Point)
+		for _, name := range names {
+			point, ok := points[name]
+			if !ok {
+				http.Error(res, fmt.Sprintf("model %s not found", name), http.StatusNotFound)
+				return
+			}
+			selected[name] = point
+		}
+		if len(names) == 0 {
+			selected = points
+		}
+		body, err = marshalDataPoints(selected, format)
+	case strings.HasPrefix(path, "/data/"):
+		name := strings.TrimPrefix(path, "/data/")
+		point, ok := points[name]
+		if !ok {
+			http.Error(res, fmt.Sprintf("model %s not found", name), http.StatusNotFound)
+			return
+		}
+		if format == formatTrace {
+			body, err = marshalDataPoints(map[string]datapoint.Point{name: point}, format)
+		} else {
+			body, err = json.Marshal(point)
+		}
+	default:
+		http.NotFound(res, req)
+		return
+	}
+	if err != nil {
+		a.log.
+			WithError(err).
+			WithField("url", req.URL.String()).
+			Error("Unable to marshal response")
+		http.Error(res, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// The ETag depends only on the response content, so it changes only
+	// if data points have changed since the last update.
+	hash := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(hash[:16]) + `"`
+	res.Header().Set("ETag", etag)
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Last-Modified", updated.UTC().Format(http.TimeFormat))
+	if match := req.Header.Get("If-None-Match"); match != "" && etagMatch(match, etag) {
+		res.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if format == formatTrace {
+		res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		res.Header().Set("Content-Type", "application/json")
+	}
+	res.WriteHeader(http.StatusOK)
+	if req.Method == http.MethodGet {
+		_, _ = res.Write(body)
+	}
+}
+
+func (a *API) updateRoutine() {
+	a.update()
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-a.ticker.TickCh():
+			a.update()
+		}
+	}
+}
+
+func (a *API) contextCancelHandler() {
+	defer func() { close(a.waitCh) }()
+	defer a.log.Debug("Stopped")
+	<-a.ctx.Done()
+	<-a.server.Wait()
+}
+
+// etagMatch checks if the If-None-Match header matches the given ETag.
+func etagMatch(header, etag string) bool {
+	for _, m := range strings.Split(header, ",") {
+		m = strings.TrimSpace(m)
+		if m == "*" || strings.TrimPrefix(m, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func marshalDataPoints(points map[string]datapoint.Point, format string) ([]byte, error) {
+	if format != formatTrace {
+		return json.Marshal(points)
+	}
+	var buf bytes.Buffer
+	for _, name := range maputil.SortKeys(points, sort.Strings) {
+		bts, err := points[name].MarshalTrace()
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(fmt.Sprintf("Data point for %s:\n", name))
+		buf.Write(bts)
+	}
+	return buf.Bytes(), nil
+}
+
+func marshalModels(models map[string]datapoint.Model, format string) ([]byte, error) {
+	if format != formatTrace {
+		return json.Marshal(models)
+	}
+	var buf bytes.Buffer
+	for _, name := range maputil.SortKeys(models, sort.Strings) {
+		bts, err := models[name].MarshalTrace()
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(fmt.Sprintf("Model for %s:\n", name))
+		buf.Write(bts)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,135 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/mocks"
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/value"
+	"github.com/chronicleprotocol/oracle-suite/pkg/httpserver"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/bn"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+func TestAPI(t *testing.T) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+
+	points := func(v float64) map[string]datapoint.Point {
+		return map[string]datapoint.Point{
+			"AAA/BBB": {Value: value.StaticValue{Value: bn.DecFloatPoint(v)}, Time: time.Unix(1700000000, 0)},
+			"CCC/DDD": {Value: value.StaticValue{Value: bn.DecFloatPoint(2)}, Time: time.Unix(1700000000, 0)},
+		}
+	}
+	models := map[string]datapoint.Model{
+		"AAA/BBB": {Meta: map[string]any{"type": "origin"}},
+		"CCC/DDD": {Meta: map[string]any{"type": "origin"}},
+	}
+	names := []string{"AAA/BBB", "CCC/DDD"}
+
+	provider := &mocks.Provider{}
+	provider.On("ModelNames", mock.Anything).Return(names)
+	provider.On("DataPoints", mock.Anything, names).Return(points(1), nil).Once()
+	provider.On("DataPoints", mock.Anything, names).Return(points(1), nil).Once()
+	provider.On("DataPoints", mock.Anything, names).Return(points(3), nil).Once()
+	provider.On("Models", mock.Anything, names).Return(models, nil)
+
+	srv := httpserver.New(&http.Server{Addr: "127.0.0.1:0"})
+	ticker := timeutil.NewTicker(0)
+	api, err := New(Config{
+		Provider: provider,
+		Server:   srv,
+		Ticker:   ticker,
+	})
+	require.NoError(t, err)
+	require.NoError(t, api.Start(ctx))
+	require.Eventually(t, func() bool { return !api.Updated().IsZero() }, time.Second, 10*time.Millisecond)
+
+	get := func(path, etag string) (*http.Response, []byte) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+srv.Addr().String()+path, nil)
+		require.NoError(t, err)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, body
+	}
+
+	// Models:
+	res, body := get("/models", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.JSONEq(t, `{"AAA/BBB":{"type":"origin","models":null},"CCC/DDD":{"type":"origin","models":null}}`, string(body))
+
+	// All data points:
+	res, body = get("/data", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	var all map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(body, &all))
+	assert.Len(t, all, 2)
+
+	// Selected data points:
+	res, body = get("/data?models=CCC/DDD", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	var selected map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(body, &selected))
+	assert.Len(t, selected, 1)
+	assert.Contains(t, selected, "CCC/DDD")
+
+	// Single data point and trace:
+	res, _ = get("/data/AAA/BBB", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	etag := res.Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+	res, body = get("/data/AAA/BBB?format=trace", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, string(body), "Data point for AAA/BBB")
+
+	// Unknown model:
+	res, _ = get("/data/XXX/YYY", "")
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	res, _ = get("/data?models=AAA/BBB,XXX/YYY", "")
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	// ETag does not change if the data point did not change:
+	updated := api.Updated()
+	ticker.Tick()
+	require.Eventually(t, func() bool { return api.Updated() != updated }, time.Second, 10*time.Millisecond)
+	res, _ = get("/data/AAA/BBB", etag)
+	assert.Equal(t, http.StatusNotModified, res.StatusCode)
+
+	// ETag changes if the data point changed:
+	updated = api.Updated()
+	ticker.Tick()
+	require.Eventually(t, func() bool { return api.Updated() != updated }, time.Second, 10*time.Millisecond)
+	res, _ = get("/data/AAA/BBB", etag)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.NotEqual(t, etag, res.Header.Get("ETag"))
+}
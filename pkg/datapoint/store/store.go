@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/defiweb/go-eth/types"
@@ -29,6 +30,7 @@ import (
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/value"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/metrics"
 	"github.com/chronicleprotocol/oracle-suite/pkg/transport"
 	"github.com/chronicleprotocol/oracle-suite/pkg/transport/messages"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/bn"
@@ -87,12 +89,13 @@ func StoredDataPointLogFields(o StoredDataPoint) log.Fields {
 // Store stores latest data points from feeds.
 type Store struct {
 	ctx    context.Context
+	mu     sync.RWMutex
 	waitCh chan error
 	log    log.Logger
 
 	storage    Storage
 	transport  transport.Service
-	models     []string
+	models     []string // Guarded by mu.
 	recoverers []datapoint.Recoverer
 }
 
@@ -159,6 +162,14 @@ func (p *Store) Wait() <-chan error {
 	return p.waitCh
 }
 
+// SetModels replaces the list of models which are supported by the store.
+// Data points already collected are kept.
+func (p *Store) SetModels(models []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.models = models
+}
+
 // LatestFrom implements the DataPointProvider interface.
 func (p *Store) LatestFrom(ctx context.Context, from types.Address, model string) (StoredDataPoint, bool, error) {
 	return p.storage.LatestFrom(ctx, from, model)
@@ -183,6 +194,7 @@ func (p *Store) collectDataPoint(point *messages.DataPoint) {
 					WithFields(datapoint.PointLogFields(point.Point)).
 					WithAdvice("This is a sign of a misbehaving feed or a serious bug in the feed software").
 					Error("Unable to recover address from the data point")
+				metrics.StoreDataPointsRejected.WithLabelValues(point.Model).Inc()
 				return
 			}
 			sdp := StoredDataPoint{
@@ -196,8 +208,11 @@ func (p *Store) collectDataPoint(point *messages.DataPoint) {
 					WithError(err).
 					WithFields(StoredDataPointLogFields(sdp)).
 					Error("Unable to add data point to the storage")
+				metrics.StoreDataPointsRejected.WithLabelValues(point.Model).Inc()
 				return
 			}
+			metrics.StoreDataPointsReceived.WithLabelValues(sdp.From.String(), sdp.Model).Inc()
+			metrics.StoreDataPointAge.Set(sdp.DataPoint.Time, sdp.From.String(), sdp.Model)
 			p.log.
 				WithFields(StoredDataPointLogFields(sdp)).
 				Debug("Data point collected")
@@ -209,9 +224,12 @@ func (p *Store) collectDataPoint(point *messages.DataPoint) {
 		WithFields(datapoint.PointLogFields(point.Point)).
 		WithAdvice("This is probably caused by misconfigured feed or an error in the data model").
 		Error("Unable to find recoverer for the data point")
+	metrics.StoreDataPointsRejected.WithLabelValues(point.Model).Inc()
 }
 
 func (p *Store) shouldCollect(model string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	for _, a := range p.models {
 		if a == model {
 			return true
@@ -300,8 +318,11 @@ func (p *Store) handleLegacyPriceMessage(msg transport.ReceivedMessage) {
 // logDataPointsSince logs a short summary of data points collected since the
 // given time.
 func (p *Store) logDataPointsSince(since time.Time) {
+	p.mu.RLock()
+	models := p.models
+	p.mu.RUnlock()
 	dataPointsLog := make(map[string]any)
-	for _, model := range p.models {
+	for _, model := range models {
 		dataPoints, err := p.storage.Latest(p.ctx, model)
 		if err != nil {
 			p.log.
@@ -121,3 +121,18 @@ func TestStore(t *testing.T) {
 	assert.Equal(t, "3", b[types.MustAddressFromHex("0x1111111111111111111111111111111111111111")].DataPoint.Value.Print())
 	assert.Equal(t, "4", b[types.MustAddressFromHex("0x2222222222222222222222222222222222222222")].DataPoint.Value.Print())
 }
+
+func TestStore_SetModels(t *testing.T) {
+	store, err := New(Config{
+		Storage:   NewMemoryStorage(),
+		Transport: local.New([]byte("test"), 0, nil),
+		Models:    []string{"AAABBB"},
+	})
+	require.NoError(t, err)
+	assert.True(t, store.shouldCollect("AAABBB"))
+	assert.False(t, store.shouldCollect("XXXYYY"))
+
+	store.SetModels([]string{"XXXYYY"})
+	assert.False(t, store.shouldCollect("AAABBB"))
+	assert.True(t, store.shouldCollect("XXXYYY"))
+}
@@ -0,0 +1,480 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/rpc/transport"
+	"github.com/defiweb/go-eth/types"
+)
+
+const (
+	defaultFeeHistoryBlocks     = 10
+	defaultFeeHistoryPercentile = 50
+	defaultBaseFeeMultiplier    = 2
+)
+
+// L2 chains supported by the L2FeeEstimator.
+const (
+	L2ChainOPStack  = "op_stack"
+	L2ChainArbitrum = "arbitrum"
+)
+
+var (
+	// GasPriceOracle predeploy on OP-stack chains.
+	opGasPriceOracle = types.MustAddressFromHex("0x420000000000000000000000000000000000000F")
+
+	// NodeInterface virtual contract on Arbitrum chains.
+	arbNodeInterface = types.MustAddressFromHex("0x00000000000000000000000000000000000000C8")
+
+	opGetL1Fee                = abi.MustParseMethod("getL1Fee(bytes)(uint256)")
+	arbGasEstimateL1Component = abi.MustParseMethod(
+		"gasEstimateL1Component(address,bool,bytes)(uint64 gasEstimateForL1, uint256 baseFee, uint256 l1BaseFeeEstimate)",
+	)
+)
+
+// FeeEstimate describes how the fees of a transaction were estimated.
+type FeeEstimate struct {
+	// Estimator is the name of the estimator that set the fees.
+	Estimator string
+
+	// Reason is a human-readable explanation of how the fees were
+	// calculated.
+	Reason string
+
+	// L1Fee is the estimated L1 data fee, in wei, for transactions sent to
+	// L2 chains. It is nil for L1 chains.
+	L1Fee *big.Int
+}
+
+type feeEstimateKey struct{}
+
+// WithFeeEstimate returns a context that collects information about fee
+// estimation. The returned FeeEstimate is filled by fee estimators when the
+// context is used to send a transaction.
+func WithFeeEstimate(ctx context.Context) (context.Context, *FeeEstimate) {
+	fe := &FeeEstimate{}
+	return context.WithValue(ctx, feeEstimateKey{}, fe), fe
+}
+
+// feeEstimateFromContext returns the FeeEstimate stored in the context, or
+// nil if there is none.
+func feeEstimateFromContext(ctx context.Context) *FeeEstimate {
+	fe, _ := ctx.Value(feeEstimateKey{}).(*FeeEstimate)
+	return fe
+}
+
+// NamedFeeEstimator records the name of a fee estimator and the reason for
+// its fees in the FeeEstimate, so that estimators that do not do that
+// themselves, like the ones from the go-eth txmodifier package, can be
+// reported as well.
+type NamedFeeEstimator struct {
+	name      string
+	reason    string
+	estimator rpc.TXModifier
+}
+
+// NewNamedFeeEstimator returns a new NamedFeeEstimator.
+func NewNamedFeeEstimator(name, reason string, estimator rpc.TXModifier) *NamedFeeEstimator {
+	return &NamedFeeEstimator{name: name, reason: reason, estimator: estimator}
+}
+
+// Modify implements the rpc.TXModifier interface.
+func (e *NamedFeeEstimator) Modify(ctx context.Context, client rpc.RPC, tx *types.Transaction) error {
+	if err := e.estimator.Modify(ctx, client, tx); err != nil {
+		return err
+	}
+	if fe := feeEstimateFromContext(ctx); fe != nil {
+		fe.Estimator = e.name
+		fe.Reason = e.reason
+	}
+	return nil
+}
+
+// FeeHistoryEstimator is a transaction modifier that estimates EIP-1559 fees
+// using the eth_feeHistory method.
+//
+// The priority fee is the median of the given percentile of priority fees
+// paid in recent blocks. The max fee is the base fee of the next block
+// multiplied by the base fee multiplier, plus the priority fee.
+//
+// It sets transaction type to types.DynamicFeeTxType.
+type FeeHistoryEstimator struct {
+	transport            transport.Transport
+	blocks               uint64
+	percentile           float64
+	baseFeeMultiplier    float64
+	maxFeePerGas         *big.Int
+	maxPriorityFeePerGas *big.Int
+}
+
+// FeeHistoryEstimatorOptions is the options for NewFeeHistoryEstimator.
+type FeeHistoryEstimatorOptions struct {
+	// Transport is used to call the eth_feeHistory method, which is not
+	// available in the rpc.RPC interface.
+	Transport transport.Transport
+
+	// Blocks is the number of recent blocks to use. If zero, 10 blocks are
+	// used.
+	Blocks uint64
+
+	// Percentile is the percentile, between 0 and 100, of priority fees paid
+	// in a block. If zero, the 50th percentile is used.
+	Percentile float64
+
+	// BaseFeeMultiplier is applied to the base fee to allow the transaction
+	// to be included even if the base fee increases in the next blocks.
+	// If zero, the multiplier is 2.
+	BaseFeeMultiplier float64
+
+	// MaxFeePerGas is the maximum fee per gas, or nil if there is no upper
+	// bound.
+	MaxFeePerGas *big.Int
+
+	// MaxPriorityFeePerGas is the maximum priority fee per gas, or nil if
+	// there is no upper bound.
+	MaxPriorityFeePerGas *big.Int
+}
+
+// NewFeeHistoryEstimator returns a new FeeHistoryEstimator.
+func NewFeeHistoryEstimator(opts FeeHistoryEstimatorOptions) (*FeeHistoryEstimator, error) {
+	if opts.Transport == nil {
+		return nil, errors.New("fee history estimator: transport must not be nil")
+	}
+	if opts.Percentile < 0 || opts.Percentile > 100 {
+		return nil, errors.New("fee history estimator: percentile must be between 0 and 100")
+	}
+	if opts.Blocks == 0 {
+		opts.Blocks = defaultFeeHistoryBlocks
+	}
+	if opts.Percentile == 0 {
+		opts.Percentile = defaultFeeHistoryPercentile
+	}
+	if opts.BaseFeeMultiplier == 0 {
+		opts.BaseFeeMultiplier = defaultBaseFeeMultiplier
+	}
+	return &FeeHistoryEstimator{
+		transport:            opts.Transport,
+		blocks:               opts.Blocks,
+		percentile:           opts.Percentile,
+		baseFeeMultiplier:    opts.BaseFeeMultiplier,
+		maxFeePerGas:         opts.MaxFeePerGas,
+		maxPriorityFeePerGas: opts.MaxPriorityFeePerGas,
+	}, nil
+}
+
+// Modify implements the rpc.TXModifier interface.
+func (e *FeeHistoryEstimator) Modify(ctx context.Context, _ rpc.RPC, tx *types.Transaction) error {
+	if tx.MaxFeePerGas != nil && tx.MaxPriorityFeePerGas != nil {
+		return nil
+	}
+	var history types.FeeHistory
+	err := e.transport.Call(
+		ctx,
+		&history,
+		"eth_feeHistory",
+		types.NumberFromUint64(e.blocks),
+		types.LatestBlockNumber,
+		[]float64{e.percentile},
+	)
+	if err != nil {
+		return fmt.Errorf("fee history estimator: eth_feeHistory failed: %w", err)
+	}
+	if len(history.BaseFeePerGas) == 0 {
+		return errors.New("fee history estimator: eth_feeHistory returned no base fees")
+	}
+
+	// The last base fee is the base fee of the next block.
+	baseFee := history.BaseFeePerGas[len(history.BaseFeePerGas)-1]
+	var rewards []*big.Int
+	for _, r := range history.Reward {
+		if len(r) > 0 && r[0] != nil {
+			rewards = append(rewards, r[0])
+		}
+	}
+	priorityFee := median(rewards)
+	maxFee, _ := new(big.Float).Mul(new(big.Float).SetInt(baseFee), big.NewFloat(e.baseFeeMultiplier)).Int(nil)
+	maxFee.Add(maxFee, priorityFee)
+
+	var limits []string
+	if e.maxPriorityFeePerGas != nil && priorityFee.Cmp(e.maxPriorityFeePerGas) > 0 {
+		priorityFee = e.maxPriorityFeePerGas
+		limits = append(limits, "priority fee capped by max priority fee")
+	}
+	if e.maxFeePerGas != nil && maxFee.Cmp(e.maxFeePerGas) > 0 {
+		maxFee = e.maxFeePerGas
+		limits = append(limits, "max fee capped by max gas fee")
+	}
+	if maxFee.Cmp(priorityFee) < 0 {
+		priorityFee = maxFee
+	}
+	tx.GasPrice = nil
+	tx.MaxFeePerGas = maxFee
+	tx.MaxPriorityFeePerGas = priorityFee
+	tx.Type = types.DynamicFeeTxType
+
+	if fe := feeEstimateFromContext(ctx); fe != nil {
+		fe.Estimator = "fee_history"
+		fe.Reason = fmt.Sprintf(
+			"next base fee %s wei multiplied by %g, median of %g percentile priority fees over %d blocks is %s wei",
+			baseFee, e.baseFeeMultiplier, e.percentile, len(rewards), median(rewards),
+		)
+		if len(limits) > 0 {
+			fe.Reason += ", " + strings.Join(limits, ", ")
+		}
+	}
+	return nil
+}
+
+// FixedFeeEstimator is a transaction modifier that sets fixed fees. It is
+// useful for private chains, where fees do not change.
+//
+// If the gas price is set, it sets transaction type to types.LegacyTxType,
+// otherwise to types.DynamicFeeTxType.
+type FixedFeeEstimator struct {
+	gasPrice             *big.Int
+	maxFeePerGas         *big.Int
+	maxPriorityFeePerGas *big.Int
+}
+
+// FixedFeeEstimatorOptions is the options for NewFixedFeeEstimator.
+type FixedFeeEstimatorOptions struct {
+	// GasPrice is the gas price for legacy transactions. If set, the
+	// remaining fields are ignored.
+	GasPrice *big.Int
+
+	// MaxFeePerGas is the max fee per gas for EIP-1559 transactions.
+	MaxFeePerGas *big.Int
+
+	// MaxPriorityFeePerGas is the priority fee per gas for EIP-1559
+	// transactions.
+	MaxPriorityFeePerGas *big.Int
+}
+
+// NewFixedFeeEstimator returns a new FixedFeeEstimator.
+func NewFixedFeeEstimator(opts FixedFeeEstimatorOptions) (*FixedFeeEstimator, error) {
+	if opts.GasPrice == nil && (opts.MaxFeePerGas == nil || opts.MaxPriorityFeePerGas == nil) {
+		return nil, errors.New("fixed fee estimator: either gas price or both max fee and priority fee must be set")
+	}
+	if opts.GasPrice == nil && opts.MaxFeePerGas.Cmp(opts.MaxPriorityFeePerGas) < 0 {
+		return nil, errors.New("fixed fee estimator: max fee must not be lower than priority fee")
+	}
+	return &FixedFeeEstimator{
+		gasPrice:             opts.GasPrice,
+		maxFeePerGas:         opts.MaxFeePerGas,
+		maxPriorityFeePerGas: opts.MaxPriorityFeePerGas,
+	}, nil
+}
+
+// Modify implements the rpc.TXModifier interface.
+func (e *FixedFeeEstimator) Modify(ctx context.Context, _ rpc.RPC, tx *types.Transaction) error {
+	var reason string
+	if e.gasPrice != nil {
+		if tx.GasPrice != nil {
+			return nil
+		}
+		tx.GasPrice = new(big.Int).Set(e.gasPrice)
+		tx.MaxFeePerGas = nil
+		tx.MaxPriorityFeePerGas = nil
+		tx.Type = types.LegacyTxType
+		if tx.AccessList != nil {
+			tx.Type = types.AccessListTxType
+		}
+		reason = fmt.Sprintf("fixed gas price %s wei", e.gasPrice)
+	} else {
+		if tx.MaxFeePerGas != nil && tx.MaxPriorityFeePerGas != nil {
+			return nil
+		}
+		tx.GasPrice = nil
+		tx.MaxFeePerGas = new(big.Int).Set(e.maxFeePerGas)
+		tx.MaxPriorityFeePerGas = new(big.Int).Set(e.maxPriorityFeePerGas)
+		tx.Type = types.DynamicFeeTxType
+		reason = fmt.Sprintf("fixed max fee %s wei and priority fee %s wei", e.maxFeePerGas, e.maxPriorityFeePerGas)
+	}
+	if fe := feeEstimateFromContext(ctx); fe != nil {
+		fe.Estimator = "fixed"
+		fe.Reason = reason
+	}
+	return nil
+}
+
+// L2FeeEstimator is a transaction modifier that accounts for the L1 data fee
+// paid by transactions on L2 chains. It uses another estimator for the L2
+// execution fees and then estimates the L1 data fee:
+//
+//   - On OP-stack chains, the L1 fee is charged in addition to the L2 fee,
+//     and it is estimated using the GasPriceOracle predeploy.
+//   - On Arbitrum chains, the L1 fee is paid as part of the L2 gas, and it
+//     is estimated using the NodeInterface virtual contract.
+//
+// If the total fee, that is the gas limit multiplied by the fee per gas plus
+// the L1 fee charged separately, exceeds the maximum total fee, the
+// transaction is rejected.
+//
+// Because the transaction is not signed yet, the L1 fee is estimated using
+// the transaction input only, so it is slightly underestimated.
+type L2FeeEstimator struct {
+	chain       string
+	estimator   rpc.TXModifier
+	maxTotalFee *big.Int
+}
+
+// L2FeeEstimatorOptions is the options for NewL2FeeEstimator.
+type L2FeeEstimatorOptions struct {
+	// Chain is the type of the L2 chain, either L2ChainOPStack or
+	// L2ChainArbitrum.
+	Chain string
+
+	// Estimator estimates the L2 execution fees.
+	Estimator rpc.TXModifier
+
+	// MaxTotalFee is the maximum total fee, in wei, including the L1 fee,
+	// or nil if there is no upper bound.
+	MaxTotalFee *big.Int
+}
+
+// NewL2FeeEstimator returns a new L2FeeEstimator.
+func NewL2FeeEstimator(opts L2FeeEstimatorOptions) (*L2FeeEstimator, error) {
+	if opts.Chain != L2ChainOPStack && opts.Chain != L2ChainArbitrum {
+		return nil, fmt.Errorf("L2 fee estimator: unsupported chain %q", opts.Chain)
+	}
+	if opts.Estimator == nil {
+		return nil, errors.New("L2 fee estimator: estimator must not be nil")
+	}
+	return &L2FeeEstimator{
+		chain:       opts.Chain,
+		estimator:   opts.Estimator,
+		maxTotalFee: opts.MaxTotalFee,
+	}, nil
+}
+
+// Modify implements the rpc.TXModifier interface.
+func (e *L2FeeEstimator) Modify(ctx context.Context, client rpc.RPC, tx *types.Transaction) error {
+	if err := e.estimator.Modify(ctx, client, tx); err != nil {
+		return err
+	}
+	var (
+		l1Fee    *big.Int
+		l1Gas    uint64
+		totalFee = new(big.Int)
+		err      error
+	)
+	switch e.chain {
+	case L2ChainOPStack:
+		l1Fee, err = e.opL1Fee(ctx, client, tx)
+	case L2ChainArbitrum:
+		l1Fee, l1Gas, err = e.arbL1Fee(ctx, client, tx)
+	}
+	if err != nil {
+		return err
+	}
+	if tx.GasLimit != nil {
+		feePerGas := tx.GasPrice
+		if feePerGas == nil {
+			feePerGas = tx.MaxFeePerGas
+		}
+		if feePerGas != nil {
+			totalFee.Mul(new(big.Int).SetUint64(*tx.GasLimit), feePerGas)
+		}
+	}
+	if e.chain == L2ChainOPStack {
+		// On Arbitrum, the L1 fee is already included in the gas limit.
+		totalFee.Add(totalFee, l1Fee)
+	}
+	if e.maxTotalFee != nil && totalFee.Cmp(e.maxTotalFee) > 0 {
+		return fmt.Errorf(
+			"L2 fee estimator: total fee %s wei, including L1 fee %s wei, exceeds the limit of %s wei",
+			totalFee, l1Fee, e.maxTotalFee,
+		)
+	}
+	if fe := feeEstimateFromContext(ctx); fe != nil {
+		fe.L1Fee = l1Fee
+		switch e.chain {
+		case L2ChainOPStack:
+			fe.Reason += fmt.Sprintf(", L1 data fee %s wei, total fee up to %s wei", l1Fee, totalFee)
+		case L2ChainArbitrum:
+			fe.Reason += fmt.Sprintf(", L1 data fee %s wei (%d gas), total fee up to %s wei", l1Fee, l1Gas, totalFee)
+		}
+	}
+	return nil
+}
+
+// opL1Fee returns the L1 data fee on OP-stack chains.
+func (e *L2FeeEstimator) opL1Fee(ctx context.Context, client rpc.RPC, tx *types.Transaction) (*big.Int, error) {
+	input, err := opGetL1Fee.EncodeArgs(tx.Input)
+	if err != nil {
+		return nil, fmt.Errorf("L2 fee estimator: %w", err)
+	}
+	res, _, err := client.Call(ctx, types.Call{To: &opGasPriceOracle, Input: input}, types.LatestBlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("L2 fee estimator: getL1Fee call failed: %w", err)
+	}
+	var fee *big.Int
+	if err := opGetL1Fee.DecodeValues(res, &fee); err != nil {
+		return nil, fmt.Errorf("L2 fee estimator: %w", err)
+	}
+	return fee, nil
+}
+
+// arbL1Fee returns the L1 data fee and the amount of L2 gas used to pay
+// for it on Arbitrum chains.
+func (e *L2FeeEstimator) arbL1Fee(ctx context.Context, client rpc.RPC, tx *types.Transaction) (*big.Int, uint64, error) {
+	var to types.Address
+	if tx.To != nil {
+		to = *tx.To
+	}
+	input, err := arbGasEstimateL1Component.EncodeArgs(to, tx.To == nil, tx.Input)
+	if err != nil {
+		return nil, 0, fmt.Errorf("L2 fee estimator: %w", err)
+	}
+	res, _, err := client.Call(ctx, types.Call{To: &arbNodeInterface, Input: input}, types.LatestBlockNumber)
+	if err != nil {
+		return nil, 0, fmt.Errorf("L2 fee estimator: gasEstimateL1Component call failed: %w", err)
+	}
+	var (
+		gas               uint64
+		baseFee           *big.Int
+		l1BaseFeeEstimate *big.Int
+	)
+	if err := arbGasEstimateL1Component.DecodeValues(res, &gas, &baseFee, &l1BaseFeeEstimate); err != nil {
+		return nil, 0, fmt.Errorf("L2 fee estimator: %w", err)
+	}
+	return new(big.Int).Mul(new(big.Int).SetUint64(gas), baseFee), gas, nil
+}
+
+// median returns the median of the given values. If there are no values,
+// zero is returned.
+func median(vs []*big.Int) *big.Int {
+	if len(vs) == 0 {
+		return new(big.Int)
+	}
+	s := make([]*big.Int, len(vs))
+	copy(s, vs)
+	sort.Slice(s, func(i, j int) bool { return s[i].Cmp(s[j]) < 0 })
+	if len(s)%2 == 1 {
+		return new(big.Int).Set(s[len(s)/2])
+	}
+	m := new(big.Int).Add(s[len(s)/2-1], s[len(s)/2])
+	return m.Rsh(m, 1)
+}
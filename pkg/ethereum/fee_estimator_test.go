@@ -0,0 +1,197 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/ethereum/mocks"
+)
+
+// feeHistoryTransport returns the same fee history for every call.
+type feeHistoryTransport string
+
+func (t feeHistoryTransport) Call(_ context.Context, result any, method string, _ ...any) error {
+	if method != "eth_feeHistory" {
+		panic("unexpected method " + method)
+	}
+	return json.Unmarshal([]byte(t), result)
+}
+
+func TestFeeHistoryEstimator(t *testing.T) {
+	history := feeHistoryTransport(`{
+		"oldestBlock": "0x1",
+		"baseFeePerGas": ["0x64", "0x64", "0x64", "0xc8"],
+		"gasUsedRatio": [0.5, 0.5, 0.5],
+		"reward": [["0xa"], ["0x14"], ["0x1e"]]
+	}`)
+
+	t.Run("estimate", func(t *testing.T) {
+		e, err := NewFeeHistoryEstimator(FeeHistoryEstimatorOptions{Transport: history})
+		require.NoError(t, err)
+
+		ctx, fe := WithFeeEstimate(context.Background())
+		tx := types.NewTransaction()
+		require.NoError(t, e.Modify(ctx, nil, tx))
+		assert.Equal(t, types.DynamicFeeTxType, tx.Type)
+		assert.Nil(t, tx.GasPrice)
+		assert.Equal(t, big.NewInt(420), tx.MaxFeePerGas) // 200 * 2 + 20
+		assert.Equal(t, big.NewInt(20), tx.MaxPriorityFeePerGas)
+		assert.Equal(t, "fee_history", fe.Estimator)
+		assert.Contains(t, fe.Reason, "next base fee 200 wei")
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		e, err := NewFeeHistoryEstimator(FeeHistoryEstimatorOptions{
+			Transport:            history,
+			BaseFeeMultiplier:    1,
+			MaxFeePerGas:         big.NewInt(210),
+			MaxPriorityFeePerGas: big.NewInt(15),
+		})
+		require.NoError(t, err)
+
+		ctx, fe := WithFeeEstimate(context.Background())
+		tx := types.NewTransaction()
+		require.NoError(t, e.Modify(ctx, nil, tx))
+		assert.Equal(t, big.NewInt(210), tx.MaxFeePerGas)
+		assert.Equal(t, big.NewInt(15), tx.MaxPriorityFeePerGas)
+		assert.Contains(t, fe.Reason, "capped by max priority fee")
+		assert.Contains(t, fe.Reason, "capped by max gas fee")
+	})
+
+	t.Run("invalid percentile", func(t *testing.T) {
+		_, err := NewFeeHistoryEstimator(FeeHistoryEstimatorOptions{Transport: history, Percentile: 101})
+		assert.Error(t, err)
+	})
+}
+
+func TestFixedFeeEstimator(t *testing.T) {
+	t.Run("legacy", func(t *testing.T) {
+		e, err := NewFixedFeeEstimator(FixedFeeEstimatorOptions{GasPrice: big.NewInt(100)})
+		require.NoError(t, err)
+
+		ctx, fe := WithFeeEstimate(context.Background())
+		tx := types.NewTransaction()
+		require.NoError(t, e.Modify(ctx, nil, tx))
+		assert.Equal(t, types.LegacyTxType, tx.Type)
+		assert.Equal(t, big.NewInt(100), tx.GasPrice)
+		assert.Equal(t, "fixed", fe.Estimator)
+	})
+
+	t.Run("eip1559", func(t *testing.T) {
+		e, err := NewFixedFeeEstimator(FixedFeeEstimatorOptions{
+			MaxFeePerGas:         big.NewInt(100),
+			MaxPriorityFeePerGas: big.NewInt(10),
+		})
+		require.NoError(t, err)
+
+		tx := types.NewTransaction()
+		require.NoError(t, e.Modify(context.Background(), nil, tx))
+		assert.Equal(t, types.DynamicFeeTxType, tx.Type)
+		assert.Equal(t, big.NewInt(100), tx.MaxFeePerGas)
+		assert.Equal(t, big.NewInt(10), tx.MaxPriorityFeePerGas)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewFixedFeeEstimator(FixedFeeEstimatorOptions{MaxFeePerGas: big.NewInt(100)})
+		assert.Error(t, err)
+		_, err = NewFixedFeeEstimator(FixedFeeEstimatorOptions{
+			MaxFeePerGas:         big.NewInt(10),
+			MaxPriorityFeePerGas: big.NewInt(100),
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestL2FeeEstimator(t *testing.T) {
+	fixed, err := NewFixedFeeEstimator(FixedFeeEstimatorOptions{GasPrice: big.NewInt(10)})
+	require.NoError(t, err)
+
+	t.Run("op stack", func(t *testing.T) {
+		client := new(mocks.RPC)
+		e, err := NewL2FeeEstimator(L2FeeEstimatorOptions{
+			Chain:       L2ChainOPStack,
+			Estimator:   fixed,
+			MaxTotalFee: big.NewInt(2000),
+		})
+		require.NoError(t, err)
+
+		client.On("Call", mock.Anything, mock.MatchedBy(func(call types.Call) bool {
+			return *call.To == opGasPriceOracle
+		}), types.LatestBlockNumber).Return(
+			types.MustHashFromBigInt(big.NewInt(500)).Bytes(),
+			&types.Call{},
+			nil,
+		)
+
+		ctx, fe := WithFeeEstimate(context.Background())
+		tx := types.NewTransaction().SetGasLimit(100).SetInput([]byte{1, 2, 3})
+		require.NoError(t, e.Modify(ctx, client, tx))
+		assert.Equal(t, "fixed", fe.Estimator)
+		assert.Equal(t, big.NewInt(500), fe.L1Fee)
+		assert.Contains(t, fe.Reason, "L1 data fee 500 wei, total fee up to 1500 wei")
+
+		// Total fee exceeds the limit.
+		tx = types.NewTransaction().SetGasLimit(200).SetInput([]byte{1, 2, 3})
+		assert.Error(t, e.Modify(context.Background(), client, tx))
+	})
+
+	t.Run("arbitrum", func(t *testing.T) {
+		client := new(mocks.RPC)
+		e, err := NewL2FeeEstimator(L2FeeEstimatorOptions{
+			Chain:     L2ChainArbitrum,
+			Estimator: fixed,
+		})
+		require.NoError(t, err)
+
+		res, err := abi.EncodeValues(arbGasEstimateL1Component.Outputs(), uint64(30), big.NewInt(10), big.NewInt(50))
+		require.NoError(t, err)
+		client.On("Call", mock.Anything, mock.MatchedBy(func(call types.Call) bool {
+			return *call.To == arbNodeInterface
+		}), types.LatestBlockNumber).Return(res, &types.Call{}, nil)
+
+		ctx, fe := WithFeeEstimate(context.Background())
+		tx := types.NewTransaction().SetGasLimit(100).SetTo(types.ZeroAddress)
+		require.NoError(t, e.Modify(ctx, client, tx))
+		assert.Equal(t, big.NewInt(300), fe.L1Fee)
+		assert.Contains(t, fe.Reason, "L1 data fee 300 wei (30 gas), total fee up to 1000 wei")
+	})
+
+	t.Run("unsupported chain", func(t *testing.T) {
+		_, err := NewL2FeeEstimator(L2FeeEstimatorOptions{Chain: "foo", Estimator: fixed})
+		assert.Error(t, err)
+	})
+}
+
+func TestNamedFeeEstimator(t *testing.T) {
+	fixed, err := NewFixedFeeEstimator(FixedFeeEstimatorOptions{GasPrice: big.NewInt(10)})
+	require.NoError(t, err)
+
+	ctx, fe := WithFeeEstimate(context.Background())
+	e := NewNamedFeeEstimator("multiplier", "gas price multiplied by 1.5", fixed)
+	require.NoError(t, e.Modify(ctx, nil, types.NewTransaction()))
+	assert.Equal(t, "multiplier", fe.Estimator)
+	assert.Equal(t, "gas price multiplied by 1.5", fe.Reason)
+}
@@ -0,0 +1,113 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mocks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+)
+
+// RemoteSigner is a fake external signing service that signs messages and
+// transactions with a local key using the eth_sign and eth_signTransaction
+// JSON-RPC methods. It is intended to be used with httptest.Server.
+type RemoteSigner struct {
+	Key wallet.Key
+}
+
+// NewRemoteSigner returns a new RemoteSigner for the given key.
+func NewRemoteSigner(key wallet.Key) *RemoteSigner {
+	return &RemoteSigner{Key: key}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s *RemoteSigner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage   `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+	result, err := s.call(req.Method, req.Params)
+	if err != nil {
+		res["error"] = map[string]any{"code": -32000, "message": err.Error()}
+	} else {
+		res["result"] = result
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *RemoteSigner) call(method string, params []json.RawMessage) (any, error) {
+	switch method {
+	case "eth_sign":
+		var (
+			addr types.Address
+			data types.Bytes
+		)
+		if len(params) != 2 {
+			return nil, fmt.Errorf("invalid number of params")
+		}
+		if err := json.Unmarshal(params[0], &addr); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(params[1], &data); err != nil {
+			return nil, err
+		}
+		if addr != s.Key.Address() {
+			return nil, fmt.Errorf("unknown account %s", addr)
+		}
+		return s.Key.SignMessage(data)
+	case "eth_signTransaction":
+		if len(params) != 1 {
+			return nil, fmt.Errorf("invalid number of params")
+		}
+		tx := new(types.Transaction)
+		if err := json.Unmarshal(params[0], tx); err != nil {
+			return nil, err
+		}
+		var fields struct {
+			ChainID *types.Number `json:"chainId"`
+			Type    *types.Number `json:"type"`
+		}
+		if err := json.Unmarshal(params[0], &fields); err != nil {
+			return nil, err
+		}
+		if fields.ChainID != nil {
+			tx.SetChainID(fields.ChainID.Big().Uint64())
+		}
+		if fields.Type != nil {
+			tx.SetType(types.TransactionType(fields.Type.Big().Uint64()))
+		}
+		if err := s.Key.SignTransaction(tx); err != nil {
+			return nil, err
+		}
+		raw, err := tx.Raw()
+		if err != nil {
+			return nil, err
+		}
+		return types.Bytes(raw), nil
+	default:
+		return nil, fmt.Errorf("method %s not supported", method)
+	}
+}
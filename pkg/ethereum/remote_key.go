@@ -0,0 +1,205 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ethereum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/rpc/transport"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+)
+
+const defaultRemoteKeyTimeout = 10 * time.Second
+
+// RemoteKey is a wallet.Key that delegates signing to an external signing
+// service over JSON-RPC, using the eth_sign and eth_signTransaction methods
+// supported by Clef and Web3Signer. The private key never leaves the
+// signing service.
+//
+// Because the eth_sign method always signs the message with the Ethereum
+// message prefix, signing raw hashes is not supported.
+type RemoteKey struct {
+	address   types.Address
+	transport transport.Transport
+	timeout   time.Duration
+}
+
+// RemoteKeyOptions is the configuration for the RemoteKey.
+type RemoteKeyOptions struct {
+	// URL is the JSON-RPC endpoint of the signing service.
+	URL string
+
+	// Address is the address of the key managed by the signing service.
+	Address types.Address
+
+	// Timeout is the timeout for a single signing request. If zero, the
+	// default timeout of 10 seconds is used.
+	Timeout time.Duration
+
+	// HTTPClient is the HTTP client used to connect to the signing service.
+	// If nil, a new client is created.
+	HTTPClient *http.Client
+}
+
+// NewRemoteKey returns a new RemoteKey.
+func NewRemoteKey(opts RemoteKeyOptions) (*RemoteKey, error) {
+	if opts.Address == types.ZeroAddress {
+		return nil, errors.New("remote key: address must not be empty")
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultRemoteKeyTimeout
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{}
+	}
+	t, err := transport.NewHTTP(transport.HTTPOptions{
+		URL:        opts.URL,
+		HTTPClient: opts.HTTPClient,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("remote key: %w", err)
+	}
+	return &RemoteKey{
+		address:   opts.Address,
+		transport: t,
+		timeout:   opts.Timeout,
+	}, nil
+}
+
+// Address implements the wallet.Key interface.
+func (k *RemoteKey) Address() types.Address {
+	return k.address
+}
+
+// SignHash implements the wallet.Key interface.
+//
+// Signing raw hashes is not supported by the remote signer, so this method
+// always returns an error.
+func (k *RemoteKey) SignHash(_ types.Hash) (*types.Signature, error) {
+	return nil, errors.New("remote key: signing hashes is not supported")
+}
+
+// SignMessage implements the wallet.Key interface.
+func (k *RemoteKey) SignMessage(data []byte) (*types.Signature, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), k.timeout)
+	defer cancel()
+	var sig types.Signature
+	if err := k.transport.Call(ctx, &sig, "eth_sign", k.address, types.Bytes(data)); err != nil {
+		return nil, fmt.Errorf("remote key: eth_sign failed: %w", err)
+	}
+	if !k.VerifyMessage(data, sig) {
+		return nil, errors.New("remote key: eth_sign returned a signature of a different key")
+	}
+	return &sig, nil
+}
+
+// SignTransaction implements the wallet.Key interface.
+func (k *RemoteKey) SignTransaction(tx *types.Transaction) error {
+	if tx.From != nil && *tx.From != k.address {
+		return fmt.Errorf("remote key: invalid signer address: %s", tx.From)
+	}
+	txCpy := tx.Copy()
+	txCpy.From = &k.address
+	ctx, cancel := context.WithTimeout(context.Background(), k.timeout)
+	defer cancel()
+	var res signTransactionResult
+	if err := k.transport.Call(ctx, &res, "eth_signTransaction", remoteTransaction{txCpy}); err != nil {
+		return fmt.Errorf("remote key: eth_signTransaction failed: %w", err)
+	}
+	signed := new(types.Transaction)
+	if _, err := signed.DecodeRLP(res.Raw); err != nil {
+		return fmt.Errorf("remote key: unable to decode signed transaction: %w", err)
+	}
+	if signed.Signature == nil {
+		return errors.New("remote key: eth_signTransaction returned an unsigned transaction")
+	}
+
+	// Verify that the signature is valid for the original transaction, so
+	// the signing service cannot change any of the transaction fields.
+	txCpy.Signature = signed.Signature
+	from, err := crypto.ECRecoverer.RecoverTransaction(txCpy)
+	if err != nil || *from != k.address {
+		return errors.New("remote key: eth_signTransaction returned an invalid signature")
+	}
+	tx.From = txCpy.From
+	tx.Signature = txCpy.Signature
+	return nil
+}
+
+// VerifyHash implements the wallet.Key interface.
+func (k *RemoteKey) VerifyHash(hash types.Hash, sig types.Signature) bool {
+	addr, err := crypto.ECRecoverer.RecoverHash(hash, sig)
+	if err != nil {
+		return false
+	}
+	return *addr == k.address
+}
+
+// VerifyMessage implements the wallet.Key interface.
+func (k *RemoteKey) VerifyMessage(data []byte, sig types.Signature) bool {
+	addr, err := crypto.ECRecoverer.RecoverMessage(data, sig)
+	if err != nil {
+		return false
+	}
+	return *addr == k.address
+}
+
+// remoteTransaction is a transaction encoded for the eth_signTransaction
+// method. Unlike types.Transaction, it includes the chain ID and the
+// transaction type.
+type remoteTransaction struct {
+	*types.Transaction
+}
+
+func (t remoteTransaction) MarshalJSON() ([]byte, error) {
+	b, err := t.Transaction.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]any)
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	if t.ChainID != nil {
+		fields["chainId"] = types.NumberFromUint64(*t.ChainID)
+	}
+	fields["type"] = types.NumberFromUint64(uint64(t.Type))
+	return json.Marshal(fields)
+}
+
+// signTransactionResult is the result of the eth_signTransaction method.
+// Web3Signer returns the signed transaction as raw bytes, while Clef returns
+// an object with the raw bytes and the decoded transaction.
+type signTransactionResult struct {
+	Raw types.Bytes `json:"raw"`
+}
+
+func (r *signTransactionResult) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '{' {
+		type result signTransactionResult
+		return json.Unmarshal(b, (*result)(r))
+	}
+	return json.Unmarshal(b, &r.Raw)
+}
+
+var _ wallet.Key = (*RemoteKey)(nil)
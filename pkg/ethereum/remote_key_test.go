@@ -0,0 +1,93 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ethereum
+
+import (
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/ethereum/mocks"
+)
+
+func TestRemoteKey(t *testing.T) {
+	local := wallet.NewRandomKey()
+	srv := httptest.NewServer(mocks.NewRemoteSigner(local))
+	defer srv.Close()
+
+	key, err := NewRemoteKey(RemoteKeyOptions{URL: srv.URL, Address: local.Address()})
+	require.NoError(t, err)
+	assert.Equal(t, local.Address(), key.Address())
+
+	t.Run("sign message", func(t *testing.T) {
+		sig, err := key.SignMessage([]byte("foo"))
+		require.NoError(t, err)
+		assert.True(t, local.VerifyMessage([]byte("foo"), *sig))
+		assert.True(t, key.VerifyMessage([]byte("foo"), *sig))
+		assert.False(t, key.VerifyMessage([]byte("bar"), *sig))
+	})
+
+	t.Run("sign hash", func(t *testing.T) {
+		_, err := key.SignHash(types.Hash{})
+		assert.Error(t, err)
+	})
+
+	txs := map[string]*types.Transaction{
+		"legacy": types.NewTransaction().
+			SetType(types.LegacyTxType).
+			SetChainID(1).
+			SetNonce(1).
+			SetTo(types.MustAddressFromHex("0x1111111111111111111111111111111111111111")).
+			SetGasLimit(21000).
+			SetGasPrice(big.NewInt(1e9)),
+		"dynamic fee": types.NewTransaction().
+			SetType(types.DynamicFeeTxType).
+			SetChainID(1).
+			SetNonce(2).
+			SetTo(types.MustAddressFromHex("0x1111111111111111111111111111111111111111")).
+			SetGasLimit(21000).
+			SetMaxFeePerGas(big.NewInt(2e9)).
+			SetMaxPriorityFeePerGas(big.NewInt(1e9)).
+			SetInput([]byte{1, 2, 3}),
+	}
+	for name, tx := range txs {
+		t.Run("sign transaction "+name, func(t *testing.T) {
+			require.NoError(t, key.SignTransaction(tx))
+			require.NotNil(t, tx.Signature)
+			from, err := crypto.ECRecoverer.RecoverTransaction(tx)
+			require.NoError(t, err)
+			assert.Equal(t, local.Address(), *from)
+		})
+	}
+}
+
+func TestRemoteKey_DifferentKey(t *testing.T) {
+	srv := httptest.NewServer(mocks.NewRemoteSigner(wallet.NewRandomKey()))
+	defer srv.Close()
+
+	// The signing service does not manage the key with the given address.
+	key, err := NewRemoteKey(RemoteKeyOptions{URL: srv.URL, Address: wallet.NewRandomKey().Address()})
+	require.NoError(t, err)
+	_, err = key.SignMessage([]byte("foo"))
+	assert.Error(t, err)
+	assert.Error(t, key.SignTransaction(types.NewTransaction().SetChainID(1).SetNonce(1)))
+}
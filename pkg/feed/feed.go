@@ -19,10 +19,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math/rand"
+	"time"
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
+	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/value"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/metrics"
 	"github.com/chronicleprotocol/oracle-suite/pkg/transport/messages"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/bn"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/sliceutil"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
 
@@ -42,9 +48,24 @@ type Feed struct {
 	dataProvider datapoint.Provider
 	dataModels   []string
 	signers      []datapoint.Signer
-	hooks        []Hook
 	transport    transport.Service
-	interval     *timeutil.Ticker
+	groups       []*group
+	hooks        map[string][]Hook // Hooks indexed by data model.
+	dueCh        chan int          // Indices of groups that are due.
+	coalesce     time.Duration
+
+	triggers        map[string]Trigger
+	triggerModels   []string
+	triggerInterval *timeutil.Ticker
+	last            map[string]lastBroadcast // Used only by broadcasterRoutine.
+}
+
+// group is a group of data models broadcast on the same interval.
+type group struct {
+	name     string
+	models   []string // Data models without triggers.
+	interval *timeutil.Ticker
+	jitter   time.Duration
 }
 
 // Config is the configuration for the Feed.
@@ -72,11 +93,80 @@ type Config struct {
 	// Interval describes how often data points should be sent to the network.
 	Interval *timeutil.Ticker
 
+	// Jitter is the maximum random delay added to every Interval tick.
+	// Optional.
+	Jitter time.Duration
+
+	// Groups is an optional list of additional groups of data models, each
+	// broadcast on its own interval and using its own hooks. Data models
+	// listed in groups must not be listed in DataModels or other groups.
+	//
+	// If groups are due within the CoalesceWindow, their data points are
+	// fetched in a single update cycle.
+	Groups []Group
+
+	// CoalesceWindow is the time to wait for other groups after a group is
+	// due, so groups due on the same tick are fetched in a single update
+	// cycle. If zero, defaultCoalesceWindow is used.
+	CoalesceWindow time.Duration
+
+	// Triggers enables event-driven publishing for data models listed in
+	// the map. These data models are evaluated on every TriggerInterval tick,
+	// but they are broadcast only if their value has moved past the trigger
+	// deviation from the last broadcast value, or the trigger heartbeat has
+	// elapsed. Other data models are broadcast on every Interval tick.
+	Triggers map[string]Trigger
+
+	// TriggerInterval describes how often data models with triggers are
+	// evaluated. Required if Triggers is not empty.
+	TriggerInterval *timeutil.Ticker
+
 	// Logger is a current logger interface used by the Feed.
 	// If nil, null logger will be used.
 	Logger log.Logger
 }
 
+// Group is a group of data models broadcast on the same interval.
+type Group struct {
+	// Name is the name of the group, used in logs.
+	Name string
+
+	// DataModels is a list of data models in the group.
+	DataModels []string
+
+	// Interval describes how often data points in the group should be sent
+	// to the network.
+	Interval *timeutil.Ticker
+
+	// Jitter is the maximum random delay added to every Interval tick.
+	// Optional.
+	Jitter time.Duration
+
+	// Hooks is a list of hooks that will be called before broadcasting
+	// data points in the group.
+	Hooks []Hook
+}
+
+// defaultGroupName is the name of the group made of the data models listed
+// directly in the Config.
+const defaultGroupName = "default"
+
+// defaultCoalesceWindow is the default value of Config.CoalesceWindow.
+const defaultCoalesceWindow = 250 * time.Millisecond
+
+// Trigger describes when a data model evaluated by the Feed should be
+// broadcast.
+type Trigger struct {
+	// Deviation is the minimum deviation from the last broadcast value, as
+	// a fraction (e.g. 0.005 for 0.5%), that triggers a broadcast. Zero
+	// disables the deviation trigger.
+	Deviation float64
+
+	// Heartbeat is the maximum time between broadcasts. Zero disables the
+	// heartbeat trigger.
+	Heartbeat time.Duration
+}
+
 type Hook interface {
 	BeforeSign(ctx context.Context, dp *datapoint.Point) error
 	BeforeBroadcast(ctx context.Context, dp *datapoint.Point) error
@@ -84,7 +174,7 @@ type Hook interface {
 
 // New creates a new instance of the Feed.
 func New(cfg Config) (*Feed, error) {
-	if cfg.DataModels == nil {
+	if cfg.DataModels == nil && len(cfg.Groups) == 0 {
 		return nil, errors.New("data models must not be nil")
 	}
 	if cfg.DataProvider == nil {
@@ -93,12 +183,18 @@ func New(cfg Config) (*Feed, error) {
 	if cfg.Transport == nil {
 		return nil, errors.New("transport must not be nil")
 	}
-	if len(cfg.DataModels) == 0 {
-		return nil, errors.New("at least one data model must be provided")
-	}
 	if len(cfg.Signers) == 0 {
 		return nil, errors.New("at least one signer must be provided")
 	}
+	if len(cfg.Triggers) > 0 && cfg.TriggerInterval == nil {
+		return nil, errors.New("trigger interval must be provided if triggers are used")
+	}
+	if cfg.CoalesceWindow < 0 {
+		return nil, errors.New("coalesce window must not be negative")
+	}
+	if cfg.CoalesceWindow == 0 {
+		cfg.CoalesceWindow = defaultCoalesceWindow
+	}
 	if cfg.Logger == nil {
 		cfg.Logger = null.New()
 	}
@@ -106,11 +202,53 @@ func New(cfg Config) (*Feed, error) {
 		waitCh:       make(chan error),
 		log:          cfg.Logger.WithField("tag", LoggerTag),
 		dataProvider: cfg.DataProvider,
-		dataModels:   cfg.DataModels,
 		signers:      cfg.Signers,
-		hooks:        cfg.Hooks,
 		transport:    cfg.Transport,
-		interval:     cfg.Interval,
+		hooks:        make(map[string][]Hook),
+		dueCh:        make(chan int),
+		coalesce:     cfg.CoalesceWindow,
+
+		triggers:        cfg.Triggers,
+		triggerInterval: cfg.TriggerInterval,
+		last:            make(map[string]lastBroadcast),
+	}
+	groups := append([]Group{{
+		Name:       defaultGroupName,
+		DataModels: cfg.DataModels,
+		Interval:   cfg.Interval,
+		Jitter:     cfg.Jitter,
+		Hooks:      cfg.Hooks,
+	}}, cfg.Groups...)
+	for _, g := range groups {
+		if len(g.DataModels) == 0 && g.Name == defaultGroupName {
+			continue
+		}
+		if len(g.DataModels) == 0 {
+			return nil, fmt.Errorf("group %s: at least one data model must be provided", g.Name)
+		}
+		if g.Interval == nil {
+			return nil, fmt.Errorf("group %s: interval must not be nil", g.Name)
+		}
+		if g.Jitter < 0 {
+			return nil, fmt.Errorf("group %s: jitter must not be negative", g.Name)
+		}
+		fg := &group{name: g.Name, interval: g.Interval, jitter: g.Jitter}
+		for _, model := range g.DataModels {
+			if _, ok := f.hooks[model]; ok {
+				return nil, fmt.Errorf("group %s: data model %s is already used", g.Name, model)
+			}
+			f.hooks[model] = g.Hooks
+			f.dataModels = append(f.dataModels, model)
+			if _, ok := f.triggers[model]; ok {
+				f.triggerModels = append(f.triggerModels, model)
+			} else {
+				fg.models = append(fg.models, model)
+			}
+		}
+		f.groups = append(f.groups, fg)
+	}
+	if len(f.dataModels) == 0 {
+		return nil, errors.New("at least one data model must be provided")
 	}
 	return f, nil
 }
@@ -125,12 +263,23 @@ func (f *Feed) Start(ctx context.Context) error {
 	}
 	f.ctx = ctx
 	f.log.
-		WithFields(log.Fields{
-			"dataModels": f.dataModels,
-			"interval":   f.interval.Duration(),
-		}).
+		WithField("dataModels", f.dataModels).
 		Debug("Starting")
-	f.interval.Start(f.ctx)
+	for i, g := range f.groups {
+		f.log.
+			WithFields(log.Fields{
+				"group":      g.name,
+				"dataModels": g.models,
+				"interval":   g.interval.Duration(),
+				"jitter":     g.jitter,
+			}).
+			Debug("Starting group")
+		g.interval.Start(f.ctx)
+		go f.schedulerRoutine(i)
+	}
+	if f.triggerInterval != nil {
+		f.triggerInterval.Start(f.ctx)
+	}
 	go f.broadcasterRoutine()
 	go f.contextCancelHandler()
 	return nil
@@ -141,9 +290,12 @@ func (f *Feed) Wait() <-chan error {
 	return f.waitCh
 }
 
-// broadcast sends data point to the network.
-func (f *Feed) broadcast(model string, point datapoint.Point) {
+// broadcast sends data point to the network. It returns true if the data
+// point was broadcast using at least one signer.
+func (f *Feed) broadcast(model string, point datapoint.Point) bool {
+	hooks := f.hooks[model]
 	found := false
+	sent := false
 	for _, signer := range f.signers {
 		if !signer.Supports(f.ctx, point) {
 			continue
@@ -151,15 +303,28 @@ func (f *Feed) broadcast(model string, point datapoint.Point) {
 		found = true
 
 		// BeforeSign hook.
-		for _, hook := range f.hooks {
+		for _, hook := range hooks {
 			if err := hook.BeforeSign(f.ctx, &point); err != nil {
+				if errors.Is(err, ErrDataPointRejected) {
+					trace, _ := json.Marshal(point)
+					f.log.
+						WithError(err).
+						WithField("model", model).
+						WithField("trace", string(trace)).
+						WithFields(datapoint.PointLogFields(point)).
+						WithAdvice("Check the data model and its origins, the data point may be invalid").
+						Warn("Data point rejected by a hook; data point will not be broadcasted")
+					metrics.FeedDataPointsSkipped.WithLabelValues(model).Inc()
+					return false
+				}
 				f.log.
 					WithError(err).
 					WithField("model", model).
 					WithFields(datapoint.PointLogFields(point)).
 					WithAdvice("This is a bug and must be investigated").
 					Error("BeforeSign hook failed; data point will not be broadcasted")
-				return
+				metrics.FeedDataPointsSkipped.WithLabelValues(model).Inc()
+				return false
 			}
 		}
 
@@ -172,10 +337,12 @@ func (f *Feed) broadcast(model string, point datapoint.Point) {
 				WithFields(datapoint.PointLogFields(point)).
 				WithAdvice("This is a bug and must be investigated").
 				Error("Failed to sign the data point; data point will not be broadcasted")
+			metrics.FeedDataPointsSkipped.WithLabelValues(model).Inc()
+			return false
 		}
 
 		// BeforeBroadcast hook.
-		for _, hook := range f.hooks {
+		for _, hook := range hooks {
 			if err := hook.BeforeBroadcast(f.ctx, &point); err != nil {
 				f.log.
 					WithError(err).
@@ -183,7 +350,8 @@ func (f *Feed) broadcast(model string, point datapoint.Point) {
 					WithFields(datapoint.PointLogFields(point)).
 					WithAdvice("This is a bug and must be investigated").
 					Error("BeforeBroadcast hook failed; data point will not be broadcasted")
-				return
+				metrics.FeedDataPointsSkipped.WithLabelValues(model).Inc()
+				return false
 			}
 		}
 
@@ -199,7 +367,10 @@ func (f *Feed) broadcast(model string, point datapoint.Point) {
 				WithFields(messages.DataPointMessageLogFields(*msg)).
 				WithAdvice("Ignore if it is related to temporary network issues").
 				Error("Failed to broadcast the data point")
+			metrics.FeedDataPointsSkipped.WithLabelValues(model).Inc()
 		} else {
+			sent = true
+			metrics.FeedDataPointsBroadcast.WithLabelValues(model).Inc()
 			f.log.
 				WithFields(messages.DataPointMessageLogFields(*msg)).
 				Info("Data point successfully broadcasted")
@@ -212,54 +383,161 @@ func (f *Feed) broadcast(model string, point datapoint.Point) {
 			WithAdvice("This is a bug and must be investigated, probably caused by adding an invalid data model in the config").
 			Warn("No signer algorithm found for the data point")
 	}
+	return sent
+}
+
+// schedulerRoutine notifies the broadcasterRoutine when the group with
+// the given index is due.
+func (f *Feed) schedulerRoutine(idx int) {
+	g := f.groups[idx]
+	for {
+		select {
+		case <-f.ctx.Done():
+			return
+		case <-g.interval.TickCh():
+			if g.jitter > 0 {
+				t := time.NewTimer(time.Duration(rand.Int63n(int64(g.jitter)))) //nolint:gosec
+				select {
+				case <-f.ctx.Done():
+					t.Stop()
+					return
+				case <-t.C:
+				}
+			}
+			select {
+			case <-f.ctx.Done():
+				return
+			case f.dueCh <- idx:
+			}
+		}
+	}
 }
 
 func (f *Feed) broadcasterRoutine() {
+	var triggerCh <-chan time.Time
+	if f.triggerInterval != nil {
+		triggerCh = f.triggerInterval.TickCh()
+	}
 	for {
 		select {
 		case <-f.ctx.Done():
 			return
-		case <-f.interval.TickCh():
-			// Fetch data points from the data provider.
-			models := sliceutil.Intersect(
-				f.dataProvider.ModelNames(f.ctx),
-				f.dataModels,
-			)
-			points, err := f.dataProvider.DataPoints(f.ctx, models...)
-			if err != nil {
-				f.log.
-					WithError(err).
-					WithField("models", models).
-					Error("Failed to fetch data points from provider")
+		case idx := <-f.dueCh:
+			f.publish(f.collectDue(idx), false)
+		case <-triggerCh:
+			f.publish(f.triggerModels, true)
+		}
+	}
+}
+
+// collectDue returns data models of the group with the given index and
+// of other groups that become due within the coalesce window, so their
+// data points are fetched in a single update cycle.
+func (f *Feed) collectDue(idx int) []string {
+	due := map[int]bool{idx: true}
+	models := append([]string{}, f.groups[idx].models...)
+	t := time.NewTimer(f.coalesce)
+	defer t.Stop()
+	for {
+		select {
+		case <-f.ctx.Done():
+			return models
+		case <-t.C:
+			return models
+		case idx := <-f.dueCh:
+			if due[idx] {
 				continue
 			}
+			due[idx] = true
+			models = append(models, f.groups[idx].models...)
+		}
+	}
+}
 
-			// Send data points to the network.
-			for model, point := range points {
-				if err := point.Validate(); err != nil {
-					if log.IsLevel(f.log, log.Debug) {
-						trace, _ := json.Marshal(point)
-						f.log.
-							WithError(err).
-							WithField("model", model).
-							WithField("trace", string(trace)).
-							WithFields(datapoint.PointLogFields(point)).
-							WithAdvice("Ignore if this occurs occasionally").
-							Warn("Data point is invalid; it will be skipped")
-					} else {
-						f.log.
-							WithError(err).
-							WithField("model", model).
-							WithFields(datapoint.PointLogFields(point)).
-							WithAdvice("Ignore if this occurs occasionally").
-							Warn("Data point is invalid; it will be skipped")
-					}
-					continue
-				}
-				f.broadcast(model, point)
+// publish fetches data points for the given models and sends them to the
+// network. If triggered is true, data points are sent only if their triggers
+// are met.
+func (f *Feed) publish(dataModels []string, triggered bool) {
+	if len(dataModels) == 0 {
+		return
+	}
+
+	// Fetch data points from the data provider.
+	models := sliceutil.Intersect(
+		f.dataProvider.ModelNames(f.ctx),
+		dataModels,
+	)
+	points, err := f.dataProvider.DataPoints(f.ctx, models...)
+	if err != nil {
+		f.log.
+			WithError(err).
+			WithField("models", models).
+			Error("Failed to fetch data points from provider")
+		return
+	}
+
+	// Send data points to the network.
+	for model, point := range points {
+		if err := point.Validate(); err != nil {
+			if log.IsLevel(f.log, log.Debug) {
+				trace, _ := json.Marshal(point)
+				f.log.
+					WithError(err).
+					WithField("model", model).
+					WithField("trace", string(trace)).
+					WithFields(datapoint.PointLogFields(point)).
+					WithAdvice("Ignore if this occurs occasionally").
+					Warn("Data point is invalid; it will be skipped")
+			} else {
+				f.log.
+					WithError(err).
+					WithField("model", model).
+					WithFields(datapoint.PointLogFields(point)).
+					WithAdvice("Ignore if this occurs occasionally").
+					Warn("Data point is invalid; it will be skipped")
 			}
+			metrics.FeedDataPointsSkipped.WithLabelValues(model).Inc()
+			continue
+		}
+		if triggered && !f.isTriggered(model, point) {
+			continue
 		}
+		if f.broadcast(model, point) {
+			f.last[model] = lastBroadcast{price: pointNumber(point), time: time.Now()}
+		}
+	}
+}
+
+// isTriggered returns true if the data point should be broadcast according
+// to the trigger of the data model.
+func (f *Feed) isTriggered(model string, point datapoint.Point) bool {
+	last, ok := f.last[model]
+	if !ok {
+		return true
+	}
+	trigger := f.triggers[model]
+	if trigger.Heartbeat > 0 && time.Since(last.time) >= trigger.Heartbeat {
+		return true
+	}
+	if trigger.Deviation > 0 {
+		price := pointNumber(point)
+		if price == nil || last.price == nil {
+			return true
+		}
+		if deviation(price, last.price) >= trigger.Deviation {
+			return true
+		}
+	}
+	return false
+}
+
+// pointNumber returns the numeric value of the data point, or nil if the
+// value is not numeric.
+func pointNumber(point datapoint.Point) *bn.FloatNumber {
+	if v, ok := point.Value.(value.NumericValue); ok {
+		return v.Number()
 	}
+	return nil
 }
 
 func (f *Feed) contextCancelHandler() {
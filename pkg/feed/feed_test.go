@@ -216,3 +216,241 @@ func TestFeed_Start(t *testing.T) {
 
 	ctxCancel()
 }
+
+func TestFeed_Triggers(t *testing.T) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer ctxCancel()
+
+	// Setup test environment.
+	interval := timeutil.NewTicker(0)
+	triggerInterval := timeutil.NewTicker(0)
+	dataProvider := &dataMocks.Provider{}
+	localTransport := local.New([]byte("test"), 0, map[string]transport.Message{
+		messages.DataPointV1MessageName: (*messages.DataPoint)(nil),
+	})
+
+	// Prepare mocks. The second value deviates less than the trigger
+	// deviation, so it should not be broadcast.
+	dataProvider.On("ModelNames", mock.Anything).Return([]string{"AAABBB", "CCCDDD"})
+	for _, v := range []float64{100, 100.5, 110} {
+		dataProvider.On("DataPoints", mock.Anything, []string{"AAABBB"}).Return(
+			map[string]datapoint.Point{"AAABBB": {
+				Value: value.StaticValue{Value: bn.DecFloatPoint(v)},
+				Time:  time.Unix(100, 0),
+			}},
+			nil,
+		).Once()
+	}
+
+	// Start feed.
+	feed, err := New(Config{
+		DataModels:      []string{"AAABBB", "CCCDDD"},
+		DataProvider:    dataProvider,
+		Signers:         []datapoint.Signer{mockSigner{}},
+		Transport:       localTransport,
+		Interval:        interval,
+		Triggers:        map[string]Trigger{"AAABBB": {Deviation: 0.01, Heartbeat: time.Hour}},
+		TriggerInterval: triggerInterval,
+	})
+	require.NoError(t, err)
+	require.NoError(t, localTransport.Start(ctx))
+	require.NoError(t, feed.Start(ctx))
+	defer func() {
+		ctxCancel()
+		<-feed.Wait()
+		<-localTransport.Wait()
+	}()
+
+	// Wait for services to start.
+	time.Sleep(time.Millisecond * 100)
+
+	for i := 0; i < 3; i++ {
+		triggerInterval.Tick()
+	}
+
+	// Get messages.
+	var dataPoints []*messages.DataPoint
+	msgCh := localTransport.Messages(messages.DataPointV1MessageName)
+	for len(dataPoints) < 2 {
+		msg := <-msgCh
+		dataPoints = append(dataPoints, msg.Message.(*messages.DataPoint))
+	}
+	assert.Equal(t, "100", dataPoints[0].Point.Value.Print())
+	assert.Equal(t, "110", dataPoints[1].Point.Value.Print())
+
+	// Models with triggers are not fetched on the regular interval.
+	called := make(chan struct{})
+	dataProvider.On("DataPoints", mock.Anything, []string{"CCCDDD"}).
+		Return(map[string]datapoint.Point{}, nil).
+		Run(func(mock.Arguments) { close(called) }).
+		Once()
+	interval.Tick()
+	<-called
+}
+
+func TestFeed_TriggersWithoutInterval(t *testing.T) {
+	_, err := New(Config{
+		DataModels:   []string{"AAABBB"},
+		DataProvider: &dataMocks.Provider{},
+		Signers:      []datapoint.Signer{mockSigner{}},
+		Transport:    local.New([]byte("test"), 0, map[string]transport.Message{}),
+		Interval:     timeutil.NewTicker(time.Second),
+		Triggers:     map[string]Trigger{"AAABBB": {Heartbeat: time.Minute}},
+	})
+	assert.Error(t, err)
+}
+
+type groupHook struct {
+	group string
+}
+
+func (h groupHook) BeforeSign(_ context.Context, _ *datapoint.Point) error {
+	return nil
+}
+
+func (h groupHook) BeforeBroadcast(_ context.Context, dp *datapoint.Point) error {
+	dp.Meta = map[string]any{"group": h.group}
+	return nil
+}
+
+func TestFeed_Groups(t *testing.T) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer ctxCancel()
+
+	// Setup test environment.
+	interval := timeutil.NewTicker(0)
+	slowInterval := timeutil.NewTicker(0)
+	dataProvider := &dataMocks.Provider{}
+	localTransport := local.New([]byte("test"), 0, map[string]transport.Message{
+		messages.DataPointV1MessageName: (*messages.DataPoint)(nil),
+	})
+	point := datapoint.Point{
+		Value: value.StaticValue{Value: bn.DecFloatPoint(42)},
+		Time:  time.Unix(100, 0),
+	}
+	dataProvider.On("ModelNames", mock.Anything).Return([]string{"AAABBB", "CCCDDD"})
+	dataProvider.On("DataPoints", mock.Anything, []string{"CCCDDD"}).Return(
+		map[string]datapoint.Point{"CCCDDD": point},
+		nil,
+	).Once()
+	dataProvider.On("DataPoints", mock.Anything, []string{"AAABBB"}).Return(
+		map[string]datapoint.Point{"AAABBB": point},
+		nil,
+	).Once()
+
+	// Start feed.
+	feed, err := New(Config{
+		DataModels:   []string{"AAABBB"},
+		DataProvider: dataProvider,
+		Signers:      []datapoint.Signer{mockSigner{}},
+		Hooks:        []Hook{groupHook{group: "default"}},
+		Transport:    localTransport,
+		Interval:     interval,
+		Groups: []Group{{
+			Name:       "slow",
+			DataModels: []string{"CCCDDD"},
+			Interval:   slowInterval,
+			Hooks:      []Hook{groupHook{group: "slow"}},
+		}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, localTransport.Start(ctx))
+	require.NoError(t, feed.Start(ctx))
+	defer func() {
+		ctxCancel()
+		<-feed.Wait()
+		<-localTransport.Wait()
+	}()
+
+	// Wait for services to start.
+	time.Sleep(time.Millisecond * 100)
+
+	msgCh := localTransport.Messages(messages.DataPointV1MessageName)
+
+	slowInterval.Tick()
+	msg := (<-msgCh).Message.(*messages.DataPoint)
+	assert.Equal(t, "CCCDDD", msg.Model)
+	assert.Equal(t, "slow", msg.Point.Meta["group"])
+
+	interval.Tick()
+	msg = (<-msgCh).Message.(*messages.DataPoint)
+	assert.Equal(t, "AAABBB", msg.Model)
+	assert.Equal(t, "default", msg.Point.Meta["group"])
+}
+
+func TestFeed_GroupsCoalesce(t *testing.T) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer ctxCancel()
+
+	// Setup test environment.
+	interval := timeutil.NewTicker(0)
+	slowInterval := timeutil.NewTicker(0)
+	dataProvider := &dataMocks.Provider{}
+	localTransport := local.New([]byte("test"), 0, map[string]transport.Message{
+		messages.DataPointV1MessageName: (*messages.DataPoint)(nil),
+	})
+	point := datapoint.Point{
+		Value: value.StaticValue{Value: bn.DecFloatPoint(42)},
+		Time:  time.Unix(100, 0),
+	}
+	dataProvider.On("ModelNames", mock.Anything).Return([]string{"AAABBB", "CCCDDD"})
+	dataProvider.On("DataPoints", mock.Anything, []string{"AAABBB", "CCCDDD"}).Return(
+		map[string]datapoint.Point{"AAABBB": point, "CCCDDD": point},
+		nil,
+	).Once()
+
+	// Start feed.
+	feed, err := New(Config{
+		DataModels:     []string{"AAABBB"},
+		DataProvider:   dataProvider,
+		Signers:        []datapoint.Signer{mockSigner{}},
+		Transport:      localTransport,
+		Interval:       interval,
+		CoalesceWindow: time.Millisecond * 500,
+		Groups: []Group{{
+			Name:       "slow",
+			DataModels: []string{"CCCDDD"},
+			Interval:   slowInterval,
+		}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, localTransport.Start(ctx))
+	require.NoError(t, feed.Start(ctx))
+	defer func() {
+		ctxCancel()
+		<-feed.Wait()
+		<-localTransport.Wait()
+	}()
+
+	// Wait for services to start.
+	time.Sleep(time.Millisecond * 100)
+
+	msgCh := localTransport.Messages(messages.DataPointV1MessageName)
+
+	// Both groups are due on the same tick, so their data points must be
+	// fetched in a single update cycle.
+	interval.Tick()
+	slowInterval.Tick()
+	var models []string
+	for i := 0; i < 2; i++ {
+		models = append(models, (<-msgCh).Message.(*messages.DataPoint).Model)
+	}
+	assert.ElementsMatch(t, []string{"AAABBB", "CCCDDD"}, models)
+	dataProvider.AssertNumberOfCalls(t, "DataPoints", 1)
+}
+
+func TestFeed_GroupsDuplicateModel(t *testing.T) {
+	_, err := New(Config{
+		DataModels:   []string{"AAABBB"},
+		DataProvider: &dataMocks.Provider{},
+		Signers:      []datapoint.Signer{mockSigner{}},
+		Transport:    local.New([]byte("test"), 0, map[string]transport.Message{}),
+		Interval:     timeutil.NewTicker(time.Second),
+		Groups: []Group{{
+			Name:       "slow",
+			DataModels: []string{"AAABBB"},
+			Interval:   timeutil.NewTicker(time.Minute),
+		}},
+	})
+	assert.Error(t, err)
+}
@@ -17,10 +17,18 @@ package feed
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"sync"
+	"time"
 
+	"github.com/chronicleprotocol/oracle-suite/pkg/contract/chronicle"
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/value"
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/bn"
 )
 
 // TickPrecisionHook is a hook that limits the precision of the price and volume
@@ -132,3 +140,166 @@ func buildTraceMap(dp datapoint.Point) map[string]string {
 
 	return trace
 }
+
+// ErrDataPointRejected is returned by hooks that reject a data point because
+// it is likely to be invalid, e.g. by the SanityGuardHook. Rejected data
+// points are not signed nor broadcast.
+var ErrDataPointRejected = errors.New("data point rejected")
+
+// ContractReader reads the current value of the contract that is updated
+// using data points broadcast by the feed.
+type ContractReader interface {
+	Read(ctx context.Context) (chronicle.PokeData, error)
+}
+
+// SanityGuardConfig is the configuration for the SanityGuardHook.
+type SanityGuardConfig struct {
+	// MaxDeviation is the maximum allowed deviation from the last broadcast
+	// value of the same pair, as a fraction (e.g. 0.1 for 10%). The check is
+	// disabled if zero.
+	MaxDeviation float64
+
+	// Window is the period after the last broadcast during which the
+	// MaxDeviation check is applied. After that time, any value is accepted,
+	// so a legitimate price move does not block the feed forever.
+	Window time.Duration
+
+	// MaxAge is the maximum age of a data point. The check is disabled
+	// if zero.
+	MaxAge time.Duration
+
+	// Contracts is a map of contracts, indexed by the pair, whose current
+	// values are compared with data points. Optional.
+	Contracts map[value.Pair]ContractReader
+
+	// MaxContractDeviation is the maximum allowed deviation from the current
+	// contract value, as a fraction. The check is disabled if zero.
+	MaxContractDeviation float64
+
+	// Logger is used to log failures of reading contract values.
+	// If nil, null logger will be used.
+	Logger log.Logger
+}
+
+// SanityGuardHook is a hook that rejects data points that are obviously
+// invalid before they are signed: data points that are too old, whose value
+// jumps too much from the last value broadcast by the feed, or deviates too
+// much from the current value of the target contract.
+//
+// Only the age check is applied to values other than value.Tick.
+type SanityGuardHook struct {
+	mu sync.Mutex
+
+	maxDeviation         float64
+	window               time.Duration
+	maxAge               time.Duration
+	contracts            map[value.Pair]ContractReader
+	maxContractDeviation float64
+	log                  log.Logger
+	last                 map[value.Pair]lastBroadcast
+}
+
+type lastBroadcast struct {
+	price *bn.FloatNumber
+	time  time.Time
+}
+
+// NewSanityGuardHook creates a new SanityGuardHook instance.
+func NewSanityGuardHook(cfg SanityGuardConfig) *SanityGuardHook {
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	return &SanityGuardHook{
+		maxDeviation:         cfg.MaxDeviation,
+		window:               cfg.Window,
+		maxAge:               cfg.MaxAge,
+		contracts:            cfg.Contracts,
+		maxContractDeviation: cfg.MaxContractDeviation,
+		log:                  cfg.Logger.WithField("tag", LoggerTag),
+		last:                 make(map[value.Pair]lastBroadcast),
+	}
+}
+
+// BeforeSign implements the Hook interface.
+func (s *SanityGuardHook) BeforeSign(ctx context.Context, dp *datapoint.Point) error {
+	if s.maxAge > 0 && time.Since(dp.Time) > s.maxAge {
+		return fmt.Errorf(
+			"%w: data point is older than %s: %s",
+			ErrDataPointRejected,
+			s.maxAge,
+			dp.Time.Format(time.RFC3339),
+		)
+	}
+	tick, ok := dp.Value.(value.Tick)
+	if !ok || tick.Price == nil {
+		return nil
+	}
+	price := tick.Number()
+	if s.maxDeviation > 0 {
+		s.mu.Lock()
+		last, ok := s.last[tick.Pair]
+		s.mu.Unlock()
+		if ok && time.Since(last.time) <= s.window {
+			if dev := deviation(price, last.price); dev > s.maxDeviation {
+				return fmt.Errorf(
+					"%w: value %s deviates %.4f%% from the last broadcast value %s, max allowed is %.4f%%",
+					ErrDataPointRejected,
+					price.String(),
+					dev*100,
+					last.price.String(),
+					s.maxDeviation*100,
+				)
+			}
+		}
+	}
+	if s.maxContractDeviation > 0 {
+		if contract, ok := s.contracts[tick.Pair]; ok {
+			pokeData, err := contract.Read(ctx)
+			if err != nil {
+				s.log.
+					WithError(err).
+					WithField("pair", tick.Pair.String()).
+					Warn("Unable to read the contract value; skipping the contract deviation check")
+				return nil
+			}
+			if pokeData.Val == nil || pokeData.Val.Sign() <= 0 {
+				return nil
+			}
+			current := pokeData.Val.Float()
+			if dev := deviation(price, current); dev > s.maxContractDeviation {
+				return fmt.Errorf(
+					"%w: value %s deviates %.4f%% from the contract value %s, max allowed is %.4f%%",
+					ErrDataPointRejected,
+					price.String(),
+					dev*100,
+					current.String(),
+					s.maxContractDeviation*100,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// BeforeBroadcast implements the Hook interface.
+func (s *SanityGuardHook) BeforeBroadcast(_ context.Context, dp *datapoint.Point) error {
+	tick, ok := dp.Value.(value.Tick)
+	if !ok || tick.Price == nil {
+		return nil
+	}
+	s.mu.Lock()
+	s.last[tick.Pair] = lastBroadcast{price: tick.Number(), time: time.Now()}
+	s.mu.Unlock()
+	return nil
+}
+
+// deviation returns the deviation of the value from the reference value
+// calculated as abs(1.0 - (reference / value)), the same way as the
+// deviation circuit breaker does.
+func deviation(val, ref *bn.FloatNumber) float64 {
+	if val.Sign() == 0 {
+		return math.Inf(1)
+	}
+	dev, _ := bn.Float(1).Sub(ref.Div(val)).Abs().BigFloat().Float64()
+	return dev
+}
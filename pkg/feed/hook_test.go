@@ -17,10 +17,14 @@ package feed
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/chronicleprotocol/oracle-suite/pkg/contract/chronicle"
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint"
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/value"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/bn"
@@ -97,3 +101,72 @@ func TestTickTraceHook_BeforeBroadcast(t *testing.T) {
 	}
 	assert.Equal(t, expectedTrace, dp.Meta["trace"])
 }
+
+type staticContract struct {
+	val *bn.DecFixedPointNumber
+	err error
+}
+
+func (c staticContract) Read(_ context.Context) (chronicle.PokeData, error) {
+	return chronicle.PokeData{Val: c.val, Age: time.Now()}, c.err
+}
+
+func tickPoint(price float64, t time.Time) *datapoint.Point {
+	return &datapoint.Point{
+		Time:  t,
+		Value: value.NewTick(value.Pair{Base: "BTC", Quote: "USD"}, price, 0),
+		Meta:  map[string]any{},
+	}
+}
+
+func TestSanityGuardHook_MaxAge(t *testing.T) {
+	hook := NewSanityGuardHook(SanityGuardConfig{MaxAge: time.Minute})
+	ctx := context.Background()
+
+	assert.NoError(t, hook.BeforeSign(ctx, tickPoint(100, time.Now().Add(-30*time.Second))))
+	err := hook.BeforeSign(ctx, tickPoint(100, time.Now().Add(-2*time.Minute)))
+	assert.ErrorIs(t, err, ErrDataPointRejected)
+}
+
+func TestSanityGuardHook_MaxDeviation(t *testing.T) {
+	hook := NewSanityGuardHook(SanityGuardConfig{MaxDeviation: 0.1, Window: time.Minute})
+	ctx := context.Background()
+
+	// Without previous broadcast, any value is accepted.
+	require.NoError(t, hook.BeforeSign(ctx, tickPoint(100, time.Now())))
+	require.NoError(t, hook.BeforeBroadcast(ctx, tickPoint(100, time.Now())))
+
+	assert.NoError(t, hook.BeforeSign(ctx, tickPoint(105, time.Now())))
+	assert.ErrorIs(t, hook.BeforeSign(ctx, tickPoint(150, time.Now())), ErrDataPointRejected)
+	assert.ErrorIs(t, hook.BeforeSign(ctx, tickPoint(50, time.Now())), ErrDataPointRejected)
+
+	// After the window, the last broadcast value is not used.
+	hook.last[value.Pair{Base: "BTC", Quote: "USD"}] = lastBroadcast{
+		price: bn.Float(100),
+		time:  time.Now().Add(-2 * time.Minute),
+	}
+	assert.NoError(t, hook.BeforeSign(ctx, tickPoint(150, time.Now())))
+}
+
+func TestSanityGuardHook_Contract(t *testing.T) {
+	pair := value.Pair{Base: "BTC", Quote: "USD"}
+	ctx := context.Background()
+
+	hook := NewSanityGuardHook(SanityGuardConfig{
+		Contracts: map[value.Pair]ContractReader{
+			pair: staticContract{val: bn.DecFixedPoint(100, 18)},
+		},
+		MaxContractDeviation: 0.1,
+	})
+	assert.NoError(t, hook.BeforeSign(ctx, tickPoint(95, time.Now())))
+	assert.ErrorIs(t, hook.BeforeSign(ctx, tickPoint(80, time.Now())), ErrDataPointRejected)
+
+	// If the contract cannot be read, the check is skipped.
+	hook = NewSanityGuardHook(SanityGuardConfig{
+		Contracts: map[value.Pair]ContractReader{
+			pair: staticContract{err: errors.New("rpc error")},
+		},
+		MaxContractDeviation: 0.1,
+	})
+	assert.NoError(t, hook.BeforeSign(ctx, tickPoint(80, time.Now())))
+}
@@ -0,0 +1,78 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// AgeVec is a gauge vector that reports the time elapsed since the time
+// set for given label values. Unlike a regular gauge, the age is calculated
+// when metrics are collected, so it keeps growing if the time is not updated.
+type AgeVec struct {
+	mu    sync.Mutex
+	desc  *prometheus.Desc
+	times map[string]ageEntry
+}
+
+type ageEntry struct {
+	labels []string
+	time   time.Time
+}
+
+// NewAgeVec creates a new AgeVec.
+func NewAgeVec(opts prometheus.Opts, labels []string) *AgeVec {
+	return &AgeVec{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(opts.Namespace, opts.Subsystem, opts.Name),
+			opts.Help,
+			labels,
+			opts.ConstLabels,
+		),
+		times: make(map[string]ageEntry),
+	}
+}
+
+// Set sets the time for given label values. Times older than the currently
+// set time are ignored.
+func (v *AgeVec) Set(t time.Time, labels ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	key := strings.Join(labels, "\x00")
+	if e, ok := v.times[key]; ok && e.time.After(t) {
+		return
+	}
+	v.times[key] = ageEntry{labels: labels, time: t}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (v *AgeVec) Describe(ch chan<- *prometheus.Desc) {
+	ch <- v.desc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (v *AgeVec) Collect(ch chan<- prometheus.Metric) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	now := time.Now()
+	for _, e := range v.times {
+		ch <- prometheus.MustNewConstMetric(v.desc, prometheus.GaugeValue, now.Sub(e.time).Seconds(), e.labels...)
+	}
+}
@@ -0,0 +1,304 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package metrics defines Prometheus metrics exposed by the applications.
+//
+// Metrics are updated directly by the instrumented services and can be
+// exposed over HTTP using the Handler function.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace is the prefix of all metric names.
+const Namespace = "chronicle"
+
+// Registry is the registry that contains all metrics defined in this package,
+// and the Go runtime and process metrics.
+var Registry = prometheus.NewRegistry()
+
+// Data point metrics, updated by the data provider:
+var (
+	DataPointValue = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "data_point",
+		Name:      "value",
+		Help:      "The last valid value of the data model.",
+	}, []string{"model"})
+
+	DataPointValid = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "data_point",
+		Name:      "valid",
+		Help:      "Whether the last data point of the data model is valid (1) or not (0).",
+	}, []string{"model"})
+
+	DataPointAge = NewAgeVec(prometheus.Opts{
+		Namespace: Namespace,
+		Subsystem: "data_point",
+		Name:      "age_seconds",
+		Help:      "The age of the last valid data point of the data model.",
+	}, []string{"model"})
+)
+
+// Origin metrics, updated by the graph updater:
+var (
+	OriginFetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "origin",
+		Name:      "fetch_duration_seconds",
+		Help:      "The time it took to fetch data points from the origin.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"origin"})
+
+	OriginFetchErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "origin",
+		Name:      "fetch_errors_total",
+		Help:      "The number of invalid data points returned by the origin.",
+	}, []string{"origin"})
+
+	OriginDataPointAge = NewAgeVec(prometheus.Opts{
+		Namespace: Namespace,
+		Subsystem: "origin",
+		Name:      "data_point_age_seconds",
+		Help:      "The age of the data point currently used for the origin query.",
+	}, []string{"origin", "query"})
+)
+
+// Feed metrics, updated by the feed service:
+var (
+	FeedDataPointsBroadcast = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "feed",
+		Name:      "data_points_broadcast_total",
+		Help:      "The number of data points broadcast by the feed.",
+	}, []string{"model"})
+
+	FeedDataPointsSkipped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "feed",
+		Name:      "data_points_skipped_total",
+		Help:      "The number of data points that were not broadcast because they were invalid or could not be signed.",
+	}, []string{"model"})
+)
+
+// Store metrics, updated by the data point store:
+var (
+	StoreDataPointsReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "store",
+		Name:      "data_points_received_total",
+		Help:      "The number of data points received from feeds and added to the store.",
+	}, []string{"feed", "model"})
+
+	StoreDataPointsRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "store",
+		Name:      "data_points_rejected_total",
+		Help:      "The number of received data points that could not be added to the store.",
+	}, []string{"model"})
+
+	StoreDataPointAge = NewAgeVec(prometheus.Opts{
+		Namespace: Namespace,
+		Subsystem: "store",
+		Name:      "data_point_age_seconds",
+		Help:      "The age of the last data point received from the feed.",
+	}, []string{"feed", "model"})
+
+	StoreMuSigSignaturesReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "store",
+		Name:      "musig_signatures_received_total",
+		Help:      "The number of MuSig signatures received from feeds and added to the store.",
+	}, []string{"feed", "model"})
+)
+
+// Relay metrics, updated by the relay service:
+var (
+	RelayPokesSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "relay",
+		Name:      "pokes_sent_total",
+		Help:      "The number of poke transactions sent to the contract.",
+	}, []string{"contract"})
+
+	RelayPokesFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "relay",
+		Name:      "pokes_failed_total",
+		Help:      "The number of poke transactions that could not be sent to the contract.",
+	}, []string{"contract"})
+
+	RelayPokesReverted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "relay",
+		Name:      "pokes_reverted_total",
+		Help:      "The number of poke calls that reverted during gas estimation.",
+	}, []string{"contract"})
+)
+
+// Transport metrics, updated by the transport services:
+var (
+	LibP2PPeers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "libp2p",
+		Name:      "peers",
+		Help:      "The number of peers connected to the libp2p node.",
+	})
+
+	LibP2PConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "libp2p",
+		Name:      "connections",
+		Help:      "The number of open libp2p connections.",
+	})
+
+	WebAPIConsumerPending = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "webapi",
+		Name:      "consumer_pending_messages",
+		Help:      "The number of messages waiting in the outbound queue of the consumer.",
+	}, []string{"consumer"})
+
+	WebAPIConsumerConsecutiveFailures = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "webapi",
+		Name:      "consumer_consecutive_failures",
+		Help:      "The number of failed deliveries to the consumer since the last successful one.",
+	}, []string{"consumer"})
+
+	WebAPIConsumerDelivered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "webapi",
+		Name:      "consumer_delivered_messages_total",
+		Help:      "The number of messages delivered to the consumer.",
+	}, []string{"consumer"})
+
+	WebAPIConsumerDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "webapi",
+		Name:      "consumer_dropped_messages_total",
+		Help:      "The number of messages dropped from the outbound queue of the consumer.",
+	}, []string{"consumer"})
+
+	WebAPIConsumerFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "webapi",
+		Name:      "consumer_failed_deliveries_total",
+		Help:      "The number of failed deliveries to the consumer.",
+	}, []string{"consumer"})
+)
+
+// RPC-Splitter metrics, updated by RPC-Splitters used by Ethereum clients.
+// Endpoints are identified only by the scheme and host of their URLs, so
+// API keys in URLs are not exposed:
+var (
+	RPCSplitterEndpointCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "rpcsplitter",
+		Name:      "endpoint_calls_total",
+		Help:      "The number of calls sent to the endpoint.",
+	}, []string{"client", "endpoint"})
+
+	RPCSplitterEndpointErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "rpcsplitter",
+		Name:      "endpoint_errors_total",
+		Help:      "The number of calls to the endpoint that failed.",
+	}, []string{"client", "endpoint"})
+
+	RPCSplitterEndpointCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "rpcsplitter",
+		Name:      "endpoint_call_duration_seconds",
+		Help:      "The time it took the endpoint to respond to successful calls.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"client", "endpoint"})
+
+	RPCSplitterEndpointErrorRate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "rpcsplitter",
+		Name:      "endpoint_error_rate",
+		Help:      "The ratio of failed calls to the endpoint within the health check window.",
+	}, []string{"client", "endpoint"})
+
+	RPCSplitterEndpointDisagreementRate = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "rpcsplitter",
+		Name:      "endpoint_disagreement_rate",
+		Help:      "The ratio of responses of the endpoint that differ from the majority within the health check window.",
+	}, []string{"client", "endpoint"})
+
+	RPCSplitterEndpointBlocksBehind = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "rpcsplitter",
+		Name:      "endpoint_blocks_behind",
+		Help:      "The number of blocks the endpoint was behind in the last eth_blockNumber call.",
+	}, []string{"client", "endpoint"})
+
+	RPCSplitterEndpointEjected = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "rpcsplitter",
+		Name:      "endpoint_ejected",
+		Help:      "Whether the endpoint is ejected (1) or not (0).",
+	}, []string{"client", "endpoint"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		DataPointValue,
+		DataPointValid,
+		DataPointAge,
+		OriginFetchDuration,
+		OriginFetchErrors,
+		OriginDataPointAge,
+		FeedDataPointsBroadcast,
+		FeedDataPointsSkipped,
+		StoreDataPointsReceived,
+		StoreDataPointsRejected,
+		StoreDataPointAge,
+		StoreMuSigSignaturesReceived,
+		RelayPokesSent,
+		RelayPokesFailed,
+		RelayPokesReverted,
+		LibP2PPeers,
+		LibP2PConnections,
+		WebAPIConsumerPending,
+		WebAPIConsumerConsecutiveFailures,
+		WebAPIConsumerDelivered,
+		WebAPIConsumerDropped,
+		WebAPIConsumerFailures,
+		RPCSplitterEndpointCalls,
+		RPCSplitterEndpointErrors,
+		RPCSplitterEndpointCallDuration,
+		RPCSplitterEndpointErrorRate,
+		RPCSplitterEndpointDisagreementRate,
+		RPCSplitterEndpointBlocksBehind,
+		RPCSplitterEndpointEjected,
+	)
+}
+
+// Handler returns an HTTP handler that exposes metrics from the Registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
@@ -0,0 +1,70 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgeVec(t *testing.T) {
+	v := NewAgeVec(prometheus.Opts{Name: "test_age_seconds", Help: "Test."}, []string{"model"})
+	v.Set(time.Now().Add(-time.Minute), "AAA/BBB")
+	v.Set(time.Now().Add(-time.Hour), "AAA/BBB") // Older time must be ignored.
+	v.Set(time.Now().Add(-time.Second), "CCC/DDD")
+
+	assert.Equal(t, 2, testutil.CollectAndCount(v))
+
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(v))
+	mfs, err := reg.Gather()
+	require.NoError(t, err)
+	require.Len(t, mfs, 1)
+	for _, m := range mfs[0].GetMetric() {
+		age := m.GetGauge().GetValue()
+		switch m.GetLabel()[0].GetValue() {
+		case "AAA/BBB":
+			assert.InDelta(t, 60, age, 5)
+		case "CCC/DDD":
+			assert.InDelta(t, 1, age, 5)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	DataPointValue.WithLabelValues("AAA/BBB").Set(42)
+
+	srv := httptest.NewServer(Handler())
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, string(body), `chronicle_data_point_value{model="AAA/BBB"} 42`)
+	assert.Contains(t, string(body), `go_goroutines`)
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/defiweb/go-eth/types"
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/metrics"
 	"github.com/chronicleprotocol/oracle-suite/pkg/transport"
 	"github.com/chronicleprotocol/oracle-suite/pkg/transport/messages"
 )
@@ -48,7 +49,7 @@ type Store struct {
 	log    log.Logger
 
 	transport  transport.Transport
-	dataModels []string
+	dataModels []string // Guarded by mu.
 	signatures map[storeKey]*messages.MuSigSignature
 }
 
@@ -97,6 +98,14 @@ func (m *Store) Wait() <-chan error {
 	return m.waitCh
 }
 
+// SetDataModels replaces the list of models for which signatures are
+// collected. Signatures already collected are kept.
+func (m *Store) SetDataModels(models []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dataModels = models
+}
+
 // SignaturesByDataModel implements SignatureProvider interface.
 func (m *Store) SignaturesByDataModel(model string) []*messages.MuSigSignature {
 	m.mu.Lock()
@@ -130,6 +139,7 @@ func (m *Store) collectSignature(feed types.Address, sig *messages.MuSigSignatur
 	}
 
 	m.signatures[key] = sig
+	metrics.StoreMuSigSignaturesReceived.WithLabelValues(feed.String(), key.wat).Inc()
 }
 
 func (m *Store) shouldCollectSignature(sig *messages.MuSigSignature) bool {
@@ -137,6 +147,8 @@ func (m *Store) shouldCollectSignature(sig *messages.MuSigSignature) bool {
 	if model == "" {
 		return false
 	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	for _, a := range m.dataModels {
 		if a == model {
 			return true
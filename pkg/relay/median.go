@@ -28,6 +28,7 @@ import (
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/store"
 	"github.com/chronicleprotocol/oracle-suite/pkg/datapoint/value"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/metrics"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/bn"
 )
 
@@ -122,6 +123,7 @@ func (w *median) createRelayCall(ctx context.Context) []relayCall {
 				WithFields(w.logFields()).
 				WithAdvice("Ignore if it is related to temporary network issues").
 				Error("Failed to poke the Median contract")
+			metrics.RelayPokesReverted.WithLabelValues(w.contract.Address().String()).Inc()
 			return nil
 		}
 
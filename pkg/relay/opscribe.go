@@ -26,6 +26,7 @@ import (
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/contract/chronicle"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/metrics"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/sliceutil"
 )
 
@@ -180,4 +181,5 @@ func (w *opScribe) handlePokeErr(err error) {
 		WithFields(w.logFields()).
 		WithAdvice("Ignore if it is related to temporary network issues").
 		Error("Failed to poke the ScribeOptimistic contract")
+	metrics.RelayPokesReverted.WithLabelValues(w.contract.Address().String()).Inc()
 }
@@ -30,8 +30,10 @@ import (
 	"github.com/chronicleprotocol/oracle-suite/pkg/contract/chronicle"
 	"github.com/chronicleprotocol/oracle-suite/pkg/contract/multicall"
 	datapointStore "github.com/chronicleprotocol/oracle-suite/pkg/datapoint/store"
+	"github.com/chronicleprotocol/oracle-suite/pkg/ethereum"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/metrics"
 	musigStore "github.com/chronicleprotocol/oracle-suite/pkg/musig/store"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/bn"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/errutil"
@@ -287,7 +289,8 @@ func (m *Relay) sendRelayTransactions() {
 		// Note, that there is not need to create a separate branch for
 		// a single call because MultiCall internally handles this case.
 		call := multicall.AggregateCallables(client, calls...).AllowFail()
-		txHash, tx, err := call.SendTransaction(m.ctx)
+		ctx, fees := ethereum.WithFeeEstimate(m.ctx)
+		txHash, tx, err := call.SendTransaction(ctx)
 		if err != nil {
 			if strings.Contains(err.Error(), "nonce too low") || strings.Contains(err.Error(), "replacement transaction underpriced") {
 				m.log.
@@ -309,8 +312,14 @@ func (m *Relay) sendRelayTransactions() {
 				}).
 				WithAdvice("Ignore if it is related to temporary network issues").
 				Error("Failed to send transaction")
+			for _, addr := range addressesFromCalls(calls) {
+				metrics.RelayPokesFailed.WithLabelValues(addr.String()).Inc()
+			}
 			continue
 		}
+		for _, addr := range addressesFromCalls(calls) {
+			metrics.RelayPokesSent.WithLabelValues(addr.String()).Inc()
+		}
 		m.log.
 			WithFields(log.Fields{
 				"txHash":                 txHash,
@@ -323,6 +332,9 @@ func (m *Relay) sendRelayTransactions() {
 				"txGasLimit":             tx.GasLimit,
 				"txMaxFeePerGas":         tx.MaxFeePerGas,
 				"txMaxPriorityFeePerGas": tx.MaxPriorityFeePerGas,
+				"txL1Fee":                fees.L1Fee,
+				"feeEstimator":           fees.Estimator,
+				"feeEstimatorReason":     fees.Reason,
 				"contractAddresses":      addressesFromCalls(calls),
 				"txInput":                hexutil.BytesToHex(tx.Input),
 			}).
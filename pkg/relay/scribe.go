@@ -25,6 +25,7 @@ import (
 	"github.com/chronicleprotocol/oracle-suite/pkg/contract/chronicle"
 	"github.com/chronicleprotocol/oracle-suite/pkg/contract/multicall"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/metrics"
 	"github.com/chronicleprotocol/oracle-suite/pkg/musig/store"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/sliceutil"
 )
@@ -155,6 +156,7 @@ func (w *scribe) createRelayCall(ctx context.Context) []relayCall {
 					WithFields(w.logFields()).
 					WithAdvice("Ignore if it is related to temporary network issues").
 					Error("Failed to poke the Scribe contract")
+				metrics.RelayPokesReverted.WithLabelValues(w.contract.Address().String()).Inc()
 				return nil
 			}
 
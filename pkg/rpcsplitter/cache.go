@@ -0,0 +1,90 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// cache is a short-lived cache for resolved responses. Entries are keyed by
+// the method name and its arguments, which for cached methods always
+// include a resolved block number, so identical calls made within the same
+// block are sent to the endpoints only once.
+type cache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	result  any
+	expires time.Time
+}
+
+func newCache(ttl time.Duration, size int) *cache {
+	return &cache{
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// key returns a cache key for the given method and arguments. If the
+// arguments cannot be marshaled, an empty string is returned.
+func (c *cache) key(method string, args []any) string {
+	b, err := json.Marshal(args)
+	if err != nil {
+		return ""
+	}
+	return method + string(b)
+}
+
+// get returns a cached result for the given key.
+func (c *cache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.result, true
+}
+
+// set adds a result to the cache. If the cache is full, expired entries are
+// removed first, and if that is not enough, the whole cache is cleared.
+func (c *cache) set(key string, result any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= c.size {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= c.size {
+			c.entries = make(map[string]cacheEntry)
+		}
+	}
+	c.entries[key] = cacheEntry{result: result, expires: now.Add(c.ttl)}
+}
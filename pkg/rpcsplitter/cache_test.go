@@ -0,0 +1,81 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/rpcsplitter/types"
+)
+
+func TestCache(t *testing.T) {
+	c := newCache(time.Minute, 2)
+	key := c.key("eth_call", []any{"foo", 1})
+	assert.NotEmpty(t, key)
+	assert.NotEqual(t, key, c.key("eth_call", []any{"foo", 2}))
+	assert.NotEqual(t, key, c.key("eth_getBalance", []any{"foo", 1}))
+
+	_, ok := c.get(key)
+	assert.False(t, ok)
+	c.set(key, 42)
+	v, ok := c.get(key)
+	assert.True(t, ok)
+	assert.Equal(t, 42, v)
+
+	// Cache is cleared when it is full.
+	c.set("a", 1)
+	c.set("b", 2)
+	_, ok = c.get(key)
+	assert.False(t, ok)
+	_, ok = c.get("b")
+	assert.True(t, ok)
+}
+
+func TestCache_Expired(t *testing.T) {
+	c := newCache(time.Millisecond, 10)
+	c.set("a", 1)
+	time.Sleep(5 * time.Millisecond)
+	_, ok := c.get("a")
+	assert.False(t, ok)
+}
+
+func Test_RPC_Cache(t *testing.T) {
+	address := types.HexToAddress("0xb59f67a8bff5d8cd03f6ac17265c550ed8f33907")
+	balance := types.HexToNumber("0x100000000000")
+	blockNumber := types.StringToBlockNumber("0x10")
+
+	// Every client expects to be called only once, the second call must be
+	// served from the cache.
+	callers := map[string]caller{}
+	for i := 0; i < 2; i++ {
+		c := &mockClient{t: t}
+		c.mockCall(balance, "eth_getBalance", address, blockNumber)
+		callers[fmt.Sprintf("%d", i)] = c
+	}
+	h, err := NewServer(withCallers(callers), WithRequirements(2, 10), WithCache(time.Minute, 100))
+	require.NoError(t, err)
+	s := h.(*server)
+	for i := 0; i < 2; i++ {
+		res, err := s.eth.GetBalance(address, blockNumber)
+		require.NoError(t, err)
+		assert.Equal(t, balance.Big(), res.(*types.Number).Big())
+	}
+}
@@ -0,0 +1,260 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	gethRPC "github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxRequestSize is the maximum size of the HTTP request body handled by
+// the dispatcher. Larger requests are passed to the go-ethereum RPC server,
+// which rejects them.
+const maxRequestSize = 5 * 1024 * 1024
+
+// maxConcurrentBatchCalls is the maximum number of calls from a single batch
+// request that are executed concurrently.
+const maxConcurrentBatchCalls = 16
+
+// JSON-RPC error codes.
+const (
+	errCodeMethodNotFound = -32601
+	errCodeInvalidParams  = -32602
+	errCodeDefault        = -32000
+)
+
+// PassthroughPolicy specifies how calls to methods that are not supported by
+// the RPC-Splitter are handled.
+type PassthroughPolicy int
+
+const (
+	// PassthroughDisabled rejects calls to unsupported methods.
+	PassthroughDisabled PassthroughPolicy = iota
+
+	// PassthroughFirst forwards calls to unsupported methods to endpoints and
+	// returns the first successful response.
+	PassthroughFirst
+
+	// PassthroughMajority forwards calls to unsupported methods to endpoints
+	// and returns the most common response that occurred at least as many
+	// times as specified in the minRes method.
+	PassthroughMajority
+)
+
+// defaultPassthroughDenied lists methods that are not forwarded to endpoints,
+// unless they are allowed using the WithPassthroughMethods option, because
+// they give access to accounts or to the administration of the nodes.
+var defaultPassthroughDenied = []string{"admin_*", "personal_*", "debug_*"}
+
+type jsonrpcMessage struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type jsonrpcResponse struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *jsonrpcError   `json:"error,omitempty"`
+}
+
+type jsonrpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// serveDispatch handles batch requests and, if the passthrough is enabled,
+// all single requests. Calls are executed using an in-process connection to
+// the go-ethereum RPC server, calls to unsupported methods are handled
+// according to the passthrough policy.
+//
+// The go-ethereum RPC server executes batch calls one by one. Because every
+// call may wait for slower endpoints, calls from a batch are executed
+// concurrently instead.
+//
+// It returns false if the request was not handled and should be passed to
+// the go-ethereum RPC server, e.g. because it is malformed.
+func (s *server) serveDispatch(rw http.ResponseWriter, req *http.Request, body []byte) bool {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return false
+	}
+	var (
+		msgs  []jsonrpcMessage
+		batch = body[0] == '['
+	)
+	if batch {
+		if err := json.Unmarshal(body, &msgs); err != nil || len(msgs) == 0 {
+			return false
+		}
+	} else {
+		if s.passthrough == PassthroughDisabled {
+			return false
+		}
+		var msg jsonrpcMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return false
+		}
+		msgs = []jsonrpcMessage{msg}
+	}
+
+	// Execute calls.
+	var (
+		wg    sync.WaitGroup
+		sem   = make(chan struct{}, maxConcurrentBatchCalls)
+		resps = make([]*jsonrpcResponse, len(msgs))
+	)
+	for i, msg := range msgs {
+		i, msg := i, msg
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			resps[i] = s.dispatch(req.Context(), msg)
+		}()
+	}
+	wg.Wait()
+
+	// Notifications, calls without an ID, do not have responses.
+	var res []*jsonrpcResponse
+	for i, msg := range msgs {
+		if len(msg.ID) > 0 {
+			res = append(res, resps[i])
+		}
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	switch {
+	case len(res) == 0:
+		return true
+	case batch:
+		_ = json.NewEncoder(rw).Encode(res)
+	default:
+		_ = json.NewEncoder(rw).Encode(res[0])
+	}
+	return true
+}
+
+// dispatch executes a single call.
+func (s *server) dispatch(ctx context.Context, msg jsonrpcMessage) *jsonrpcResponse {
+	resp := &jsonrpcResponse{Version: "2.0", ID: msg.ID}
+	var params []json.RawMessage
+	if len(msg.Params) > 0 && !bytes.Equal(msg.Params, []byte("null")) {
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			resp.Error = &jsonrpcError{Code: errCodeInvalidParams, Message: "invalid params, expected an array"}
+			return resp
+		}
+	}
+	args := make([]any, len(params))
+	for i, p := range params {
+		args[i] = p
+	}
+	var res json.RawMessage
+	err := s.inproc.CallContext(ctx, &res, msg.Method, args...)
+	if errorCode(err) == errCodeMethodNotFound && s.passthroughAllowed(msg.Method) {
+		err = s.passthroughCall(ctx, &res, msg.Method, args)
+	}
+	if err != nil {
+		resp.Error = &jsonrpcError{Code: errorCode(err), Message: err.Error()}
+		var dataErr gethRPC.DataError
+		if errors.As(err, &dataErr) {
+			resp.Error.Data = dataErr.ErrorData()
+		}
+		return resp
+	}
+	if len(res) == 0 {
+		res = json.RawMessage("null")
+	}
+	resp.Result = res
+	return resp
+}
+
+// passthroughCall forwards a call to an unsupported method to endpoints
+// according to the passthrough policy. Unlike for supported methods, block
+// tags in arguments are not replaced with block numbers.
+func (s *server) passthroughCall(ctx context.Context, result *json.RawMessage, method string, args []any) error {
+	ctx, ctxCancel := context.WithTimeout(ctx, s.totalTimeout)
+	defer ctxCancel()
+
+	var r resolver = s.firstResolver
+	if s.passthrough == PassthroughMajority {
+		r = s.defaultResolver
+	}
+	res := new(any)
+	if err := s.call(ctx, r, res, method, args...); err != nil {
+		return err
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	*result = b
+	return nil
+}
+
+// passthroughAllowed reports whether a call to the given unsupported method
+// can be forwarded to endpoints.
+func (s *server) passthroughAllowed(method string) bool {
+	switch {
+	case s.passthrough == PassthroughDisabled:
+		return false
+	case len(s.passthroughMethods) > 0:
+		return matchMethod(s.passthroughMethods, method)
+	default:
+		return !matchMethod(defaultPassthroughDenied, method)
+	}
+}
+
+// matchMethod reports whether the method matches any of the patterns. A
+// pattern that ends with "*" matches all methods with the same prefix.
+func matchMethod(patterns []string, method string) bool {
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(method, prefix) {
+				return true
+			}
+			continue
+		}
+		if p == method {
+			return true
+		}
+	}
+	return false
+}
+
+// errorCode returns the JSON-RPC error code of the error. If the error does
+// not have a code, the default code is returned. For nil errors, zero is
+// returned.
+func errorCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var rpcErr gethRPC.Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.ErrorCode()
+	}
+	return errCodeDefault
+}
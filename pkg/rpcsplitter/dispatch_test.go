@@ -0,0 +1,147 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticClient returns the same response for every call to a given method.
+// Unlike mockClient, it may be called concurrently.
+type staticClient map[string]any
+
+func (c staticClient) CallContext(_ context.Context, result any, method string, _ ...any) error {
+	res, ok := c[method]
+	if !ok {
+		return fmt.Errorf("unexpected call to %s", method)
+	}
+	if err, ok := res.(error); ok {
+		return err
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, result)
+}
+
+func serveRaw(t *testing.T, callers []caller, body string, opts ...Option) []byte {
+	m := map[string]caller{}
+	for n, c := range callers {
+		m[fmt.Sprintf("%d", n)] = c
+	}
+	h, err := NewServer(append([]Option{withCallers(m)}, opts...)...)
+	require.NoError(t, err)
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(body)))
+	r.Header.Set("Content-Type", "application/json")
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, r)
+	return rw.Body.Bytes()
+}
+
+func Test_RPC_Batch(t *testing.T) {
+	client := staticClient{
+		"eth_blockNumber": "0x10",
+		"eth_chainId":     "0x1",
+	}
+	res := serveRaw(t, []caller{client, client}, `[
+		{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"},
+		{"jsonrpc":"2.0","id":2,"method":"eth_chainId"},
+		{"jsonrpc":"2.0","method":"eth_chainId"},
+		{"jsonrpc":"2.0","id":3,"method":"eth_unknown"}
+	]`, WithRequirements(2, 10))
+
+	var resps []rpcRes
+	jsonUnmarshal(t, res, &resps)
+	require.Len(t, resps, 3) // The notification must be omitted.
+	assert.Equal(t, 1, resps[0].ID)
+	assert.Equal(t, "0x10", resps[0].Result)
+	assert.Equal(t, 2, resps[1].ID)
+	assert.Equal(t, "0x1", resps[1].Result)
+	assert.Equal(t, 3, resps[2].ID)
+	assert.Equal(t, errCodeMethodNotFound, resps[2].Error.Code)
+}
+
+func Test_RPC_Passthrough(t *testing.T) {
+	req := `{"jsonrpc":"2.0","id":1,"method":"eth_unknown","params":["0x1"]}`
+	t.Run("disabled", func(t *testing.T) {
+		var res rpcRes
+		jsonUnmarshal(t, serveRaw(t, []caller{staticClient{}}, req, WithRequirements(1, 10)), &res)
+		assert.Equal(t, errCodeMethodNotFound, res.Error.Code)
+	})
+	t.Run("first", func(t *testing.T) {
+		c1 := &mockClient{t: t}
+		c2 := &mockClient{t: t}
+		c1.mockCall(errors.New("error#1"), "eth_unknown", json.RawMessage(`"0x1"`))
+		c2.mockCall("0x2", "eth_unknown", json.RawMessage(`"0x1"`))
+		var res rpcRes
+		jsonUnmarshal(t, serveRaw(t, []caller{c1, c2}, req, WithRequirements(2, 10), WithPassthrough(PassthroughFirst)), &res)
+		assert.Equal(t, 1, res.ID)
+		assert.Equal(t, "0x2", res.Result)
+	})
+	t.Run("majority", func(t *testing.T) {
+		c1 := &mockClient{t: t}
+		c2 := &mockClient{t: t}
+		c3 := &mockClient{t: t}
+		c1.mockCall("0x2", "eth_unknown", json.RawMessage(`"0x1"`))
+		c2.mockCall("0x2", "eth_unknown", json.RawMessage(`"0x1"`))
+		c3.mockCall("0x3", "eth_unknown", json.RawMessage(`"0x1"`))
+		var res rpcRes
+		jsonUnmarshal(t, serveRaw(t, []caller{c1, c2, c3}, req, WithRequirements(2, 10), WithPassthrough(PassthroughMajority)), &res)
+		assert.Equal(t, "0x2", res.Result)
+	})
+	t.Run("majority-different-responses", func(t *testing.T) {
+		c1 := &mockClient{t: t}
+		c2 := &mockClient{t: t}
+		c1.mockCall("0x2", "eth_unknown", json.RawMessage(`"0x1"`))
+		c2.mockCall("0x3", "eth_unknown", json.RawMessage(`"0x1"`))
+		var res rpcRes
+		jsonUnmarshal(t, serveRaw(t, []caller{c1, c2}, req, WithRequirements(2, 10), WithPassthrough(PassthroughMajority)), &res)
+		assert.Contains(t, res.Error.Message, "RPC servers returned different responses")
+	})
+	t.Run("denied-by-default", func(t *testing.T) {
+		c := &mockClient{t: t}
+		var res rpcRes
+		jsonUnmarshal(t, serveRaw(t, []caller{c}, `{"jsonrpc":"2.0","id":1,"method":"admin_peers"}`, WithRequirements(1, 10), WithPassthrough(PassthroughFirst)), &res)
+		assert.Equal(t, errCodeMethodNotFound, res.Error.Code)
+	})
+	t.Run("allowed-methods", func(t *testing.T) {
+		c := &mockClient{t: t}
+		c.mockCall("0x2", "debug_unknown")
+		opts := []Option{WithRequirements(1, 10), WithPassthrough(PassthroughFirst), WithPassthroughMethods([]string{"debug_*"})}
+		var res rpcRes
+		jsonUnmarshal(t, serveRaw(t, []caller{c}, `{"jsonrpc":"2.0","id":1,"method":"debug_unknown"}`, opts...), &res)
+		assert.Equal(t, "0x2", res.Result)
+		jsonUnmarshal(t, serveRaw(t, []caller{c}, req, opts...), &res)
+		assert.Equal(t, errCodeMethodNotFound, res.Error.Code)
+	})
+	t.Run("notification", func(t *testing.T) {
+		c := &mockClient{t: t}
+		c.mockCall("0x2", "eth_unknown", json.RawMessage(`"0x1"`))
+		res := serveRaw(t, []caller{c}, `{"jsonrpc":"2.0","method":"eth_unknown","params":["0x1"]}`, WithRequirements(1, 10), WithPassthrough(PassthroughFirst))
+		assert.Empty(t, res)
+	})
+}
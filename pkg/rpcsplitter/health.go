@@ -0,0 +1,385 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/metrics"
+)
+
+const defaultHealthWindow = 100
+const defaultHealthMinSamples = 10
+const defaultEjectionTime = time.Minute
+
+// maxEjectionMultiplier limits how many times the ejection time can be
+// extended for endpoints that are ejected repeatedly.
+const maxEjectionMultiplier = 10
+
+// HealthCheckOptions configures ejection of unhealthy endpoints.
+type HealthCheckOptions struct {
+	// Window is the number of recent calls used to calculate health stats.
+	// If zero, the last 100 calls are used.
+	Window int
+
+	// MinSamples is the minimum number of calls in the window before an
+	// endpoint can be ejected. If zero, 10 calls are required.
+	MinSamples int
+
+	// MaxErrorRate is the maximum ratio of failed calls, between 0 and 1.
+	// If exceeded, the endpoint is ejected. If zero, error rate is not
+	// checked.
+	MaxErrorRate float64
+
+	// MaxDisagreementRate is the maximum ratio of responses, between 0
+	// and 1, that are different from the response returned by the majority
+	// of endpoints, or, for the eth_blockNumber method, that are more than
+	// the allowed number of blocks behind. If exceeded, the endpoint is
+	// ejected. If zero, disagreement rate is not checked.
+	MaxDisagreementRate float64
+
+	// EjectionTime is the time for which the endpoint is ejected. Every
+	// consecutive ejection extends that time, up to 10 times. If zero,
+	// endpoints are ejected for one minute.
+	EjectionTime time.Duration
+}
+
+// EndpointStatus contains health stats for an endpoint. It is returned by
+// the "splitter_status" method.
+type EndpointStatus struct {
+	Endpoint         string     `json:"endpoint"`
+	Calls            uint64     `json:"calls"`            // Total number of calls.
+	Errors           uint64     `json:"errors"`           // Total number of failed calls.
+	LatencyP50       float64    `json:"latencyP50"`       // In milliseconds.
+	LatencyP90       float64    `json:"latencyP90"`       // In milliseconds.
+	LatencyP99       float64    `json:"latencyP99"`       // In milliseconds.
+	ErrorRate        float64    `json:"errorRate"`        // Within the window.
+	DisagreementRate float64    `json:"disagreementRate"` // Within the window.
+	BlocksBehind     int64      `json:"blocksBehind"`     // In the last eth_blockNumber call.
+	Ejected          bool       `json:"ejected"`
+	EjectedUntil     *time.Time `json:"ejectedUntil,omitempty"`
+	Ejections        int        `json:"ejections"` // Number of consecutive ejections.
+}
+
+// health tracks the health of endpoints and decides which endpoints should
+// be temporarily ejected. Health stats are also reported as metrics.
+//
+// Ejected endpoints are not used until the ejection time passes. After that,
+// the endpoint is actively probed using the probe function. If the probe
+// succeeds, the endpoint is used again, with its stats cleared, and if it is
+// still unhealthy, it will be ejected again for a longer time. If the probe
+// fails, the endpoint is ejected again right away.
+type health struct {
+	mu        sync.Mutex
+	log       log.Logger
+	opts      HealthCheckOptions
+	eject     bool
+	endpoints map[string]*endpointHealth
+
+	// probe checks if the ejected endpoint can be used again. If nil,
+	// endpoints are used again without probing.
+	probe func(name string) error
+
+	// client is the name of the RPC-Splitter used in metrics.
+	client string
+}
+
+type endpointHealth struct {
+	latencies     window[time.Duration]
+	errors        window[bool]
+	disagreements window[bool]
+	calls         uint64
+	errorsTotal   uint64
+	blocksBehind  int64
+	ejected       bool // Set until the endpoint passes a probe.
+	probing       bool
+	ejectedUntil  time.Time
+	ejections     int
+}
+
+func newHealth(opts *HealthCheckOptions, logger log.Logger) *health {
+	h := &health{log: logger, endpoints: make(map[string]*endpointHealth)}
+	if opts != nil {
+		h.opts = *opts
+		h.eject = true
+	}
+	if h.opts.Window <= 0 {
+		h.opts.Window = defaultHealthWindow
+	}
+	if h.opts.MinSamples <= 0 {
+		h.opts.MinSamples = defaultHealthMinSamples
+	}
+	if h.opts.EjectionTime <= 0 {
+		h.opts.EjectionTime = defaultEjectionTime
+	}
+	return h
+}
+
+// recordCall records the result of a call to the endpoint.
+func (h *health) recordCall(name string, latency time.Duration, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e := h.endpoint(name)
+	e.calls++
+	e.errors.add(err != nil)
+	metrics.RPCSplitterEndpointCalls.WithLabelValues(h.labels(name)...).Inc()
+	if err != nil {
+		e.errorsTotal++
+		metrics.RPCSplitterEndpointErrors.WithLabelValues(h.labels(name)...).Inc()
+	} else {
+		e.latencies.add(latency)
+		metrics.RPCSplitterEndpointCallDuration.WithLabelValues(h.labels(name)...).Observe(latency.Seconds())
+	}
+	h.check(name, e)
+	h.report(name, e)
+}
+
+// recordDisagreement records whether the response of the endpoint was
+// different from the resolved response.
+func (h *health) recordDisagreement(name string, disagree bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e := h.endpoint(name)
+	e.disagreements.add(disagree)
+	h.check(name, e)
+	h.report(name, e)
+}
+
+// recordBlocksBehind records how many blocks the endpoint is behind the
+// highest known block.
+func (h *health) recordBlocksBehind(name string, blocks int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e := h.endpoint(name)
+	e.blocksBehind = blocks
+	h.report(name, e)
+}
+
+// isEjected reports whether the endpoint is currently ejected. If the
+// ejection time of the endpoint has passed, the endpoint is probed in the
+// background and it is ejected until the probe succeeds.
+func (h *health) isEjected(name string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e, ok := h.endpoints[name]
+	if !ok || !e.ejected {
+		return false
+	}
+	if time.Now().Before(e.ejectedUntil) || e.probing {
+		return true
+	}
+	if h.probe == nil {
+		e.ejected = false
+		h.report(name, e)
+		return false
+	}
+	e.probing = true
+	go h.runProbe(name)
+	return true
+}
+
+// runProbe probes the ejected endpoint and re-admits it if the probe
+// succeeds, otherwise the ejection is extended.
+func (h *health) runProbe(name string) {
+	err := h.probe(name)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	e := h.endpoint(name)
+	e.probing = false
+	defer h.report(name, e)
+	if err == nil {
+		e.ejected = false
+		h.log.
+			WithField("name", name).
+			Info("Endpoint re-admitted")
+		return
+	}
+	h.extendEjection(e)
+	h.log.
+		WithField("name", name).
+		WithField("ejectionTime", time.Until(e.ejectedUntil)).
+		WithError(err).
+		Warn("Endpoint probe failed")
+}
+
+// status returns health stats for the given endpoints.
+func (h *health) status(names []string) []EndpointStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sort.Strings(names)
+	res := make([]EndpointStatus, 0, len(names))
+	for _, name := range names {
+		e := h.endpoint(name)
+		s := EndpointStatus{
+			Endpoint:         name,
+			Calls:            e.calls,
+			Errors:           e.errorsTotal,
+			ErrorRate:        rate(e.errors.values),
+			DisagreementRate: rate(e.disagreements.values),
+			BlocksBehind:     e.blocksBehind,
+			Ejections:        e.ejections,
+		}
+		if len(e.latencies.values) > 0 {
+			ls := make([]time.Duration, len(e.latencies.values))
+			copy(ls, e.latencies.values)
+			sort.Slice(ls, func(i, j int) bool { return ls[i] < ls[j] })
+			s.LatencyP50 = milliseconds(percentile(ls, 50))
+			s.LatencyP90 = milliseconds(percentile(ls, 90))
+			s.LatencyP99 = milliseconds(percentile(ls, 99))
+		}
+		if e.ejected {
+			until := e.ejectedUntil
+			s.Ejected = true
+			s.EjectedUntil = &until
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+// report updates metrics of the endpoint. It must be called with the mutex
+// locked.
+func (h *health) report(name string, e *endpointHealth) {
+	labels := h.labels(name)
+	metrics.RPCSplitterEndpointErrorRate.WithLabelValues(labels...).Set(rate(e.errors.values))
+	metrics.RPCSplitterEndpointDisagreementRate.WithLabelValues(labels...).Set(rate(e.disagreements.values))
+	metrics.RPCSplitterEndpointBlocksBehind.WithLabelValues(labels...).Set(float64(e.blocksBehind))
+	ejected := 0.0
+	if e.ejected {
+		ejected = 1
+	}
+	metrics.RPCSplitterEndpointEjected.WithLabelValues(labels...).Set(ejected)
+}
+
+// labels returns metric labels for the endpoint. Only the scheme and host
+// of the endpoint URL are used, because other parts of the URL may contain
+// API keys.
+func (h *health) labels(name string) []string {
+	endpoint := name
+	if u, err := url.Parse(name); err == nil && u.Host != "" {
+		endpoint = u.Scheme + "://" + u.Host
+	}
+	return []string{h.client, endpoint}
+}
+
+// endpoint returns the health of the endpoint with the given name. It must
+// be called with the mutex locked.
+func (h *health) endpoint(name string) *endpointHealth {
+	e, ok := h.endpoints[name]
+	if !ok {
+		e = &endpointHealth{
+			latencies:     window[time.Duration]{size: h.opts.Window},
+			errors:        window[bool]{size: h.opts.Window},
+			disagreements: window[bool]{size: h.opts.Window},
+		}
+		h.endpoints[name] = e
+	}
+	return e
+}
+
+// check ejects the endpoint if it is unhealthy. It must be called with the
+// mutex locked.
+func (h *health) check(name string, e *endpointHealth) {
+	if !h.eject || e.ejected {
+		return
+	}
+	unhealthy := false
+	if h.opts.MaxErrorRate > 0 && len(e.errors.values) >= h.opts.MinSamples {
+		unhealthy = unhealthy || rate(e.errors.values) > h.opts.MaxErrorRate
+	}
+	if h.opts.MaxDisagreementRate > 0 && len(e.disagreements.values) >= h.opts.MinSamples {
+		unhealthy = unhealthy || rate(e.disagreements.values) > h.opts.MaxDisagreementRate
+	}
+	if !unhealthy {
+		// The endpoint was healthy during the whole window, so it is no
+		// longer considered to be repeatedly ejected.
+		if len(e.errors.values) >= h.opts.Window {
+			e.ejections = 0
+		}
+		return
+	}
+	h.log.
+		WithField("name", name).
+		WithField("errorRate", rate(e.errors.values)).
+		WithField("disagreementRate", rate(e.disagreements.values)).
+		WithField("ejectionTime", h.extendEjection(e)).
+		Warn("Endpoint ejected")
+	e.latencies.reset()
+	e.errors.reset()
+	e.disagreements.reset()
+}
+
+// extendEjection ejects the endpoint for a time that grows with every
+// consecutive ejection and returns that time. It must be called with the
+// mutex locked.
+func (h *health) extendEjection(e *endpointHealth) time.Duration {
+	if e.ejections < maxEjectionMultiplier {
+		e.ejections++
+	}
+	ejectionTime := h.opts.EjectionTime * time.Duration(e.ejections)
+	e.ejected = true
+	e.ejectedUntil = time.Now().Add(ejectionTime)
+	return ejectionTime
+}
+
+// window stores the last size values.
+type window[T any] struct {
+	size   int
+	values []T
+}
+
+func (w *window[T]) add(v T) {
+	w.values = append(w.values, v)
+	if len(w.values) > w.size {
+		w.values = w.values[len(w.values)-w.size:]
+	}
+}
+
+func (w *window[T]) reset() {
+	w.values = nil
+}
+
+// rate returns the ratio of true values.
+func rate(vs []bool) float64 {
+	if len(vs) == 0 {
+		return 0
+	}
+	n := 0
+	for _, v := range vs {
+		if v {
+			n++
+		}
+	}
+	return float64(n) / float64(len(vs))
+}
+
+// percentile returns the p-th percentile of sorted durations using the
+// nearest-rank method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,217 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/metrics"
+)
+
+func TestHealth_ErrorRate(t *testing.T) {
+	h := newHealth(&HealthCheckOptions{
+		Window:       10,
+		MinSamples:   4,
+		MaxErrorRate: 0.5,
+		EjectionTime: time.Hour,
+	}, null.New())
+
+	// Not enough samples to eject the endpoint.
+	for i := 0; i < 3; i++ {
+		h.recordCall("a", time.Millisecond, errors.New("error"))
+	}
+	assert.False(t, h.isEjected("a"))
+
+	h.recordCall("a", time.Millisecond, errors.New("error"))
+	assert.True(t, h.isEjected("a"))
+
+	// Other endpoints are not affected.
+	h.recordCall("b", time.Millisecond, nil)
+	assert.False(t, h.isEjected("b"))
+
+	s := h.status([]string{"b", "a"})
+	require.Len(t, s, 2)
+	assert.Equal(t, "a", s[0].Endpoint)
+	assert.True(t, s[0].Ejected)
+	assert.Equal(t, 1, s[0].Ejections)
+	assert.Equal(t, uint64(4), s[0].Calls)
+	assert.Equal(t, uint64(4), s[0].Errors)
+	assert.Equal(t, "b", s[1].Endpoint)
+	assert.False(t, s[1].Ejected)
+	assert.Equal(t, float64(1), s[1].LatencyP50)
+}
+
+func TestHealth_Probe(t *testing.T) {
+	h := newHealth(&HealthCheckOptions{
+		MinSamples:          2,
+		MaxDisagreementRate: 0.5,
+		EjectionTime:        time.Millisecond,
+	}, null.New())
+	probeErr := make(chan error, 1)
+	h.probe = func(name string) error {
+		assert.Equal(t, "a", name)
+		return <-probeErr
+	}
+
+	h.recordDisagreement("a", true)
+	h.recordDisagreement("a", true)
+	assert.True(t, h.isEjected("a"))
+
+	// After the ejection time, the endpoint is probed, and it is ejected
+	// until the probe finishes. If the probe fails, it is ejected again for
+	// a longer time.
+	time.Sleep(5 * time.Millisecond)
+	assert.True(t, h.isEjected("a"))
+	probeErr <- errors.New("error")
+	assert.Eventually(t, func() bool {
+		return h.status([]string{"a"})[0].Ejections == 2
+	}, time.Second, time.Millisecond)
+	assert.True(t, h.isEjected("a"))
+
+	// If the probe succeeds, the endpoint is used again with its stats
+	// cleared.
+	time.Sleep(5 * time.Millisecond)
+	probeErr <- nil
+	assert.Eventually(t, func() bool {
+		return !h.isEjected("a")
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, float64(0), h.status([]string{"a"})[0].DisagreementRate)
+
+	// If it is still unhealthy, it is ejected again for a longer time.
+	h.recordDisagreement("a", true)
+	h.recordDisagreement("a", true)
+	s := h.status([]string{"a"})[0]
+	assert.True(t, s.Ejected)
+	assert.Equal(t, 3, s.Ejections)
+}
+
+func Test_RPC_ProbeEjected(t *testing.T) {
+	c := &mockClient{t: t}
+	h, err := NewServer(
+		withCallers(map[string]caller{"a": c}),
+		WithRequirements(1, 10),
+		WithHealthCheck(HealthCheckOptions{MinSamples: 1, MaxErrorRate: 0.5, EjectionTime: time.Millisecond}),
+	)
+	require.NoError(t, err)
+	s := h.(*server)
+	s.health.recordCall("a", time.Millisecond, errors.New("error"))
+	time.Sleep(5 * time.Millisecond)
+
+	// The endpoint is re-admitted only after it responds to the probe.
+	c.mockCall("0x1", "eth_blockNumber")
+	assert.True(t, s.health.isEjected("a"))
+	assert.Eventually(t, func() bool {
+		return !s.health.isEjected("a")
+	}, time.Second, time.Millisecond)
+}
+
+func TestHealth_Metrics(t *testing.T) {
+	h := newHealth(&HealthCheckOptions{
+		MinSamples:   2,
+		MaxErrorRate: 0.4,
+		EjectionTime: time.Hour,
+	}, null.New())
+	h.client = "test_metrics"
+
+	endpoint := "https://rpc.example/v3/secret"
+	h.recordCall(endpoint, time.Millisecond, nil)
+	h.recordCall(endpoint, time.Millisecond, errors.New("error"))
+	h.recordBlocksBehind(endpoint, 3)
+
+	// API keys in URLs are not exposed.
+	labels := []string{"test_metrics", "https://rpc.example"}
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.RPCSplitterEndpointCalls.WithLabelValues(labels...)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.RPCSplitterEndpointErrors.WithLabelValues(labels...)))
+	assert.Equal(t, float64(3), testutil.ToFloat64(metrics.RPCSplitterEndpointBlocksBehind.WithLabelValues(labels...)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.RPCSplitterEndpointEjected.WithLabelValues(labels...)))
+}
+
+func TestHealth_Disabled(t *testing.T) {
+	h := newHealth(nil, null.New())
+	for i := 0; i < 100; i++ {
+		h.recordCall("a", time.Millisecond, errors.New("error"))
+	}
+	assert.False(t, h.isEjected("a"))
+	assert.Equal(t, float64(1), h.status([]string{"a"})[0].ErrorRate)
+}
+
+func TestHealth_Percentile(t *testing.T) {
+	var ls []time.Duration
+	for i := 1; i <= 100; i++ {
+		ls = append(ls, time.Duration(i))
+	}
+	assert.Equal(t, time.Duration(50), percentile(ls, 50))
+	assert.Equal(t, time.Duration(90), percentile(ls, 90))
+	assert.Equal(t, time.Duration(99), percentile(ls, 99))
+	assert.Equal(t, time.Duration(1), percentile(ls[:1], 99))
+}
+
+func TestServer_routeEjected(t *testing.T) {
+	s := &server{
+		callers:   map[string]caller{"a": nil, "b": nil, "c": nil},
+		endpoints: map[string]Endpoint{},
+		routes:    map[string]Route{"eth_call": {Method: "eth_call", Endpoints: 1}},
+		health:    newHealth(&HealthCheckOptions{MinSamples: 1, MaxErrorRate: 0.5, EjectionTime: time.Hour}, null.New()),
+	}
+	s.health.recordCall("a", time.Millisecond, errors.New("error"))
+
+	callers, _ := s.route("eth_chainId", 2)
+	assert.Len(t, callers, 2)
+	assert.NotContains(t, callers, "a")
+
+	for i := 0; i < 10; i++ {
+		callers, _ = s.route("eth_call", 2)
+		assert.Len(t, callers, 1)
+		assert.NotContains(t, callers, "a")
+	}
+
+	// If there are not enough healthy endpoints, ejected ones are used.
+	callers, _ = s.route("eth_chainId", 3)
+	assert.Len(t, callers, 3)
+}
+
+func Test_RPC_SplitterStatus(t *testing.T) {
+	prepareHandlerTest(t, 2, "splitter_status").
+		setOptions(WithRequirements(2, 10)).
+		expectedResult([]EndpointStatus{{Endpoint: "0"}, {Endpoint: "1"}}).
+		test()
+}
+
+func Test_RPC_Disagreement(t *testing.T) {
+	callers := map[string]caller{}
+	for i, res := range []string{"0x1", "0x1", "0x2"} {
+		c := &mockClient{t: t}
+		c.mockCall(res, "eth_chainId")
+		callers[fmt.Sprintf("%d", i)] = c
+	}
+	h, err := NewServer(withCallers(callers), WithRequirements(2, 10), WithGracefulTimeout(time.Second))
+	require.NoError(t, err)
+	s := h.(*server)
+	_, err = s.eth.ChainId()
+	require.NoError(t, err)
+	status := s.health.status([]string{"0", "1", "2"})
+	assert.Equal(t, float64(0), status[0].DisagreementRate)
+	assert.Equal(t, float64(0), status[1].DisagreementRate)
+	assert.Equal(t, float64(1), status[2].DisagreementRate)
+}
@@ -16,6 +16,9 @@
 package rpcsplitter
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	gethRPC "github.com/ethereum/go-ethereum/rpc"
@@ -27,6 +30,8 @@ type Option func(s *server) error
 
 // WithEndpoints options instructs RPC-Splitter to use provided list of
 // Ethereum RPC nodes.
+//
+// Subscriptions are supported only for WebSocket endpoints.
 func WithEndpoints(endpoints []string) Option {
 	return func(s *server) error {
 		for _, e := range endpoints {
@@ -34,8 +39,68 @@ func WithEndpoints(endpoints []string) Option {
 			if err != nil {
 				return err
 			}
-			s.callers[e] = c
+			s.callers[e] = rpcClient{Client: c}
+		}
+		return nil
+	}
+}
+
+// WithWeightedEndpoints works like WithEndpoints, but it allows to specify
+// weights and tags for endpoints. Weights and tags are used by routes
+// defined using the WithRoutes option.
+func WithWeightedEndpoints(endpoints []Endpoint) Option {
+	return func(s *server) error {
+		for _, e := range endpoints {
+			if e.Weight < 0 {
+				return fmt.Errorf("weight of the %s endpoint must not be negative", e.URL)
+			}
+			c, err := gethRPC.Dial(e.URL)
+			if err != nil {
+				return err
+			}
+			s.callers[e.URL] = rpcClient{Client: c}
+			s.endpoints[e.URL] = e
+		}
+		return nil
+	}
+}
+
+// WithRoutes defines to which endpoints calls to specific methods are sent.
+// Calls to methods without a route are sent to all endpoints.
+//
+// For example, a route for the "eth_call" method with the Endpoints field
+// set to 2 will send every eth_call request to only two endpoints, selected
+// randomly based on their weights.
+func WithRoutes(routes []Route) Option {
+	return func(s *server) error {
+		for _, r := range routes {
+			if r.Endpoints < 0 || r.MinResponses < 0 {
+				return fmt.Errorf("invalid route for the %s method", r.Method)
+			}
+			if r.Endpoints > 0 && r.MinResponses > r.Endpoints {
+				return fmt.Errorf("minimum number of responses for the %s method is greater than number of endpoints", r.Method)
+			}
+			s.routes[r.Method] = r
+		}
+		return nil
+	}
+}
+
+// WithCache enables a short-lived cache for responses. Only responses for
+// methods that refer to a specific block are cached, and because tags like
+// "latest" are resolved to block numbers, identical calls made within the
+// same block are sent to the endpoints only once.
+//
+// ttl - the time after which cached responses expire, it should be no
+// longer than the block time.
+//
+// size - the maximum number of cached responses.
+func WithCache(ttl time.Duration, size int) Option {
+	return func(s *server) error {
+		if ttl <= 0 || size <= 0 {
+			return errors.New("cache TTL and size must be positive")
 		}
+		s.cache = newCache(ttl, size)
 		return nil
 	}
 }
@@ -56,6 +121,7 @@ func WithRequirements(minResponses int, maxBlockBehind int) Option {
 	return func(s *server) error {
 		s.defaultResolver = &defaultResolver{minResponses: minResponses}
 		s.gasValueResolver = &gasValueResolver{minResponses: minResponses}
+		s.maxValueResolver = &maxValueResolver{minResponses: minResponses}
 		s.blockNumberResolver = &blockNumberResolver{minResponses: minResponses, maxBlocksBehind: maxBlockBehind}
 		return nil
 	}
@@ -82,6 +148,59 @@ func WithGracefulTimeout(t time.Duration) Option {
 	}
 }
 
+// WithHealthCheck enables temporary ejection of unhealthy endpoints. Health
+// stats are always collected and are available through the "splitter_status"
+// method, but endpoints are ejected only if this option is used.
+func WithHealthCheck(opts HealthCheckOptions) Option {
+	return func(s *server) error {
+		if opts.MaxErrorRate < 0 || opts.MaxErrorRate > 1 {
+			return errors.New("maximum error rate must be between 0 and 1")
+		}
+		if opts.MaxDisagreementRate < 0 || opts.MaxDisagreementRate > 1 {
+			return errors.New("maximum disagreement rate must be between 0 and 1")
+		}
+		s.healthCheck = &opts
+		return nil
+	}
+}
+
+// WithPassthrough sets the policy for calls to methods that are not
+// supported by RPC-Splitter. By default, such calls are rejected.
+func WithPassthrough(policy PassthroughPolicy) Option {
+	return func(s *server) error {
+		s.passthrough = policy
+		return nil
+	}
+}
+
+// WithPassthroughMethods sets the list of unsupported methods that are
+// forwarded to endpoints if the passthrough is enabled using the
+// WithPassthrough option. A method that ends with "*" matches all methods
+// with the same prefix, e.g. "eth_*".
+//
+// If not used, all methods except "admin_*", "personal_*" and "debug_*"
+// are forwarded.
+func WithPassthroughMethods(methods []string) Option {
+	return func(s *server) error {
+		for _, m := range methods {
+			if m == "" || strings.Contains(strings.TrimSuffix(m, "*"), "*") {
+				return fmt.Errorf("invalid passthrough method %q", m)
+			}
+		}
+		s.passthroughMethods = methods
+		return nil
+	}
+}
+
+// WithName sets the name of the RPC-Splitter, used to identify it in
+// metrics, e.g. the name of the Ethereum client that uses it.
+func WithName(name string) Option {
+	return func(s *server) error {
+		s.name = name
+		return nil
+	}
+}
+
 // WithLogger sets logger.
 func WithLogger(logger log.Logger) Option {
 	return func(s *server) error {
@@ -30,6 +30,13 @@ var errDifferentResponses = errors.New("RPC servers returned different responses
 // response.
 type resolver interface {
 	resolve([]any) (any, error)
+
+	// withMinResponses returns a copy of the resolver that requires the
+	// given minimum number of responses.
+	withMinResponses(n int) resolver
+
+	// minimumResponses returns the minimum number of required responses.
+	minimumResponses() int
 }
 
 // defaultResolver compares responses with each other and returns the most
@@ -73,6 +80,18 @@ func (r *defaultResolver) resolve(resps []any) (any, error) {
 	return mostCommonResp, nil
 }
 
+// withMinResponses implements resolver interface.
+func (r *defaultResolver) withMinResponses(n int) resolver {
+	cpy := *r
+	cpy.minResponses = n
+	return &cpy
+}
+
+// minimumResponses implements resolver interface.
+func (r *defaultResolver) minimumResponses() int {
+	return r.minResponses
+}
+
 // gasValueResolver is designed to handle responses from methods returning a
 // gas value. The way how the response is calculated depends on the number of
 // responses:
@@ -118,6 +137,18 @@ func (r *gasValueResolver) resolve(resps []any) (any, error) {
 	return ns[len(ns)/2], nil
 }
 
+// withMinResponses implements resolver interface.
+func (r *gasValueResolver) withMinResponses(n int) resolver {
+	cpy := *r
+	cpy.minResponses = n
+	return &cpy
+}
+
+// minimumResponses implements resolver interface.
+func (r *gasValueResolver) minimumResponses() int {
+	return r.minResponses
+}
+
 // blockNumberResolver is designed to handle responses from eth_blockNumber method.
 //
 // Because some RPC endpoints may be behind others, the blockNumberResolver
@@ -157,6 +188,78 @@ func (r *blockNumberResolver) resolve(resps []any) (any, error) {
 	return bigToNumberPtr(block), nil
 }
 
+// withMinResponses implements resolver interface.
+func (r *blockNumberResolver) withMinResponses(n int) resolver {
+	cpy := *r
+	cpy.minResponses = n
+	return &cpy
+}
+
+// minimumResponses implements resolver interface.
+func (r *blockNumberResolver) minimumResponses() int {
+	return r.minResponses
+}
+
+// maxValueResolver is designed to handle responses from methods returning a
+// fee value for which underestimation is more harmful than overestimation,
+// like eth_blobBaseFee. It returns the highest value from all responses.
+type maxValueResolver struct {
+	minResponses int // specifies minimum number of valid responses
+}
+
+// resolve implements resolver interface.
+func (r *maxValueResolver) resolve(resps []any) (any, error) {
+	resps, errs := extractErrors(resps)
+	ns := filterByNumberType(resps)
+	if len(ns) < r.minResponses || len(ns) == 0 {
+		return nil, addError(errNotEnoughResponses, errs...)
+	}
+	high := ns[0].Big()
+	for _, n := range ns[1:] {
+		if nb := n.Big(); nb.Cmp(high) > 0 {
+			high = nb
+		}
+	}
+	return bigToNumberPtr(high), nil
+}
+
+// withMinResponses implements resolver interface.
+func (r *maxValueResolver) withMinResponses(n int) resolver {
+	cpy := *r
+	cpy.minResponses = n
+	return &cpy
+}
+
+// minimumResponses implements resolver interface.
+func (r *maxValueResolver) minimumResponses() int {
+	return r.minResponses
+}
+
+// firstResolver returns the first successful response. It is designed for
+// methods whose responses cannot be reliably compared, like debug traces,
+// or that are not supported by all endpoints.
+type firstResolver struct{}
+
+// resolve implements resolver interface.
+func (r *firstResolver) resolve(resps []any) (any, error) {
+	resps, errs := extractErrors(resps)
+	if len(resps) == 0 {
+		return nil, addError(errNotEnoughResponses, errs...)
+	}
+	return resps[0], nil
+}
+
+// withMinResponses implements resolver interface. The firstResolver always
+// requires only one response.
+func (r *firstResolver) withMinResponses(int) resolver {
+	return r
+}
+
+// minimumResponses implements resolver interface.
+func (r *firstResolver) minimumResponses() int {
+	return 1
+}
+
 func extractErrors(resps []any) (filtered []any, errs []error) {
 	for _, r := range resps {
 		if e, ok := r.(error); ok {
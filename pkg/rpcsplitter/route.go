@@ -0,0 +1,153 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Endpoint describes an Ethereum RPC endpoint used by the RPC-Splitter.
+type Endpoint struct {
+	// URL is the URL of the endpoint.
+	URL string
+
+	// Weight is the relative weight of the endpoint. Endpoints with higher
+	// weights are more likely to be selected for methods that are routed
+	// to only some of the endpoints. If zero, the weight is 1.
+	Weight int
+
+	// Tags are arbitrary labels, like "archive", "fast" or "cheap", that can
+	// be used in routes to select endpoints.
+	Tags []string
+}
+
+// Route describes to which endpoints calls to a given method are sent.
+type Route struct {
+	// Method is the name of the RPC method, e.g. "eth_call".
+	Method string
+
+	// Endpoints is the number of endpoints to which the call is sent. The
+	// endpoints are selected randomly, based on their weights. If zero, or
+	// greater than the number of matching endpoints, all matching endpoints
+	// are used.
+	Endpoints int
+
+	// Tags limits the route to endpoints that have all the given tags.
+	Tags []string
+
+	// MinResponses is the minimum number of valid responses required for
+	// the method. If zero, the number from the WithRequirements option is
+	// used, but no more than the number of selected endpoints.
+	MinResponses int
+}
+
+// hasTags reports whether the endpoint has all the given tags.
+func (e Endpoint) hasTags(tags []string) bool {
+	for _, t := range tags {
+		found := false
+		for _, et := range e.Tags {
+			if et == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// weight returns the weight of the endpoint.
+func (e Endpoint) weight() int {
+	if e.Weight <= 0 {
+		return 1
+	}
+	return e.Weight
+}
+
+// route returns callers to which the given method should be sent and
+// the minimum number of responses required.
+//
+// Ejected endpoints are skipped, unless there would not be enough remaining
+// endpoints to meet the minimum number of responses.
+func (s *server) route(method string, minResponses int) (map[string]caller, int) {
+	r, routed := s.routes[method]
+	var names []string
+	for n := range s.callers {
+		if !routed || s.endpoint(n).hasTags(r.Tags) {
+			names = append(names, n)
+		}
+	}
+	required := minResponses
+	if routed && r.Endpoints > 0 && r.Endpoints < required {
+		required = r.Endpoints
+	}
+	if routed && r.MinResponses > 0 {
+		required = r.MinResponses
+	}
+	var healthy []string
+	for _, n := range names {
+		if !s.health.isEjected(n) {
+			healthy = append(healthy, n)
+		}
+	}
+	if len(healthy) >= required {
+		names = healthy
+	}
+	if routed && r.Endpoints > 0 && r.Endpoints < len(names) {
+		names = s.selectEndpoints(names, r.Endpoints)
+	}
+	callers := make(map[string]caller, len(names))
+	for _, n := range names {
+		callers[n] = s.callers[n]
+	}
+	switch {
+	case routed && r.MinResponses > 0:
+		minResponses = r.MinResponses
+	case minResponses > len(callers):
+		minResponses = len(callers)
+	}
+	return callers, minResponses
+}
+
+// endpoint returns the endpoint with the given name. If the endpoint was
+// not configured using the WithWeightedEndpoints option, an endpoint with
+// the default weight and no tags is returned.
+func (s *server) endpoint(name string) Endpoint {
+	if e, ok := s.endpoints[name]; ok {
+		return e
+	}
+	return Endpoint{URL: name}
+}
+
+// selectEndpoints randomly selects n of the given endpoints, without
+// replacement, with probability proportional to their weights.
+func (s *server) selectEndpoints(names []string, n int) []string {
+	// Weighted random sampling by Efraimidis and Spirakis: every endpoint
+	// gets a key u^(1/w), where u is a random number from (0, 1], and the
+	// endpoints with the largest keys are selected.
+	keys := make(map[string]float64, len(names))
+	for _, name := range names {
+		keys[name] = math.Pow(1-rand.Float64(), 1/float64(s.endpoint(name).weight())) //nolint:gosec
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return keys[names[i]] > keys[names[j]]
+	})
+	return names[:n]
+}
@@ -0,0 +1,120 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
+	"github.com/chronicleprotocol/oracle-suite/pkg/rpcsplitter/types"
+)
+
+func withEndpointTags(name string, weight int, tags ...string) Option {
+	return func(s *server) error {
+		s.endpoints[name] = Endpoint{URL: name, Weight: weight, Tags: tags}
+		return nil
+	}
+}
+
+func Test_RPC_Route(t *testing.T) {
+	address := types.HexToAddress("0xb59f67a8bff5d8cd03f6ac17265c550ed8f33907")
+	balance := types.HexToNumber("0x100000000000")
+	blockNumber := types.StringToBlockNumber("0x10")
+	t.Run("tags", func(t *testing.T) {
+		prepareHandlerTest(t, 3, "eth_getBalance", address, blockNumber).
+			setOptions(
+				WithRequirements(2, 10),
+				WithRoutes([]Route{{Method: "eth_getBalance", Tags: []string{"archive"}}}),
+				withEndpointTags("1", 1, "archive", "fast"),
+			).
+			mockClientCall(1, balance, "eth_getBalance", address, blockNumber).
+			expectedResult(balance).
+			test()
+	})
+	t.Run("no-matching-endpoints", func(t *testing.T) {
+		prepareHandlerTest(t, 3, "eth_getBalance", address, blockNumber).
+			setOptions(
+				WithRequirements(2, 10),
+				WithRoutes([]Route{{Method: "eth_getBalance", Tags: []string{"archive"}}}),
+			).
+			expectedError("no endpoints available").
+			test()
+	})
+	t.Run("min-responses", func(t *testing.T) {
+		prepareHandlerTest(t, 3, "eth_getBalance", address, blockNumber).
+			setOptions(
+				WithRequirements(1, 10),
+				WithRoutes([]Route{{Method: "eth_getBalance", Tags: []string{"archive"}, MinResponses: 2}}),
+				withEndpointTags("0", 1, "archive"),
+				withEndpointTags("2", 1, "archive"),
+			).
+			mockClientCall(0, balance, "eth_getBalance", address, blockNumber).
+			mockClientCall(2, errors.New("error"), "eth_getBalance", address, blockNumber).
+			expectedError("not enough responses").
+			test()
+	})
+}
+
+func TestServer_route(t *testing.T) {
+	s := &server{
+		callers:   map[string]caller{"a": nil, "b": nil, "c": nil, "d": nil},
+		endpoints: map[string]Endpoint{"a": {URL: "a", Tags: []string{"cheap"}}},
+		routes: map[string]Route{
+			"eth_call":               {Method: "eth_call", Endpoints: 2},
+			"eth_getLogs":            {Method: "eth_getLogs", Endpoints: 2, Tags: []string{"cheap"}},
+			"eth_sendRawTransaction": {Method: "eth_sendRawTransaction"},
+		},
+		health: newHealth(nil, null.New()),
+	}
+	callers, minResponses := s.route("eth_call", 3)
+	assert.Len(t, callers, 2)
+	assert.Equal(t, 2, minResponses)
+
+	callers, minResponses = s.route("eth_getLogs", 3)
+	assert.Len(t, callers, 1)
+	assert.Contains(t, callers, "a")
+	assert.Equal(t, 1, minResponses)
+
+	callers, minResponses = s.route("eth_sendRawTransaction", 3)
+	assert.Len(t, callers, 4)
+	assert.Equal(t, 3, minResponses)
+
+	callers, minResponses = s.route("eth_chainId", 3)
+	assert.Len(t, callers, 4)
+	assert.Equal(t, 3, minResponses)
+}
+
+func TestServer_selectEndpoints(t *testing.T) {
+	s := &server{
+		endpoints: map[string]Endpoint{
+			"heavy": {URL: "heavy", Weight: 1000},
+			"light": {URL: "light", Weight: 1},
+		},
+	}
+	heavy := 0
+	for i := 0; i < 100; i++ {
+		names := s.selectEndpoints([]string{"light", "heavy"}, 1)
+		require.Len(t, names, 1)
+		if names[0] == "heavy" {
+			heavy++
+		}
+	}
+	assert.Greater(t, heavy, 90)
+}
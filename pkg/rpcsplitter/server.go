@@ -16,12 +16,16 @@
 package rpcsplitter
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math/big"
 	"net/http"
 	"reflect"
+	"strings"
 	"time"
 
 	gethRPC "github.com/ethereum/go-ethereum/rpc"
@@ -42,13 +46,34 @@ type caller interface {
 
 // server is an RPC proxy server. It merges multiple RPC endpoints into one.
 type server struct {
-	rpc *gethRPC.Server // rpc is an RPC server.
-	eth *rpcETHAPI      // eth implements procedures with the "eth_" prefix.
-	net *rpcNETAPI      // net implements procedures with the "net_" prefix.
-	log log.Logger
-
+	rpc      *gethRPC.Server // rpc is an RPC server.
+	inproc   *gethRPC.Client // inproc is an in-process client connected to rpc.
+	eth      *rpcETHAPI      // eth implements procedures with the "eth_" prefix.
+	net      *rpcNETAPI      // net implements procedures with the "net_" prefix.
+	debug    *rpcDebugAPI    // debug implements procedures with the "debug_" prefix.
+	splitter *rpcSplitterAPI // splitter implements procedures with the "splitter_" prefix.
+	log      log.Logger
+
+	// Name used in metrics.
+	name string
 	// List of endpoint callers.
 	callers map[string]caller
+	// Weights and tags of endpoints, indexed by caller name.
+	endpoints map[string]Endpoint
+	// Routing rules, indexed by method name.
+	routes map[string]Route
+	// Cache for resolved responses, nil if disabled.
+	cache *cache
+	// Health of endpoints.
+	health *health
+	// Health check options, nil if ejection of unhealthy endpoints is
+	// disabled.
+	healthCheck *HealthCheckOptions
+	// Policy for calls to unsupported methods.
+	passthrough PassthroughPolicy
+	// Unsupported methods that can be forwarded, if empty, all methods
+	// except defaultPassthroughDenied can be forwarded.
+	passthroughMethods []string
 	// Total timeout for all endpoints.
 	totalTimeout time.Duration
 	// Timeout for slower endpoints, when it exceeds, request will be canceled
@@ -58,7 +83,9 @@ type server struct {
 	// Resolvers used to convert multiple responses into a single response:
 	defaultResolver     *defaultResolver
 	gasValueResolver    *gasValueResolver
+	maxValueResolver    *maxValueResolver
 	blockNumberResolver *blockNumberResolver
+	firstResolver       *firstResolver
 }
 
 type rpcETHAPI struct {
@@ -69,21 +96,42 @@ type rpcNETAPI struct {
 	handler *server
 }
 
+type rpcDebugAPI struct {
+	handler *server
+}
+
+type rpcSplitterAPI struct {
+	handler *server
+}
+
 func NewServer(opts ...Option) (http.Handler, error) {
 	h := &server{
-		rpc:     gethRPC.NewServer(),
-		callers: map[string]caller{},
+		rpc:           gethRPC.NewServer(),
+		callers:       map[string]caller{},
+		endpoints:     map[string]Endpoint{},
+		routes:        map[string]Route{},
+		firstResolver: &firstResolver{},
 	}
 	eth := &rpcETHAPI{handler: h}
 	net := &rpcNETAPI{handler: h}
+	debug := &rpcDebugAPI{handler: h}
+	splitter := &rpcSplitterAPI{handler: h}
 	h.eth = eth
 	h.net = net
+	h.debug = debug
+	h.splitter = splitter
 	if err := h.rpc.RegisterName("eth", eth); err != nil {
 		return nil, err
 	}
 	if err := h.rpc.RegisterName("net", net); err != nil {
 		return nil, err
 	}
+	if err := h.rpc.RegisterName("debug", debug); err != nil {
+		return nil, err
+	}
+	if err := h.rpc.RegisterName("splitter", splitter); err != nil {
+		return nil, err
+	}
 	for _, opt := range opts {
 		err := opt(h)
 		if err != nil {
@@ -96,7 +144,7 @@ func NewServer(opts ...Option) (http.Handler, error) {
 	if h.callers == nil {
 		return nil, fmt.Errorf("rpc-splitter error: WithEndpoints option is required")
 	}
-	if h.defaultResolver == nil || h.gasValueResolver == nil || h.blockNumberResolver == nil {
+	if h.defaultResolver == nil || h.gasValueResolver == nil || h.maxValueResolver == nil || h.blockNumberResolver == nil {
 		return nil, fmt.Errorf("rpc-splitter error: WithRequirements option is required")
 	}
 	if h.totalTimeout == 0 {
@@ -106,13 +154,58 @@ func NewServer(opts ...Option) (http.Handler, error) {
 		h.gracefulTimeout = defaultGracefulTimeout
 	}
 	h.log = h.log.WithField("tag", LoggerTag)
+	h.health = newHealth(h.healthCheck, h.log)
+	h.health.probe = h.probe
+	h.health.client = h.name
+	h.inproc = gethRPC.DialInProc(h.rpc)
 	return h, nil
 }
 
+// ServeHTTP implements the http.Handler interface. WebSocket connections,
+// which are required for subscriptions, are served on the same endpoint.
 func (s *server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if isWebsocket(req) {
+		s.rpc.WebsocketHandler(nil).ServeHTTP(rw, req)
+		return
+	}
+	if req.Method == http.MethodPost && req.Body != nil {
+		body, err := io.ReadAll(io.LimitReader(req.Body, maxRequestSize+1))
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(body) <= maxRequestSize && s.serveDispatch(rw, req, body) {
+			return
+		}
+		req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), req.Body))
+	}
 	s.rpc.ServeHTTP(rw, req)
 }
 
+// probe checks if the endpoint responds to the "eth_blockNumber" call. It is
+// used to check ejected endpoints before they are used again.
+func (s *server) probe(name string) error {
+	c, ok := s.callers[name]
+	if !ok {
+		return fmt.Errorf("unknown endpoint: %s", name)
+	}
+	ctx, ctxCancel := context.WithTimeout(context.Background(), s.totalTimeout)
+	defer ctxCancel()
+	var res types.Number
+	return c.CallContext(ctx, &res, "eth_blockNumber")
+}
+
+// close closes the in-process client and connections to the endpoints.
+func (s *server) close() {
+	s.inproc.Close()
+	s.rpc.Stop()
+	for _, c := range s.callers {
+		if c, ok := c.(rpcClient); ok {
+			c.Close()
+		}
+	}
+}
+
 // BlockNumber implements the "eth_blockNumber" call.
 //
 // It returns the most common response that occurred at least as many times as
@@ -162,7 +255,7 @@ func (r *rpcETHAPI) GetBlockByNumber(blockNumber types.Number, obj bool) (any, e
 	case false:
 		res = &types.BlockTxHashes{}
 	}
-	err := r.handler.call(ctx, r.handler.defaultResolver, res, "eth_getBlockByNumber", blockNumber, obj)
+	err := r.handler.cachedCall(ctx, types.BlockNumber(blockNumber), r.handler.defaultResolver, res, "eth_getBlockByNumber", blockNumber, obj)
 
 	return res, err
 }
@@ -198,7 +291,7 @@ func (r *rpcETHAPI) GetTransactionCount(addr types.Address, blockID types.BlockN
 		return nil, err
 	}
 	res := &types.Number{}
-	err = r.handler.call(ctx, r.handler.defaultResolver, res, "eth_getTransactionCount", addr, blockNumber)
+	err = r.handler.cachedCall(ctx, blockNumber, r.handler.defaultResolver, res, "eth_getTransactionCount", addr, blockNumber)
 
 	return res, err
 }
@@ -252,7 +345,7 @@ func (r *rpcETHAPI) GetBalance(addr types.Address, blockID types.BlockNumber) (a
 		return nil, err
 	}
 	res := &types.Number{}
-	err = r.handler.call(ctx, r.handler.defaultResolver, res, "eth_getBalance", addr, blockNumber)
+	err = r.handler.cachedCall(ctx, blockNumber, r.handler.defaultResolver, res, "eth_getBalance", addr, blockNumber)
 
 	return res, err
 }
@@ -274,7 +367,7 @@ func (r *rpcETHAPI) GetCode(addr types.Address, blockID types.BlockNumber) (any,
 		return nil, err
 	}
 	res := &types.Bytes{}
-	err = r.handler.call(ctx, r.handler.defaultResolver, res, "eth_getCode", addr, blockNumber)
+	err = r.handler.cachedCall(ctx, blockNumber, r.handler.defaultResolver, res, "eth_getCode", addr, blockNumber)
 
 	return res, err
 }
@@ -296,13 +389,56 @@ func (r *rpcETHAPI) GetStorageAt(data types.Address, pos types.Number, blockID t
 		return nil, err
 	}
 	res := &types.Hash{}
-	err = r.handler.call(ctx, r.handler.defaultResolver, res, "eth_getStorageAt", data, pos, blockNumber)
+	err = r.handler.cachedCall(ctx, blockNumber, r.handler.defaultResolver, res, "eth_getStorageAt", data, pos, blockNumber)
+
+	return res, err
+}
+
+// GetBlockReceipts implements the "eth_getBlockReceipts" call.
+//
+// It returns the most common response that occurred at least as many times as
+// specified in the minRes method.
+//
+// If the block number is set to "latest" or "pending", it will be replaced by
+// the block number returned by the BlockNumber method. The "earliest" tag is
+// not supported.
+func (r *rpcETHAPI) GetBlockReceipts(blockID types.BlockNumber) (any, error) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+	defer ctxCancel()
+
+	blockNumber, err := r.handler.taggedBlockToNumber(ctx, blockID)
+	if err != nil {
+		return nil, err
+	}
+	res := &[]types.TransactionReceiptType{}
+	err = r.handler.cachedCall(ctx, blockNumber, r.handler.defaultResolver, res, "eth_getBlockReceipts", blockNumber)
 
 	return res, err
 }
 
 // TODO: eth_accounts
-// TODO: eth_getProof
+
+// GetProof implements the "eth_getProof" call.
+//
+// It returns the most common response that occurred at least as many times as
+// specified in the minRes method.
+//
+// If the block number is set to "latest" or "pending", it will be replaced by
+// the block number returned by the BlockNumber method. The "earliest" tag is
+// not supported.
+func (r *rpcETHAPI) GetProof(addr types.Address, keys []types.Hash, blockID types.BlockNumber) (any, error) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+	defer ctxCancel()
+
+	blockNumber, err := r.handler.taggedBlockToNumber(ctx, blockID)
+	if err != nil {
+		return nil, err
+	}
+	res := new(any)
+	err = r.handler.cachedCall(ctx, blockNumber, r.handler.defaultResolver, res, "eth_getProof", addr, keys, blockNumber)
+
+	return res, err
+}
 
 // Call implements the "eth_call" call.
 //
@@ -321,7 +457,7 @@ func (r *rpcETHAPI) Call(args Any, blockID types.BlockNumber, overrides *Any) (a
 		return nil, err
 	}
 	res := &types.Bytes{}
-	err = r.handler.call(ctx, r.handler.defaultResolver, res, "eth_call", args, blockNumber, overrides)
+	err = r.handler.cachedCall(ctx, blockNumber, r.handler.defaultResolver, res, "eth_call", args, blockNumber, overrides)
 
 	return res, err
 }
@@ -352,8 +488,13 @@ func (r *rpcETHAPI) GetLogs(logFilter types.FilterLogsQuery) (any, error) {
 		}
 		*logFilter.ToBlock = blockNumber
 	}
+	// Logs can be cached only if the block range is closed.
+	blockNumber := types.LatestBlockNumber
+	if logFilter.FromBlock != nil && logFilter.ToBlock != nil {
+		blockNumber = *logFilter.ToBlock
+	}
 	res := &[]types.Log{}
-	err := r.handler.call(ctx, r.handler.defaultResolver, res, "eth_getLogs", logFilter)
+	err := r.handler.cachedCall(ctx, blockNumber, r.handler.defaultResolver, res, "eth_getLogs", logFilter)
 
 	return res, err
 }
@@ -409,7 +550,46 @@ func (r *rpcETHAPI) FeeHistory(count types.Number, newestBlockID types.BlockNumb
 		return nil, err
 	}
 	res := &types.FeeHistory{}
-	err = r.handler.call(ctx, r.handler.defaultResolver, res, "eth_feeHistory", count, blockNumber, percentiles)
+	err = r.handler.cachedCall(ctx, blockNumber, r.handler.defaultResolver, res, "eth_feeHistory", count, blockNumber, percentiles)
+
+	return res, err
+}
+
+// CreateAccessList implements the "eth_createAccessList" call.
+//
+// It returns the most common response that occurred at least as many times as
+// specified in the minRes method.
+//
+// If the block number is not set, or set to "latest" or "pending", it will be
+// replaced by the block number returned by the BlockNumber method. The
+// "earliest" tag is not supported.
+func (r *rpcETHAPI) CreateAccessList(args Any, blockID *types.BlockNumber) (any, error) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+	defer ctxCancel()
+
+	if blockID == nil {
+		blockID = &types.LatestBlockNumber
+	}
+	blockNumber, err := r.handler.taggedBlockToNumber(ctx, *blockID)
+	if err != nil {
+		return nil, err
+	}
+	res := new(any)
+	err = r.handler.cachedCall(ctx, blockNumber, r.handler.defaultResolver, res, "eth_createAccessList", args, blockNumber)
+
+	return res, err
+}
+
+// BlobBaseFee implements the "eth_blobBaseFee" call.
+//
+// The number returned by this method is the highest of all numbers returned
+// by the endpoints, so that blob transactions are not underpriced.
+func (r *rpcETHAPI) BlobBaseFee() (any, error) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+	defer ctxCancel()
+
+	res := &types.Number{}
+	err := r.handler.call(ctx, r.handler.maxValueResolver, res, "eth_blobBaseFee")
 
 	return res, err
 }
@@ -467,6 +647,40 @@ func (r *rpcNETAPI) Version() (any, error) {
 	return res, err
 }
 
+// TraceCall implements the "debug_traceCall" call.
+//
+// It returns the first successful response, because traces returned by
+// different node implementations cannot be reliably compared and not all
+// endpoints support the debug namespace.
+//
+// If the block number is set to "latest" or "pending", it will be replaced by
+// the block number returned by the BlockNumber method. The "earliest" tag is
+// not supported.
+func (r *rpcDebugAPI) TraceCall(args Any, blockID types.BlockNumber, config *Any) (any, error) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), r.handler.totalTimeout)
+	defer ctxCancel()
+
+	blockNumber, err := r.handler.taggedBlockToNumber(ctx, blockID)
+	if err != nil {
+		return nil, err
+	}
+	res := new(any)
+	err = r.handler.cachedCall(ctx, blockNumber, r.handler.firstResolver, res, "debug_traceCall", args, blockNumber, config)
+
+	return res, err
+}
+
+// Status implements the "splitter_status" call.
+//
+// It returns health stats for all endpoints.
+func (r *rpcSplitterAPI) Status() (any, error) {
+	names := make([]string, 0, len(r.handler.callers))
+	for n := range r.handler.callers {
+		names = append(names, n)
+	}
+	return r.handler.health.status(names), nil
+}
+
 // taggedBlockToNumber returns a block number for tagged blocks. This is
 // necessary because different RPC endpoints may convert tags to different
 // block numbers.
@@ -491,9 +705,45 @@ func (s *server) taggedBlockToNumber(ctx context.Context, blockID types.BlockNum
 	return types.BlockNumber(*res), nil
 }
 
-// call executes RPC on all endpoints with the given arguments. If the context is
+// cachedCall works like call, but if the cache is enabled, the result is
+// cached, so identical calls are not sent to the endpoints again. The result
+// is cached only if the block number is not a tag, otherwise the response
+// could change while being cached.
+func (s *server) cachedCall(
+	ctx context.Context,
+	blockNumber types.BlockNumber,
+	resolver resolver,
+	result any,
+	method string,
+	args ...any,
+) error {
+
+	if s.cache == nil || blockNumber.IsTag() {
+		return s.call(ctx, resolver, result, method, args...)
+	}
+	key := s.cache.key(method, args)
+	if key == "" {
+		return s.call(ctx, resolver, result, method, args...)
+	}
+	if res, ok := s.cache.get(key); ok {
+		if reflect.TypeOf(res) == reflect.TypeOf(result) {
+			reflect.ValueOf(result).Elem().Set(reflect.ValueOf(res).Elem())
+			return nil
+		}
+	}
+	if err := s.call(ctx, resolver, result, method, args...); err != nil {
+		return err
+	}
+	s.cache.set(key, result)
+	return nil
+}
+
+// call executes RPC on endpoints with the given arguments. If the context is
 // canceled before the call has successfully returned, call returns immediately.
 //
+// If a route is defined for the method, the call is sent only to endpoints
+// selected by the route, otherwise it is sent to all endpoints.
+//
 // The result must be a pointer with a proper type.
 //
 //nolint:funlen
@@ -520,10 +770,19 @@ func (s *server) call(
 		}
 	}()
 
-	// Send request to all endpoints.
-	ch := make(chan any, len(s.callers))
+	// Select endpoints.
+	callers, minResponses := s.route(method, resolver.minimumResponses())
+	if len(callers) == 0 {
+		return fmt.Errorf("no endpoints available for the %s method", method)
+	}
+	if minResponses != resolver.minimumResponses() {
+		resolver = resolver.withMinResponses(minResponses)
+	}
+
+	// Send request to selected endpoints.
+	ch := make(chan callResponse, len(callers))
 	rt := reflect.TypeOf(result).Elem()
-	for n, c := range s.callers {
+	for n, c := range callers {
 		n, c := n, c
 		go func() {
 			t := time.Now()
@@ -533,6 +792,11 @@ func (s *server) call(
 				if r := recover(); r != nil {
 					err = fmt.Errorf("panic: %s", r)
 				}
+				// Calls canceled after the response was resolved are not
+				// the endpoint's fault.
+				if !errors.Is(ctx.Err(), context.Canceled) {
+					s.health.recordCall(n, time.Since(t), err)
+				}
 				switch {
 				case err != nil:
 					s.log.
@@ -542,7 +806,7 @@ func (s *server) call(
 						WithField("duration", time.Since(t)).
 						WithError(err).
 						Debug("Call error")
-					ch <- err
+					ch <- callResponse{name: n, res: err}
 				default:
 					s.log.
 						WithField("name", n).
@@ -550,7 +814,7 @@ func (s *server) call(
 						// WithField("args", args).
 						WithField("duration", time.Since(t)).
 						Debug("Call")
-					ch <- res
+					ch <- callResponse{name: n, res: res}
 				}
 			}()
 			res = reflect.New(rt).Interface()
@@ -563,31 +827,82 @@ func (s *server) call(
 	// and the response returned.
 	t := time.NewTimer(s.gracefulTimeout)
 	defer t.Stop()
-	var rs []any
+	var (
+		rs    []any
+		names []string
+	)
 	for {
 		wait := true
 		select {
 		case r := <-ch:
-			rs = append(rs, r)
+			rs = append(rs, r.res)
+			names = append(names, r.name)
 		case <-t.C:
 			wait = false
 		}
-		if len(rs) == len(s.callers) {
+		if len(rs) == len(callers) {
+			wait = false
+		}
+		if _, ok := resolver.(*firstResolver); ok {
+			// There is no need to wait for other responses.
 			wait = false
 		}
 		if !wait {
 			res, err := resolver.resolve(rs)
 			switch {
 			case err == nil:
+				s.recordResolved(resolver, names, rs, res)
 				reflect.ValueOf(result).Elem().Set(reflect.ValueOf(res).Elem())
 				return nil
-			case len(rs) >= len(s.callers):
+			case len(rs) >= len(callers):
 				return err
 			}
 		}
 	}
 }
 
+// callResponse is a response, or an error, from a single endpoint.
+type callResponse struct {
+	name string
+	res  any
+}
+
+// recordResolved compares responses from endpoints with the resolved
+// response and updates the health of the endpoints.
+func (s *server) recordResolved(resolver resolver, names []string, rs []any, resolved any) {
+	switch r := resolver.(type) {
+	case *defaultResolver:
+		for i, res := range rs {
+			if _, ok := res.(error); ok {
+				continue
+			}
+			s.health.recordDisagreement(names[i], !compare(res, resolved))
+		}
+	case *blockNumberResolver:
+		var high *big.Int
+		for _, res := range rs {
+			if n, ok := res.(*types.Number); ok && (high == nil || n.Big().Cmp(high) > 0) {
+				high = n.Big()
+			}
+		}
+		for i, res := range rs {
+			n, ok := res.(*types.Number)
+			if !ok {
+				continue
+			}
+			behind := new(big.Int).Sub(high, n.Big()).Int64()
+			s.health.recordBlocksBehind(names[i], behind)
+			s.health.recordDisagreement(names[i], behind > int64(r.maxBlocksBehind))
+		}
+	}
+}
+
+// isWebsocket reports whether the request is a WebSocket upgrade request.
+func isWebsocket(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(req.Header.Get("Connection")), "upgrade")
+}
+
 // removeTrailingNilArgs removes trailing nil parameters from the params
 // slice. Some RPC servers do not like null parameters and will return a
 // "bad request" error if they occur.
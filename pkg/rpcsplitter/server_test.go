@@ -837,15 +837,6 @@ func Test_RPC_Accounts(t *testing.T) {
 	})
 }
 
-func Test_RPC_GetProof(t *testing.T) {
-	t.Run("simple", func(t *testing.T) {
-		prepareHandlerTest(t, 3, "eth_getProof").
-			setOptions(WithRequirements(1, 10)).
-			expectedError("the method eth_getProof does not exist").
-			test()
-	})
-}
-
 func Test_RPC_Call(t *testing.T) {
 	call := newAny(`
 		{
@@ -1390,6 +1381,115 @@ func Test_RPC_Timeout(t *testing.T) {
 	})
 }
 
+func Test_RPC_GetBlockReceipts(t *testing.T) {
+	blockNumber := types.StringToBlockNumber("0x10")
+	receipts := json.RawMessage("[" + string(transactionReceipt1Resp) + "]")
+	t.Run("simple", func(t *testing.T) {
+		prepareHandlerTest(t, 3, "eth_getBlockReceipts", blockNumber).
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, receipts, "eth_getBlockReceipts", blockNumber).
+			mockClientCall(1, receipts, "eth_getBlockReceipts", blockNumber).
+			mockClientCall(2, errors.New("error#1"), "eth_getBlockReceipts", blockNumber).
+			expectedResult(receipts).
+			test()
+	})
+}
+
+func Test_RPC_GetProof(t *testing.T) {
+	address := types.HexToAddress("0xb59f67a8bff5d8cd03f6ac17265c550ed8f33907")
+	keys := []types.Hash{types.HexToHash("0x0000000000000000000000000000000000000000000000000000000000000001")}
+	blockNumber := types.StringToBlockNumber("0x10")
+	proof1 := json.RawMessage(`{"address":"0xb59f67a8bff5d8cd03f6ac17265c550ed8f33907","balance":"0x1","storageProof":[]}`)
+	proof2 := json.RawMessage(`{"address":"0xb59f67a8bff5d8cd03f6ac17265c550ed8f33907","balance":"0x2","storageProof":[]}`)
+	t.Run("simple", func(t *testing.T) {
+		prepareHandlerTest(t, 3, "eth_getProof", address, keys, blockNumber).
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, proof1, "eth_getProof", address, keys, blockNumber).
+			mockClientCall(1, proof1, "eth_getProof", address, keys, blockNumber).
+			mockClientCall(2, proof2, "eth_getProof", address, keys, blockNumber).
+			expectedResult(proof1).
+			test()
+	})
+	t.Run("different-responses", func(t *testing.T) {
+		prepareHandlerTest(t, 2, "eth_getProof", address, keys, blockNumber).
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, proof1, "eth_getProof", address, keys, blockNumber).
+			mockClientCall(1, proof2, "eth_getProof", address, keys, blockNumber).
+			expectedError("RPC servers returned different responses").
+			test()
+	})
+}
+
+func Test_RPC_CreateAccessList(t *testing.T) {
+	call := newAny(`{"to":"0xd46e8dd67c5d32be8058bb8eb970870f07244567","data":"0x01"}`)
+	blockNumber := types.StringToBlockNumber("0x10")
+	list := json.RawMessage(`{"accessList":[],"gasUsed":"0x5208"}`)
+	t.Run("simple", func(t *testing.T) {
+		prepareHandlerTest(t, 3, "eth_createAccessList", call, blockNumber).
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, list, "eth_createAccessList", call, blockNumber).
+			mockClientCall(1, list, "eth_createAccessList", call, blockNumber).
+			mockClientCall(2, list, "eth_createAccessList", call, blockNumber).
+			expectedResult(list).
+			test()
+	})
+	t.Run("default-block", func(t *testing.T) {
+		prepareHandlerTest(t, 2, "eth_createAccessList", call).
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, `0x10`, "eth_blockNumber").
+			mockClientCall(1, `0x10`, "eth_blockNumber").
+			mockClientCall(0, list, "eth_createAccessList", call, blockNumber).
+			mockClientCall(1, list, "eth_createAccessList", call, blockNumber).
+			expectedResult(list).
+			test()
+	})
+}
+
+func Test_RPC_BlobBaseFee(t *testing.T) {
+	t.Run("three-responses", func(t *testing.T) {
+		prepareHandlerTest(t, 3, "eth_blobBaseFee").
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, `0x1`, "eth_blobBaseFee").
+			mockClientCall(1, `0x5`, "eth_blobBaseFee").
+			mockClientCall(2, `0x3`, "eth_blobBaseFee").
+			expectedResult(`0x5`).
+			test()
+	})
+	t.Run("two-failed", func(t *testing.T) {
+		prepareHandlerTest(t, 3, "eth_blobBaseFee").
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, `0x1`, "eth_blobBaseFee").
+			mockClientCall(1, errors.New("error#1"), "eth_blobBaseFee").
+			mockClientCall(2, errors.New("error#2"), "eth_blobBaseFee").
+			expectedError("error#1").
+			expectedError("error#2").
+			test()
+	})
+}
+
+func Test_RPC_TraceCall(t *testing.T) {
+	call := newAny(`{"to":"0xd46e8dd67c5d32be8058bb8eb970870f07244567","data":"0x01"}`)
+	blockNumber := types.StringToBlockNumber("0x10")
+	trace := json.RawMessage(`{"gas":21000,"failed":false,"returnValue":"","structLogs":[]}`)
+	t.Run("first-success", func(t *testing.T) {
+		prepareHandlerTest(t, 2, "debug_traceCall", call, blockNumber).
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, errors.New("method not found"), "debug_traceCall", call, blockNumber).
+			mockClientCall(1, trace, "debug_traceCall", call, blockNumber).
+			expectedResult(trace).
+			test()
+	})
+	t.Run("all-failed", func(t *testing.T) {
+		prepareHandlerTest(t, 2, "debug_traceCall", call, blockNumber).
+			setOptions(WithRequirements(2, 10)).
+			mockClientCall(0, errors.New("error#1"), "debug_traceCall", call, blockNumber).
+			mockClientCall(1, errors.New("error#2"), "debug_traceCall", call, blockNumber).
+			expectedError("error#1").
+			expectedError("error#2").
+			test()
+	})
+}
+
 func newAny(j string) *Any {
 	t := &Any{}
 	if err := t.UnmarshalJSON([]byte(j)); err != nil {
@@ -0,0 +1,349 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	gethRPC "github.com/ethereum/go-ethereum/rpc"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/rpcsplitter/types"
+)
+
+// subscriptionDepth is the number of blocks for which notifications are
+// remembered to detect duplicates and reorgs.
+const subscriptionDepth = 128
+
+// resubscribeDelay is the delay before resubscribing to an endpoint after
+// its subscription has failed.
+const resubscribeDelay = 5 * time.Second
+
+// subscriber is implemented by callers that support subscriptions.
+type subscriber interface {
+	// subscribe starts a new "eth_subscribe" subscription with the given
+	// arguments. Notifications are sent to the ch channel. The returned
+	// channel receives an error if the subscription fails. The subscription
+	// is canceled when the context is canceled.
+	subscribe(ctx context.Context, ch chan<- json.RawMessage, args ...any) (<-chan error, error)
+}
+
+// rpcClient wraps the go-ethereum RPC client to implement the subscriber
+// interface. Subscriptions are supported only by WebSocket and IPC clients.
+type rpcClient struct {
+	*gethRPC.Client
+}
+
+// subscribe implements the subscriber interface.
+func (c rpcClient) subscribe(ctx context.Context, ch chan<- json.RawMessage, args ...any) (<-chan error, error) {
+	sub, err := c.EthSubscribe(ctx, ch, args...)
+	if err != nil {
+		return nil, err
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		defer sub.Unsubscribe()
+		select {
+		case <-ctx.Done():
+		case err := <-sub.Err():
+			if err == nil {
+				err = fmt.Errorf("subscription closed")
+			}
+			errCh <- err
+		}
+	}()
+	return errCh, nil
+}
+
+// NewHeads implements the "newHeads" subscription.
+//
+// It subscribes to all endpoints that support subscriptions and emits a
+// block header only after it has been seen by at least as many endpoints as
+// specified in the minRes method. Every header is emitted only once. A header
+// for an already emitted block number is emitted again only if its hash is
+// different, which happens during reorgs.
+func (r *rpcETHAPI) NewHeads(ctx context.Context) (*gethRPC.Subscription, error) {
+	return r.handler.subscription(ctx, newHeadsMerger(), "newHeads")
+}
+
+// Logs implements the "logs" subscription.
+//
+// It subscribes to all endpoints that support subscriptions and emits a log
+// only after it has been seen by at least as many endpoints as specified in
+// the minRes method. Every log is emitted only once, logs removed due to
+// reorgs are emitted again with the "removed" field set to true.
+func (r *rpcETHAPI) Logs(ctx context.Context, filter *Any) (*gethRPC.Subscription, error) {
+	if filter == nil {
+		return r.handler.subscription(ctx, newLogsMerger(), "logs")
+	}
+	return r.handler.subscription(ctx, newLogsMerger(), "logs", filter)
+}
+
+// subscription creates a new subscription for the client and forwards merged
+// notifications from the endpoints to it.
+func (s *server) subscription(ctx context.Context, m merger, args ...any) (*gethRPC.Subscription, error) {
+	notifier, ok := gethRPC.NotifierFromContext(ctx)
+	if !ok {
+		return nil, gethRPC.ErrNotificationsUnsupported
+	}
+	// The context passed to the subscription method is canceled as soon as
+	// the method returns, so a separate context must be used.
+	subCtx, subCancel := context.WithCancel(context.Background())
+	msgCh, err := s.subscribe(subCtx, m, args...)
+	if err != nil {
+		subCancel()
+		return nil, err
+	}
+	sub := notifier.CreateSubscription()
+	go func() {
+		defer subCancel()
+		for {
+			select {
+			case <-sub.Err():
+				return
+			case msg := <-msgCh:
+				if err := notifier.Notify(sub.ID, msg); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// subscribe subscribes to all endpoints that support subscriptions and
+// returns a channel with merged notifications. If a subscription to one of
+// the endpoints fails, it is resubscribed after a delay.
+//
+// At least as many endpoints as specified in the minRes method must accept
+// the subscription, otherwise an error is returned.
+func (s *server) subscribe(ctx context.Context, m merger, args ...any) (<-chan json.RawMessage, error) {
+	type notification struct {
+		name string
+		msg  json.RawMessage
+	}
+	quorum := s.defaultResolver.minResponses
+	notifications := make(chan notification)
+	subscribed := 0
+	for n, c := range s.callers {
+		n := n
+		sub, ok := c.(subscriber)
+		if !ok {
+			continue
+		}
+		ch := make(chan json.RawMessage)
+		errCh, err := sub.subscribe(ctx, ch, args...)
+		if err != nil {
+			s.log.
+				WithField("name", n).
+				WithField("subscription", args[0]).
+				WithError(err).
+				Debug("Unable to subscribe")
+			continue
+		}
+		subscribed++
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case msg := <-ch:
+					select {
+					case notifications <- notification{name: n, msg: msg}:
+					case <-ctx.Done():
+						return
+					}
+				case err := <-errCh:
+					s.log.
+						WithField("name", n).
+						WithField("subscription", args[0]).
+						WithError(err).
+						Warn("Subscription failed, resubscribing")
+					errCh = s.resubscribe(ctx, n, sub, ch, args)
+					if errCh == nil {
+						return
+					}
+				}
+			}
+		}()
+	}
+	if subscribed == 0 || subscribed < quorum {
+		return nil, fmt.Errorf("not enough endpoints support subscriptions: %d out of %d required", subscribed, quorum)
+	}
+	msgCh := make(chan json.RawMessage)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n := <-notifications:
+				msg, ok := m.merge(n.name, n.msg, quorum)
+				if !ok {
+					continue
+				}
+				select {
+				case msgCh <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return msgCh, nil
+}
+
+// resubscribe tries to subscribe to the endpoint until it succeeds or the
+// context is canceled. In the latter case, nil is returned.
+func (s *server) resubscribe(ctx context.Context, name string, sub subscriber, ch chan json.RawMessage, args []any) <-chan error {
+	t := time.NewTicker(resubscribeDelay)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+			errCh, err := sub.subscribe(ctx, ch, args...)
+			if err == nil {
+				return errCh
+			}
+			s.log.
+				WithField("name", name).
+				WithField("subscription", args[0]).
+				WithError(err).
+				Debug("Unable to resubscribe")
+		}
+	}
+}
+
+// merger merges notifications from multiple endpoints into one stream.
+type merger interface {
+	// merge adds a notification from the given endpoint. It returns the
+	// notification and true if the notification should be emitted.
+	merge(name string, msg json.RawMessage, quorum int) (json.RawMessage, bool)
+}
+
+// quorumTracker tracks which endpoints have sent a notification with a
+// given key. The state is kept only for the last subscriptionDepth blocks.
+type quorumTracker struct {
+	seen    map[string]*quorumItem
+	highest uint64
+}
+
+type quorumItem struct {
+	number    uint64
+	endpoints map[string]struct{}
+	emitted   bool
+}
+
+func newQuorumTracker() quorumTracker {
+	return quorumTracker{seen: make(map[string]*quorumItem)}
+}
+
+// add records that the endpoint has sent a notification with the given key
+// for the given block number. It returns true if the notification has just
+// been seen by quorum endpoints.
+func (t *quorumTracker) add(name, key string, number uint64, quorum int) bool {
+	if number+subscriptionDepth < t.highest {
+		return false
+	}
+	if number > t.highest {
+		t.highest = number
+		for k, i := range t.seen {
+			if i.number+subscriptionDepth < t.highest {
+				delete(t.seen, k)
+			}
+		}
+	}
+	i, ok := t.seen[key]
+	if !ok {
+		i = &quorumItem{number: number, endpoints: make(map[string]struct{})}
+		t.seen[key] = i
+	}
+	i.endpoints[name] = struct{}{}
+	if i.emitted || len(i.endpoints) < quorum {
+		return false
+	}
+	i.emitted = true
+	return true
+}
+
+// headsMerger merges "newHeads" notifications.
+type headsMerger struct {
+	tracker quorumTracker
+	emitted map[uint64]types.Hash // hashes of emitted heads by block number
+	last    uint64                // last emitted block number
+}
+
+func newHeadsMerger() *headsMerger {
+	return &headsMerger{
+		tracker: newQuorumTracker(),
+		emitted: make(map[uint64]types.Hash),
+	}
+}
+
+// merge implements the merger interface.
+func (m *headsMerger) merge(name string, msg json.RawMessage, quorum int) (json.RawMessage, bool) {
+	var head struct {
+		Number types.Number `json:"number"`
+		Hash   types.Hash   `json:"hash"`
+	}
+	if err := json.Unmarshal(msg, &head); err != nil {
+		return nil, false
+	}
+	number := head.Number.Big().Uint64()
+	if !m.tracker.add(name, head.Hash.String(), number, quorum) {
+		return nil, false
+	}
+	// Heads older than the last emitted one are emitted only if they replace
+	// a previously emitted head, which means that a reorg has occurred.
+	if len(m.emitted) > 0 && number <= m.last {
+		if hash, ok := m.emitted[number]; !ok || hash == head.Hash {
+			return nil, false
+		}
+	}
+	m.emitted[number] = head.Hash
+	m.last = number
+	for n := range m.emitted {
+		if n+subscriptionDepth < number || n > number {
+			delete(m.emitted, n)
+		}
+	}
+	return msg, true
+}
+
+// logsMerger merges "logs" notifications.
+type logsMerger struct {
+	tracker quorumTracker
+}
+
+func newLogsMerger() *logsMerger {
+	return &logsMerger{tracker: newQuorumTracker()}
+}
+
+// merge implements the merger interface.
+func (m *logsMerger) merge(name string, msg json.RawMessage, quorum int) (json.RawMessage, bool) {
+	var log types.Log
+	if err := json.Unmarshal(msg, &log); err != nil {
+		return nil, false
+	}
+	key := fmt.Sprintf("%s:%s:%t", log.BlockHash.String(), log.LogIndex.Big().String(), log.Removed)
+	if !m.tracker.add(name, key, log.BlockNumber.Big().Uint64(), quorum) {
+		return nil, false
+	}
+	return msg, true
+}
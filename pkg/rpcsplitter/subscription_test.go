@@ -0,0 +1,188 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rpcsplitter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gethRPC "github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSubscriber struct {
+	msgs []json.RawMessage
+}
+
+func (m *mockSubscriber) CallContext(context.Context, any, string, ...any) error {
+	return errors.New("not implemented")
+}
+
+func (m *mockSubscriber) subscribe(ctx context.Context, ch chan<- json.RawMessage, _ ...any) (<-chan error, error) {
+	go func() {
+		for _, msg := range m.msgs {
+			select {
+			case ch <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return make(chan error), nil
+}
+
+func head(number int, hash string) json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{"number":"0x%x","hash":"0x%064s"}`, number, hash))
+}
+
+func logMsg(number int, hash string, index int, removed bool) json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(
+		`{"blockNumber":"0x%x","blockHash":"0x%064s","logIndex":"0x%x","removed":%t}`,
+		number, hash, index, removed,
+	))
+}
+
+func TestHeadsMerger(t *testing.T) {
+	m := newHeadsMerger()
+	emit := func(name string, msg json.RawMessage) bool {
+		_, ok := m.merge(name, msg, 2)
+		return ok
+	}
+
+	// Head is emitted once it is seen by two endpoints.
+	assert.False(t, emit("a", head(1, "1a")))
+	assert.True(t, emit("b", head(1, "1a")))
+	assert.False(t, emit("c", head(1, "1a")))
+	assert.False(t, emit("a", head(1, "1a")))
+
+	assert.False(t, emit("a", head(2, "2a")))
+	assert.True(t, emit("c", head(2, "2a")))
+
+	// Reorg, block 2 is replaced.
+	assert.False(t, emit("a", head(2, "2b")))
+	assert.True(t, emit("b", head(2, "2b")))
+
+	// Old heads are not emitted again.
+	assert.False(t, emit("b", head(2, "2a")))
+
+	// Reorg, block 1 is replaced.
+	assert.False(t, emit("a", head(1, "1b")))
+	assert.True(t, emit("b", head(1, "1b")))
+	assert.False(t, emit("a", head(2, "2a")))
+
+	// New chain continues.
+	assert.False(t, emit("a", head(2, "2c")))
+	assert.True(t, emit("b", head(2, "2c")))
+}
+
+func TestLogsMerger(t *testing.T) {
+	m := newLogsMerger()
+	emit := func(name string, msg json.RawMessage) bool {
+		_, ok := m.merge(name, msg, 2)
+		return ok
+	}
+	assert.False(t, emit("a", logMsg(1, "1a", 0, false)))
+	assert.False(t, emit("a", logMsg(1, "1a", 1, false)))
+	assert.True(t, emit("b", logMsg(1, "1a", 0, false)))
+	assert.True(t, emit("b", logMsg(1, "1a", 1, false)))
+	assert.False(t, emit("c", logMsg(1, "1a", 0, false)))
+
+	// Removed logs are emitted again.
+	assert.False(t, emit("a", logMsg(1, "1a", 0, true)))
+	assert.True(t, emit("c", logMsg(1, "1a", 0, true)))
+}
+
+func TestQuorumTracker_Depth(t *testing.T) {
+	q := newQuorumTracker()
+	assert.True(t, q.add("a", "x", 1, 1))
+	assert.True(t, q.add("a", "y", subscriptionDepth+10, 1))
+	assert.Len(t, q.seen, 1)
+	assert.False(t, q.add("a", "z", 2, 1))
+}
+
+func newSubscriptionServer(t *testing.T, opts ...Option) *server {
+	heads := []json.RawMessage{head(1, "1a"), head(2, "2a"), head(3, "3a")}
+	callers := map[string]caller{
+		"0": &mockSubscriber{msgs: heads},
+		"1": &mockSubscriber{msgs: heads},
+		"2": &mockSubscriber{msgs: heads[:1]},
+	}
+	h, err := NewServer(append([]Option{withCallers(callers), WithRequirements(2, 10)}, opts...)...)
+	require.NoError(t, err)
+	return h.(*server)
+}
+
+func Test_RPC_NewHeads(t *testing.T) {
+	srv := httptest.NewServer(newSubscriptionServer(t))
+	defer srv.Close()
+
+	client, err := gethRPC.Dial("ws" + strings.TrimPrefix(srv.URL, "http"))
+	require.NoError(t, err)
+	defer client.Close()
+
+	ch := make(chan json.RawMessage)
+	sub, err := client.EthSubscribe(context.Background(), ch, "newHeads")
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+	for _, exp := range []json.RawMessage{head(1, "1a"), head(2, "2a"), head(3, "3a")} {
+		select {
+		case msg := <-ch:
+			assert.JSONEq(t, string(exp), string(msg))
+		case <-time.After(time.Second):
+			require.Fail(t, "timeout")
+		}
+	}
+}
+
+func Test_RPC_NewHeads_NotEnoughEndpoints(t *testing.T) {
+	s := newSubscriptionServer(t, withCallers(map[string]caller{
+		"0": &mockSubscriber{},
+		"1": &mockClient{t: t},
+	}))
+	client := gethRPC.DialInProc(s.rpc)
+	defer client.Close()
+
+	_, err := client.EthSubscribe(context.Background(), make(chan json.RawMessage), "newHeads")
+	assert.ErrorContains(t, err, "not enough endpoints support subscriptions")
+}
+
+func TestTransport_Subscribe(t *testing.T) {
+	tr, err := NewTransport("vhost", nil, withCallers(map[string]caller{
+		"0": &mockSubscriber{msgs: []json.RawMessage{logMsg(1, "1a", 0, false)}},
+		"1": &mockSubscriber{msgs: []json.RawMessage{logMsg(1, "1a", 0, false)}},
+	}), WithRequirements(2, 10))
+	require.NoError(t, err)
+
+	ch, id, err := tr.Subscribe(context.Background(), "logs", map[string]any{})
+	require.NoError(t, err)
+	select {
+	case msg := <-ch:
+		assert.JSONEq(t, string(logMsg(1, "1a", 0, false)), string(msg))
+	case <-time.After(time.Second):
+		require.Fail(t, "timeout")
+	}
+
+	require.NoError(t, tr.Unsubscribe(context.Background(), id))
+	_, ok := <-ch
+	assert.False(t, ok)
+}
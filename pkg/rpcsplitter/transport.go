@@ -17,17 +17,37 @@ package rpcsplitter
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"sync"
+
+	gethRPC "github.com/ethereum/go-ethereum/rpc"
 )
 
 // Transport implements the http.RoundTripper interface. It creates a virtual
 // host with RPC Splitter.
+//
+// Because subscriptions cannot be used over HTTP, Transport also provides
+// the Call, Subscribe and Unsubscribe methods that use an in-process
+// connection to RPC Splitter. These methods are compatible with the
+// SubscriptionTransport interface from the go-eth package.
 type Transport struct {
 	transport http.RoundTripper
-	server    http.Handler
+	server    *server
 	vhost     string
+
+	mu     sync.Mutex
+	subs   map[string]transportSubscription
+	lastID uint64
+}
+
+type transportSubscription struct {
+	sub *gethRPC.ClientSubscription
+	ch  chan json.RawMessage
 }
 
 // NewTransport returns a new instance of Transport.
@@ -42,7 +62,8 @@ func NewTransport(vhost string, transport http.RoundTripper, opts ...Option) (*T
 	return &Transport{
 		transport: transport,
 		vhost:     vhost,
-		server:    rpcServer,
+		server:    rpcServer.(*server),
+		subs:      make(map[string]transportSubscription),
 	}, nil
 }
 
@@ -56,6 +77,65 @@ func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	return t.buildResponse(rec), nil
 }
 
+// Call performs a JSON-RPC call using an in-process connection.
+func (t *Transport) Call(ctx context.Context, result any, method string, args ...any) error {
+	return t.server.inproc.CallContext(ctx, result, method, args...)
+}
+
+// Subscribe starts a new subscription, e.g. "newHeads" or "logs", using an
+// in-process connection. It returns a channel that receives notifications
+// and a subscription ID.
+func (t *Transport) Subscribe(ctx context.Context, method string, args ...any) (chan json.RawMessage, string, error) {
+	ch := make(chan json.RawMessage)
+	sub, err := t.server.inproc.EthSubscribe(ctx, ch, append([]any{method}, args...)...)
+	if err != nil {
+		return nil, "", err
+	}
+	t.mu.Lock()
+	t.lastID++
+	id := strconv.FormatUint(t.lastID, 10)
+	t.subs[id] = transportSubscription{sub: sub, ch: ch}
+	t.mu.Unlock()
+
+	// Close the channel if the subscription ends unexpectedly.
+	go func() {
+		<-sub.Err()
+		_ = t.Unsubscribe(ctx, id)
+	}()
+	return ch, id, nil
+}
+
+// Unsubscribe cancels a subscription. The channel returned by Subscribe
+// is closed.
+func (t *Transport) Unsubscribe(_ context.Context, id string) error {
+	t.mu.Lock()
+	s, ok := t.subs[id]
+	delete(t.subs, id)
+	t.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown subscription: %s", id)
+	}
+	s.sub.Unsubscribe()
+	close(s.ch)
+	return nil
+}
+
+// Close cancels all subscriptions and closes connections to the RPC
+// endpoints. The transport cannot be used after it is closed.
+func (t *Transport) Close() error {
+	t.mu.Lock()
+	ids := make([]string, 0, len(t.subs))
+	for id := range t.subs {
+		ids = append(ids, id)
+	}
+	t.mu.Unlock()
+	for _, id := range ids {
+		_ = t.Unsubscribe(context.Background(), id)
+	}
+	t.server.close()
+	return nil
+}
+
 func (t *Transport) isVirtualHost(req *http.Request) bool {
 	return req.Host == t.vhost
 }
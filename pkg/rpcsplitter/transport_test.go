@@ -17,6 +17,7 @@ package rpcsplitter
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"testing"
@@ -53,3 +54,21 @@ func TestTransport(t *testing.T) {
 	require.NoError(t, err)
 	require.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":1}`, string(body))
 }
+
+func TestTransport_Close(t *testing.T) {
+	rpcMock := &mockClient{t: t}
+	transport, err := NewTransport(
+		"rpcsplitter-vhost",
+		nil,
+		withCallers(map[string]caller{"caller": rpcMock}),
+		WithRequirements(1, 1),
+	)
+	require.NoError(t, err)
+
+	rpcMock.mockCall(1, "net_version")
+
+	var res any
+	require.NoError(t, transport.Call(context.Background(), &res, "net_version"))
+	require.NoError(t, transport.Close())
+	require.Error(t, transport.Call(context.Background(), &res, "net_version"))
+}
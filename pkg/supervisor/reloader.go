@@ -60,6 +60,26 @@ type ReloaderConfig struct {
 	Logger log.Logger
 }
 
+// ChannelFactory returns a factory function for the ReloaderConfig that
+// starts the given service and then replaces it with every service received
+// from the serviceCh channel.
+func ChannelFactory(service Service, serviceCh <-chan Service) func(ctx context.Context, ch chan Service) error {
+	return func(ctx context.Context, ch chan Service) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case ch <- service:
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case service = <-serviceCh:
+			}
+		}
+	}
+}
+
 // NewReloader returns a new Reloader instance.
 func NewReloader(cfg ReloaderConfig) *Reloader {
 	if cfg.Logger == nil {
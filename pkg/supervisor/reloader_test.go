@@ -95,6 +95,29 @@ func TestReloader(t *testing.T) {
 		}, 100*time.Millisecond, 10*time.Millisecond)
 	})
 
+	t.Run("channel factory", func(t *testing.T) {
+		s1 := &service{waitCh: make(chan error)}
+		s2 := &service{waitCh: make(chan error)}
+		ch := make(chan Service)
+		r := NewReloader(ReloaderConfig{
+			Factory: ChannelFactory(s1, ch),
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		require.NoError(t, r.Start(ctx))
+		assert.Eventually(t, func() bool {
+			return s1.Started()
+		}, 100*time.Millisecond, 10*time.Millisecond)
+		ch <- s2
+		assert.Eventually(t, func() bool {
+			return !s1.Started() && s2.Started()
+		}, 100*time.Millisecond, 10*time.Millisecond)
+		cancel()
+		assert.Eventually(t, func() bool {
+			return !s2.Started()
+		}, 100*time.Millisecond, 10*time.Millisecond)
+	})
+
 	t.Run("service failed to start", func(t *testing.T) {
 		s := &service{waitCh: make(chan error), failOnStart: true}
 		r := NewReloader(ReloaderConfig{
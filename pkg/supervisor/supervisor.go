@@ -44,6 +44,24 @@ type Service interface {
 	Wait() <-chan error
 }
 
+// ErrRestartRequired is returned by Reloadable.Reload if the configuration
+// changes options that cannot be applied without a restart.
+var ErrRestartRequired = errors.New("restart required")
+
+// Reloadable is an optional interface that can be implemented by a service
+// returned by Config.Services to apply a new configuration without
+// restarting the whole application.
+type Reloadable interface {
+	Service
+
+	// Reload applies the given configuration. Only the services affected by
+	// the configuration are rebuilt, the rest of them keep running. If the
+	// configuration cannot be applied, an error is returned and the running
+	// services are left unchanged. If the configuration can only be applied
+	// by restarting the application, the error wraps ErrRestartRequired.
+	Reload(cfg Config) error
+}
+
 // WithName is an optional interface that can be implemented by a service
 // to provide a name. The name is used in logs and metrics.
 type WithName interface {
@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/defiweb/go-eth/types"
+
 	"github.com/chronicleprotocol/oracle-suite/pkg/supervisor"
 	"github.com/chronicleprotocol/oracle-suite/pkg/transport"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/chanutil"
@@ -70,6 +72,42 @@ func (m *Chain) Messages(topic string) <-chan transport.ReceivedMessage {
 	return fi.Chan()
 }
 
+// Send implements the transport.DirectTransport interface.
+//
+// The message is sent using the first transport that supports direct
+// messages and is able to deliver it.
+func (m *Chain) Send(peerAddr types.Address, topic string, message transport.Message) error {
+	err := transport.ErrDirectNotSupported
+	for _, t := range m.ts {
+		dt, ok := t.(transport.DirectTransport)
+		if !ok {
+			continue
+		}
+		sErr := dt.Send(peerAddr, topic, message)
+		if sErr == nil {
+			return nil
+		}
+		if errors.Is(err, transport.ErrDirectNotSupported) {
+			err = sErr
+		} else {
+			err = errutil.Append(err, sErr)
+		}
+	}
+	return err
+}
+
+// DirectMessages implements the transport.DirectTransport interface.
+func (m *Chain) DirectMessages(topic string) <-chan transport.ReceivedMessage {
+	fi := chanutil.NewFanIn[transport.ReceivedMessage]()
+	for _, t := range m.ts {
+		if dt, ok := t.(transport.DirectTransport); ok {
+			_ = fi.Add(dt.DirectMessages(topic))
+		}
+	}
+	fi.AutoClose()
+	return fi.Chan()
+}
+
 // Start implements the transport.Transport interface.
 func (m *Chain) Start(ctx context.Context) error {
 	if m.ctx != nil {
@@ -21,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/defiweb/go-eth/types"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/transport"
@@ -81,6 +82,32 @@ func TestChain_Broadcast(t *testing.T) {
 	wg.Wait()
 }
 
+func TestChain_Send(t *testing.T) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer ctxCancel()
+
+	addr := types.MustAddressFromHex("0x1234567890123456789012345678901234567890")
+	l1 := local.New([]byte("test"), 1, map[string]transport.Message{"foo": (*testMsg)(nil)})
+	l2 := local.New(addr.Bytes(), 1, map[string]transport.Message{"foo": (*testMsg)(nil)})
+
+	l := New(l1, l2)
+	_ = l.Start(ctx)
+
+	// The first transport does not know the recipient, so the message should
+	// be sent using the second one.
+	tm := &testMsg{Val: "bar"}
+	assert.NoError(t, l.Send(addr, "foo", tm))
+	select {
+	case m := <-l2.DirectMessages("foo"):
+		assert.Equal(t, tm, m.Message)
+	case <-ctx.Done():
+		assert.Fail(t, "message not received")
+	}
+
+	// None of the transports knows the recipient.
+	assert.ErrorContains(t, l.Send(types.ZeroAddress, "foo", tm), local.ErrUnknownRecipient.Error())
+}
+
 func TestChain_Wait(t *testing.T) {
 	ctx, ctxCancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer ctxCancel()
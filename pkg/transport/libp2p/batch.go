@@ -0,0 +1,161 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package libp2p
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/transport"
+	"github.com/chronicleprotocol/oracle-suite/pkg/transport/webapi/pb"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/maputil"
+)
+
+// BatchTopicName is the name of the topic on which batches of messages are
+// published.
+//
+// A batch uses the same format as the message pack used by the WebAPI
+// transport: a gzip compressed, protobuf encoded MessagePack with messages
+// grouped by topic and signed by the author of the batch.
+const BatchTopicName = "batch/v1"
+
+// maxBatchSize is the maximum size of a decompressed batch. It protects
+// against decompression bombs.
+const maxBatchSize = 10 * 1024 * 1024 // 10MB
+
+// batchMessage is a single message unpacked from a batch.
+type batchMessage struct {
+	topic   string
+	message transport.Message
+}
+
+// batchBuffer collects messages that will be published in the next batch.
+type batchBuffer struct {
+	pack *pb.MessagePack
+}
+
+// add adds a binary encoded message to the buffer.
+func (b *batchBuffer) add(topic string, data []byte) {
+	if b.pack == nil {
+		b.pack = &pb.MessagePack{Messages: make(map[string]*pb.MessagePack_Messages)}
+	}
+	if b.pack.Messages[topic] == nil {
+		b.pack.Messages[topic] = &pb.MessagePack_Messages{}
+	}
+	b.pack.Messages[topic].Data = append(b.pack.Messages[topic].Data, data)
+}
+
+// take returns the buffered message pack and clears the buffer. It returns
+// nil if the buffer is empty.
+func (b *batchBuffer) take() *pb.MessagePack {
+	pack := b.pack
+	b.pack = nil
+	if pack == nil || len(pack.Messages) == 0 {
+		return nil
+	}
+	return pack
+}
+
+// encodeBatch signs, encodes and compresses the given message pack.
+func encodeBatch(pack *pb.MessagePack, signer wallet.Key) ([]byte, error) {
+	sig, err := signer.SignMessage(batchSigningData(pack))
+	if err != nil {
+		return nil, err
+	}
+	pack.Signature = sig.Bytes()
+	bin, err := proto.Marshal(pack)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	g := gzip.NewWriter(&buf)
+	if _, err := g.Write(bin); err != nil {
+		return nil, err
+	}
+	if err := g.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeBatch decompresses and decodes the batch, verifies its signature
+// and unmarshalls all messages for topics listed in the topics map. Messages
+// for other topics are skipped. It returns the address of the batch author.
+func decodeBatch(data []byte, topics map[string]transport.Message, recoverer crypto.Recoverer) (*types.Address, []batchMessage, error) { //nolint:lll
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+	bin, err := io.ReadAll(io.LimitReader(r, maxBatchSize+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(bin) > maxBatchSize {
+		return nil, nil, errors.New("batch is too large")
+	}
+	pack := &pb.MessagePack{}
+	if err := proto.Unmarshal(bin, pack); err != nil {
+		return nil, nil, err
+	}
+	sig, err := types.SignatureFromBytes(pack.Signature)
+	if err != nil {
+		return nil, nil, err
+	}
+	author, err := recoverer.RecoverMessage(batchSigningData(pack), sig)
+	if err != nil {
+		return nil, nil, err
+	}
+	var msgs []batchMessage
+	for _, topic := range maputil.SortKeys(pack.Messages, sort.Strings) {
+		typ, ok := topics[topic]
+		if !ok || topic == BatchTopicName {
+			continue
+		}
+		for _, msgData := range pack.Messages[topic].Data {
+			msg := reflect.New(reflect.TypeOf(typ).Elem()).Interface().(transport.Message)
+			if err := msg.UnmarshallBinary(msgData); err != nil {
+				return nil, nil, fmt.Errorf("unable to unmarshall message for topic %s: %w", topic, err)
+			}
+			msgs = append(msgs, batchMessage{topic: topic, message: msg})
+		}
+	}
+	return author, msgs, nil
+}
+
+// batchSigningData returns the data used to sign the given message pack.
+// The data is the concatenation of all topics followed by topic data. Topics
+// are sorted in ascending order using sort.Strings.
+func batchSigningData(pack *pb.MessagePack) []byte {
+	var signingData []byte
+	for _, topic := range maputil.SortKeys(pack.Messages, sort.Strings) {
+		signingData = append(signingData, topic...)
+		for _, data := range pack.Messages[topic].Data {
+			signingData = append(signingData, data...)
+		}
+	}
+	return signingData
+}
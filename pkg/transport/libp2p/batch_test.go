@@ -0,0 +1,74 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package libp2p
+
+import (
+	"testing"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/transport"
+	"github.com/chronicleprotocol/oracle-suite/pkg/transport/messages"
+)
+
+func TestBatch(t *testing.T) {
+	key := wallet.NewRandomKey()
+	topics := map[string]transport.Message{
+		messages.GreetV1MessageName: (*messages.Greet)(nil),
+	}
+
+	msg1, err := (&messages.Greet{WebURL: "a"}).MarshallBinary()
+	require.NoError(t, err)
+	msg2, err := (&messages.Greet{WebURL: "b"}).MarshallBinary()
+	require.NoError(t, err)
+
+	var buf batchBuffer
+	assert.Nil(t, buf.take())
+	buf.add(messages.GreetV1MessageName, msg1)
+	buf.add(messages.GreetV1MessageName, msg2)
+	buf.add("unknown", []byte("unknown"))
+	pack := buf.take()
+	require.NotNil(t, pack)
+	assert.Nil(t, buf.take())
+
+	data, err := encodeBatch(pack, key)
+	require.NoError(t, err)
+
+	author, msgs, err := decodeBatch(data, topics, crypto.ECRecoverer)
+	require.NoError(t, err)
+	assert.Equal(t, key.Address(), *author)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, messages.GreetV1MessageName, msgs[0].topic)
+	assert.Equal(t, "a", msgs[0].message.(*messages.Greet).WebURL)
+	assert.Equal(t, "b", msgs[1].message.(*messages.Greet).WebURL)
+}
+
+func TestBatch_Invalid(t *testing.T) {
+	topics := map[string]transport.Message{
+		messages.GreetV1MessageName: (*messages.Greet)(nil),
+	}
+
+	// Not compressed:
+	_, _, err := decodeBatch([]byte("invalid"), topics, crypto.ECRecoverer)
+	assert.Error(t, err)
+
+	// Empty batch:
+	_, _, err = decodeBatch(nil, topics, crypto.ECRecoverer)
+	assert.Error(t, err)
+}
@@ -0,0 +1,454 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package libp2p
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	gethCrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/protocol"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/transport"
+	"github.com/chronicleprotocol/oracle-suite/pkg/transport/libp2p/crypto/ethkey"
+)
+
+// DirectProtocolID is the ID of the stream protocol used to send direct
+// messages.
+//
+// The protocol works as follows:
+//  1. The sender opens a stream and sends a random challenge.
+//  2. The recipient responds with a signature of the challenge and its own
+//     peer ID, made with its Ethereum key. The sender recovers the recipient's
+//     public key from the signature and verifies the recipient's address.
+//  3. The sender sends the message encrypted with the recipient's public key
+//     using ECIES. The encrypted message is signed by the sender.
+//  4. The recipient responds with a single byte acknowledgment.
+const DirectProtocolID = protocol.ID("/chronicle/direct/1.0.0")
+
+const (
+	directChallengeSize  = 32
+	directSignatureSize  = 65
+	directAck            = 0x01
+	maxDirectMessageSize = 1024 * 1024 // 1MB
+
+	// maxDirectProbes is the maximum number of peers that are checked
+	// concurrently while looking for the recipient of a direct message.
+	maxDirectProbes = 16
+
+	// directMessageChanSize is the size of the channel for received direct
+	// messages. If the channel is full, new messages are rejected.
+	directMessageChanSize = 1000
+)
+
+var (
+	errUnknownRecipient = errors.New("recipient is not connected")
+	errInvalidFrame     = errors.New("invalid frame")
+	errAlreadySent      = errors.New("message already sent to another peer")
+)
+
+// privateKey is implemented by keys that expose the private key, which is
+// required to decrypt direct messages.
+type privateKey interface {
+	PrivateKey() *ecdsa.PrivateKey
+}
+
+// Send implements the transport.DirectTransport interface.
+func (p *P2P) Send(peerAddr types.Address, topic string, message transport.Message) error {
+	if p.mode != ClientMode || p.signer == nil || p.noDirect {
+		return transport.ErrDirectNotSupported
+	}
+	p.mu.Lock()
+	ctx := p.ctx
+	p.mu.Unlock()
+	if ctx == nil {
+		return errors.New("P2P transport error, transport is not started")
+	}
+	if len(p.feeds) > 0 && !feedAllowed(peerAddr, p.feeds) {
+		return fmt.Errorf("P2P transport error, recipient %s is not on the author allowlist", peerAddr)
+	}
+	if _, ok := p.topics[topic]; !ok {
+		return fmt.Errorf("P2P transport error, unknown topic %s", topic)
+	}
+	if appInfo, ok := message.(transport.WithAppInfo); ok {
+		appInfo.SetAppInfo(transport.AppInfo{
+			Name:    p.appName,
+			Version: p.appVersion,
+		})
+	}
+	data, err := message.MarshallBinary()
+	if err != nil {
+		return fmt.Errorf("P2P transport error, unable to marshall message: %w", err)
+	}
+	payload, err := encodeDirectPayload(p.signer, peerAddr, topic, data)
+	if err != nil {
+		return fmt.Errorf("P2P transport error, unable to encode direct message: %w", err)
+	}
+
+	// Try the last known peer of the recipient first, then all other
+	// connected peers whose address is not known yet.
+	p.mu.Lock()
+	known, hasKnown := p.directPeers[peerAddr]
+	p.mu.Unlock()
+	if hasKnown {
+		if err := p.sendDirect(ctx, known, peerAddr, payload, nil); err == nil {
+			return nil
+		}
+	}
+	var ids []peer.ID
+	for _, id := range p.node.Host().Network().Peers() {
+		if hasKnown && id == known {
+			continue
+		}
+		p.mu.Lock()
+		_, checked := p.directAddrs[id]
+		p.mu.Unlock()
+		if !checked {
+			ids = append(ids, id)
+		}
+	}
+	if err := p.probeDirect(ctx, ids, peerAddr, payload); err != nil {
+		return fmt.Errorf("P2P transport error, unable to send direct message to %s: %w", peerAddr, err)
+	}
+	return nil
+}
+
+// probeDirect concurrently checks the given peers and sends the payload to
+// the first one that proves that it owns the recipient address.
+func (p *P2P) probeDirect(ctx context.Context, ids []peer.ID, recipient types.Address, payload []byte) error {
+	type result struct {
+		id  peer.ID
+		err error
+	}
+	ctx, ctxCancel := context.WithCancel(ctx)
+	defer ctxCancel()
+	var sent atomic.Bool
+	claim := func() bool { return sent.CompareAndSwap(false, true) }
+	sem := make(chan struct{}, maxDirectProbes)
+	resCh := make(chan result, len(ids))
+	for _, id := range ids {
+		go func(id peer.ID) {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			resCh <- result{id: id, err: p.sendDirect(ctx, id, recipient, payload, claim)}
+		}(id)
+	}
+	for range ids {
+		res := <-resCh
+		if res.err == nil {
+			return nil
+		}
+		p.logger.
+			WithError(res.err).
+			WithField("peerID", res.id.String()).
+			Debug("Peer is not the direct message recipient")
+	}
+	return errUnknownRecipient
+}
+
+// DirectMessages implements the transport.DirectTransport interface.
+func (p *P2P) DirectMessages(topic string) <-chan transport.ReceivedMessage {
+	if fo, ok := p.directFanOut[topic]; ok {
+		return fo.Chan()
+	}
+	return nil
+}
+
+// sendDirect opens a stream to the peer with the given ID and sends the
+// payload if the peer proves that it owns the recipient address.
+//
+// If the claim function is not nil, it is called after the peer is verified
+// and the payload is sent only if it returns true. It is used to prevent
+// sending the same message to multiple peers.
+func (p *P2P) sendDirect(ctx context.Context, id peer.ID, recipient types.Address, payload []byte, claim func() bool) error {
+	ctx, ctxCancel := context.WithTimeout(ctx, connectionTimeout)
+	defer ctxCancel()
+	s, err := p.node.Host().NewStream(ctx, id, DirectProtocolID)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	_ = s.SetDeadline(time.Now().Add(connectionTimeout))
+
+	// Send challenge and verify the recipient.
+	challenge := make([]byte, directChallengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return err
+	}
+	if err := writeFrame(s, challenge); err != nil {
+		return err
+	}
+	sig, err := readFrame(s, directSignatureSize)
+	if err != nil {
+		return err
+	}
+	pub, err := recoverPublicKey(directHandshakeData(challenge, id), sig)
+	if err != nil {
+		return err
+	}
+	addr := crypto.ECPublicKeyToAddress(pub)
+	p.mu.Lock()
+	p.directAddrs[id] = addr
+	if addr == recipient {
+		p.directPeers[addr] = id
+	}
+	p.mu.Unlock()
+	if addr != recipient {
+		return fmt.Errorf("peer %s belongs to %s", id, addr)
+	}
+	if claim != nil && !claim() {
+		return errAlreadySent
+	}
+
+	// Encrypt and send the message.
+	encrypted, err := ecies.Encrypt(rand.Reader, ecies.ImportECDSAPublic(pub), payload, nil, nil)
+	if err != nil {
+		return err
+	}
+	if err := writeFrame(s, encrypted); err != nil {
+		return err
+	}
+	ack, err := readFrame(s, 1)
+	if err != nil {
+		return err
+	}
+	if len(ack) != 1 || ack[0] != directAck {
+		return errInvalidFrame
+	}
+	return nil
+}
+
+// handleDirectStream handles incoming direct message streams.
+func (p *P2P) handleDirectStream(s network.Stream) {
+	defer s.Close()
+	_ = s.SetDeadline(time.Now().Add(connectionTimeout))
+
+	remoteID := s.Conn().RemotePeer()
+	fields := log.Fields{
+		"peerID": remoteID.String(),
+	}
+	if err := p.receiveDirect(s); err != nil {
+		_ = s.Reset()
+		p.logger.
+			WithError(err).
+			WithFields(fields).
+			Warn("Unable to receive direct message")
+	}
+}
+
+func (p *P2P) receiveDirect(s network.Stream) error {
+	key, ok := p.signer.(privateKey)
+	if !ok {
+		return errors.New("signer does not support decryption")
+	}
+
+	// Respond to the challenge.
+	challenge, err := readFrame(s, directChallengeSize)
+	if err != nil {
+		return err
+	}
+	if len(challenge) != directChallengeSize {
+		return errInvalidFrame
+	}
+	sig, err := p.signer.SignMessage(directHandshakeData(challenge, p.node.Host().ID()))
+	if err != nil {
+		return err
+	}
+	if err := writeFrame(s, sig.Bytes()); err != nil {
+		return err
+	}
+
+	// Read and decrypt the message. The sender may close the stream if
+	// it is looking for another recipient.
+	encrypted, err := readFrame(s, maxDirectMessageSize)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// The private key must be converted to use the curve implementation
+	// supported by the ecies package.
+	prv, err := gethCrypto.ToECDSA(gethCrypto.FromECDSA(key.PrivateKey()))
+	if err != nil {
+		return err
+	}
+	payload, err := ecies.ImportECDSA(prv).Decrypt(encrypted, nil, nil)
+	if err != nil {
+		return fmt.Errorf("unable to decrypt message: %w", err)
+	}
+	author, topic, data, err := decodeDirectPayload(p.signer.Address(), payload)
+	if err != nil {
+		return err
+	}
+	if len(p.feeds) > 0 && !feedAllowed(*author, p.feeds) {
+		return fmt.Errorf("feed %s is not allowed to send messages", author)
+	}
+	typ, ok := p.topics[topic]
+	if !ok {
+		return fmt.Errorf("unknown topic %s", topic)
+	}
+	msg := reflect.New(reflect.TypeOf(typ).Elem()).Interface().(transport.Message)
+	if err := msg.UnmarshallBinary(data); err != nil {
+		return fmt.Errorf("unable to unmarshall message: %w", err)
+	}
+
+	p.mu.Lock()
+	p.directAddrs[s.Conn().RemotePeer()] = *author
+	p.directPeers[*author] = s.Conn().RemotePeer()
+	p.mu.Unlock()
+
+	userAgent := ""
+	if appInfo, ok := msg.(transport.WithAppInfo); ok {
+		userAgent = fmt.Sprintf("%s/%s", appInfo.GetAppInfo().Name, appInfo.GetAppInfo().Version)
+	}
+	authorID := ethkey.AddressToPeerID(*author)
+	received := transport.ReceivedMessage{
+		Message: msg,
+		Author:  author.Bytes(),
+		Meta: transport.Meta{
+			Transport:            TransportName,
+			Topic:                topic,
+			MessageID:            hex.EncodeToString(crypto.Keccak256(payload).Bytes()),
+			PeerID:               authorID.String(),
+			PeerAddr:             author.String(),
+			ReceivedFromPeerID:   s.Conn().RemotePeer().String(),
+			ReceivedFromPeerAddr: author.String(),
+			UserAgent:            userAgent,
+		},
+	}
+
+	// The message is acknowledged only if it was queued, so the sender
+	// knows that the message was not delivered if the channel is full.
+	select {
+	case p.directCh[topic] <- received:
+	default:
+		return errors.New("direct message channel is full, message dropped")
+	}
+	return writeFrame(s, []byte{directAck})
+}
+
+// directHandshakeData returns the data signed by the recipient to prove
+// the ownership of its Ethereum address. The peer ID is included to prevent
+// other peers from replaying the signature.
+func directHandshakeData(challenge []byte, id peer.ID) []byte {
+	return bytes.Join([][]byte{[]byte(DirectProtocolID), challenge, []byte(id)}, nil)
+}
+
+// directSigningData returns the data signed by the sender of a direct
+// message. The recipient address is included to prevent the recipient from
+// forwarding the message to other feeds on behalf of the sender.
+func directSigningData(recipient types.Address, topic string, data []byte) []byte {
+	return bytes.Join([][]byte{recipient.Bytes(), []byte(topic), {0}, data}, nil)
+}
+
+// encodeDirectPayload encodes and signs the direct message. The payload has
+// the following format: signature || uint16(len(topic)) || topic || data.
+func encodeDirectPayload(signer wallet.Key, recipient types.Address, topic string, data []byte) ([]byte, error) {
+	if len(topic) > 0xffff {
+		return nil, errors.New("topic name is too long")
+	}
+	sig, err := signer.SignMessage(directSigningData(recipient, topic, data))
+	if err != nil {
+		return nil, err
+	}
+	payload := make([]byte, 0, directSignatureSize+2+len(topic)+len(data))
+	payload = append(payload, sig.Bytes()...)
+	payload = binary.BigEndian.AppendUint16(payload, uint16(len(topic)))
+	payload = append(payload, topic...)
+	payload = append(payload, data...)
+	return payload, nil
+}
+
+// decodeDirectPayload decodes the direct message and recovers its author.
+func decodeDirectPayload(recipient types.Address, payload []byte) (*types.Address, string, []byte, error) {
+	if len(payload) < directSignatureSize+2 {
+		return nil, "", nil, errInvalidFrame
+	}
+	sig, err := types.SignatureFromBytes(payload[:directSignatureSize])
+	if err != nil {
+		return nil, "", nil, err
+	}
+	topicLen := int(binary.BigEndian.Uint16(payload[directSignatureSize:]))
+	if len(payload) < directSignatureSize+2+topicLen {
+		return nil, "", nil, errInvalidFrame
+	}
+	topic := string(payload[directSignatureSize+2 : directSignatureSize+2+topicLen])
+	data := payload[directSignatureSize+2+topicLen:]
+	author, err := crypto.ECRecoverer.RecoverMessage(directSigningData(recipient, topic, data), sig)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return author, topic, data, nil
+}
+
+// recoverPublicKey recovers the public key from the signature created by
+// the wallet.Key.SignMessage method.
+func recoverPublicKey(data []byte, sigBytes []byte) (*ecdsa.PublicKey, error) {
+	if len(sigBytes) != directSignatureSize {
+		return nil, errInvalidFrame
+	}
+	sig := make([]byte, directSignatureSize)
+	copy(sig, sigBytes)
+	if sig[64] < 27 {
+		return nil, errors.New("invalid signature")
+	}
+	sig[64] -= 27
+	return gethCrypto.SigToPub(crypto.Keccak256(crypto.AddMessagePrefix(data)).Bytes(), sig)
+}
+
+// writeFrame writes a length-prefixed frame to the writer.
+func writeFrame(w io.Writer, data []byte) error {
+	buf := make([]byte, 4, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	_, err := w.Write(append(buf, data...))
+	return err
+}
+
+// readFrame reads a length-prefixed frame from the reader. Frames longer
+// than maxSize are rejected.
+func readFrame(r io.Reader, maxSize int) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if int64(n) > int64(maxSize) {
+		return nil, errInvalidFrame
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
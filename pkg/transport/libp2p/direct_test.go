@@ -0,0 +1,107 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package libp2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/transport"
+	"github.com/chronicleprotocol/oracle-suite/pkg/transport/libp2p/crypto/ethkey"
+	"github.com/chronicleprotocol/oracle-suite/pkg/transport/messages"
+)
+
+func TestDirectPayload(t *testing.T) {
+	key := wallet.NewRandomKey()
+	recipient := wallet.NewRandomKey().Address()
+
+	payload, err := encodeDirectPayload(key, recipient, "topic", []byte("data"))
+	require.NoError(t, err)
+
+	author, topic, data, err := decodeDirectPayload(recipient, payload)
+	require.NoError(t, err)
+	assert.Equal(t, key.Address(), *author)
+	assert.Equal(t, "topic", topic)
+	assert.Equal(t, []byte("data"), data)
+
+	// Message forwarded to a different recipient must not be attributed to
+	// the original author.
+	author, _, _, err = decodeDirectPayload(wallet.NewRandomKey().Address(), payload)
+	if err == nil {
+		assert.NotEqual(t, key.Address(), *author)
+	}
+
+	// Truncated payload:
+	_, _, _, err = decodeDirectPayload(recipient, payload[:directSignatureSize+3])
+	assert.Error(t, err)
+}
+
+func TestRecoverPublicKey(t *testing.T) {
+	key := wallet.NewRandomKey()
+	sig, err := key.SignMessage([]byte("data"))
+	require.NoError(t, err)
+
+	pub, err := recoverPublicKey([]byte("data"), sig.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, key.Address(), crypto.ECPublicKeyToAddress(pub))
+}
+
+func TestP2P_Send(t *testing.T) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer ctxCancel()
+
+	key1 := wallet.NewRandomKey()
+	key2 := wallet.NewRandomKey()
+	topics := map[string]transport.Message{
+		messages.GreetV1MessageName: (*messages.Greet)(nil),
+	}
+	newNode := func(key *wallet.PrivateKey) *P2P {
+		p, err := New(Config{
+			Mode:            ClientMode,
+			Topics:          topics,
+			MessagePrivKey:  ethkey.NewPrivKey(key),
+			ListenAddrs:     []string{"/ip4/127.0.0.1/tcp/0"},
+			AuthorAllowlist: []types.Address{key1.Address(), key2.Address()},
+			Signer:          key,
+		})
+		require.NoError(t, err)
+		require.NoError(t, p.Start(ctx))
+		return p
+	}
+	p1 := newNode(key1)
+	p2 := newNode(key2)
+	require.NoError(t, p1.node.Host().Connect(ctx, peer.AddrInfo{
+		ID:    p2.node.Host().ID(),
+		Addrs: p2.node.Host().Addrs(),
+	}))
+
+	ch := p2.DirectMessages(messages.GreetV1MessageName)
+	require.NoError(t, p1.Send(key2.Address(), messages.GreetV1MessageName, &messages.Greet{WebURL: "test"}))
+	msg := <-ch
+	assert.Equal(t, "test", msg.Message.(*messages.Greet).WebURL)
+	assert.Equal(t, key1.Address().Bytes(), msg.Author)
+
+	// Recipient must be on the author allowlist.
+	assert.Error(t, p1.Send(wallet.NewRandomKey().Address(), messages.GreetV1MessageName, &messages.Greet{}))
+}
@@ -23,9 +23,12 @@ import (
 	"github.com/multiformats/go-multiaddr"
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/metrics"
 	"github.com/chronicleprotocol/oracle-suite/pkg/transport/libp2p/internal/sets"
 )
 
+// MonitorConfig is the configuration for the Monitor option. Regardless of
+// the configuration, the monitor keeps libp2p metrics up to date.
 type MonitorConfig struct {
 	// ShowLogOnChange enables logging immediately when the number of peers
 	// or connections changes.
@@ -53,9 +56,7 @@ func (n *monitorNotifee) ListenClose(network.Network, multiaddr.Multiaddr) {}
 func (n *monitorNotifee) Connected(_ network.Network, _ network.Conn) {
 	n.mu.RLock()
 	defer n.mu.RUnlock()
-	if !n.stopped {
-		n.notifeeCh <- struct{}{}
-	}
+	n.notify()
 }
 
 // Disconnected implements the network.Notifiee interface.
@@ -63,8 +64,18 @@ func (n *monitorNotifee) Disconnected(_ network.Network, _ network.Conn) {
 	n.mu.RLock()
 	defer n.mu.RUnlock()
 
-	if !n.stopped {
-		n.notifeeCh <- struct{}{}
+	n.notify()
+}
+
+// notify sends a notification without blocking. Notifications are coalesced
+// if the previous one has not been received yet.
+func (n *monitorNotifee) notify() {
+	if n.stopped {
+		return
+	}
+	select {
+	case n.notifeeCh <- struct{}{}:
+	default:
 	}
 }
 
@@ -88,12 +99,17 @@ func Monitor(cfg MonitorConfig) Options {
 				Info("Connection monitor")
 		}
 
-		notifeeCh := make(chan struct{})
-		notifee := &monitorNotifee{notifeeCh: notifeeCh}
-		if cfg.ShowLogOnChange {
-			n.AddNotifee(notifee)
+		updateMetrics := func() {
+			metrics.LibP2PPeers.Set(float64(len(n.host.Network().Peers())))
+			metrics.LibP2PConnections.Set(float64(len(n.host.Network().Conns())))
 		}
 
+		// The notifee is always added to keep metrics up to date, even if
+		// logs are not shown on change.
+		notifeeCh := make(chan struct{}, 1)
+		notifee := &monitorNotifee{notifeeCh: notifeeCh}
+		n.AddNotifee(notifee)
+
 		n.AddNodeEventHandler(sets.NodeEventHandlerFunc(func(event interface{}) {
 			if _, ok := event.(sets.NodeStartedEvent); ok {
 				go func() {
@@ -101,9 +117,13 @@ func Monitor(cfg MonitorConfig) Options {
 					for {
 						select {
 						case <-notifeeCh:
-							printLog()
-							t.Reset(cfg.ShowLogInterval)
+							updateMetrics()
+							if cfg.ShowLogOnChange {
+								printLog()
+								t.Reset(cfg.ShowLogInterval)
+							}
 						case <-t.C:
+							updateMetrics()
 							printLog()
 						case <-n.ctx.Done():
 							notifee.Stop()
@@ -19,7 +19,9 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	cryptoETH "github.com/defiweb/go-eth/crypto"
@@ -38,6 +40,7 @@ import (
 	"github.com/chronicleprotocol/oracle-suite/pkg/transport/libp2p/internal"
 	"github.com/chronicleprotocol/oracle-suite/pkg/transport/messages"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/chanutil"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
 )
 
 const LoggerTag = "LIBP2P"
@@ -77,14 +80,28 @@ var defaultListenAddrs = []string{"/ip4/0.0.0.0/tcp/0"}
 // P2P is the wrapper for the Node that implements the transport.Transport
 // interface.
 type P2P struct {
-	id         peer.ID
-	node       *internal.Node
-	mode       Mode
-	topics     map[string]transport.Message
-	msgCh      map[string]chan transport.ReceivedMessage
-	msgFanOut  map[string]*chanutil.FanOut[transport.ReceivedMessage]
-	appName    string
-	appVersion string
+	mu  sync.Mutex
+	ctx context.Context
+
+	id           peer.ID
+	node         *internal.Node
+	mode         Mode
+	topics       map[string]transport.Message
+	msgCh        map[string]chan transport.ReceivedMessage
+	msgFanOut    map[string]*chanutil.FanOut[transport.ReceivedMessage]
+	directCh     map[string]chan transport.ReceivedMessage
+	directFanOut map[string]*chanutil.FanOut[transport.ReceivedMessage]
+	directPeers  map[types.Address]peer.ID // Peers of known direct message recipients.
+	directAddrs  map[peer.ID]types.Address // Addresses of peers verified during handshakes.
+	feeds        []types.Address
+	batchTopics  map[string]struct{}
+	batchTicker  *timeutil.Ticker
+	batchBuffer  batchBuffer
+	signer       wallet.Key
+	noDirect     bool
+	logger       log.Logger
+	appName      string
+	appVersion   string
 }
 
 // Config is the configuration for the P2P transport.
@@ -136,9 +153,30 @@ type Config struct {
 	// to connect to the network. Always enabled in bootstrap mode.
 	Discovery bool
 
-	// Signer used to verify price messages. Ignored in bootstrap mode.
+	// Signer used to verify price messages, to sign batches and to sign
+	// direct messages. Ignored in bootstrap mode.
+	//
+	// To receive direct messages, the signer must expose its private key,
+	// which is used to decrypt them (see DirectProtocolID). Otherwise,
+	// DisableDirectMessages must be set.
 	Signer wallet.Key
 
+	// DisableDirectMessages disables sending and receiving direct messages.
+	DisableDirectMessages bool
+
+	// BatchTopics is a list of topics for which messages are not published
+	// individually. Instead, they are collected and published periodically
+	// as a single compressed and signed batch on the BatchTopicName topic.
+	// If empty, batching is disabled. Ignored in bootstrap mode.
+	//
+	// Messages from batches are always unpacked by receivers, regardless
+	// of this option.
+	BatchTopics []string
+
+	// BatchTicker specifies how often batches are published. Required if
+	// BatchTopics is not empty.
+	BatchTicker *timeutil.Ticker
+
 	// Logger is a custom logger instance. If not provided then null
 	// logger is used.
 	Logger log.Logger
@@ -170,6 +208,19 @@ func New(cfg Config) (*P2P, error) {
 	if cfg.Logger == nil {
 		cfg.Logger = null.New()
 	}
+	if cfg.Mode == ClientMode && cfg.Signer != nil && !cfg.DisableDirectMessages {
+		if _, ok := cfg.Signer.(privateKey); !ok {
+			return nil, errors.New("P2P transport error, signer does not expose the private key required to decrypt direct messages, direct messages must be disabled") //nolint:lll
+		}
+	}
+	if cfg.Mode == ClientMode && len(cfg.BatchTopics) > 0 {
+		if cfg.BatchTicker == nil {
+			return nil, errors.New("P2P transport error, batch ticker must be provided if batching is enabled")
+		}
+		if cfg.Signer == nil {
+			return nil, errors.New("P2P transport error, signer must be provided if batching is enabled")
+		}
+	}
 
 	listenAddrs, err := strsToMaddrs(cfg.ListenAddrs)
 	if err != nil {
@@ -229,7 +280,7 @@ func New(cfg Config) (*P2P, error) {
 				}
 				return nil
 			}),
-			messageValidator(cfg.Topics, logger), // must be registered before any other validator
+			messageValidator(cfg.Topics, logger, cryptoETH.ECRecoverer), // must be registered before any other validator
 			feedValidator(cfg.AuthorAllowlist, logger),
 			priceValidator(logger, cryptoETH.ECRecoverer),
 		)
@@ -258,15 +309,30 @@ func New(cfg Config) (*P2P, error) {
 		return nil, fmt.Errorf("P2P transport error, unable to get public ID from private key: %w", err)
 	}
 
+	batchTopics := make(map[string]struct{}, len(cfg.BatchTopics))
+	for _, topic := range cfg.BatchTopics {
+		batchTopics[topic] = struct{}{}
+	}
+
 	return &P2P{
-		id:         id,
-		node:       n,
-		mode:       cfg.Mode,
-		topics:     cfg.Topics,
-		msgCh:      map[string]chan transport.ReceivedMessage{},
-		msgFanOut:  map[string]*chanutil.FanOut[transport.ReceivedMessage]{},
-		appName:    cfg.AppName,
-		appVersion: cfg.AppVersion,
+		id:           id,
+		node:         n,
+		mode:         cfg.Mode,
+		topics:       cfg.Topics,
+		msgCh:        map[string]chan transport.ReceivedMessage{},
+		msgFanOut:    map[string]*chanutil.FanOut[transport.ReceivedMessage]{},
+		directCh:     map[string]chan transport.ReceivedMessage{},
+		directFanOut: map[string]*chanutil.FanOut[transport.ReceivedMessage]{},
+		directPeers:  map[types.Address]peer.ID{},
+		directAddrs:  map[peer.ID]types.Address{},
+		feeds:        cfg.AuthorAllowlist,
+		batchTopics:  batchTopics,
+		batchTicker:  cfg.BatchTicker,
+		signer:       cfg.Signer,
+		noDirect:     cfg.DisableDirectMessages,
+		logger:       logger,
+		appName:      cfg.AppName,
+		appVersion:   cfg.AppVersion,
 	}, nil
 }
 
@@ -275,15 +341,33 @@ func (p *P2P) Start(ctx context.Context) error {
 	if err := p.node.Start(ctx); err != nil {
 		return fmt.Errorf("P2P transport error, unable to start node: %w", err)
 	}
+	p.mu.Lock()
+	p.ctx = ctx
+	p.mu.Unlock()
 	if p.mode == ClientMode {
 		for topic := range p.topics {
 			msgCh := make(chan transport.ReceivedMessage)
 			p.msgCh[topic] = msgCh
 			p.msgFanOut[topic] = chanutil.NewFanOut(msgCh)
+			directCh := make(chan transport.ReceivedMessage, directMessageChanSize)
+			p.directCh[topic] = directCh
+			p.directFanOut[topic] = chanutil.NewFanOut(directCh)
 			if err := p.subscribe(topic); err != nil {
 				return err
 			}
 		}
+		if _, ok := p.signer.(privateKey); ok && !p.noDirect && len(p.topics) > 0 {
+			p.node.Host().SetStreamHandler(DirectProtocolID, p.handleDirectStream)
+		}
+		if len(p.topics) > 0 {
+			if err := p.subscribe(BatchTopicName); err != nil {
+				return err
+			}
+		}
+		if len(p.batchTopics) > 0 {
+			p.batchTicker.Start(ctx)
+			go p.batchRoutine(ctx)
+		}
 	}
 	return nil
 }
@@ -309,6 +393,13 @@ func (p *P2P) Broadcast(topic string, message transport.Message) error {
 	if err != nil {
 		return fmt.Errorf("P2P transport error, unable to marshall message: %w", err)
 	}
+	if _, ok := p.batchTopics[topic]; ok {
+		// Message will be published in the next batch (see batchRoutine).
+		p.mu.Lock()
+		p.batchBuffer.add(topic, data)
+		p.mu.Unlock()
+		return nil
+	}
 	return sub.Publish(data)
 }
 
@@ -335,32 +426,87 @@ func (p *P2P) messagesLoop(topic string, sub *internal.Subscription) {
 		if !ok {
 			return
 		}
-		id := nodeMsg.GetFrom()
-		if msg, ok := nodeMsg.ValidatorData.(transport.Message); ok {
-			userAgent := ""
-			if appInfo, ok := msg.(transport.WithAppInfo); ok {
-				userAgent = fmt.Sprintf("%s/%s", appInfo.GetAppInfo().Name, appInfo.GetAppInfo().Version)
+		if msgs, ok := nodeMsg.ValidatorData.([]batchMessage); ok {
+			for i, msg := range msgs {
+				p.msgCh[msg.topic] <- receivedMessage(
+					msg.topic,
+					fmt.Sprintf("%s:%d", hex.EncodeToString([]byte(nodeMsg.ID)), i),
+					msg.message,
+					nodeMsg,
+				)
 			}
+			continue
+		}
+		if msg, ok := nodeMsg.ValidatorData.(transport.Message); ok {
+			p.msgCh[topic] <- receivedMessage(topic, hex.EncodeToString([]byte(nodeMsg.ID)), msg, nodeMsg)
+		}
+	}
+}
 
-			p.msgCh[topic] <- transport.ReceivedMessage{
-				Message: msg,
-				Author:  ethkey.PeerIDToAddress(id).Bytes(),
-				Data:    nodeMsg,
-				Meta: transport.Meta{
-					Transport:            TransportName,
-					Topic:                topic,
-					MessageID:            hex.EncodeToString([]byte(nodeMsg.ID)),
-					PeerID:               id.String(),
-					PeerAddr:             ethkey.PeerIDToAddress(id).String(),
-					ReceivedFromPeerID:   nodeMsg.ReceivedFrom.String(),
-					ReceivedFromPeerAddr: ethkey.PeerIDToAddress(nodeMsg.ReceivedFrom).String(),
-					UserAgent:            userAgent,
-				},
+// batchRoutine periodically publishes buffered messages as a batch.
+func (p *P2P) batchRoutine(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.batchTicker.TickCh():
+			if err := p.publishBatch(); err != nil {
+				p.logger.
+					WithError(err).
+					Error("Unable to publish batch")
 			}
 		}
 	}
 }
 
+// publishBatch publishes all buffered messages as a single batch.
+func (p *P2P) publishBatch() error {
+	p.mu.Lock()
+	pack := p.batchBuffer.take()
+	p.mu.Unlock()
+	if pack == nil {
+		return nil // Nothing to send.
+	}
+	data, err := encodeBatch(pack, p.signer)
+	if err != nil {
+		return fmt.Errorf("P2P transport error, unable to encode batch: %w", err)
+	}
+	sub, err := p.node.Subscription(BatchTopicName)
+	if err != nil {
+		return fmt.Errorf("P2P transport error, unable to get subscription for %s topic: %w", BatchTopicName, err)
+	}
+	return sub.Publish(data)
+}
+
+func receivedMessage(
+	topic string,
+	msgID string,
+	msg transport.Message,
+	nodeMsg *pubsub.Message,
+) transport.ReceivedMessage {
+
+	id := nodeMsg.GetFrom()
+	userAgent := ""
+	if appInfo, ok := msg.(transport.WithAppInfo); ok {
+		userAgent = fmt.Sprintf("%s/%s", appInfo.GetAppInfo().Name, appInfo.GetAppInfo().Version)
+	}
+	return transport.ReceivedMessage{
+		Message: msg,
+		Author:  ethkey.PeerIDToAddress(id).Bytes(),
+		Data:    nodeMsg,
+		Meta: transport.Meta{
+			Transport:            TransportName,
+			Topic:                topic,
+			MessageID:            msgID,
+			PeerID:               id.String(),
+			PeerAddr:             ethkey.PeerIDToAddress(id).String(),
+			ReceivedFromPeerID:   nodeMsg.ReceivedFrom.String(),
+			ReceivedFromPeerAddr: ethkey.PeerIDToAddress(nodeMsg.ReceivedFrom).String(),
+			UserAgent:            userAgent,
+		},
+	}
+}
+
 // ServiceName implements the supervisor.WithName interface.
 func (p *P2P) ServiceName() string {
 	return "LibP2P"
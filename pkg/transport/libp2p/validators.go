@@ -33,13 +33,16 @@ import (
 	"github.com/chronicleprotocol/oracle-suite/pkg/transport/messages"
 )
 
-func messageValidator(topics map[string]transport.Message, logger log.Logger) internal.Options {
+func messageValidator(topics map[string]transport.Message, logger log.Logger, recoverer crypto.Recoverer) internal.Options {
 	return func(n *internal.Node) error {
 		// Validator actually have two roles in the libp2p: it unmarshalls messages
 		// and then validates them. Unmarshalled message is stored in the
 		// ValidatorData field which was created for this purpose:
 		// https://github.com/libp2p/go-libp2p-pubsub/pull/231
 		n.AddValidator(func(ctx context.Context, topic string, id peer.ID, psMsg *pubsub.Message) pubsub.ValidationResult {
+			if topic == BatchTopicName {
+				return validateBatch(topics, logger, recoverer, psMsg)
+			}
 			if typ, ok := topics[topic]; ok {
 				typRefl := reflect.TypeOf(typ).Elem()
 				msg := reflect.New(typRefl).Interface().(transport.Message)
@@ -65,6 +68,40 @@ func messageValidator(topics map[string]transport.Message, logger log.Logger) in
 	}
 }
 
+// validateBatch unpacks messages from a batch and stores them in the
+// ValidatorData field. The batch must be signed by the same author as the
+// libp2p message.
+func validateBatch(
+	topics map[string]transport.Message,
+	logger log.Logger,
+	recoverer crypto.Recoverer,
+	psMsg *pubsub.Message,
+) pubsub.ValidationResult {
+
+	peerAddr := ethkey.PeerIDToAddress(psMsg.GetFrom())
+	fields := log.Fields{
+		"peerID":   psMsg.GetFrom().String(),
+		"peerAddr": peerAddr.String(),
+	}
+	author, msgs, err := decodeBatch(psMsg.Data, topics, recoverer)
+	if err != nil {
+		logger.
+			WithError(err).
+			WithFields(fields).
+			Warn("Batch rejected, unable to decode")
+		return pubsub.ValidationReject
+	}
+	if *author != peerAddr {
+		logger.
+			WithField("from", author.String()).
+			WithFields(fields).
+			Warn("Batch rejected, the message and batch signatures do not match")
+		return pubsub.ValidationReject
+	}
+	psMsg.ValidatorData = msgs
+	return pubsub.ValidationAccept
+}
+
 func feedValidator(feeds []types.Address, logger log.Logger) internal.Options {
 	return func(n *internal.Node) error {
 		if len(feeds) == 0 {
@@ -98,58 +135,79 @@ func feedAllowed(addr types.Address, feeds []types.Address) bool {
 
 // priceValidator adds a validator for price messages. The validator checks if
 // the price message is valid, and if the price is not older than 5 min.
+// Price messages published in a batch are validated separately.
 func priceValidator(logger log.Logger, recoverer crypto.Recoverer) internal.Options {
 	return func(n *internal.Node) error {
 		n.AddValidator(func(ctx context.Context, topic string, id peer.ID, psMsg *pubsub.Message) pubsub.ValidationResult {
-			p, ok := psMsg.ValidatorData.(*messages.Price)
-			if !ok {
-				return pubsub.ValidationAccept
-			}
-			peerAddr := ethkey.PeerIDToAddress(psMsg.GetFrom())
-			fields := log.Fields{
-				"peerAddr": peerAddr.String(),
-				"peerID":   psMsg.GetFrom().String(),
-				"wat":      p.Price.Wat,
-				"age":      p.Price.Age.UTC().Format(time.RFC3339),
-				"val":      p.Price.Val.String(),
-				"version":  p.Version,
-				"hash":     hex.EncodeToString(p.Price.Hash().Bytes()),
-				"V":        hex.EncodeToString(p.Price.Sig.V.Bytes()),
-				"R":        hex.EncodeToString(p.Price.Sig.R.Bytes()),
-				"S":        hex.EncodeToString(p.Price.Sig.S.Bytes()),
-			}
-			// Check is a message signature is valid and extract author's address:
-			priceFrom, err := p.Price.From(recoverer)
-			if err != nil {
-				logger.
-					WithError(err).
-					WithFields(fields).
-					Warn("Price message rejected, invalid signature")
-				return pubsub.ValidationReject
-			}
-			// The libp2p message MUST be created by the same person who signs the price message.
-			if *priceFrom != peerAddr {
-				logger.
-					WithField("from", *priceFrom).
-					WithFields(fields).
-					Warn("Price message rejected, the message and price signatures do not match")
-				return pubsub.ValidationReject
-			}
-			// Check when message was created, ignore if older than 5 min, reject if older than 10 min:
-			if time.Since(p.Price.Age) > 5*time.Minute {
-				if time.Since(p.Price.Age) > 10*time.Minute {
-					logger.
-						WithFields(fields).
-						Warn("Price message rejected, the message is older than 10 min")
-					return pubsub.ValidationReject
+			switch data := psMsg.ValidatorData.(type) {
+			case *messages.Price:
+				return validatePrice(logger, recoverer, psMsg, data)
+			case []batchMessage:
+				for _, msg := range data {
+					p, ok := msg.message.(*messages.Price)
+					if !ok {
+						continue
+					}
+					if res := validatePrice(logger, recoverer, psMsg, p); res != pubsub.ValidationAccept {
+						return res
+					}
 				}
-				logger.
-					WithFields(fields).
-					Warn("Price message ignored, the message is older than 5 min")
-				return pubsub.ValidationIgnore
 			}
 			return pubsub.ValidationAccept
 		})
 		return nil
 	}
 }
+
+func validatePrice(
+	logger log.Logger,
+	recoverer crypto.Recoverer,
+	psMsg *pubsub.Message,
+	p *messages.Price,
+) pubsub.ValidationResult {
+
+	peerAddr := ethkey.PeerIDToAddress(psMsg.GetFrom())
+	fields := log.Fields{
+		"peerAddr": peerAddr.String(),
+		"peerID":   psMsg.GetFrom().String(),
+		"wat":      p.Price.Wat,
+		"age":      p.Price.Age.UTC().Format(time.RFC3339),
+		"val":      p.Price.Val.String(),
+		"version":  p.Version,
+		"hash":     hex.EncodeToString(p.Price.Hash().Bytes()),
+		"V":        hex.EncodeToString(p.Price.Sig.V.Bytes()),
+		"R":        hex.EncodeToString(p.Price.Sig.R.Bytes()),
+		"S":        hex.EncodeToString(p.Price.Sig.S.Bytes()),
+	}
+	// Check is a message signature is valid and extract author's address:
+	priceFrom, err := p.Price.From(recoverer)
+	if err != nil {
+		logger.
+			WithError(err).
+			WithFields(fields).
+			Warn("Price message rejected, invalid signature")
+		return pubsub.ValidationReject
+	}
+	// The libp2p message MUST be created by the same person who signs the price message.
+	if *priceFrom != peerAddr {
+		logger.
+			WithField("from", *priceFrom).
+			WithFields(fields).
+			Warn("Price message rejected, the message and price signatures do not match")
+		return pubsub.ValidationReject
+	}
+	// Check when message was created, ignore if older than 5 min, reject if older than 10 min:
+	if time.Since(p.Price.Age) > 5*time.Minute {
+		if time.Since(p.Price.Age) > 10*time.Minute {
+			logger.
+				WithFields(fields).
+				Warn("Price message rejected, the message is older than 10 min")
+			return pubsub.ValidationReject
+		}
+		logger.
+			WithFields(fields).
+			Warn("Price message ignored, the message is older than 5 min")
+		return pubsub.ValidationIgnore
+	}
+	return pubsub.ValidationAccept
+}
@@ -21,6 +21,8 @@ import (
 	"reflect"
 	"sync"
 
+	"github.com/defiweb/go-eth/types"
+
 	"github.com/chronicleprotocol/oracle-suite/pkg/transport"
 	"github.com/chronicleprotocol/oracle-suite/pkg/util/chanutil"
 )
@@ -28,9 +30,15 @@ import (
 const TransportName = "local"
 
 var ErrNotSubscribed = errors.New("topic is not subscribed")
+var ErrUnknownRecipient = errors.New("unknown recipient")
 
 // Local is a simple implementation of the transport.Transport interface
 // using local channels.
+//
+// Local also implements the transport.DirectTransport interface. Direct
+// messages are delivered to the instance whose author is equal to the
+// recipient address. Instances sharing the same base (see WithAuthor) can
+// send direct messages to each other.
 type Local struct {
 	*base
 	author []byte
@@ -40,7 +48,10 @@ type base struct {
 	mu     sync.RWMutex
 	ctx    context.Context
 	waitCh chan error
+	queue  int
+	topics map[string]transport.Message
 	subs   map[string]*subscription
+	direct map[string]map[string]*subscription // Direct message subscriptions by author and topic.
 }
 
 type rawMsg struct {
@@ -71,25 +82,24 @@ func New(author []byte, queue int, topics map[string]transport.Message) *Local {
 	l := &Local{
 		base: &base{
 			waitCh: make(chan error),
+			queue:  queue,
+			topics: topics,
 			subs:   make(map[string]*subscription),
+			direct: make(map[string]map[string]*subscription),
 		},
 		author: author,
 	}
 	for topic, typ := range topics {
-		msgCh := make(chan transport.ReceivedMessage)
-		sub := &subscription{
-			typ:       reflect.TypeOf(typ).Elem(),
-			rawMsgCh:  make(chan rawMsg, queue),
-			msgCh:     msgCh,
-			msgFanOut: chanutil.NewFanOut(msgCh),
-		}
-		l.subs[topic] = sub
-		go l.unmarshallRoutine(sub)
+		l.subs[topic] = l.newSubscription(typ)
 	}
+	l.addDirectSubscriptions(author)
 	return l
 }
 
 func (l *Local) WithAuthor(author []byte) *Local {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.addDirectSubscriptions(author)
 	return &Local{base: l.base, author: author}
 }
 
@@ -136,6 +146,60 @@ func (l *Local) Messages(topic string) <-chan transport.ReceivedMessage {
 	return nil
 }
 
+// Send implements the transport.DirectTransport interface.
+func (l *Local) Send(peerAddr types.Address, topic string, message transport.Message) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	subs, ok := l.direct[string(peerAddr.Bytes())]
+	if !ok {
+		return ErrUnknownRecipient
+	}
+	if sub, ok := subs[topic]; ok {
+		data, err := message.MarshallBinary()
+		if err != nil {
+			return err
+		}
+		sub.rawMsgCh <- rawMsg{author: l.author, data: data}
+		return nil
+	}
+	return ErrNotSubscribed
+}
+
+// DirectMessages implements the transport.DirectTransport interface.
+func (l *Local) DirectMessages(topic string) <-chan transport.ReceivedMessage {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if sub, ok := l.direct[string(l.author)][topic]; ok {
+		return sub.msgFanOut.Chan()
+	}
+	return nil
+}
+
+func (l *Local) newSubscription(typ transport.Message) *subscription {
+	msgCh := make(chan transport.ReceivedMessage)
+	sub := &subscription{
+		typ:       reflect.TypeOf(typ).Elem(),
+		rawMsgCh:  make(chan rawMsg, l.queue),
+		msgCh:     msgCh,
+		msgFanOut: chanutil.NewFanOut(msgCh),
+	}
+	go l.unmarshallRoutine(sub)
+	return sub
+}
+
+// addDirectSubscriptions creates subscriptions for direct messages sent to
+// the given author, unless they already exist.
+func (l *Local) addDirectSubscriptions(author []byte) {
+	if _, ok := l.direct[string(author)]; ok || l.direct == nil {
+		return
+	}
+	subs := make(map[string]*subscription, len(l.topics))
+	for topic, typ := range l.topics {
+		subs[topic] = l.newSubscription(typ)
+	}
+	l.direct[string(author)] = subs
+}
+
 func (l *Local) unmarshallRoutine(sub *subscription) {
 	for {
 		rawMsg, ok := <-sub.rawMsgCh
@@ -164,5 +228,11 @@ func (l *Local) contextCancelHandler() {
 	for _, sub := range l.subs {
 		close(sub.msgCh)
 	}
+	for _, subs := range l.direct {
+		for _, sub := range subs {
+			close(sub.msgCh)
+		}
+	}
 	l.subs = nil
+	l.direct = nil
 }
@@ -19,6 +19,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/defiweb/go-eth/types"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/transport"
@@ -60,3 +61,29 @@ func TestLocal_Messages(t *testing.T) {
 	assert.NoError(t, l.Broadcast("foo", &testMsg{Val: "bar"}))
 	assert.Equal(t, &testMsg{Val: "bar"}, (<-l.Messages("foo")).Message)
 }
+
+func TestLocal_Send(t *testing.T) {
+	ctx, ctxCancel := context.WithCancel(context.Background())
+	defer ctxCancel()
+
+	addr1 := types.MustAddressFromHex("0x1234567890123456789012345678901234567890")
+	addr2 := types.MustAddressFromHex("0x2345678901234567890123456789012345678901")
+	l1 := New(addr1.Bytes(), 1, map[string]transport.Message{"foo": (*testMsg)(nil)})
+	l2 := l1.WithAuthor(addr2.Bytes())
+	_ = l1.Start(ctx)
+
+	// Message is delivered only to the recipient:
+	assert.NoError(t, l1.Send(addr2, "foo", &testMsg{Val: "bar"}))
+	msg := <-l2.DirectMessages("foo")
+	assert.Equal(t, &testMsg{Val: "bar"}, msg.Message)
+	assert.Equal(t, addr1.Bytes(), msg.Author)
+	select {
+	case <-l1.DirectMessages("foo"):
+		assert.Fail(t, "unexpected message")
+	default:
+	}
+
+	// Unknown recipient and topic:
+	assert.ErrorIs(t, l1.Send(types.ZeroAddress, "foo", &testMsg{Val: "bar"}), ErrUnknownRecipient)
+	assert.ErrorIs(t, l1.Send(addr2, "bar", &testMsg{Val: "bar"}), ErrNotSubscribed)
+}
@@ -19,6 +19,8 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/defiweb/go-eth/types"
+
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
 	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
 	"github.com/chronicleprotocol/oracle-suite/pkg/supervisor"
@@ -96,6 +98,56 @@ func (r *Logger) Messages(topic string) <-chan transport.ReceivedMessage {
 	return fo.Chan()
 }
 
+// Send implements the transport.DirectTransport interface.
+func (r *Logger) Send(peerAddr types.Address, topic string, msg transport.Message) error {
+	dt, ok := r.t.(transport.DirectTransport)
+	if !ok {
+		return transport.ErrDirectNotSupported
+	}
+	if !log.IsLevel(r.l, log.Debug) {
+		return dt.Send(peerAddr, topic, msg)
+	}
+	err := dt.Send(peerAddr, topic, msg)
+	log := r.l.
+		WithFields(log.Fields{
+			"peerAddr": peerAddr,
+			"topic":    topic,
+			"message":  msg,
+		})
+	if err != nil {
+		log = log.WithError(err)
+	}
+	log.Debug("Send direct message")
+	return err
+}
+
+// DirectMessages implements the transport.DirectTransport interface.
+func (r *Logger) DirectMessages(topic string) <-chan transport.ReceivedMessage {
+	dt, ok := r.t.(transport.DirectTransport)
+	if !ok {
+		return nil
+	}
+	if !log.IsLevel(r.l, log.Debug) {
+		return dt.DirectMessages(topic)
+	}
+	in := dt.DirectMessages(topic)
+	if in == nil {
+		return nil
+	}
+	fo := chanutil.NewFanOut(in)
+	go func() {
+		for msg := range fo.Chan() {
+			r.l.
+				WithFields(log.Fields{
+					"topic":   topic,
+					"message": msg,
+				}).
+				Debug("Received direct message")
+		}
+	}()
+	return fo.Chan()
+}
+
 // ServiceName implements the supervisor.WithName interface.
 func (r *Logger) ServiceName() string {
 	return fmt.Sprintf("Logger(%s)", supervisor.ServiceName(r.t))
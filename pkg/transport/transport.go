@@ -17,8 +17,11 @@ package transport
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 
+	"github.com/defiweb/go-eth/types"
+
 	"github.com/chronicleprotocol/oracle-suite/pkg/log"
 	"github.com/chronicleprotocol/oracle-suite/pkg/supervisor"
 )
@@ -55,6 +58,24 @@ type Transport interface {
 	Messages(topic string) <-chan ReceivedMessage
 }
 
+// ErrDirectNotSupported is returned when a message cannot be sent directly
+// because the transport does not support direct messages.
+var ErrDirectNotSupported = errors.New("direct messages are not supported")
+
+// DirectTransport is implemented by transports that are able to send messages
+// directly to a single recipient instead of broadcasting them.
+type DirectTransport interface {
+	// Send sends a message with a given topic directly to the feed with the
+	// given Ethereum address. The message is encrypted, so it can be read
+	// only by the recipient.
+	Send(peerAddr types.Address, topic string, message Message) error
+
+	// DirectMessages returns a channel for incoming direct messages. It
+	// works the same as the Messages method, but only messages sent
+	// directly to this node are returned.
+	DirectMessages(topic string) <-chan ReceivedMessage
+}
+
 // ReceivedMessage contains a Message received from Transport.
 type ReceivedMessage struct {
 	// Message contains the message content. It is nil when the Error field
@@ -17,12 +17,21 @@ package webapi
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/defiweb/go-eth/abi"
 	"github.com/defiweb/go-eth/rpc"
 	"github.com/defiweb/go-eth/types"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/log/null"
 )
 
 // AddressBook provides a list of addresses to which the messages should be
@@ -31,6 +40,14 @@ type AddressBook interface {
 	Consumers(ctx context.Context) ([]string, error)
 }
 
+// ReachabilityChecker is an optional interface of an AddressBook that knows
+// which of its consumers are temporarily unreachable. Such consumers are
+// still returned by the Consumers method, so that their outbound queues are
+// kept, but messages are not sent to them until they are reachable again.
+type ReachabilityChecker interface {
+	Reachable(addr string) bool
+}
+
 type NullAddressBook struct{}
 
 func (NullAddressBook) Consumers(_ context.Context) ([]string, error) {
@@ -74,6 +91,17 @@ func (m *MultiAddressBook) Consumers(ctx context.Context) ([]string, error) {
 	return addresses, nil
 }
 
+// Reachable implements the ReachabilityChecker interface. A consumer is
+// reachable unless any of the address books reports otherwise.
+func (m *MultiAddressBook) Reachable(addr string) bool {
+	for _, book := range m.books {
+		if rc, ok := book.(ReachabilityChecker); ok && !rc.Reachable(addr) {
+			return false
+		}
+	}
+	return true
+}
+
 // StaticAddressBook is an implementation of AddressBook that returns a static
 // list of addresses.
 type StaticAddressBook struct {
@@ -150,4 +178,253 @@ func (c *EthereumAddressBook) fetchConsumers(ctx context.Context) ([]string, err
 	return addrs, nil
 }
 
+const (
+	// defaultTorCheckInterval is the default interval at which reachable
+	// consumers are checked again.
+	defaultTorCheckInterval = 5 * time.Minute
+
+	// defaultTorCheckTimeout is the default timeout for a single health check.
+	defaultTorCheckTimeout = 30 * time.Second
+
+	// defaultTorCooldown is the default time for which unreachable consumers
+	// are skipped.
+	defaultTorCooldown = 10 * time.Minute
+)
+
+// onionV3Regexp matches the host part of a v3 onion address.
+var onionV3Regexp = regexp.MustCompile(`^[a-z2-7]{56}\.onion$`)
+
+// DialContextFunc is a function used to establish network connections.
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// TorAddressBookConfig is the configuration for the TorAddressBook.
+type TorAddressBookConfig struct {
+	// Register is the address book that provides the list of onion addresses,
+	// usually an EthereumAddressBook that reads the TorAddressRegister
+	// contract. Addresses that are not valid v3 onion addresses are ignored.
+	Register AddressBook
+
+	// Static is an optional list of static addresses. Static addresses are
+	// always returned and are not health-checked. Register addresses that
+	// duplicate static ones are ignored.
+	Static []string
+
+	// Dial is used to health-check consumers. It should establish connections
+	// through the SOCKS5 proxy of the Tor client.
+	Dial DialContextFunc
+
+	// CheckInterval is the interval at which reachable consumers are checked
+	// again. If zero, the default value of 5 minutes is used.
+	CheckInterval time.Duration
+
+	// CheckTimeout is the timeout for a single health check. If zero, the
+	// default value of 30 seconds is used.
+	CheckTimeout time.Duration
+
+	// Cooldown is the time for which unreachable consumers are skipped.
+	// If zero, the default value of 10 minutes is used.
+	Cooldown time.Duration
+
+	// Logger is a custom logger instance. If not provided then null
+	// logger is used.
+	Logger log.Logger
+}
+
+// TorAddressBook is an AddressBook implementation that provides onion
+// addresses of consumers from the register address book.
+//
+// Consumers are health-checked in the background by connecting to them using
+// the provided dialer. Consumers that are unreachable are reported by the
+// Reachable method until the cooldown period expires, after which they are
+// checked again. They are still returned by the Consumers method, so messages
+// for them are queued and delivered once they are reachable again. Consumers
+// that have not been checked yet are assumed to be reachable.
+type TorAddressBook struct {
+	mu sync.Mutex
+
+	register      AddressBook
+	static        []string
+	dial          DialContextFunc
+	checkInterval time.Duration
+	checkTimeout  time.Duration
+	cooldown      time.Duration
+	log           log.Logger
+	health        map[string]*torConsumerHealth
+}
+
+// torConsumerHealth is the health-check state of a single consumer.
+type torConsumerHealth struct {
+	checking    bool      // True if the health check is in progress.
+	unreachable bool      // True if the last health check failed.
+	nextCheck   time.Time // Time of the next health check.
+}
+
+// NewTorAddressBook creates a new instance of TorAddressBook.
+func NewTorAddressBook(cfg TorAddressBookConfig) (*TorAddressBook, error) {
+	if cfg.Register == nil {
+		return nil, errors.New("register address book must be provided")
+	}
+	if cfg.Dial == nil {
+		return nil, errors.New("dial function must be provided")
+	}
+	if cfg.CheckInterval == 0 {
+		cfg.CheckInterval = defaultTorCheckInterval
+	}
+	if cfg.CheckTimeout == 0 {
+		cfg.CheckTimeout = defaultTorCheckTimeout
+	}
+	if cfg.Cooldown == 0 {
+		cfg.Cooldown = defaultTorCooldown
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = null.New()
+	}
+	return &TorAddressBook{
+		register:      cfg.Register,
+		static:        cfg.Static,
+		dial:          cfg.Dial,
+		checkInterval: cfg.CheckInterval,
+		checkTimeout:  cfg.CheckTimeout,
+		cooldown:      cfg.Cooldown,
+		log:           cfg.Logger.WithField("tag", LoggerTag),
+		health:        make(map[string]*torConsumerHealth),
+	}, nil
+}
+
+// Consumers implements the AddressBook interface.
+func (c *TorAddressBook) Consumers(ctx context.Context) ([]string, error) {
+	addrs, err := c.register.Consumers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var (
+		consumers []string
+		seen      = make(map[string]bool)
+		now       = time.Now()
+	)
+	for _, addr := range c.static {
+		key := consumerKey(addr)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		consumers = append(consumers, addr)
+	}
+	for _, addr := range addrs {
+		key := consumerKey(addr)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		hostport, err := onionHostPort(addr)
+		if err != nil {
+			c.log.
+				WithError(err).
+				WithField("address", addr).
+				WithAdvice("The address in the register is invalid and must be corrected by its owner").
+				Warn("Invalid consumer address")
+			continue
+		}
+		h := c.health[key]
+		if h == nil {
+			h = &torConsumerHealth{}
+			c.health[key] = h
+		}
+		if !h.checking && !now.Before(h.nextCheck) {
+			h.checking = true
+			go c.check(ctx, key, addr, hostport)
+		}
+		consumers = append(consumers, addr)
+	}
+
+	// Forget consumers that are no longer in the register.
+	for key := range c.health {
+		if !seen[key] {
+			delete(c.health, key)
+		}
+	}
+	return consumers, nil
+}
+
+// Reachable implements the ReachabilityChecker interface.
+func (c *TorAddressBook) Reachable(addr string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.health[consumerKey(addr)]
+	return h == nil || !h.unreachable
+}
+
+// check performs a health check of a single consumer.
+func (c *TorAddressBook) check(ctx context.Context, key, addr, hostport string) {
+	ctx, ctxCancel := context.WithTimeout(ctx, c.checkTimeout)
+	defer ctxCancel()
+	conn, err := c.dial(ctx, "tcp", hostport)
+	if err == nil {
+		_ = conn.Close()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.health[key]
+	if h == nil {
+		return
+	}
+	h.checking = false
+	switch {
+	case err == nil:
+		if h.unreachable {
+			c.log.
+				WithField("address", addr).
+				Info("Consumer is reachable again")
+		}
+		h.unreachable = false
+		h.nextCheck = time.Now().Add(c.checkInterval)
+	case errors.Is(ctx.Err(), context.Canceled):
+		// The parent context was canceled, the result is not reliable.
+	default:
+		c.log.
+			WithError(err).
+			WithField("address", addr).
+			WithField("cooldown", c.cooldown).
+			WithAdvice("Ignore if occurs occasionally, the consumer will be skipped until the cooldown expires").
+			Warn("Consumer is unreachable")
+		h.unreachable = true
+		h.nextCheck = time.Now().Add(c.cooldown)
+	}
+}
+
+// consumerKey returns a key used to deduplicate consumer addresses.
+func consumerKey(addr string) string {
+	return strings.TrimRight(strings.ToLower(normalizeAddr(strings.TrimSpace(addr))), "/")
+}
+
+// onionHostPort verifies that the address is a valid v3 onion address and
+// returns its host and port in the format expected by the dialer.
+func onionHostPort(addr string) (string, error) {
+	u, err := url.Parse(normalizeAddr(strings.TrimSpace(addr)))
+	if err != nil {
+		return "", err
+	}
+	host := strings.ToLower(u.Hostname())
+	if !onionV3Regexp.MatchString(host) {
+		return "", fmt.Errorf("%q is not a valid v3 onion address", host)
+	}
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		default:
+			return "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+		}
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
 var consumersMethod = abi.MustParseMethod("function list() returns (string[])")
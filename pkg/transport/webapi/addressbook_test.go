@@ -2,7 +2,11 @@ package webapi
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -181,3 +185,108 @@ func TestEthereumAddressBook_Cache(t *testing.T) {
 func encodeAddresses(addresses []string) []byte {
 	return errutil.Must(abi.EncodeValues(consumersMethod.Outputs(), addresses))
 }
+
+func TestTorAddressBook_Consumers(t *testing.T) {
+	var (
+		onion1 = strings.Repeat("a", 56) + ".onion"
+		onion2 = strings.Repeat("b", 56) + ".onion"
+		onion3 = strings.Repeat("c", 56) + ".onion"
+	)
+
+	var (
+		mu      sync.Mutex
+		dialed  []string
+		offline = map[string]bool{onion2 + ":80": true}
+	)
+	dial := func(_ context.Context, _, addr string) (net.Conn, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		dialed = append(dialed, addr)
+		if offline[addr] {
+			return nil, errors.New("unreachable")
+		}
+		c1, c2 := net.Pipe()
+		_ = c2.Close()
+		return c1, nil
+	}
+	dialCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(dialed)
+	}
+
+	book, err := NewTorAddressBook(TorAddressBookConfig{
+		Register: NewStaticAddressBook([]string{
+			onion1,
+			onion2,
+			"http://" + onion3 + ":8080",
+			"example.com",        // Not an onion address.
+			"abc.onion",          // Invalid onion address.
+			"http://" + onion1,   // Duplicate.
+			"http://static.test", // Duplicate of the static address.
+		}),
+		Static:   []string{"static.test"},
+		Dial:     dial,
+		Cooldown: 200 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	// Consumers that have not been checked yet are assumed to be reachable.
+	ctx := context.Background()
+	consumers, err := book.Consumers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"static.test", onion1, onion2, "http://" + onion3 + ":8080"}, consumers)
+	assert.Eventually(t, func() bool { return dialCount() == 3 }, time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []string{onion1 + ":80", onion2 + ":80", onion3 + ":8080"}, dialed)
+
+	// Unreachable consumer is flagged until the cooldown expires, but it is
+	// still returned, so its outbound queue is kept.
+	assert.Eventually(t, func() bool { return !book.Reachable(onion2) }, time.Second, 10*time.Millisecond)
+	assert.True(t, book.Reachable("http://"+onion1))
+	assert.True(t, book.Reachable("static.test"))
+	consumers, err = book.Consumers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"static.test", onion1, onion2, "http://" + onion3 + ":8080"}, consumers)
+	assert.Equal(t, 3, dialCount())
+
+	// After the cooldown, the consumer is checked again.
+	mu.Lock()
+	offline = map[string]bool{}
+	mu.Unlock()
+	time.Sleep(200 * time.Millisecond)
+	assert.Eventually(t, func() bool {
+		_, err = book.Consumers(ctx)
+		require.NoError(t, err)
+		return book.Reachable(onion2)
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 4, dialCount())
+}
+
+func Test_onionHostPort(t *testing.T) {
+	onion := strings.Repeat("a", 56) + ".onion"
+	tests := []struct {
+		addr    string
+		want    string
+		wantErr bool
+	}{
+		{addr: onion, want: onion + ":80"},
+		{addr: "http://" + onion, want: onion + ":80"},
+		{addr: "https://" + onion, want: onion + ":443"},
+		{addr: "http://" + strings.ToUpper(onion) + ":8080/", want: onion + ":8080"},
+		{addr: "example.com", wantErr: true},
+		{addr: strings.Repeat("a", 16) + ".onion", wantErr: true},
+		{addr: strings.Repeat("1", 56) + ".onion", wantErr: true},
+		{addr: "ftp://" + onion, wantErr: true},
+	}
+	for n, tt := range tests {
+		t.Run(fmt.Sprintf("case-%d", n+1), func(t *testing.T) {
+			got, err := onionHostPort(tt.addr)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
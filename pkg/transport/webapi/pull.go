@@ -0,0 +1,442 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package webapi
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	netURL "net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/log"
+	"github.com/chronicleprotocol/oracle-suite/pkg/transport/webapi/pb"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/maputil"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/sliceutil"
+)
+
+const (
+	// defaultPullWindow is the default time for which broadcast messages
+	// are available on the pull endpoint.
+	defaultPullWindow = 5 * time.Minute
+
+	// pullTimestampHeader is the name of the HTTP header that contains the
+	// timestamp of the newest message in the pull response, in nanoseconds.
+	// It should be used as the since parameter in the next pull request.
+	pullTimestampHeader = "X-Timestamp"
+
+	// maxPullResponseSize is the maximum size of the pull response body.
+	maxPullResponseSize = 10 * 1024 * 1024 // 10MB
+
+	// pullNonceSize is the size of the random value in the pull request URL.
+	pullNonceSize = 16
+)
+
+// pullMessage is a message available on the pull endpoint.
+type pullMessage struct {
+	topic string
+	data  []byte
+	time  int64 // UNIX timestamp in nanoseconds, unique for each message.
+}
+
+// addPullMessages makes messages from the message pack available on the pull
+// endpoint and removes messages older than the pull window.
+//
+// Each message gets a unique timestamp that is greater than timestamps of
+// all previous messages, so consumers that use the timestamp of the last
+// pulled message as the since parameter never skip a message, even if
+// several messages are added within the same clock tick.
+func (w *WebAPI) addPullMessages(mp *pb.MessagePack, t time.Time) {
+	if len(w.pullAllow) == 0 {
+		return
+	}
+	w.pullMu.Lock()
+	defer w.pullMu.Unlock()
+	n := 0
+	for n < len(w.pullMsgs) && w.pullMsgs[n].time < t.Add(-w.pullWindow).UnixNano() {
+		n++
+	}
+	w.pullMsgs = w.pullMsgs[n:]
+	if mp == nil {
+		return
+	}
+	tm := t.UnixNano()
+	for _, topic := range maputil.SortKeys(mp.Messages, sort.Strings) {
+		for _, data := range mp.Messages[topic].Data {
+			if tm <= w.pullLast {
+				tm = w.pullLast + 1
+			}
+			w.pullLast = tm
+			w.pullMsgs = append(w.pullMsgs, pullMessage{topic: topic, data: data, time: tm})
+		}
+	}
+}
+
+// pullHandler serves messages to consumers that pull them.
+//
+// Request must be a GET request to the /pull path. The URL must be signed
+// using the signPullURL function by one of the allowed consumers. The since
+// query parameter specifies the UNIX timestamp in nanoseconds of the newest
+// message already received by the consumer, only newer messages are returned. Each
+// signed URL is accepted only once.
+//
+// The response body has the same format as the body of the consume request.
+// If there are no new messages, the 204 No Content response is returned.
+func (w *WebAPI) pullHandler(res http.ResponseWriter, req *http.Request) {
+	// Skip if the context is canceled.
+	if w.ctx.Err() != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	fields := log.Fields{
+		"method": req.Method,
+		"addr":   req.RemoteAddr,
+		"url":    req.URL.String(),
+	}
+
+	w.log.
+		WithFields(fields).
+		Debug("Received pull request")
+
+	// Pull endpoint is available only if there are allowed consumers.
+	if len(w.pullAllow) == 0 || w.signer == nil {
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Only GET requests are allowed.
+	if req.Method != http.MethodGet {
+		w.log.
+			WithFields(fields).
+			WithAdvice("This may happen if someone is trying to connect to the WebAPI server with incompatible software").
+			Warn("Invalid request method")
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Verify the request URL signature.
+	pr, err := verifyPullURL(req.URL.String(), w.recover)
+	if err != nil {
+		w.log.
+			WithFields(fields).
+			WithError(err).
+			WithAdvice("This may indicate a bug in the WebAPI server or a bug in the consumer software, or someone is trying to connect to the WebAPI server with incompatible software"). //nolint:lll
+			Warn("Invalid request signature")
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	fields["author"] = pr.author
+	fields["timestamp"] = pr.timestamp
+
+	// Verify if the consumer is allowed to pull messages.
+	if !sliceutil.Contains(w.pullAllow, pr.author) {
+		w.log.
+			WithFields(fields).
+			Debug("Consumer is not allowed to pull messages")
+		res.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	// Request timestamp must be within the allowed time window:
+	// [now - maxClockSkew, now + maxClockSkew].
+	currentTimestamp := time.Now()
+	if pr.timestamp.After(currentTimestamp.Add(w.maxClockSkew)) || pr.timestamp.Before(currentTimestamp.Add(-w.maxClockSkew)) {
+		w.log.
+			WithFields(fields).
+			WithAdvice("This may be cased by setting incorrect system time on this server or server that send the request").
+			Warn("Invalid request timestamp")
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Each signed URL can be used only once.
+	if !w.usePullNonce(pr, currentTimestamp) {
+		w.log.
+			WithFields(fields).
+			WithAdvice("This may indicate that someone is trying to replay requests of the consumer").
+			Warn("Replayed pull request")
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	since := pr.since
+
+	// Collect messages newer than the since parameter.
+	mp := &pb.MessagePack{Messages: make(map[string]*pb.MessagePack_Messages)}
+	last := since
+	w.pullMu.Lock()
+	for _, msg := range w.pullMsgs {
+		if msg.time <= since {
+			continue
+		}
+		if mp.Messages[msg.topic] == nil {
+			mp.Messages[msg.topic] = &pb.MessagePack_Messages{}
+		}
+		mp.Messages[msg.topic].Data = append(mp.Messages[msg.topic].Data, msg.data)
+		if msg.time > last {
+			last = msg.time
+		}
+	}
+	w.pullMu.Unlock()
+	res.Header().Set(pullTimestampHeader, strconv.FormatInt(last, 10))
+	if len(mp.Messages) == 0 {
+		res.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Sign and encode the message pack.
+	bin, err := encodeMessagePack(mp, w.signer)
+	if err != nil {
+		w.log.
+			WithFields(fields).
+			WithError(err).
+			WithAdvice("This is a bug and must be investigated").
+			Error("Unable to encode messages")
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	res.Header().Set("Content-Type", "application/x-protobuf")
+	res.Header().Set("Content-Encoding", "gzip")
+	res.WriteHeader(http.StatusOK)
+	_, _ = res.Write(bin)
+}
+
+// pullRoutine periodically pulls messages from the producers.
+func (w *WebAPI) pullRoutine(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case tm := <-w.pullTicker.TickCh():
+			for _, addr := range w.pullFrom {
+				go func(addr string) {
+					if err := w.pullMessages(ctx, normalizeAddr(addr), tm); err != nil {
+						w.log.
+							WithError(err).
+							WithField("address", addr).
+							WithAdvice("Ignore if occurs occasionally, especially if it is related to temporary network issues").
+							Warn("Failed to pull messages from producer")
+					}
+				}(addr)
+			}
+		}
+	}
+}
+
+// pullMessages pulls new messages from the producer with the given address.
+// The t parameter is the time used for the URL signature.
+//
+//nolint:funlen
+func (w *WebAPI) pullMessages(ctx context.Context, addr string, t time.Time) error {
+	w.log.WithField("address", addr).Debug("Pulling messages from producer")
+
+	w.pullMu.Lock()
+	since := w.lastPulls[addr]
+	w.pullMu.Unlock()
+
+	// Sign the URL.
+	url, err := signPullURL(
+		fmt.Sprintf("%s%s", strings.TrimRight(addr, "/"), pullPath),
+		t,
+		since,
+		w.signer,
+		w.rand,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to sign URL: %w", err)
+	}
+
+	// Send the request.
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	// Setting the Accept-Encoding header explicitly disables transparent
+	// decompression in the HTTP client.
+	req.Header.Set("Accept-Encoding", "gzip")
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		return nil
+	default:
+		return fmt.Errorf("unexpected response status: %s", res.Status)
+	}
+	if h := res.Header.Get("Content-Encoding"); h != "gzip" {
+		return fmt.Errorf("invalid response encoding: %s", h)
+	}
+	last, err := strconv.ParseInt(res.Header.Get(pullTimestampHeader), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s header: %w", pullTimestampHeader, err)
+	}
+
+	// Read and decode the response body.
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxPullResponseSize))
+	if err != nil {
+		return err
+	}
+	body, err = gzipDecompress(body)
+	if err != nil {
+		return err
+	}
+	mp := &pb.MessagePack{}
+	if err := proto.Unmarshal(body, mp); err != nil {
+		return err
+	}
+
+	// Verify the message signature and verify that the author is allowed
+	// to send messages.
+	author, err := verifyMessage(mp, w.recover)
+	if err != nil {
+		return fmt.Errorf("invalid message pack signature: %w", err)
+	}
+	if !sliceutil.Contains(w.allowlist, *author) {
+		return fmt.Errorf("feed %s is not allowed to send messages", author)
+	}
+
+	w.pullMu.Lock()
+	if last > w.lastPulls[addr] {
+		w.lastPulls[addr] = last
+	}
+	w.pullMu.Unlock()
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.ctx.Err() != nil {
+		return nil
+	}
+	w.dispatchMessages(mp, *author, log.Fields{"addr": addr, "author": author})
+	return nil
+}
+
+// pullRequest contains the parameters of a signed pull request URL.
+type pullRequest struct {
+	author    types.Address
+	timestamp time.Time
+	since     int64
+	nonce     string
+}
+
+// usePullNonce records the random value of the pull request and returns
+// false if it was already used by the same consumer. Values are kept only
+// as long as the request timestamp is within the allowed clock skew,
+// because older requests are rejected anyway.
+func (w *WebAPI) usePullNonce(pr *pullRequest, now time.Time) bool {
+	w.pullMu.Lock()
+	defer w.pullMu.Unlock()
+	for key, t := range w.pullNonces {
+		if t.Before(now.Add(-w.maxClockSkew)) {
+			delete(w.pullNonces, key)
+		}
+	}
+	key := pr.author.String() + pr.nonce
+	if _, ok := w.pullNonces[key]; ok {
+		return false
+	}
+	w.pullNonces[key] = pr.timestamp
+	return true
+}
+
+// signPullURL signs the pull request URL. It works like signURL, but the
+// since parameter is also signed and the signed values are delimited, so
+// digits cannot be moved between them without invalidating the signature:
+//
+//	signature = sign(timestamp + ":" + rand + ":" + since)
+func signPullURL(url string, tm time.Time, since int64, signer wallet.Key, rand io.Reader) (string, error) {
+	r := make([]byte, pullNonceSize)
+	if _, err := rand.Read(r); err != nil {
+		return "", err
+	}
+	t := strconv.FormatInt(tm.Unix(), 10)
+	v := strconv.FormatInt(since, 10)
+	s, err := signer.SignMessage(pullSigningData(t, hex.EncodeToString(r), v))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s?t=%s&r=%x&since=%s&s=%x", url, t, r, v, s.Bytes()), nil
+}
+
+// verifyPullURL verifies URL signature calculated by signPullURL and returns
+// the pull request parameters.
+func verifyPullURL(url string, recover crypto.Recoverer) (*pullRequest, error) {
+	p, err := netURL.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+	q := p.Query()
+	t, err := parseCanonicalInt(q.Get("t"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid t parameter: %w", err)
+	}
+	since, err := parseCanonicalInt(q.Get("since"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid since parameter: %w", err)
+	}
+	r := q.Get("r")
+	if len(r) != pullNonceSize*2 {
+		return nil, errors.New("invalid r parameter length")
+	}
+	if rb, err := hex.DecodeString(r); err != nil || hex.EncodeToString(rb) != r {
+		return nil, errors.New("invalid r parameter")
+	}
+	s, err := hex.DecodeString(q.Get("s"))
+	if err != nil {
+		return nil, err
+	}
+	if len(s) != 65 {
+		return nil, errors.New("invalid signature length")
+	}
+	addr, err := recover.RecoverMessage(pullSigningData(q.Get("t"), r, q.Get("since")), types.MustSignatureFromBytes(s))
+	if err != nil {
+		return nil, err
+	}
+	return &pullRequest{author: *addr, timestamp: time.Unix(t, 0), since: since, nonce: r}, nil
+}
+
+// pullSigningData returns the data signed in the pull request URL.
+func pullSigningData(t, r, since string) []byte {
+	return []byte(t + ":" + r + ":" + since)
+}
+
+// parseCanonicalInt parses a non-negative decimal integer. Numbers with
+// signs or leading zeros are rejected, so every number has exactly one
+// valid representation.
+func parseCanonicalInt(s string) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || strconv.FormatInt(n, 10) != s {
+		return 0, fmt.Errorf("%q is not a canonical decimal number", s)
+	}
+	return n, nil
+}
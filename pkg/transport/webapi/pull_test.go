@@ -0,0 +1,240 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package webapi
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	netURL "net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/httpserver"
+	"github.com/chronicleprotocol/oracle-suite/pkg/transport"
+	"github.com/chronicleprotocol/oracle-suite/pkg/transport/webapi/pb"
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/timeutil"
+)
+
+func Test_WebAPI_Pull(t *testing.T) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+
+	prodKey := wallet.NewRandomKey()
+	consKey := wallet.NewRandomKey()
+	prodSrv := httpserver.New(&http.Server{Addr: "127.0.0.1:0"})
+
+	prod, err := New(Config{
+		Topics:        map[string]transport.Message{"test": (*message)(nil)},
+		AddressBook:   NullAddressBook{},
+		Signer:        prodKey,
+		FlushTicker:   timeutil.NewTicker(0),
+		Server:        prodSrv,
+		PullAllowlist: []types.Address{consKey.Address()},
+	})
+	require.NoError(t, err)
+
+	cons, err := New(Config{
+		Topics:          map[string]transport.Message{"test": (*message)(nil)},
+		AuthorAllowlist: []types.Address{prodKey.Address()},
+		AddressBook:     NullAddressBook{},
+		Signer:          consKey,
+		FlushTicker:     timeutil.NewTicker(0),
+		PullProducers:   []string{"placeholder"},
+		PullTicker:      timeutil.NewTicker(0),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, prod.Start(ctx))
+	require.NoError(t, cons.Start(ctx))
+	cons.pullFrom = []string{prodSrv.Addr().String()}
+	ch := cons.Messages("test")
+
+	// Broadcast a message and wait until it is available on the pull endpoint.
+	require.NoError(t, prod.Broadcast("test", &message{data: []byte("data")}))
+	prod.flushTicker.TickAt(time.Now().Add(-time.Second))
+	assert.Eventually(t, func() bool {
+		prod.pullMu.Lock()
+		defer prod.pullMu.Unlock()
+		return len(prod.pullMsgs) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Pull the message.
+	cons.pullTicker.Tick()
+	msg := <-ch
+	assert.Equal(t, []byte("data"), msg.Message.(*message).data)
+	assert.Equal(t, prodKey.Address().Bytes(), msg.Author)
+
+	// The same message must not be pulled twice.
+	addr := normalizeAddr(prodSrv.Addr().String())
+	assert.Eventually(t, func() bool {
+		cons.pullMu.Lock()
+		defer cons.pullMu.Unlock()
+		return cons.lastPulls[addr] > 0
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, cons.pullMessages(ctx, addr, time.Now()))
+	select {
+	case <-ch:
+		assert.Fail(t, "unexpected message")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func Test_WebAPI_PullNotAllowed(t *testing.T) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+
+	prodKey := wallet.NewRandomKey()
+	consKey := wallet.NewRandomKey()
+	prodSrv := httpserver.New(&http.Server{Addr: "127.0.0.1:0"})
+
+	prod, err := New(Config{
+		AddressBook:   NullAddressBook{},
+		Signer:        prodKey,
+		FlushTicker:   timeutil.NewTicker(0),
+		Server:        prodSrv,
+		PullAllowlist: []types.Address{prodKey.Address()},
+	})
+	require.NoError(t, err)
+
+	cons, err := New(Config{
+		AuthorAllowlist: []types.Address{prodKey.Address()},
+		AddressBook:     NullAddressBook{},
+		Signer:          consKey,
+		FlushTicker:     timeutil.NewTicker(0),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, prod.Start(ctx))
+	require.NoError(t, cons.Start(ctx))
+
+	err = cons.pullMessages(ctx, normalizeAddr(prodSrv.Addr().String()), time.Now())
+	assert.ErrorContains(t, err, "403")
+}
+
+func Test_WebAPI_PullReplay(t *testing.T) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+
+	prodKey := wallet.NewRandomKey()
+	consKey := wallet.NewRandomKey()
+	prodSrv := httpserver.New(&http.Server{Addr: "127.0.0.1:0"})
+
+	prod, err := New(Config{
+		AddressBook:   NullAddressBook{},
+		Signer:        prodKey,
+		FlushTicker:   timeutil.NewTicker(0),
+		Server:        prodSrv,
+		PullAllowlist: []types.Address{consKey.Address()},
+	})
+	require.NoError(t, err)
+	require.NoError(t, prod.Start(ctx))
+
+	get := func(url string) int {
+		res, err := http.Get(url)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	pullURL := "http://" + prodSrv.Addr().String() + pullPath
+	url, err := signPullURL(pullURL, time.Now(), 0, consKey, rand.Reader)
+	require.NoError(t, err)
+
+	// The first request is accepted, the same URL cannot be used again.
+	assert.Equal(t, http.StatusNoContent, get(url))
+	assert.Equal(t, http.StatusBadRequest, get(url))
+
+	// The since parameter is covered by the signature, so a modified URL
+	// recovers to an address that is not allowed to pull messages.
+	url, err = signPullURL(pullURL, time.Now(), 0, consKey, rand.Reader)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, get(strings.Replace(url, "since=0", "since=1", 1)))
+
+	// Digits moved between signed parameters must not produce a valid URL
+	// with a different random value or since parameter.
+	url, err = signPullURL(pullURL, time.Now(), 12, consKey, rand.Reader)
+	require.NoError(t, err)
+	q := pullQuery(t, url)
+	shifted := fmt.Sprintf("%s?t=%s&r=%s1&since=2&s=%s", pullURL, q.Get("t"), q.Get("r"), q.Get("s"))
+	assert.Equal(t, http.StatusBadRequest, get(shifted))
+	tm := q.Get("t")
+	shifted = fmt.Sprintf("%s?t=%s&r=%s%s&since=12&s=%s", pullURL, tm[:len(tm)-1], tm[len(tm)-1:], q.Get("r"), q.Get("s"))
+	assert.Equal(t, http.StatusBadRequest, get(shifted))
+	assert.Equal(t, http.StatusNoContent, get(url))
+}
+
+func Test_WebAPI_PullSameTime(t *testing.T) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+
+	prodKey := wallet.NewRandomKey()
+	consKey := wallet.NewRandomKey()
+	prodSrv := httpserver.New(&http.Server{Addr: "127.0.0.1:0"})
+
+	prod, err := New(Config{
+		AddressBook:   NullAddressBook{},
+		Signer:        prodKey,
+		FlushTicker:   timeutil.NewTicker(0),
+		Server:        prodSrv,
+		PullAllowlist: []types.Address{consKey.Address()},
+	})
+	require.NoError(t, err)
+	require.NoError(t, prod.Start(ctx))
+
+	pull := func(since int64) (int, int64) {
+		url, err := signPullURL("http://"+prodSrv.Addr().String()+pullPath, time.Now(), since, consKey, rand.Reader)
+		require.NoError(t, err)
+		res, err := http.Get(url)
+		require.NoError(t, err)
+		res.Body.Close()
+		last, err := strconv.ParseInt(res.Header.Get(pullTimestampHeader), 10, 64)
+		require.NoError(t, err)
+		return res.StatusCode, last
+	}
+	pack := func(data string) *pb.MessagePack {
+		return &pb.MessagePack{Messages: map[string]*pb.MessagePack_Messages{
+			"test": {Data: [][]byte{[]byte(data)}},
+		}}
+	}
+
+	// A message added at the same time as the previously pulled message
+	// must be returned by the next pull.
+	now := time.Now()
+	prod.addPullMessages(pack("a"), now)
+	status, last := pull(0)
+	require.Equal(t, http.StatusOK, status)
+	prod.addPullMessages(pack("b"), now)
+	status, next := pull(last)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Greater(t, next, last)
+	status, _ = pull(next)
+	assert.Equal(t, http.StatusNoContent, status)
+}
+
+func pullQuery(t *testing.T, url string) netURL.Values {
+	p, err := netURL.Parse(url)
+	require.NoError(t, err)
+	return p.Query()
+}
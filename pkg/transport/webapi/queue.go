@@ -0,0 +1,261 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package webapi
+
+import (
+	"time"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/metrics"
+	"github.com/chronicleprotocol/oracle-suite/pkg/transport/webapi/pb"
+)
+
+const (
+	// defaultQueueMaxAge is the default maximum age of a message in the
+	// outbound queue. Older messages are dropped.
+	defaultQueueMaxAge = 15 * time.Minute
+
+	// defaultQueueMaxSize is the default maximum number of messages per topic
+	// in the outbound queue. If the limit is exceeded, the oldest messages
+	// are dropped.
+	defaultQueueMaxSize = 1000
+
+	// defaultDeadConsumerThreshold is the default number of consecutive
+	// failed deliveries after which the consumer is considered dead.
+	defaultDeadConsumerThreshold = 5
+
+	// defaultDeadConsumerBackoff is the default time after which the
+	// delivery to a dead consumer is retried.
+	defaultDeadConsumerBackoff = 5 * time.Minute
+)
+
+// ConsumerStats contains delivery statistics for a single consumer.
+type ConsumerStats struct {
+	// Address is the consumer address.
+	Address string `json:"address"`
+
+	// Pending is the number of messages waiting in the queue.
+	Pending int `json:"pending"`
+
+	// Delivered is the number of messages delivered to the consumer.
+	Delivered uint64 `json:"delivered"`
+
+	// Dropped is the number of messages dropped from the queue, either
+	// because they expired or because the queue was full.
+	Dropped uint64 `json:"dropped"`
+
+	// Attempts is the total number of delivery attempts.
+	Attempts uint64 `json:"attempts"`
+
+	// Failures is the total number of failed delivery attempts.
+	Failures uint64 `json:"failures"`
+
+	// ConsecutiveFailures is the number of failed delivery attempts since
+	// the last successful delivery.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+
+	// Dead is true if the consumer exceeded the number of consecutive failed
+	// deliveries and is retried less frequently.
+	Dead bool `json:"dead"`
+
+	// LastSuccess is the time of the last successful delivery.
+	LastSuccess time.Time `json:"last_success"`
+
+	// LastFailure is the time of the last failed delivery.
+	LastFailure time.Time `json:"last_failure"`
+
+	// LastError is the error message of the last failed delivery.
+	LastError string `json:"last_error,omitempty"`
+
+	// NextAttempt is the earliest time of the next delivery attempt.
+	NextAttempt time.Time `json:"next_attempt"`
+}
+
+// queuedMessage is a message waiting in the outbound queue.
+type queuedMessage struct {
+	seq   uint64
+	data  []byte
+	added time.Time
+}
+
+// consumerQueue is an outbound message queue for a single consumer.
+//
+// Messages are kept in the queue until they are delivered or until they
+// expire. After a failed delivery, the next attempt is delayed using an
+// exponential backoff, expressed as a multiple of the flush interval, because
+// consumers do not accept requests more often than once per flush interval.
+// Retries send only the most recent message of each topic, older messages
+// are dropped.
+type consumerQueue struct {
+	messages  map[string][]queuedMessage // Messages grouped by topic.
+	seq       uint64                     // Sequence number of the last added message.
+	sentSeq   uint64                     // Sequence number of the last message in the pending request.
+	inFlight  bool                       // True if there is a pending request.
+	sentCount int                        // Number of messages in the pending request.
+	stats     ConsumerStats
+	reported  ConsumerStats // Stats at the time of the last metrics update.
+}
+
+func newConsumerQueue(addr string) *consumerQueue {
+	return &consumerQueue{
+		messages: make(map[string][]queuedMessage),
+		stats:    ConsumerStats{Address: addr},
+	}
+}
+
+// push adds a message to the queue. If the number of messages for the topic
+// exceeds maxSize, the oldest messages are dropped.
+func (q *consumerQueue) push(topic string, data []byte, now time.Time, maxSize int) {
+	q.seq++
+	q.messages[topic] = append(q.messages[topic], queuedMessage{seq: q.seq, data: data, added: now})
+	if over := len(q.messages[topic]) - maxSize; over > 0 {
+		q.messages[topic] = q.messages[topic][over:]
+		q.stats.Dropped += uint64(over)
+	}
+}
+
+// expire removes messages older than maxAge.
+func (q *consumerQueue) expire(now time.Time, maxAge time.Duration) {
+	for topic, msgs := range q.messages {
+		n := 0
+		for n < len(msgs) && now.Sub(msgs[n].added) > maxAge {
+			n++
+		}
+		if n == len(msgs) {
+			delete(q.messages, topic)
+		} else {
+			q.messages[topic] = msgs[n:]
+		}
+		q.stats.Dropped += uint64(n)
+	}
+}
+
+// ready returns true if the queue has messages to send and the next delivery
+// attempt is allowed at the given time. Because flush ticks are not perfectly
+// regular, the time is compared with a tolerance of half the flush interval.
+func (q *consumerQueue) ready(t time.Time, flushInterval time.Duration) bool {
+	return !q.inFlight && len(q.messages) > 0 && !t.Add(flushInterval/2).Before(q.stats.NextAttempt)
+}
+
+// take returns all queued messages as a message pack and marks the queue
+// as in-flight. Messages stay in the queue until the delivery is confirmed
+// by calling the done method. If the previous delivery failed, the queue is
+// collapsed first, so that only the most recent message of each topic is
+// sent.
+func (q *consumerQueue) take() *pb.MessagePack {
+	if q.stats.ConsecutiveFailures > 0 {
+		q.collapse()
+	}
+	mp := &pb.MessagePack{Messages: make(map[string]*pb.MessagePack_Messages, len(q.messages))}
+	q.sentCount = 0
+	for topic, msgs := range q.messages {
+		data := make([][]byte, len(msgs))
+		for i, msg := range msgs {
+			data[i] = msg.data
+		}
+		mp.Messages[topic] = &pb.MessagePack_Messages{Data: data}
+		q.sentCount += len(msgs)
+	}
+	q.sentSeq = q.seq
+	q.inFlight = true
+	return mp
+}
+
+// collapse removes all messages except the most recent one of each topic.
+func (q *consumerQueue) collapse() {
+	for topic, msgs := range q.messages {
+		if n := len(msgs) - 1; n > 0 {
+			q.messages[topic] = msgs[n:]
+			q.stats.Dropped += uint64(n)
+		}
+	}
+}
+
+// done updates the queue after a delivery attempt made at time t. On success,
+// delivered messages are removed from the queue. On failure, the next attempt
+// is delayed.
+func (q *consumerQueue) done(t time.Time, err error, flushInterval time.Duration, deadThreshold int, deadBackoff time.Duration) {
+	q.inFlight = false
+	q.stats.Attempts++
+	if err == nil {
+		for topic, msgs := range q.messages {
+			n := 0
+			for n < len(msgs) && msgs[n].seq <= q.sentSeq {
+				n++
+			}
+			if n == len(msgs) {
+				delete(q.messages, topic)
+			} else {
+				q.messages[topic] = msgs[n:]
+			}
+		}
+		q.stats.Delivered += uint64(q.sentCount)
+		q.stats.ConsecutiveFailures = 0
+		q.stats.Dead = false
+		q.stats.LastSuccess = t
+		q.stats.LastError = ""
+		q.stats.NextAttempt = time.Time{}
+		return
+	}
+	q.stats.Failures++
+	q.stats.ConsecutiveFailures++
+	q.stats.LastFailure = t
+	q.stats.LastError = err.Error()
+	q.stats.Dead = q.stats.ConsecutiveFailures >= deadThreshold
+	if q.stats.Dead {
+		q.stats.NextAttempt = t.Add(deadBackoff)
+		return
+	}
+	backoff := flushInterval
+	for i := 1; i < q.stats.ConsecutiveFailures && backoff < deadBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > deadBackoff {
+		backoff = deadBackoff
+	}
+	q.stats.NextAttempt = t.Add(backoff)
+}
+
+// currentStats returns the current delivery statistics.
+func (q *consumerQueue) currentStats() ConsumerStats {
+	s := q.stats
+	for _, msgs := range q.messages {
+		s.Pending += len(msgs)
+	}
+	return s
+}
+
+// reportMetrics updates the consumer metrics with changes in the delivery
+// statistics since the last call.
+func (q *consumerQueue) reportMetrics() {
+	s := q.currentStats()
+	addr := s.Address
+	metrics.WebAPIConsumerPending.WithLabelValues(addr).Set(float64(s.Pending))
+	metrics.WebAPIConsumerConsecutiveFailures.WithLabelValues(addr).Set(float64(s.ConsecutiveFailures))
+	metrics.WebAPIConsumerDelivered.WithLabelValues(addr).Add(float64(s.Delivered - q.reported.Delivered))
+	metrics.WebAPIConsumerDropped.WithLabelValues(addr).Add(float64(s.Dropped - q.reported.Dropped))
+	metrics.WebAPIConsumerFailures.WithLabelValues(addr).Add(float64(s.Failures - q.reported.Failures))
+	q.reported = s
+}
+
+// deleteConsumerMetrics removes metrics of a consumer that is no longer in
+// the address book.
+func deleteConsumerMetrics(addr string) {
+	metrics.WebAPIConsumerPending.DeleteLabelValues(addr)
+	metrics.WebAPIConsumerConsecutiveFailures.DeleteLabelValues(addr)
+	metrics.WebAPIConsumerDelivered.DeleteLabelValues(addr)
+	metrics.WebAPIConsumerDropped.DeleteLabelValues(addr)
+	metrics.WebAPIConsumerFailures.DeleteLabelValues(addr)
+}
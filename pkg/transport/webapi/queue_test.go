@@ -0,0 +1,125 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package webapi
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_consumerQueue(t *testing.T) {
+	const (
+		interval      = time.Minute
+		deadThreshold = 3
+		deadBackoff   = 10 * time.Minute
+	)
+	tm := time.Unix(1700000000, 0)
+	q := newConsumerQueue("http://localhost")
+
+	// Empty queue is never ready.
+	assert.False(t, q.ready(tm, interval))
+
+	// Successful delivery removes messages from the queue.
+	q.push("a", []byte("1"), tm, 10)
+	q.push("b", []byte("2"), tm, 10)
+	require.True(t, q.ready(tm, interval))
+	mp := q.take()
+	assert.Len(t, mp.Messages, 2)
+	assert.False(t, q.ready(tm, interval)) // in-flight
+	q.push("a", []byte("3"), tm, 10)       // added while in-flight
+	q.done(tm, nil, interval, deadThreshold, deadBackoff)
+	stats := q.currentStats()
+	assert.Equal(t, uint64(2), stats.Delivered)
+	assert.Equal(t, 1, stats.Pending)
+
+	// Failed deliveries are retried with exponential backoff.
+	tm = tm.Add(interval)
+	q.take()
+	q.done(tm, errors.New("err"), interval, deadThreshold, deadBackoff)
+	assert.Equal(t, tm.Add(interval), q.stats.NextAttempt)
+	tm = tm.Add(interval)
+	require.True(t, q.ready(tm, interval))
+	q.take()
+	q.done(tm, errors.New("err"), interval, deadThreshold, deadBackoff)
+	assert.Equal(t, tm.Add(2*interval), q.stats.NextAttempt)
+	assert.False(t, q.ready(tm.Add(interval), interval))
+	assert.True(t, q.ready(tm.Add(2*interval), interval))
+	assert.False(t, q.stats.Dead)
+
+	// After too many failures, the consumer is considered dead.
+	tm = tm.Add(2 * interval)
+	q.take()
+	q.done(tm, errors.New("err"), interval, deadThreshold, deadBackoff)
+	assert.True(t, q.stats.Dead)
+	assert.Equal(t, tm.Add(deadBackoff), q.stats.NextAttempt)
+	assert.Equal(t, 3, q.stats.ConsecutiveFailures)
+
+	// Consumer that comes back receives unexpired messages.
+	tm = tm.Add(deadBackoff)
+	q.push("a", []byte("4"), tm, 10)
+	q.expire(tm, 5*time.Minute)
+	mp = q.take()
+	assert.Equal(t, [][]byte{[]byte("4")}, mp.Messages["a"].Data)
+	q.done(tm, nil, interval, deadThreshold, deadBackoff)
+	stats = q.currentStats()
+	assert.False(t, stats.Dead)
+	assert.Equal(t, 0, stats.ConsecutiveFailures)
+	assert.Equal(t, uint64(3), stats.Delivered)
+	assert.Equal(t, uint64(1), stats.Dropped)
+	assert.Equal(t, uint64(5), stats.Attempts)
+	assert.Equal(t, uint64(3), stats.Failures)
+}
+
+func Test_consumerQueue_RetryCollapse(t *testing.T) {
+	const interval = time.Minute
+	tm := time.Unix(1700000000, 0)
+	q := newConsumerQueue("http://localhost")
+
+	// The first attempt sends all messages.
+	q.push("a", []byte("1"), tm, 10)
+	q.push("a", []byte("2"), tm, 10)
+	q.push("b", []byte("3"), tm, 10)
+	mp := q.take()
+	assert.Equal(t, [][]byte{[]byte("1"), []byte("2")}, mp.Messages["a"].Data)
+	q.done(tm, errors.New("err"), interval, 5, 10*time.Minute)
+
+	// Retries send only the most recent message of each topic.
+	tm = tm.Add(interval)
+	q.push("a", []byte("4"), tm, 10)
+	mp = q.take()
+	assert.Equal(t, [][]byte{[]byte("4")}, mp.Messages["a"].Data)
+	assert.Equal(t, [][]byte{[]byte("3")}, mp.Messages["b"].Data)
+	q.done(tm, nil, interval, 5, 10*time.Minute)
+	stats := q.currentStats()
+	assert.Equal(t, uint64(2), stats.Delivered)
+	assert.Equal(t, uint64(2), stats.Dropped)
+	assert.Equal(t, 0, stats.Pending)
+}
+
+func Test_consumerQueue_MaxSize(t *testing.T) {
+	tm := time.Unix(1700000000, 0)
+	q := newConsumerQueue("http://localhost")
+	q.push("a", []byte("1"), tm, 2)
+	q.push("a", []byte("2"), tm, 2)
+	q.push("a", []byte("3"), tm, 2)
+	mp := q.take()
+	assert.Equal(t, [][]byte{[]byte("2"), []byte("3")}, mp.Messages["a"].Data)
+	assert.Equal(t, uint64(1), q.currentStats().Dropped)
+}
@@ -57,6 +57,9 @@ const (
 	// consumePath is the URL path for the consume endpoint.
 	consumePath = "/consume"
 
+	// pullPath is the URL path for the pull endpoint.
+	pullPath = "/pull"
+
 	// messageChanSize is the size of the message channel. It is used to buffer
 	// messages before they are consumed.
 	messageChanSize = 10000
@@ -79,6 +82,14 @@ const (
 // consumers. List of addresses of message consumers is stored in the address
 // book. Address book must implement the AddressBook interface.
 //
+// Each consumer has its own outbound queue. Messages that could not be
+// delivered are kept in the queue and sent again together with the next
+// batch, but only the most recent message of each topic is sent again.
+// After a failed delivery, next attempts are delayed using an exponential
+// backoff. Consumers that fail too many times in a row are considered dead
+// and are retried only occasionally. Messages are dropped from the queue
+// when they become too old or when the queue is full.
+//
 // Message producers prepares a batch of messages and send them periodically
 // to the message consumers. The batch is sent as a single HTTP POST request.
 // Each request is signed using the private key of the producer. The signature
@@ -132,6 +143,20 @@ const (
 // The HTTP server returns HTTP 200 OK response if the request is valid.
 // Otherwise, it returns 429 Too Many Requests response if producer sends
 // messages too often or 400 Bad Request response for any other error.
+//
+// Consumers that cannot receive requests, e.g. because they are behind
+// a firewall or NAT, may pull messages instead. Producers keep broadcast
+// messages for a short period of time and serve them on the /pull endpoint.
+// Pull requests are GET requests signed in the same way as consume requests,
+// except that the since query parameter, the timestamp of the newest message
+// already pulled in nanoseconds, is also signed, and the signed values are
+// delimited:
+//
+//	signature = sign(timestamp + ":" + rand + ":" + since)
+//
+// Only consumers from the pull allowlist are allowed to pull messages, and
+// each signed URL can be used only once. The response body has the same
+// format as the consume request body.
 type WebAPI struct {
 	mu     sync.RWMutex
 	ctx    context.Context
@@ -140,6 +165,11 @@ type WebAPI struct {
 	// State fields:
 	messagePack *pb.MessagePack                                        // Message pack to be sent on next flush.
 	lastReqs    map[types.Address]time.Time                            // Last timestamp received from each producer.
+	queues      map[string]*consumerQueue                              // Outbound message queues for each consumer.
+	pullMsgs    []pullMessage                                          // Messages available on the pull endpoint.
+	pullLast    int64                                                  // Timestamp of the last message added to the pull endpoint.
+	lastPulls   map[string]int64                                       // Timestamp of the last message pulled from each producer.
+	pullNonces  map[string]time.Time                                   // Random values of recent pull requests.
 	msgCh       map[string]chan transport.ReceivedMessage              // Channels for received messages.
 	msgChFO     map[string]*chanutil.FanOut[transport.ReceivedMessage] // Fan-out channels for received messages.
 
@@ -153,11 +183,21 @@ type WebAPI struct {
 	server       httpserver.Service
 	rand         io.Reader
 	maxClockSkew time.Duration
+	queueMaxAge  time.Duration
+	queueMaxSize int
+	deadThresh   int
+	deadBackoff  time.Duration
+	pullAllow    []types.Address
+	pullWindow   time.Duration
+	pullFrom     []string
+	pullTicker   *timeutil.Ticker
 	log          log.Logger
 	appName      string
 	appVersion   string
 
 	// Internal fields:
+	queueMu sync.Mutex
+	pullMu  sync.Mutex
 	recover crypto.Recoverer
 }
 
@@ -217,6 +257,46 @@ type Config struct {
 	// and the producer. If not provided, default value will be used (10 seconds).
 	MaxClockSkew time.Duration
 
+	// QueueMaxAge is the maximum time for which undelivered messages are
+	// kept in the outbound queue of a consumer. If not provided, default
+	// value will be used (15 minutes).
+	QueueMaxAge time.Duration
+
+	// QueueMaxSize is the maximum number of undelivered messages per topic
+	// kept in the outbound queue of a consumer. If exceeded, the oldest
+	// messages are dropped. If not provided, default value will be used
+	// (1000 messages).
+	QueueMaxSize int
+
+	// DeadConsumerThreshold is the number of consecutive failed deliveries
+	// after which a consumer is considered dead. If not provided, default
+	// value will be used (5 deliveries).
+	DeadConsumerThreshold int
+
+	// DeadConsumerBackoff is the time after which the delivery to a dead
+	// consumer is retried. If not provided, default value will be used
+	// (5 minutes).
+	DeadConsumerBackoff time.Duration
+
+	// PullAllowlist is a list of consumers that are allowed to pull messages
+	// from the pull endpoint. If empty, the pull endpoint is disabled.
+	PullAllowlist []types.Address
+
+	// PullWindow specifies how long broadcast messages are available on the
+	// pull endpoint. If not provided, default value will be used (5 minutes).
+	PullWindow time.Duration
+
+	// PullProducers is a list of producer addresses from which messages
+	// are periodically pulled. It can be used by consumers that are not able
+	// to receive messages directly, e.g. because they are behind NAT.
+	//
+	// Requires Signer to be set.
+	PullProducers []string
+
+	// PullTicker specifies how often messages are pulled from producers.
+	// Required if PullProducers is not empty.
+	PullTicker *timeutil.Ticker
+
 	// Logger is a custom logger instance. If not provided then null
 	// logger is used.
 	Logger log.Logger
@@ -243,6 +323,29 @@ func New(cfg Config) (*WebAPI, error) {
 	if cfg.MaxClockSkew == 0 {
 		cfg.MaxClockSkew = defaultMaxClockSkew
 	}
+	if cfg.QueueMaxAge == 0 {
+		cfg.QueueMaxAge = defaultQueueMaxAge
+	}
+	if cfg.QueueMaxSize == 0 {
+		cfg.QueueMaxSize = defaultQueueMaxSize
+	}
+	if cfg.DeadConsumerThreshold == 0 {
+		cfg.DeadConsumerThreshold = defaultDeadConsumerThreshold
+	}
+	if cfg.DeadConsumerBackoff == 0 {
+		cfg.DeadConsumerBackoff = defaultDeadConsumerBackoff
+	}
+	if cfg.PullWindow == 0 {
+		cfg.PullWindow = defaultPullWindow
+	}
+	if len(cfg.PullProducers) > 0 {
+		if cfg.PullTicker == nil {
+			return nil, errors.New("pull ticker must be provided if pull producers are set")
+		}
+		if cfg.Signer == nil {
+			return nil, errors.New("signer must be provided if pull producers are set")
+		}
+	}
 	if cfg.Rand == nil {
 		cfg.Rand = rand.Reader
 	}
@@ -276,16 +379,27 @@ func New(cfg Config) (*WebAPI, error) {
 		server:       cfg.Server,
 		signer:       cfg.Signer,
 		lastReqs:     make(map[types.Address]time.Time),
+		queues:       make(map[string]*consumerQueue),
+		lastPulls:    make(map[string]int64),
+		pullNonces:   make(map[string]time.Time),
 		msgCh:        make(map[string]chan transport.ReceivedMessage),
 		msgChFO:      make(map[string]*chanutil.FanOut[transport.ReceivedMessage]),
 		maxClockSkew: cfg.MaxClockSkew,
+		queueMaxAge:  cfg.QueueMaxAge,
+		queueMaxSize: cfg.QueueMaxSize,
+		deadThresh:   cfg.DeadConsumerThreshold,
+		deadBackoff:  cfg.DeadConsumerBackoff,
+		pullAllow:    sliceutil.Copy(cfg.PullAllowlist),
+		pullWindow:   cfg.PullWindow,
+		pullFrom:     sliceutil.Copy(cfg.PullProducers),
+		pullTicker:   cfg.PullTicker,
 		rand:         cfg.Rand,
 		log:          logger,
 		appName:      cfg.AppName,
 		appVersion:   cfg.AppVersion,
 		recover:      crypto.ECRecoverer,
 	}
-	w.server.SetHandler(http.HandlerFunc(w.consumeHandler))
+	w.server.SetHandler(http.HandlerFunc(w.handler))
 	return w, nil
 }
 
@@ -310,6 +424,10 @@ func (w *WebAPI) Start(ctx context.Context) error {
 	}
 	w.flushTicker.Start(ctx)
 	go w.flushRoutine(ctx)
+	if len(w.pullFrom) > 0 {
+		w.pullTicker.Start(ctx)
+		go w.pullRoutine(ctx)
+	}
 	go w.contextCancelHandler()
 	return nil
 }
@@ -359,50 +477,129 @@ func (w *WebAPI) Messages(topic string) <-chan transport.ReceivedMessage {
 	return nil
 }
 
-// flushMessages sends the current batch of messages to the consumers.
-// The batch is cleared after the messages are sent.
+// ConsumerStats returns delivery statistics for all consumers, sorted by
+// consumer address.
+func (w *WebAPI) ConsumerStats() []ConsumerStats {
+	w.queueMu.Lock()
+	defer w.queueMu.Unlock()
+	stats := make([]ConsumerStats, 0, len(w.queues))
+	for _, addr := range maputil.SortKeys(w.queues, sort.Strings) {
+		stats = append(stats, w.queues[addr].currentStats())
+	}
+	return stats
+}
+
+// flushMessages adds the current batch of messages to the outbound queues of
+// all consumers and sends queued messages to consumers that are ready to
+// receive them. The batch is cleared after the messages are queued.
 func (w *WebAPI) flushMessages(ctx context.Context, t time.Time) error {
 	w.mu.Lock()
-	defer func() {
-		w.messagePack = nil
-		w.mu.Unlock()
-	}()
-	if w.messagePack == nil || len(w.messagePack.Messages) == 0 {
-		return nil // Nothing to send.
-	}
-	if err := signMessage(w.messagePack, w.signer); err != nil {
-		return err
-	}
-	bin, err := proto.Marshal(w.messagePack)
-	if err != nil {
-		return err
-	}
-	bin, err = gzipCompress(bin)
-	if err != nil {
-		return err
-	}
+	mp := w.messagePack
+	w.messagePack = nil
+	w.mu.Unlock()
+
+	// Make messages available on the pull endpoint.
+	w.addPullMessages(mp, t)
+
 	cons, err := w.addressBook.Consumers(ctx)
 	if err != nil {
 		return err
 	}
-	// Consumer addresses may omit protocol scheme, so we add it here.
 	for n, addr := range cons {
-		if !strings.Contains(addr, "://") {
-			// Data transmitted over the WebAPI protocol is signed, hence
-			// there is no need to use HTTPS.
-			cons[n] = "http://" + addr
+		cons[n] = normalizeAddr(addr)
+	}
+
+	w.queueMu.Lock()
+	defer w.queueMu.Unlock()
+
+	// Remove queues for consumers that are no longer in the address book.
+	for addr := range w.queues {
+		if !sliceutil.Contains(cons, addr) {
+			delete(w.queues, addr)
+			deleteConsumerMetrics(addr)
 		}
 	}
+
+	// Consumers that are up to date receive the same message pack, so it
+	// is signed and encoded only once.
+	encoded := make(map[types.Hash][]byte)
+	var errs []error
 	for _, addr := range cons {
-		go w.doHTTPRequest(ctx, addr, bin, t)
+		q, ok := w.queues[addr]
+		if !ok {
+			q = newConsumerQueue(addr)
+			w.queues[addr] = q
+		}
+		if mp != nil {
+			for _, topic := range maputil.SortKeys(mp.Messages, sort.Strings) {
+				for _, data := range mp.Messages[topic].Data {
+					q.push(topic, data, t, w.queueMaxSize)
+				}
+			}
+		}
+		q.expire(t, w.queueMaxAge)
+		if rc, ok := w.addressBook.(ReachabilityChecker); ok && !rc.Reachable(addr) {
+			// Once the consumer is reachable again, it receives only the
+			// most recent message of each topic.
+			q.collapse()
+			if len(q.messages) > 0 {
+				w.log.
+					WithFields(consumerStatsFields(q.currentStats())).
+					Debug("Consumer is unreachable, delivery postponed")
+			}
+			q.reportMetrics()
+			continue
+		}
+		if !q.ready(t, w.flushTicker.Duration()) {
+			if q.stats.ConsecutiveFailures > 0 && len(q.messages) > 0 {
+				w.log.
+					WithFields(consumerStatsFields(q.currentStats())).
+					Debug("Delivery to consumer postponed")
+			}
+			q.reportMetrics()
+			continue
+		}
+		bin, err := encodeSharedMessagePack(encoded, q.take(), w.signer)
+		if err != nil {
+			q.done(t, err, w.flushTicker.Duration(), w.deadThresh, w.deadBackoff)
+			q.reportMetrics()
+			errs = append(errs, fmt.Errorf("consumer %s: %w", addr, err))
+			continue
+		}
+		q.reportMetrics()
+		go w.deliver(ctx, addr, bin, t)
+	}
+	return errors.Join(errs...)
+}
+
+// deliver sends the data to the consumer and updates the consumer queue
+// with the result.
+func (w *WebAPI) deliver(ctx context.Context, addr string, data []byte, t time.Time) {
+	err := w.doHTTPRequest(ctx, addr, data, t)
+	w.queueMu.Lock()
+	defer w.queueMu.Unlock()
+	q, ok := w.queues[addr]
+	if !ok {
+		return // Consumer was removed from the address book.
+	}
+	q.done(t, err, w.flushTicker.Duration(), w.deadThresh, w.deadBackoff)
+	q.reportMetrics()
+	if err != nil {
+		l := w.log.WithError(err).WithFields(consumerStatsFields(q.currentStats()))
+		if q.stats.Dead {
+			l.WithAdvice("Ignore if the consumer is known to be offline").
+				Warn("Consumer is unreachable, delivery will be retried later")
+			return
+		}
+		l.WithAdvice("Ignore if occurs occasionally, especially if it is related to temporary network issues").
+			Warn("Failed to send messages to consumer")
 	}
-	return nil
 }
 
 // doHTTPRequest sends a POST request to the given address with the given
 // data. The data must be gzipped protobuf-encoded MessagePack. The t parameter
 // is the time used for the URL signature.
-func (w *WebAPI) doHTTPRequest(ctx context.Context, addr string, data []byte, t time.Time) {
+func (w *WebAPI) doHTTPRequest(ctx context.Context, addr string, data []byte, t time.Time) error {
 	w.log.WithField("address", addr).Info("Sending messages to consumer")
 
 	// Sign the URL.
@@ -413,38 +610,48 @@ func (w *WebAPI) doHTTPRequest(ctx context.Context, addr string, data []byte, t
 		w.rand,
 	)
 	if err != nil {
-		w.log.
-			WithError(err).
-			WithField("address", addr).
-			WithAdvice("This is a bug and must be investigated").
-			Error("Failed to sign URL")
-		return
+		return fmt.Errorf("failed to sign URL: %w", err)
 	}
 
 	// Prepare the request.
-	req, err := http.NewRequest("POST", url, bytes.NewReader(data))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
 	if err != nil {
-		w.log.
-			WithError(err).
-			WithField("address", addr).
-			WithAdvice("This is a bug and must be investigated").
-			Error("Failed to create request")
+		return fmt.Errorf("failed to create request: %w", err)
 	}
-	req = req.WithContext(ctx)
 	req.Header.Set("Content-Type", "application/x-protobuf")
 	req.Header.Set("Content-Encoding", "gzip")
 
 	// Send the request.
 	res, err := w.client.Do(req)
 	if err != nil {
-		w.log.
-			WithError(err).
-			WithField("address", addr).
-			WithAdvice("Ignore if occurs occasionally, especially if it is related to temporary network issues").
-			Warn("Failed to send messages to consumer")
-		return
+		return err
 	}
 	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response status: %s", res.Status)
+	}
+	return nil
+}
+
+// consumerStatsFields returns log fields for the given consumer stats.
+func consumerStatsFields(s ConsumerStats) log.Fields {
+	return log.Fields{
+		"address":             s.Address,
+		"pending":             s.Pending,
+		"delivered":           s.Delivered,
+		"dropped":             s.Dropped,
+		"consecutiveFailures": s.ConsecutiveFailures,
+		"nextAttempt":         s.NextAttempt,
+	}
+}
+
+// handler routes incoming requests to the appropriate handler.
+func (w *WebAPI) handler(res http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == pullPath {
+		w.pullHandler(res, req)
+		return
+	}
+	w.consumeHandler(res, req)
 }
 
 // consumeHandler handles incoming messages from consumers.
@@ -639,6 +846,12 @@ func (w *WebAPI) consumeHandler(res http.ResponseWriter, req *http.Request) {
 	}
 
 	// Send messages from the MessagePack to the msgCh channel.
+	w.dispatchMessages(mp, *requestAuthor, fields)
+}
+
+// dispatchMessages unmarshalls messages from the MessagePack and sends them
+// to the msgCh channels. Caller must hold the read lock.
+func (w *WebAPI) dispatchMessages(mp *pb.MessagePack, author types.Address, fields log.Fields) {
 	for topic, msgs := range mp.Messages {
 		typ, ok := w.topics[topic]
 		if !ok {
@@ -672,12 +885,12 @@ func (w *WebAPI) consumeHandler(res http.ResponseWriter, req *http.Request) {
 
 			w.msgCh[topic] <- transport.ReceivedMessage{
 				Message: msg,
-				Author:  requestAuthor.Bytes(),
+				Author:  author.Bytes(),
 				Meta: transport.Meta{
 					Transport: TransportName,
 					Topic:     topic,
 					UserAgent: userAgent,
-					PeerAddr:  requestAuthor.String(),
+					PeerAddr:  author.String(),
 				},
 			}
 		}
@@ -776,6 +989,39 @@ func signMessage(msg *pb.MessagePack, signer wallet.Key) error {
 	return nil
 }
 
+// encodeMessagePack signs the message pack and returns it encoded using
+// protobuf and compressed using gzip.
+func encodeMessagePack(mp *pb.MessagePack, signer wallet.Key) ([]byte, error) {
+	if err := signMessage(mp, signer); err != nil {
+		return nil, err
+	}
+	bin, err := proto.Marshal(mp)
+	if err != nil {
+		return nil, err
+	}
+	return gzipCompress(bin)
+}
+
+// encodeSharedMessagePack works like encodeMessagePack, but reuses the result
+// stored in the cache if a message pack with the same content was already
+// encoded.
+func encodeSharedMessagePack(cache map[types.Hash][]byte, mp *pb.MessagePack, signer wallet.Key) ([]byte, error) {
+	bin, err := proto.MarshalOptions{Deterministic: true}.Marshal(mp)
+	if err != nil {
+		return nil, err
+	}
+	key := crypto.Keccak256(bin)
+	if bin, ok := cache[key]; ok {
+		return bin, nil
+	}
+	bin, err = encodeMessagePack(mp, signer)
+	if err != nil {
+		return nil, err
+	}
+	cache[key] = bin
+	return bin, nil
+}
+
 // verifyMessage verifies message pack signature and returns signer address.
 func verifyMessage(msg *pb.MessagePack, recover crypto.Recoverer) (*types.Address, error) {
 	return recover.RecoverMessage(messageSigningData(msg), types.MustSignatureFromBytes(msg.Signature))
@@ -821,6 +1067,16 @@ func gzipDecompress(data []byte) ([]byte, error) {
 	return io.ReadAll(r)
 }
 
+// normalizeAddr adds the protocol scheme to the address if it is missing.
+func normalizeAddr(addr string) string {
+	if !strings.Contains(addr, "://") {
+		// Data transmitted over the WebAPI protocol is signed, hence
+		// there is no need to use HTTPS.
+		return "http://" + addr
+	}
+	return addr
+}
+
 func addrToString(addr net.Addr) string {
 	if addr == nil {
 		return "<nil>"
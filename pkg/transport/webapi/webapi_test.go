@@ -20,15 +20,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/chronicleprotocol/oracle-suite/pkg/ethereum/mocks"
 	"github.com/chronicleprotocol/oracle-suite/pkg/httpserver"
@@ -229,6 +234,13 @@ func Test_WebAPI(t *testing.T) {
 				// be sent in a separate batch:
 				<-ch
 
+				// Wait for the first request to be finished, otherwise the
+				// second batch would be postponed:
+				assert.Eventually(t, func() bool {
+					stats := p.ConsumerStats()
+					return len(stats) == 1 && stats[0].Delivered == 1
+				}, time.Second, time.Millisecond*10)
+
 				// Send second message:
 				require.NoError(t, p.Broadcast("test", &message{data: []byte("data")}))
 				p.flushTicker.TickAt(tm2)
@@ -385,6 +397,148 @@ func Test_WebAPI(t *testing.T) {
 	}
 }
 
+func Test_WebAPI_flushMessages(t *testing.T) {
+	msgSig := []byte("testdata")
+
+	tests := []struct {
+		name    string
+		signErr error
+	}{
+		{name: "shared encoding"},
+		{name: "signing error", signErr: errors.New("signing failed")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var mu sync.Mutex
+			var requests int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				requests++
+				mu.Unlock()
+			}))
+			defer srv.Close()
+
+			signer := &mocks.Key{}
+			if tt.signErr != nil {
+				signer.On("SignMessage", msgSig).Return((*types.Signature)(nil), tt.signErr)
+			} else {
+				signer.On("SignMessage", msgSig).Return(&fakeSignature, nil).Once()
+			}
+			signer.On("SignMessage", mock.Anything).Return(&fakeSignature, nil)
+
+			ab := &addressBook{addresses: []string{srv.URL + "/a", srv.URL + "/b", srv.URL + "/c"}}
+			w, err := New(Config{
+				Topics:      map[string]transport.Message{"test": (*message)(nil)},
+				AddressBook: ab,
+				Signer:      signer,
+				FlushTicker: timeutil.NewTicker(time.Minute),
+				Rand:        bytes.NewReader(bytes.Repeat([]byte{0}, 1024)),
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, w.Broadcast("test", &message{data: []byte("data")}))
+			err = w.flushMessages(ctx, time.Now())
+
+			if tt.signErr != nil {
+				// All consumers must be processed, even if one of them fails.
+				require.Error(t, err)
+				stats := w.ConsumerStats()
+				require.Len(t, stats, 3)
+				for _, s := range stats {
+					assert.Equal(t, uint64(1), s.Failures)
+					assert.Equal(t, 1, s.Pending)
+				}
+				return
+			}
+
+			// Message pack must be signed only once for all consumers.
+			require.NoError(t, err)
+			assert.Eventually(t, func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return requests == 3
+			}, time.Second, 10*time.Millisecond)
+			signer.AssertNumberOfCalls(t, "SignMessage", 4) // 1 message pack + 3 URLs
+		})
+	}
+}
+
+type reachabilityAddressBook struct {
+	mu          sync.Mutex
+	addresses   []string
+	unreachable map[string]bool
+}
+
+func (a *reachabilityAddressBook) Consumers(_ context.Context) ([]string, error) {
+	return a.addresses, nil
+}
+
+func (a *reachabilityAddressBook) Reachable(addr string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return !a.unreachable[addr]
+}
+
+func Test_WebAPI_flushMessages_Unreachable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var received [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		body, err = gzipDecompress(body)
+		require.NoError(t, err)
+		mp := &pb.MessagePack{}
+		require.NoError(t, proto.Unmarshal(body, mp))
+		mu.Lock()
+		received = append(received, mp.Messages["test"].Data...)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	ab := &reachabilityAddressBook{
+		addresses:   []string{srv.URL},
+		unreachable: map[string]bool{srv.URL: true},
+	}
+	w, err := New(Config{
+		Topics:      map[string]transport.Message{"test": (*message)(nil)},
+		AddressBook: ab,
+		Signer:      wallet.NewRandomKey(),
+		FlushTicker: timeutil.NewTicker(time.Minute),
+	})
+	require.NoError(t, err)
+
+	// Messages for a consumer in cooldown are queued, but not sent. Only the
+	// most recent message of each topic is kept.
+	tm := time.Now()
+	require.NoError(t, w.Broadcast("test", &message{data: []byte("a")}))
+	require.NoError(t, w.flushMessages(ctx, tm))
+	require.NoError(t, w.Broadcast("test", &message{data: []byte("b")}))
+	require.NoError(t, w.flushMessages(ctx, tm.Add(time.Minute)))
+	stats := w.ConsumerStats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, 1, stats[0].Pending)
+	assert.Equal(t, uint64(1), stats[0].Dropped)
+	assert.Equal(t, uint64(0), stats[0].Attempts)
+
+	// After the cooldown, the queue is delivered.
+	ab.mu.Lock()
+	ab.unreachable = nil
+	ab.mu.Unlock()
+	require.NoError(t, w.flushMessages(ctx, tm.Add(2*time.Minute)))
+	assert.Eventually(t, func() bool {
+		return w.ConsumerStats()[0].Pending == 0
+	}, time.Second, 10*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, [][]byte{[]byte("b")}, received)
+	mu.Unlock()
+}
+
 func Test_signMessage(t *testing.T) {
 	var (
 		mp = &pb.MessagePack{
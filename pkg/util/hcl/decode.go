@@ -18,6 +18,7 @@ package hcl
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/hcl/v2"
@@ -44,6 +45,13 @@ type PostDecodeBlock interface {
 	PostDecodeBlock(*hcl.EvalContext, *hcl.BodySchema, *hcl.Block, *hcl.BodyContent) hcl.Diagnostics
 }
 
+// MarkHandler may be implemented by cty value marks. Marks are removed
+// before values are decoded, and marks that implement this interface are
+// called with the unmarked value they were applied to.
+type MarkHandler interface {
+	HandleMarkedValue(cty.Value)
+}
+
 // Decode decodes the given HCL body into the given value.
 // The value must be a pointer to a struct.
 //
@@ -66,6 +74,9 @@ type PostDecodeBlock interface {
 //     The field must be a struct, slice of structs or a map of structs.
 //   - remain - the field is populated with the remaining HCL body. The field
 //     must be hcl.Body.
+//   - strict - can be used only with remain. The remaining body may contain
+//     only blocks, unknown attributes are reported as errors, with
+//     a suggestion of a similar known name, if there is one.
 //   - body - the field is populated with the HCL body. The field must
 //     be hcl.BodyContent.
 //   - content - the field is populated with the HCL body content. The field
@@ -90,7 +101,7 @@ func DecodeExpression(ctx *hcl.EvalContext, expr hcl.Expression, val any) hcl.Di
 	if diags.HasErrors() {
 		return diags
 	}
-	if err := mapper.Map(ctyVal, val); err != nil {
+	if err := mapper.Map(unmark(ctyVal), val); err != nil {
 		return hcl.Diagnostics{{
 			Severity: hcl.DiagError,
 			Summary:  "Decode error",
@@ -134,6 +145,13 @@ func decodeSingleBlock(ctx *hcl.EvalContext, block *hcl.Block, ptrVal reflect.Va
 			return diags
 		}
 
+		// Report unknown attributes.
+		if meta.Remain.Strict && remain != nil {
+			if diags := unknownAttributes(remain, meta.BodySchema); diags.HasErrors() {
+				return diags
+			}
+		}
+
 		// Set remain field.
 		if remain != nil {
 			val.FieldByIndex(meta.Remain.Reflect.Index).Set(reflect.ValueOf(remain))
@@ -265,6 +283,43 @@ func decodeSingleBlock(ctx *hcl.EvalContext, block *hcl.Block, ptrVal reflect.Va
 	return nil
 }
 
+// unknownAttributes returns an error for every attribute in the remaining
+// body. Because the names of the attributes are not known, they are
+// retrieved using the JustAttributes method, which also returns attributes
+// if the body contains blocks.
+func unknownAttributes(remain hcl.Body, schema *hcl.BodySchema) hcl.Diagnostics {
+	attrs, _ := remain.JustAttributes()
+	if len(attrs) == 0 {
+		return nil
+	}
+	var names []string
+	for _, a := range schema.Attributes {
+		names = append(names, a.Name)
+	}
+	for _, b := range schema.Blocks {
+		names = append(names, b.Type)
+	}
+	var diags hcl.Diagnostics
+	for _, attr := range attrs {
+		detail := fmt.Sprintf("An argument named %q is not expected here.", attr.Name)
+		if suggestion := nameSuggestion(attr.Name, names); suggestion != "" {
+			detail += fmt.Sprintf(" Did you mean %q?", suggestion)
+		}
+		diags = append(diags, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Unsupported argument",
+			Detail:   detail,
+			Subject:  attr.NameRange.Ptr(),
+		})
+	}
+	// Map iteration order is random, so diagnostics are sorted to make
+	// the output stable.
+	sort.Slice(diags, func(i, j int) bool {
+		return diags[i].Subject.String() < diags[j].Subject.String()
+	})
+	return diags
+}
+
 // decodeMultipleBlocks decodes a multiple blocks into the given value.
 //   - If a value is a slice, it will append a new element to the slice.
 //   - If a block is a map, it will append a new element to the map and label
@@ -342,7 +397,7 @@ func decodeAttribute(ctx *hcl.EvalContext, attr *hcl.Attribute, val reflect.Valu
 	}
 
 	// Map the value.
-	if err := mapper.MapRefl(reflect.ValueOf(ctyVal), val); err != nil {
+	if err := mapper.MapRefl(reflect.ValueOf(unmark(ctyVal)), val); err != nil {
 		return hcl.Diagnostics{{
 			Severity: hcl.DiagError,
 			Summary:  "Decode error",
@@ -362,6 +417,27 @@ func decodeAttribute(ctx *hcl.EvalContext, attr *hcl.Attribute, val reflect.Valu
 	return nil
 }
 
+// unmark removes all marks from the given value and calls the marks that
+// implement the MarkHandler interface.
+func unmark(val cty.Value) cty.Value {
+	if !val.ContainsMarked() {
+		return val
+	}
+	unmarked, pvms := val.UnmarkDeepWithPaths()
+	for _, pvm := range pvms {
+		v, err := pvm.Path.Apply(unmarked)
+		if err != nil {
+			continue
+		}
+		for m := range pvm.Marks {
+			if h, ok := m.(MarkHandler); ok {
+				h.HandleMarkedValue(v)
+			}
+		}
+	}
+	return unmarked
+}
+
 // getStructMeta parses the tags of a struct and returns a structMeta.
 //
 //nolint:funlen,gocyclo
@@ -514,6 +590,8 @@ func getStructFieldMeta(field reflect.StructField) (structFieldMeta, hcl.Diagnos
 			sfm.Optional = true
 		case "ignore":
 			sfm.Ignore = true
+		case "strict":
+			sfm.Strict = true
 		default:
 			return sfm, hcl.Diagnostics{&hcl.Diagnostic{
 				Severity: hcl.DiagError,
@@ -562,6 +640,13 @@ func getStructFieldMeta(field reflect.StructField) (structFieldMeta, hcl.Diagnos
 			Detail:   "A optional tag cannot be used with a block that can be repeated",
 		}}
 	}
+	if sfm.Type != fieldRemain && sfm.Strict {
+		return sfm, hcl.Diagnostics{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Invalid tag",
+			Detail:   "A strict tag can only be used with remain",
+		}}
+	}
 	if sfm.Type == fieldRemain && field.Type != bodyTy {
 		return sfm, hcl.Diagnostics{&hcl.Diagnostic{
 			Severity: hcl.DiagError,
@@ -634,6 +719,7 @@ type structFieldMeta struct {
 	Optional      bool                // True if the field is optional.
 	Multiple      bool                // True if the field is a block and can be repeated.
 	Ignore        bool                // True if the field is ignored.
+	Strict        bool                // True if the remain field may contain only blocks.
 	Reflect       reflect.StructField // The reflect.StructField of the field.
 	StructReflect reflect.Type        // The reflect.Type of the struct to which block is decoded (if field is a block).
 }
@@ -501,3 +501,94 @@ func TestDecodeDuplicatedOptionalBlocks(t *testing.T) {
 	diags = Decode(&hcl.EvalContext{}, file.Body, &dest)
 	require.True(t, diags.HasErrors(), diags.Error())
 }
+
+func TestDecodeStrictRemain(t *testing.T) {
+	type config struct {
+		Attr   string   `hcl:"attr,optional"`
+		Block  struct{} `hcl:"block,block,optional"`
+		Remain hcl.Body `hcl:",remain,strict"`
+	}
+	tests := []struct {
+		input   string
+		wantErr string
+	}{
+		{
+			input: `
+				attr = "foo"
+				unknown {}
+			`,
+		},
+		{
+			input:   `atr = "foo"`,
+			wantErr: `An argument named "atr" is not expected here. Did you mean "attr"?`,
+		},
+		{
+			input:   `blok = {}`,
+			wantErr: `An argument named "blok" is not expected here. Did you mean "block"?`,
+		},
+		{
+			input:   `attrxyz = "foo"`,
+			wantErr: `An argument named "attrxyz" is not expected here. Did you mean "attr"?`,
+		},
+		{
+			input:   `attrwxyz = "foo"`,
+			wantErr: `An argument named "attrwxyz" is not expected here.`,
+		},
+		{
+			input: `
+				foo = "bar"
+				unknown {}
+			`,
+			wantErr: `An argument named "foo" is not expected here.`,
+		},
+	}
+	for n, tt := range tests {
+		t.Run(fmt.Sprintf("case-%d", n+1), func(t *testing.T) {
+			var dest config
+			file, diags := hclsyntax.ParseConfig([]byte(tt.input), "test.hcl", hcl.Pos{})
+			require.False(t, diags.HasErrors(), diags.Error())
+			diags = Decode(&hcl.EvalContext{}, file.Body, &dest)
+			if tt.wantErr == "" {
+				require.False(t, diags.HasErrors(), diags.Error())
+				return
+			}
+			require.True(t, diags.HasErrors())
+			assert.Equal(t, tt.wantErr, diags[0].Detail)
+		})
+	}
+}
+
+type testMark struct {
+	values *[]string
+}
+
+func (m testMark) HandleMarkedValue(val cty.Value) {
+	*m.values = append(*m.values, val.GoString())
+}
+
+func TestDecodeMarkedValues(t *testing.T) {
+	type config struct {
+		Attr string   `hcl:"attr"`
+		List []string `hcl:"list"`
+	}
+	var data = `
+		attr = "foo ${var.marked}"
+		list = [var.marked, "bar"]
+	`
+	var values []string
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var": cty.ObjectVal(map[string]cty.Value{
+				"marked": cty.StringVal("secret").Mark(testMark{values: &values}),
+			}),
+		},
+	}
+	var dest config
+	file, diags := hclsyntax.ParseConfig([]byte(data), "test.hcl", hcl.Pos{})
+	require.False(t, diags.HasErrors(), diags.Error())
+	diags = Decode(ctx, file.Body, &dest)
+	require.False(t, diags.HasErrors(), diags.Error())
+	assert.Equal(t, "foo secret", dest.Attr)
+	assert.Equal(t, []string{"secret", "bar"}, dest.List)
+	assert.ElementsMatch(t, []string{`cty.StringVal("foo secret")`, `cty.StringVal("secret")`}, values)
+}
@@ -13,6 +13,32 @@ import (
 // Include merges the contents of multiple HCL files specified in the "include"
 // attribute using glob patterns.
 func Include(ctx *hcl.EvalContext, body hcl.Body, wd string, maxDeep int) (hcl.Body, hcl.Diagnostics) {
+	return include(ctx, body, wd, maxDeep, nil)
+}
+
+// Visit works like Include, but it calls the visit function for every
+// included file, with the path and the parsed body of the file, before
+// the body is merged.
+func Visit(ctx *hcl.EvalContext, body hcl.Body, wd string, maxDeep int, visit func(path string, body hcl.Body)) (hcl.Body, hcl.Diagnostics) {
+	return include(ctx, body, wd, maxDeep, visit)
+}
+
+// Files returns the paths of all files included by the given body using the
+// "include" attribute, including files included by them.
+func Files(ctx *hcl.EvalContext, body hcl.Body, wd string, maxDeep int) ([]string, hcl.Diagnostics) {
+	var files []string
+	_, diags := include(ctx, body, wd, maxDeep, func(path string, _ hcl.Body) {
+		files = append(files, path)
+	})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	return files, diags
+}
+
+// include implements Include and Visit. The visit function is optional.
+// If provided, it is called for every included file.
+func include(ctx *hcl.EvalContext, body hcl.Body, wd string, maxDeep int, visit func(path string, body hcl.Body)) (hcl.Body, hcl.Diagnostics) {
 	// Decode the "include" attribute.
 	content, remain, diags := body.PartialContent(&hcl.BodySchema{
 		Attributes: []hcl.AttributeSchema{{Name: "include"}},
@@ -21,8 +47,8 @@ func Include(ctx *hcl.EvalContext, body hcl.Body, wd string, maxDeep int) (hcl.B
 	if diags.HasErrors() || attr == nil {
 		return body, diags
 	}
-	var include []string
-	if diags = utilHCL.DecodeExpression(ctx, attr.Expr, &include); diags.HasErrors() {
+	var patterns []string
+	if diags = utilHCL.DecodeExpression(ctx, attr.Expr, &patterns); diags.HasErrors() {
 		return nil, diags
 	}
 
@@ -38,7 +64,7 @@ func Include(ctx *hcl.EvalContext, body hcl.Body, wd string, maxDeep int) (hcl.B
 
 	// Iterate over the glob patterns.
 	var bodies []hcl.Body
-	for _, pattern := range include {
+	for _, pattern := range patterns {
 		// Find all files matching the glob pattern.
 		paths, err := glob(pattern)
 		if err != nil {
@@ -59,9 +85,12 @@ func Include(ctx *hcl.EvalContext, body hcl.Body, wd string, maxDeep int) (hcl.B
 			if diags.HasErrors() {
 				return nil, diags
 			}
+			if visit != nil {
+				visit(path, fileBody)
+			}
 
 			// Recursively include files.
-			body, diags := Include(ctx, fileBody, filepath.Dir(path), maxDeep-1)
+			body, diags := include(ctx, fileBody, filepath.Dir(path), maxDeep-1, visit)
 			if diags.HasErrors() {
 				return nil, diags
 			}
@@ -63,3 +63,28 @@ func TestVariables(t *testing.T) {
 		})
 	}
 }
+
+func TestFiles(t *testing.T) {
+	body, diags := utilHCL.ParseFile("./testdata/relative-dir.hcl", nil)
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	files, diags := Files(&hcl.EvalContext{}, body, "./testdata", 2)
+	require.False(t, diags.HasErrors(), diags.Error())
+	assert.Equal(t, []string{"testdata/subdir/included.hcl"}, files)
+}
+
+func TestVisit(t *testing.T) {
+	body, diags := utilHCL.ParseFile("./testdata/relative-dir.hcl", nil)
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	visited := map[string]hcl.Body{}
+	_, diags = Visit(&hcl.EvalContext{}, body, "./testdata", 2, func(path string, body hcl.Body) {
+		visited[path] = body
+	})
+	require.False(t, diags.HasErrors(), diags.Error())
+	require.Len(t, visited, 1)
+
+	attrs, diags := visited["testdata/subdir/included.hcl"].JustAttributes()
+	require.False(t, diags.HasErrors(), diags.Error())
+	assert.NotNil(t, attrs["foo"])
+}
@@ -0,0 +1,42 @@
+package funcs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+)
+
+// MakeFileFunc returns a function that reads the contents of the file at
+// the given path as a string. Relative paths are resolved against the
+// wd directory.
+func MakeFileFunc(wd string) function.Function {
+	return function.New(&function.Spec{
+		Description: "Reads the contents of the file at the given path as a string.",
+		Params: []function.Parameter{
+			{
+				Name:        "path",
+				Description: "The path to the file, relative to the directory of the config file that calls the function.",
+				Type:        cty.String,
+			},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			path := args[0].AsString()
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(wd, path)
+			}
+			src, err := os.ReadFile(path)
+			if err != nil {
+				return cty.NilVal, function.NewArgErrorf(0, "cannot read file %s: %s", path, err)
+			}
+			if !utf8.Valid(src) {
+				return cty.NilVal, function.NewArgError(0, fmt.Errorf("file %s is not valid UTF-8", path))
+			}
+			return cty.StringVal(string(src)), nil
+		},
+	})
+}
@@ -0,0 +1,41 @@
+package funcs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestMakeFileFunc(t *testing.T) {
+	wd := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(wd, "file.txt"), []byte("content"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(wd, "binary"), []byte{0xff, 0xfe}, 0600))
+	fileFunc := MakeFileFunc(wd)
+
+	testCases := []struct {
+		name    string
+		path    string
+		want    cty.Value
+		wantErr bool
+	}{
+		{name: "relative path", path: "file.txt", want: cty.StringVal("content")},
+		{name: "absolute path", path: filepath.Join(wd, "file.txt"), want: cty.StringVal("content")},
+		{name: "missing file", path: "missing.txt", wantErr: true},
+		{name: "invalid UTF-8", path: "binary", wantErr: true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := fileFunc.Call([]cty.Value{cty.StringVal(tt.path)})
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, output.RawEquals(tt.want), "expected output %#v, but got %#v", tt.want, output)
+		})
+	}
+}
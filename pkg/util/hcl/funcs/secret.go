@@ -0,0 +1,58 @@
+package funcs
+
+import (
+	"context"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/secret"
+)
+
+// MakeSecretFunc returns a function that resolves a secret reference, such
+// as "file:///run/secrets/key", using the given resolver.
+//
+// Returned values are marked, so values derived from them, e.g. by string
+// interpolation, are marked too. When a marked value is decoded, its string
+// values are added to the resolver as sensitive values.
+func MakeSecretFunc(r *secret.Resolver) function.Function {
+	return function.New(&function.Spec{
+		Description: `Returns the value of the secret for the given "provider://path" reference.`,
+		Params: []function.Parameter{
+			{
+				Name:        "ref",
+				Description: `The secret reference in the "provider://path" format.`,
+				Type:        cty.String,
+			},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			val, err := r.Resolve(context.Background(), args[0].AsString())
+			if err != nil {
+				return cty.NilVal, function.NewArgError(0, err)
+			}
+			return cty.StringVal(val).Mark(secretMark{resolver: r}), nil
+		},
+	})
+}
+
+// secretMark marks values returned by the secret function.
+type secretMark struct {
+	resolver *secret.Resolver
+}
+
+// HandleMarkedValue implements the hcl.MarkHandler interface.
+func (m secretMark) HandleMarkedValue(val cty.Value) {
+	if val.IsNull() || !val.IsKnown() {
+		return
+	}
+	switch {
+	case val.Type() == cty.String:
+		m.resolver.AddSensitive(val.AsString())
+	case val.CanIterateElements():
+		for it := val.ElementIterator(); it.Next(); {
+			_, v := it.Element()
+			m.HandleMarkedValue(v)
+		}
+	}
+}
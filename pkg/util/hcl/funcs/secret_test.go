@@ -0,0 +1,32 @@
+package funcs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/chronicleprotocol/oracle-suite/pkg/util/secret"
+)
+
+func TestMakeSecretFunc(t *testing.T) {
+	t.Setenv("SECRET_FUNC_TEST", "secret")
+	resolver := secret.NewResolver(map[string]secret.Provider{"env": secret.EnvProvider{}})
+	secretFunc := MakeSecretFunc(resolver)
+
+	val, err := secretFunc.Call([]cty.Value{cty.StringVal("env://SECRET_FUNC_TEST")})
+	require.NoError(t, err)
+	require.True(t, val.IsMarked())
+	unmarked, marks := val.Unmark()
+	assert.True(t, unmarked.RawEquals(cty.StringVal("secret")))
+
+	// Decoded values are reported to the mark.
+	for m := range marks {
+		m.(secretMark).HandleMarkedValue(cty.StringVal("Bearer secret"))
+	}
+	assert.Equal(t, []string{"Bearer secret"}, resolver.Sensitive())
+
+	_, err = secretFunc.Call([]cty.Value{cty.StringVal("env://SECRET_FUNC_MISSING")})
+	require.Error(t, err)
+}
@@ -0,0 +1,311 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package hcl
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// jsonSchemaDialect is the JSON Schema version used by Schema.JSONSchema.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema describes the blocks and attributes that can be decoded into
+// a struct using the Decode function.
+type Schema struct {
+	// Labels are the names of the block labels.
+	Labels []string
+
+	// Attributes are the attributes of the block.
+	Attributes []AttributeSchema
+
+	// Blocks are the nested blocks.
+	Blocks []BlockSchema
+
+	// Remain is true if the block may contain additional, unknown blocks.
+	// If RemainAttributes is also true, it may contain unknown attributes.
+	Remain           bool
+	RemainAttributes bool
+}
+
+// AttributeSchema describes an attribute.
+type AttributeSchema struct {
+	// Name is the attribute name.
+	Name string
+
+	// Type is the type of the attribute value, using the HCL type
+	// expression syntax, e.g. "string", "list(number)" or "any".
+	Type string
+
+	// Optional is true if the attribute may be omitted.
+	Optional bool
+}
+
+// BlockSchema describes a nested block.
+type BlockSchema struct {
+	// Name is the block type name.
+	Name string
+
+	// Optional is true if the block may be omitted. Blocks that can be
+	// repeated are always optional.
+	Optional bool
+
+	// Multiple is true if the block can be repeated.
+	Multiple bool
+
+	// Schema is the schema of the block body. It is nil if the block
+	// refers to one of its parent blocks, recursively.
+	Schema *Schema
+}
+
+// GetSchema returns the schema of the blocks and attributes that can be
+// decoded into the given value. The value must be a struct or a pointer to
+// a struct.
+func GetSchema(val any) (*Schema, hcl.Diagnostics) {
+	typ := reflect.TypeOf(val)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, hcl.Diagnostics{{
+			Severity: hcl.DiagError,
+			Summary:  "Schema error",
+			Detail:   "Value must be a struct or a pointer to a struct",
+		}}
+	}
+	return getSchema(typ, map[reflect.Type]bool{})
+}
+
+// getSchema returns the schema of the given struct type. The visiting map
+// contains types of the parent blocks, to detect recursive blocks.
+func getSchema(typ reflect.Type, visiting map[reflect.Type]bool) (*Schema, hcl.Diagnostics) {
+	meta, diags := getStructMeta(typ)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	visiting[typ] = true
+	defer delete(visiting, typ)
+	s := &Schema{}
+	for _, f := range meta.Labels {
+		s.Labels = append(s.Labels, f.Name)
+	}
+	for _, f := range meta.Attrs {
+		s.Attributes = append(s.Attributes, AttributeSchema{
+			Name:     f.Name,
+			Type:     typeExpr(f.Reflect.Type),
+			Optional: f.Optional,
+		})
+	}
+	for _, f := range meta.Blocks {
+		b := BlockSchema{
+			Name:     f.Name,
+			Optional: f.Optional || f.Multiple,
+			Multiple: f.Multiple,
+		}
+		if !visiting[f.StructReflect] {
+			if b.Schema, diags = getSchema(f.StructReflect, visiting); diags.HasErrors() {
+				return nil, diags
+			}
+		}
+		s.Blocks = append(s.Blocks, b)
+	}
+	if meta.Remain != nil {
+		s.Remain = true
+		s.RemainAttributes = !meta.Remain.Strict
+	}
+	return s, nil
+}
+
+var textUnmarshalerTy = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+var unmarshalerTy = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+
+// typeExpr returns the HCL type expression for values that can be decoded
+// into the given type.
+func typeExpr(typ reflect.Type) string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	ptr := reflect.PtrTo(typ)
+	switch {
+	case typ == bigIntTy || typ == bigFloatTy:
+		return "number"
+	case typ == ctyValTy || ptr.Implements(unmarshalerTy):
+		return "any"
+	case ptr.Implements(textUnmarshalerTy):
+		return "string"
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+		return fmt.Sprintf("list(%s)", typeExpr(typ.Elem()))
+	case reflect.Map:
+		return fmt.Sprintf("map(%s)", typeExpr(typ.Elem()))
+	case reflect.Struct:
+		return "object"
+	}
+	return "any"
+}
+
+// JSONSchema returns a JSON Schema that describes the configuration in the
+// HCL JSON syntax. Blocks with labels are represented as objects nested
+// once for every label, blocks that can be repeated as arrays of objects.
+func (s *Schema) JSONSchema(title string) map[string]any {
+	js := s.jsonBody()
+	js["$schema"] = jsonSchemaDialect
+	js["title"] = title
+	return js
+}
+
+// jsonBody returns a JSON Schema of the block body.
+func (s *Schema) jsonBody() map[string]any {
+	var (
+		props    = map[string]any{}
+		required []string
+	)
+	for _, a := range s.Attributes {
+		props[a.Name] = jsonType(a.Type)
+		if !a.Optional {
+			required = append(required, a.Name)
+		}
+	}
+	for _, b := range s.Blocks {
+		props[b.Name] = b.jsonBlock()
+		if !b.Optional {
+			required = append(required, b.Name)
+		}
+	}
+	js := map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": s.Remain,
+	}
+	if len(required) > 0 {
+		js["required"] = required
+	}
+	return js
+}
+
+// jsonBlock returns a JSON Schema of the block, including its labels.
+func (b BlockSchema) jsonBlock() map[string]any {
+	if b.Schema == nil {
+		return map[string]any{"type": "object"}
+	}
+	js := b.Schema.jsonBody()
+	if b.Multiple {
+		js = map[string]any{
+			"oneOf": []any{js, map[string]any{"type": "array", "items": js}},
+		}
+	}
+	for range b.Schema.Labels {
+		js = map[string]any{"type": "object", "additionalProperties": js}
+	}
+	return js
+}
+
+// jsonType returns a JSON Schema for the given HCL type expression.
+func jsonType(expr string) map[string]any {
+	switch {
+	case expr == "string":
+		return map[string]any{"type": "string"}
+	case expr == "number":
+		// Large numbers may be given as strings.
+		return map[string]any{"type": []string{"number", "string"}}
+	case expr == "bool":
+		return map[string]any{"type": "boolean"}
+	case expr == "object":
+		return map[string]any{"type": "object"}
+	case strings.HasPrefix(expr, "list(") && strings.HasSuffix(expr, ")"):
+		return map[string]any{"type": "array", "items": jsonType(expr[5 : len(expr)-1])}
+	case strings.HasPrefix(expr, "map(") && strings.HasSuffix(expr, ")"):
+		return map[string]any{"type": "object", "additionalProperties": jsonType(expr[4 : len(expr)-1])}
+	}
+	return map[string]any{}
+}
+
+// Markdown returns a reference of all blocks and attributes formatted as
+// Markdown.
+func (s *Schema) Markdown(title string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", title)
+	s.markdown(&b, "")
+	return b.String()
+}
+
+// markdown writes the reference of the block body, and recursively of its
+// nested blocks, to the builder. The path is the path of the block.
+func (s *Schema) markdown(b *strings.Builder, path string) {
+	if len(s.Attributes) > 0 {
+		b.WriteString("\n| Attribute | Type | Required |\n")
+		b.WriteString("|-----------|------|----------|\n")
+		for _, a := range s.Attributes {
+			fmt.Fprintf(b, "| `%s` | `%s` | %s |\n", a.Name, a.Type, yesNo(!a.Optional))
+		}
+	}
+	if s.Remain {
+		if s.RemainAttributes {
+			b.WriteString("\nAdditional blocks and attributes are allowed.\n")
+		} else {
+			b.WriteString("\nAdditional blocks are allowed and ignored.\n")
+		}
+	}
+	for _, blk := range s.Blocks {
+		name := blk.Name
+		if blk.Schema != nil {
+			for _, l := range blk.Schema.Labels {
+				name += fmt.Sprintf(" \"<%s>\"", l)
+			}
+		}
+		blockPath := name
+		if path != "" {
+			blockPath = path + " / " + name
+		}
+		fmt.Fprintf(b, "\n## `%s`\n\n", blockPath)
+		switch {
+		case blk.Multiple:
+			b.WriteString("Optional block, can be repeated.\n")
+		case blk.Optional:
+			b.WriteString("Optional block.\n")
+		default:
+			b.WriteString("Required block.\n")
+		}
+		if blk.Schema == nil {
+			b.WriteString("\nSame as the parent block.\n")
+			continue
+		}
+		blk.Schema.markdown(b, blockPath)
+	}
+}
+
+func yesNo(v bool) string {
+	if v {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,147 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package hcl
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type schemaConfig struct {
+	Name    string            `hcl:"name"`
+	Count   *big.Int          `hcl:"count,optional"`
+	Tags    []string          `hcl:"tags,optional"`
+	Weights map[string]uint32 `hcl:"weights,optional"`
+	Single  schemaBlock       `hcl:"single,block"`
+	Many    []schemaBlock     `hcl:"many,block"`
+
+	Remain  hcl.Body        `hcl:",remain,strict"`
+	Content hcl.BodyContent `hcl:",content"`
+}
+
+type schemaBlock struct {
+	Label string        `hcl:",label"`
+	Attr  bool          `hcl:"attr,optional"`
+	Recur []schemaBlock `hcl:"recur,block"`
+}
+
+func TestGetSchema(t *testing.T) {
+	s, diags := GetSchema(&schemaConfig{})
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	require.Len(t, s.Attributes, 4)
+	assert.Equal(t, AttributeSchema{Name: "name", Type: "string"}, s.Attributes[0])
+	assert.Equal(t, AttributeSchema{Name: "count", Type: "number", Optional: true}, s.Attributes[1])
+	assert.Equal(t, AttributeSchema{Name: "tags", Type: "list(string)", Optional: true}, s.Attributes[2])
+	assert.Equal(t, AttributeSchema{Name: "weights", Type: "map(number)", Optional: true}, s.Attributes[3])
+	assert.True(t, s.Remain)
+	assert.False(t, s.RemainAttributes)
+
+	require.Len(t, s.Blocks, 2)
+	assert.Equal(t, "single", s.Blocks[0].Name)
+	assert.False(t, s.Blocks[0].Optional)
+	assert.False(t, s.Blocks[0].Multiple)
+	assert.Equal(t, []string{"Label"}, s.Blocks[0].Schema.Labels)
+	assert.Equal(t, "many", s.Blocks[1].Name)
+	assert.True(t, s.Blocks[1].Optional)
+	assert.True(t, s.Blocks[1].Multiple)
+
+	// Recursive blocks do not have a schema.
+	require.Len(t, s.Blocks[0].Schema.Blocks, 1)
+	assert.Nil(t, s.Blocks[0].Schema.Blocks[0].Schema)
+}
+
+func TestSchema_JSONSchema(t *testing.T) {
+	s, diags := GetSchema(schemaConfig{})
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	b, err := json.Marshal(s.JSONSchema("test"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "test",
+		"type": "object",
+		"additionalProperties": true,
+		"required": ["name", "single"],
+		"properties": {
+			"name": {"type": "string"},
+			"count": {"type": ["number", "string"]},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"weights": {"type": "object", "additionalProperties": {"type": ["number", "string"]}},
+			"single": {
+				"type": "object",
+				"additionalProperties": {
+					"type": "object",
+					"additionalProperties": false,
+					"properties": {
+						"attr": {"type": "boolean"},
+						"recur": {"type": "object"}
+					}
+				}
+			},
+			"many": {
+				"type": "object",
+				"additionalProperties": {
+					"oneOf": [
+						{
+							"type": "object",
+							"additionalProperties": false,
+							"properties": {
+								"attr": {"type": "boolean"},
+								"recur": {"type": "object"}
+							}
+						},
+						{
+							"type": "array",
+							"items": {
+								"type": "object",
+								"additionalProperties": false,
+								"properties": {
+									"attr": {"type": "boolean"},
+									"recur": {"type": "object"}
+								}
+							}
+						}
+					]
+				}
+			}
+		}
+	}`, string(b))
+}
+
+func TestSchema_Markdown(t *testing.T) {
+	s, diags := GetSchema(schemaConfig{})
+	require.False(t, diags.HasErrors(), diags.Error())
+
+	md := s.Markdown("test")
+	assert.Contains(t, md, "# test\n")
+	assert.Contains(t, md, "| `name` | `string` | yes |\n")
+	assert.Contains(t, md, "| `tags` | `list(string)` | no |\n")
+	assert.Contains(t, md, "Additional blocks are allowed and ignored.\n")
+	assert.Contains(t, md, "## `single \"<Label>\"`\n\nRequired block.\n")
+	assert.Contains(t, md, "## `many \"<Label>\"`\n\nOptional block, can be repeated.\n")
+	assert.Contains(t, md, "## `single \"<Label>\" / recur`\n\nOptional block, can be repeated.\n\nSame as the parent block.\n")
+}
+
+func TestGetSchema_InvalidValue(t *testing.T) {
+	_, diags := GetSchema("foo")
+	assert.True(t, diags.HasErrors())
+}
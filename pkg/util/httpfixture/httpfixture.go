@@ -0,0 +1,409 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package httpfixture provides an HTTP transport that records HTTP responses
+// to a fixture directory and replays them later.
+//
+// Fixtures are identified by the request method, URL and body. Headers are
+// not taken into account. For JSON-RPC requests, the "id" fields are ignored,
+// and in the replay mode, the ids in the response are replaced with ids from
+// the current request, so the same fixture can be used regardless of the
+// order in which the requests are sent.
+//
+// Fixtures are stored as JSON files, one per request. Because the request URL
+// is stored in the fixture, API keys used in URLs will also be stored in the
+// fixture directory.
+package httpfixture
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Mode is the mode in which fixtures are used.
+type Mode int
+
+const (
+	// Record mode sends requests using the underlying transport and writes
+	// the responses to the fixture directory.
+	Record Mode = iota
+
+	// Replay mode serves responses from the fixture directory and never
+	// sends requests using the underlying transport.
+	Replay
+)
+
+// String implements the fmt.Stringer interface.
+func (m Mode) String() string {
+	switch m {
+	case Record:
+		return "record"
+	case Replay:
+		return "replay"
+	default:
+		return "unknown"
+	}
+}
+
+const bodyEncodingBase64 = "base64"
+
+// Fixtures is a directory with recorded HTTP responses.
+type Fixtures struct {
+	mode Mode
+	dir  string
+}
+
+// New creates a new Fixtures instance. In the record mode, the directory is
+// created if it does not exist. In the replay mode, the directory must exist.
+func New(mode Mode, dir string) (*Fixtures, error) {
+	switch mode {
+	case Record:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("httpfixture: unable to create fixture directory: %w", err)
+		}
+	case Replay:
+		s, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("httpfixture: unable to open fixture directory: %w", err)
+		}
+		if !s.IsDir() {
+			return nil, fmt.Errorf("httpfixture: %s is not a directory", dir)
+		}
+	default:
+		return nil, fmt.Errorf("httpfixture: unknown mode: %d", mode)
+	}
+	return &Fixtures{mode: mode, dir: dir}, nil
+}
+
+// Mode returns the mode in which fixtures are used.
+func (f *Fixtures) Mode() Mode {
+	return f.mode
+}
+
+// Transport returns an HTTP transport that records or replays responses,
+// depending on the mode. The name is used to separate fixtures of different
+// transports that may send identical requests, e.g. RPC clients for different
+// chains. If next is nil, http.DefaultTransport is used.
+func (f *Fixtures) Transport(name string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &roundTripper{
+		fixtures: f,
+		dir:      filepath.Join(f.dir, sanitizeName(name)),
+		next:     next,
+	}
+}
+
+type roundTripper struct {
+	fixtures *Fixtures
+	dir      string
+	next     http.RoundTripper
+}
+
+type fixture struct {
+	Request  fixtureRequest  `json:"request"`
+	Response fixtureResponse `json:"response"`
+}
+
+type fixtureRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type fixtureResponse struct {
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (r *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(r.dir, fixtureKey(req.Method, req.URL.String(), body)+".json")
+	switch r.fixtures.mode {
+	case Record:
+		return r.record(req, body, path)
+	case Replay:
+		return r.replay(req, body, path)
+	default:
+		return nil, fmt.Errorf("httpfixture: unknown mode: %d", r.fixtures.mode)
+	}
+}
+
+func (r *roundTripper) record(req *http.Request, body []byte, path string) (*http.Response, error) {
+	res, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+	fx := fixture{
+		Request: fixtureRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Body:   string(body),
+		},
+		Response: fixtureResponse{
+			StatusCode: res.StatusCode,
+			Header:     res.Header.Clone(),
+		},
+	}
+	fx.Response.Header.Del("Content-Length")
+	if utf8.Valid(resBody) {
+		fx.Response.Body = string(resBody)
+	} else {
+		fx.Response.Body = base64.StdEncoding.EncodeToString(resBody)
+		fx.Response.BodyEncoding = bodyEncodingBase64
+	}
+	if err := writeFixture(path, fx); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *roundTripper) replay(req *http.Request, body []byte, path string) (*http.Response, error) {
+	fx, err := readFixture(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("httpfixture: no fixture for %s %s", req.Method, req.URL.String())
+		}
+		return nil, err
+	}
+	resBody := []byte(fx.Response.Body)
+	if fx.Response.BodyEncoding == bodyEncodingBase64 {
+		resBody, err = base64.StdEncoding.DecodeString(fx.Response.Body)
+		if err != nil {
+			return nil, fmt.Errorf("httpfixture: invalid fixture %s: %w", path, err)
+		}
+	}
+	resBody = replaceRPCIDs(resBody, []byte(fx.Request.Body), body)
+	header := fx.Response.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(fx.Response.StatusCode) + " " + http.StatusText(fx.Response.StatusCode),
+		StatusCode:    fx.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(resBody)),
+		ContentLength: int64(len(resBody)),
+		Request:       req,
+	}, nil
+}
+
+// readRequestBody reads the request body and replaces it with a copy, so it
+// can be read again by the underlying transport.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func readFixture(path string) (fixture, error) {
+	var fx fixture
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fx, err
+	}
+	if err := json.Unmarshal(b, &fx); err != nil {
+		return fx, fmt.Errorf("httpfixture: invalid fixture %s: %w", path, err)
+	}
+	return fx, nil
+}
+
+// writeFixture writes the fixture to a temporary file first and then renames
+// it, so concurrent requests never read a partially written fixture.
+func writeFixture(path string, fx fixture) error {
+	b, err := json.MarshalIndent(fx, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("httpfixture: unable to create fixture directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".fixture-*")
+	if err != nil {
+		return fmt.Errorf("httpfixture: unable to write fixture: %w", err)
+	}
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("httpfixture: unable to write fixture: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("httpfixture: unable to write fixture: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("httpfixture: unable to write fixture: %w", err)
+	}
+	return nil
+}
+
+// fixtureKey returns a unique key for the request. JSON-RPC ids are removed
+// from the body before the key is calculated.
+func fixtureKey(method, url string, body []byte) string {
+	if normalized, _, ok := splitRPCIDs(body); ok {
+		body = normalized
+	}
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(url))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// splitRPCIDs removes the "id" fields from a JSON-RPC request or a batch of
+// requests. It returns the normalized body and the removed ids in the order
+// in which they appear in the body. If the body is not a JSON-RPC request,
+// ok is false.
+func splitRPCIDs(body []byte) (normalized []byte, ids []json.RawMessage, ok bool) {
+	msgs, batch, ok := parseRPCMessages(body)
+	if !ok {
+		return nil, nil, false
+	}
+	for _, msg := range msgs {
+		if _, ok := msg["jsonrpc"]; !ok {
+			return nil, nil, false
+		}
+		ids = append(ids, msg["id"])
+		delete(msg, "id")
+	}
+	if batch {
+		normalized, err := json.Marshal(msgs)
+		return normalized, ids, err == nil
+	}
+	normalized, err := json.Marshal(msgs[0])
+	return normalized, ids, err == nil
+}
+
+// replaceRPCIDs replaces the ids in the recorded JSON-RPC response with ids
+// from the current request. Ids are matched by their position in the recorded
+// and current requests. If any of the bodies is not a JSON-RPC message, the
+// response is returned unchanged.
+func replaceRPCIDs(res, recordedReq, currentReq []byte) []byte {
+	_, recordedIDs, ok := splitRPCIDs(recordedReq)
+	if !ok {
+		return res
+	}
+	_, currentIDs, ok := splitRPCIDs(currentReq)
+	if !ok || len(recordedIDs) != len(currentIDs) {
+		return res
+	}
+	ids := make(map[string]json.RawMessage, len(recordedIDs))
+	for i, id := range recordedIDs {
+		if id != nil {
+			ids[compactJSON(id)] = currentIDs[i]
+		}
+	}
+	msgs, batch, ok := parseRPCMessages(res)
+	if !ok {
+		return res
+	}
+	for _, msg := range msgs {
+		if id, ok := ids[compactJSON(msg["id"])]; ok {
+			msg["id"] = id
+		}
+	}
+	var (
+		b   []byte
+		err error
+	)
+	if batch {
+		b, err = json.Marshal(msgs)
+	} else {
+		b, err = json.Marshal(msgs[0])
+	}
+	if err != nil {
+		return res
+	}
+	return b
+}
+
+// parseRPCMessages parses a single JSON object or an array of JSON objects.
+func parseRPCMessages(body []byte) (msgs []map[string]json.RawMessage, batch bool, ok bool) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, false, false
+	}
+	switch body[0] {
+	case '{':
+		var msg map[string]json.RawMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return nil, false, false
+		}
+		return []map[string]json.RawMessage{msg}, false, true
+	case '[':
+		if err := json.Unmarshal(body, &msgs); err != nil || len(msgs) == 0 {
+			return nil, false, false
+		}
+		return msgs, true, true
+	default:
+		return nil, false, false
+	}
+}
+
+func compactJSON(b []byte) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, b); err != nil {
+		return string(b)
+	}
+	return buf.String()
+}
+
+var nameRegexp = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+func sanitizeName(name string) string {
+	name = nameRegexp.ReplaceAllString(name, "_")
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
@@ -0,0 +1,126 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package httpfixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordReplay(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("hello " + r.URL.Query().Get("name")))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	rec, err := New(Record, dir)
+	require.NoError(t, err)
+	recClient := &http.Client{Transport: rec.Transport("test", nil)}
+
+	res, err := recClient.Get(srv.URL + "?name=foo")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	assert.Equal(t, "hello foo", string(body))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	rep, err := New(Replay, dir)
+	require.NoError(t, err)
+	repClient := &http.Client{Transport: rep.Transport("test", nil)}
+
+	res, err = repClient.Get(srv.URL + "?name=foo")
+	require.NoError(t, err)
+	body, _ = io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusTeapot, res.StatusCode)
+	assert.Equal(t, "text/plain", res.Header.Get("Content-Type"))
+	assert.Equal(t, "hello foo", string(body))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Missing fixture.
+	_, err = repClient.Get(srv.URL + "?name=bar")
+	assert.Error(t, err)
+
+	// Fixtures are separated by the transport name.
+	_, err = (&http.Client{Transport: rep.Transport("other", nil)}).Get(srv.URL + "?name=foo")
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRecordReplay_JSONRPC(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req []map[string]json.RawMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		var res []map[string]json.RawMessage
+		for _, m := range req {
+			res = append(res, map[string]json.RawMessage{
+				"jsonrpc": json.RawMessage(`"2.0"`),
+				"id":      m["id"],
+				"result":  m["method"],
+			})
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	rec, err := New(Record, dir)
+	require.NoError(t, err)
+	rep, err := New(Replay, dir)
+	require.NoError(t, err)
+
+	post := func(rt http.RoundTripper, body string) []map[string]json.RawMessage {
+		res, err := (&http.Client{Transport: rt}).Post(srv.URL, "application/json", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		var msgs []map[string]json.RawMessage
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&msgs))
+		return msgs
+	}
+
+	post(rec.Transport("rpc", nil), `[{"jsonrpc":"2.0","id":1,"method":"a"},{"jsonrpc":"2.0","id":2,"method":"b"}]`)
+	srv.Close()
+
+	// Ids differ from the recorded ones, but the fixture must still match
+	// and the response must use the current ids.
+	msgs := post(rep.Transport("rpc", nil), `[{"jsonrpc":"2.0","id":7,"method":"a"},{"jsonrpc":"2.0","id":8,"method":"b"}]`)
+	require.Len(t, msgs, 2)
+	assert.JSONEq(t, `7`, string(msgs[0]["id"]))
+	assert.JSONEq(t, `"a"`, string(msgs[0]["result"]))
+	assert.JSONEq(t, `8`, string(msgs[1]["id"]))
+	assert.JSONEq(t, `"b"`, string(msgs[1]["result"]))
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Replay, t.TempDir()+"/missing")
+	assert.Error(t, err)
+
+	_, err = New(Mode(42), t.TempDir())
+	assert.Error(t, err)
+}
@@ -0,0 +1,135 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package secret resolves secret references, such as "file:///run/key" or
+// "vault://secret/data/app#api_key", to their values.
+//
+// Every resolved value, and every value derived from it that is reported
+// to the Resolver, is remembered so it can later be redacted from any
+// output, e.g. from a rendered config file.
+package secret
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Provider provides secret values for the given path. The path is the part
+// of the secret reference after the "scheme://" prefix.
+type Provider interface {
+	Secret(ctx context.Context, path string) (string, error)
+}
+
+// Resolver resolves secret references using providers registered for
+// the reference scheme.
+type Resolver struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+	resolved  map[string]string   // reference -> value
+	sensitive map[string]struct{} // values derived from secrets
+}
+
+// NewResolver returns a new Resolver that uses the given providers. The
+// keys of the providers map are URI schemes, e.g. "file".
+func NewResolver(providers map[string]Provider) *Resolver {
+	return &Resolver{
+		providers: providers,
+		resolved:  make(map[string]string),
+		sensitive: make(map[string]struct{}),
+	}
+}
+
+// Resolve returns the value of the secret for the given reference. The
+// reference must be in the "scheme://path" format.
+//
+// Returned errors never contain the secret value.
+func (r *Resolver) Resolve(ctx context.Context, ref string) (string, error) {
+	scheme, path, ok := strings.Cut(ref, "://")
+	if !ok || scheme == "" || path == "" {
+		return "", fmt.Errorf("invalid secret reference %q, expected format is provider://path", ref)
+	}
+	p, ok := r.providers[scheme]
+	if !ok {
+		return "", fmt.Errorf("unknown secret provider %q", scheme)
+	}
+	val, err := p.Secret(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve secret %q: %w", ref, err)
+	}
+	r.mu.Lock()
+	r.resolved[ref] = val
+	r.mu.Unlock()
+	return val, nil
+}
+
+// Resolved returns all secrets resolved so far, keyed by their references.
+func (r *Resolver) Resolved() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	resolved := make(map[string]string, len(r.resolved))
+	for ref, val := range r.resolved {
+		resolved[ref] = val
+	}
+	return resolved
+}
+
+// AddSensitive remembers a value that contains resolved secrets, e.g.
+// a string that interpolates a secret, so it can be redacted from outputs.
+func (r *Resolver) AddSensitive(val string) {
+	r.mu.Lock()
+	r.sensitive[val] = struct{}{}
+	r.mu.Unlock()
+}
+
+// Sensitive returns all values added by AddSensitive.
+func (r *Resolver) Sensitive() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sensitive := make([]string, 0, len(r.sensitive))
+	for val := range r.sensitive {
+		sensitive = append(sensitive, val)
+	}
+	return sensitive
+}
+
+// FileProvider reads secrets from files. The path is a path to the file.
+// Trailing new line characters are removed from the file content.
+type FileProvider struct{}
+
+// Secret implements the Provider interface.
+func (FileProvider) Secret(_ context.Context, path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// EnvProvider reads secrets from environment variables. The path is
+// the variable name. Unlike the env() config function, an unset variable
+// is an error.
+type EnvProvider struct{}
+
+// Secret implements the Provider interface.
+func (EnvProvider) Secret(_ context.Context, path string) (string, error) {
+	val, ok := os.LookupEnv(path)
+	if !ok {
+		return "", fmt.Errorf("environment variable %q is not set", path)
+	}
+	return val, nil
+}
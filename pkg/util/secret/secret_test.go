@@ -0,0 +1,66 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package secret
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(path, []byte("file-secret\n"), 0600))
+	t.Setenv("SECRET_TEST_VAR", "env-secret")
+
+	r := NewResolver(map[string]Provider{
+		"file": FileProvider{},
+		"env":  EnvProvider{},
+	})
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{ref: "file://" + path, want: "file-secret"},
+		{ref: "env://SECRET_TEST_VAR", want: "env-secret"},
+		{ref: "env://SECRET_TEST_MISSING", wantErr: `environment variable "SECRET_TEST_MISSING" is not set`},
+		{ref: "file://" + path + ".missing", wantErr: "no such file or directory"},
+		{ref: "foo://bar", wantErr: `unknown secret provider "foo"`},
+		{ref: "SECRET_TEST_VAR", wantErr: "invalid secret reference"},
+		{ref: "env://", wantErr: "invalid secret reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			val, err := r.Resolve(context.Background(), tt.ref)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, val)
+		})
+	}
+	assert.Equal(t, map[string]string{
+		"file://" + path:        "file-secret",
+		"env://SECRET_TEST_VAR": "env-secret",
+	}, r.Resolved())
+}
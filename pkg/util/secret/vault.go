@@ -0,0 +1,134 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const vaultDefaultTimeout = 10 * time.Second
+
+// VaultProvider reads secrets using the HashiCorp Vault HTTP API. Both the
+// KV version 1 and version 2 secret engines are supported.
+//
+// The path is in the "mount/path#key" format, where the part before "#"
+// is the API path (without the "/v1/" prefix) and the key is the name of
+// the field in the secret data, e.g. "secret/data/app#api_key".
+type VaultProvider struct {
+	// Address is the Vault server address. If empty, the VAULT_ADDR
+	// environment variable is used.
+	Address string
+
+	// Token is the Vault token. If empty, the VAULT_TOKEN environment
+	// variable is used.
+	Token string
+
+	// Namespace is the Vault Enterprise namespace. If empty, the
+	// VAULT_NAMESPACE environment variable is used.
+	Namespace string
+
+	// Client is the HTTP client used to query the server. If nil, a client
+	// with a 10-second timeout is used.
+	Client *http.Client
+}
+
+type vaultResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []string                   `json:"errors"`
+}
+
+// Secret implements the Provider interface.
+func (v *VaultProvider) Secret(ctx context.Context, path string) (string, error) {
+	path, key, ok := strings.Cut(path, "#")
+	if !ok || path == "" || key == "" {
+		return "", errors.New("vault secret path must be in the mount/path#key format")
+	}
+	addr := v.Address
+	if addr == "" {
+		addr = os.Getenv("VAULT_ADDR")
+	}
+	if addr == "" {
+		return "", errors.New("vault address is not set, use the VAULT_ADDR environment variable")
+	}
+	token := v.Token
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	namespace := v.Namespace
+	if namespace == "" {
+		namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	client := v.Client
+	if client == nil {
+		client = &http.Client{Timeout: vaultDefaultTimeout}
+	}
+
+	url := strings.TrimRight(addr, "/") + "/v1/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	var vr vaultResponse
+	if err := json.NewDecoder(res.Body).Decode(&vr); err != nil && res.StatusCode == http.StatusOK {
+		return "", fmt.Errorf("invalid vault response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		if len(vr.Errors) > 0 {
+			return "", fmt.Errorf("vault returned status %d: %s", res.StatusCode, strings.Join(vr.Errors, ", "))
+		}
+		return "", fmt.Errorf("vault returned status %d", res.StatusCode)
+	}
+
+	// The KV version 2 engine wraps the secret data in another "data" field,
+	// next to the "metadata" field.
+	data := vr.Data
+	if raw, ok := data["data"]; ok {
+		if _, ok := data["metadata"]; ok {
+			data = nil
+			if err := json.Unmarshal(raw, &data); err != nil {
+				return "", errors.New("invalid vault response: secret data is not an object")
+			}
+		}
+	}
+	raw, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found in vault secret", key)
+	}
+	var val string
+	if err := json.Unmarshal(raw, &val); err != nil {
+		return "", fmt.Errorf("value of key %q in vault secret is not a string", key)
+	}
+	return val, nil
+}
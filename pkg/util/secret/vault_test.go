@@ -0,0 +1,91 @@
+//  Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+//  This program is free software: you can redistribute it and/or modify
+//  it under the terms of the GNU Affero General Public License as
+//  published by the Free Software Foundation, either version 3 of the
+//  License, or (at your option) any later version.
+//
+//  This program is distributed in the hope that it will be useful,
+//  but WITHOUT ANY WARRANTY; without even the implied warranty of
+//  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+//  GNU Affero General Public License for more details.
+//
+//  You should have received a copy of the GNU Affero General Public License
+//  along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package secret
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVaultProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/app": // KV version 2
+			_, _ = w.Write([]byte(`{"data":{"data":{"api_key":"kv2-secret"},"metadata":{"version":1}}}`))
+		case "/v1/kv/app": // KV version 1
+			_, _ = w.Write([]byte(`{"data":{"api_key":"kv1-secret","port":8080}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path    string
+		token   string
+		want    string
+		wantErr string
+	}{
+		{path: "secret/data/app#api_key", token: "token", want: "kv2-secret"},
+		{path: "kv/app#api_key", token: "token", want: "kv1-secret"},
+		{path: "kv/app#missing", token: "token", wantErr: `key "missing" not found in vault secret`},
+		{path: "kv/app#port", token: "token", wantErr: `value of key "port" in vault secret is not a string`},
+		{path: "kv/missing#api_key", token: "token", wantErr: "vault returned status 404"},
+		{path: "kv/app#api_key", token: "invalid", wantErr: "vault returned status 403: permission denied"},
+		{path: "kv/app", token: "token", wantErr: "mount/path#key format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			p := &VaultProvider{Address: srv.URL, Token: tt.token}
+			val, err := p.Secret(context.Background(), tt.path)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, val)
+		})
+	}
+}
+
+func TestVaultProvider_Env(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "env-token", r.Header.Get("X-Vault-Token"))
+		assert.Equal(t, "ns", r.Header.Get("X-Vault-Namespace"))
+		_, _ = w.Write([]byte(`{"data":{"key":"value"}}`))
+	}))
+	defer srv.Close()
+
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "env-token")
+	t.Setenv("VAULT_NAMESPACE", "ns")
+
+	val, err := (&VaultProvider{}).Secret(context.Background(), "kv/app#key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", val)
+}